@@ -1,21 +1,55 @@
 package baja_test
 
 import (
+	"io/ioutil"
 	"os"
+	"path/filepath"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
-	. "github.com/yeo/baja"
+	"github.com/yeo/baja"
+	. "github.com/yeo/baja/cleaner"
+	"github.com/yeo/baja/utils"
 )
 
 var _ = Describe("Clean", func() {
+	var cwd, dir string
+
+	BeforeEach(func() {
+		cwd, _ = os.Getwd()
+		dir, _ = ioutil.TempDir("", "baja-clean")
+		Expect(os.Chdir(dir)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		os.Chdir(cwd)
+		os.RemoveAll(dir)
+	})
+
 	It("Delete public directory", func() {
 		os.MkdirAll("public", os.ModePerm)
 
-		Clean()
+		Clean(nil)
 
 		_, err := os.Stat("./public")
 		Expect(err).ToNot(Equal(nil))
 	})
+
+	It("deletes the configured preview directory", func() {
+		os.MkdirAll(filepath.Join("tmp", "drafts"), os.ModePerm)
+
+		Clean(&baja.Site{Config: &baja.Config{PreviewDir: "tmp/drafts"}})
+
+		Expect(utils.HasFile("tmp/drafts")).To(Equal(false))
+		Expect(utils.HasFile("tmp")).To(Equal(true))
+	})
+
+	It("leaves an unsafe preview directory alone", func() {
+		os.MkdirAll("content", os.ModePerm)
+
+		Clean(&baja.Site{Config: &baja.Config{PreviewDir: "content"}})
+
+		Expect(utils.HasFile("content")).To(Equal(true))
+	})
 })
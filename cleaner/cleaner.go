@@ -19,12 +19,30 @@ func (cmd *Command) Help() string {
 }
 
 func (cmd *Command) Run(site *baja.Site, args []string) int {
-	cleans := []string{"public"}
+	Clean(site)
+
+	return 0
+}
+
+// Clean removes generated output: public, .baja and the drafts preview directory of config
+// previewDir. A previewDir config validation rejects is left alone
+func Clean(site *baja.Site) {
+	cleans := []string{"public", ".baja"}
+
+	preview := baja.DefaultPreviewDir
+	if site != nil && site.Config != nil {
+		preview = site.Config.PreviewPath()
+		if err := site.Config.ValidateWorkDir("previewDir", preview); err != nil {
+			fmt.Println(err)
+			preview = ""
+		}
+	}
+	if preview != "" {
+		cleans = append(cleans, preview)
+	}
 
 	for _, d := range cleans {
 		fmt.Println("Clean", d)
-		os.RemoveAll(fmt.Sprintf("./%s", d))
+		os.RemoveAll(d)
 	}
-
-	return 0
 }
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/fatih/color"
+
+	"github.com/yeo/baja"
+)
+
+// completionDirs maps a completion kind to the directory whose sub directories are its values.
+// A positional argument or a flag with the same name as a kind is completed with those values
+var completionDirs = map[string]string{
+	"section": "content",
+	"theme":   "themes",
+}
+
+type CompletionCommand struct {
+	registries map[string]CmdRunner
+}
+
+func (cmd *CompletionCommand) ArgDesc() string {
+	return "bash|zsh|fish"
+}
+
+func (cmd *CompletionCommand) Help() string {
+	return "Print shell completion script. Eg: source <(baja completion bash)"
+}
+
+func (cmd *CompletionCommand) CompleteArgs() []string {
+	return []string{"shell"}
+}
+
+func (cmd *CompletionCommand) Run(site *baja.Site, args []string) int {
+	if len(args) < 1 {
+		color.Red("Usage: baja completion bash|zsh|fish")
+		return 1
+	}
+
+	specs := cmd.specs()
+	switch args[0] {
+	case "bash":
+		writeBash(os.Stdout, specs)
+	case "zsh":
+		writeZsh(os.Stdout, specs)
+	case "fish":
+		writeFish(os.Stdout, specs)
+	default:
+		color.Red("Unsupported shell %s", args[0])
+		return 1
+	}
+
+	return 0
+}
+
+// flagSpec describes a command flag for completion
+type flagSpec struct {
+	Name   string
+	Usage  string
+	IsBool bool
+	Kind   string
+}
+
+// commandSpec describes a command for completion. It's built from the registry so the scripts
+// always match the flags and arguments the commands actually accept
+type commandSpec struct {
+	Name  string
+	Help  string
+	Flags []flagSpec
+	Args  []string
+}
+
+func (cmd *CompletionCommand) specs() []commandSpec {
+	specs := []commandSpec{}
+
+	for _, name := range commandNames(cmd.registries) {
+		runner := cmd.registries[name]
+		spec := commandSpec{Name: name, Help: runner.Help()}
+
+		flagSet(name, runner).VisitAll(func(f *flag.Flag) {
+			fspec := flagSpec{Name: f.Name, Usage: f.Usage}
+			if b, ok := f.Value.(interface{ IsBoolFlag() bool }); ok {
+				fspec.IsBool = b.IsBoolFlag()
+			}
+			if _, ok := completionDirs[f.Name]; ok {
+				fspec.Kind = f.Name
+			}
+			spec.Flags = append(spec.Flags, fspec)
+		})
+
+		if c, ok := runner.(ArgCompleter); ok {
+			spec.Args = c.CompleteArgs()
+		}
+
+		specs = append(specs, spec)
+	}
+
+	return specs
+}
+
+// kindWords returns a static word list for kinds which aren't backed by a directory
+func kindWords(kind string) string {
+	switch kind {
+	case "shell":
+		return "bash zsh fish"
+	case "new":
+		return "section"
+	}
+
+	return ""
+}
+
+func writeBash(w io.Writer, specs []commandSpec) {
+	names := []string{}
+	for _, s := range specs {
+		names = append(names, s.Name)
+	}
+
+	fmt.Fprintln(w, "# bash completion for baja")
+	fmt.Fprintln(w, "_baja_dirs() {")
+	fmt.Fprintln(w, "    [ -d \"$1\" ] && (cd \"$1\" && ls -d */ 2>/dev/null | tr -d /)")
+	fmt.Fprintln(w, "}")
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "_baja() {")
+	fmt.Fprintln(w, "    local cur pos i")
+	fmt.Fprintln(w, "    cur=\"${COMP_WORDS[COMP_CWORD]}\"")
+	fmt.Fprintln(w, "    COMPREPLY=()")
+	fmt.Fprintln(w, "    if [ \"$COMP_CWORD\" -eq 1 ]; then")
+	fmt.Fprintf(w, "        COMPREPLY=( $(compgen -W %q -- \"$cur\") )\n", strings.Join(names, " "))
+	fmt.Fprintln(w, "        return")
+	fmt.Fprintln(w, "    fi")
+	fmt.Fprintln(w, "    pos=0")
+	fmt.Fprintln(w, "    for ((i = 2; i < COMP_CWORD; i++)); do")
+	fmt.Fprintln(w, "        [[ \"${COMP_WORDS[i]}\" != -* ]] && pos=$((pos + 1))")
+	fmt.Fprintln(w, "    done")
+	fmt.Fprintln(w, "    case \"${COMP_WORDS[1]}\" in")
+
+	for _, s := range specs {
+		fmt.Fprintf(w, "    %s)\n", s.Name)
+
+		flags := []string{}
+		for _, f := range s.Flags {
+			flags = append(flags, "--"+f.Name)
+			if f.Kind != "" {
+				fmt.Fprintf(w, "        if [ \"${COMP_WORDS[COMP_CWORD-1]}\" = \"--%s\" ]; then\n", f.Name)
+				fmt.Fprintf(w, "            COMPREPLY=( $(compgen -W \"$(_baja_dirs %s)\" -- \"$cur\") )\n", completionDirs[f.Kind])
+				fmt.Fprintln(w, "            return")
+				fmt.Fprintln(w, "        fi")
+			}
+		}
+		if len(flags) > 0 {
+			fmt.Fprintln(w, "        if [[ \"$cur\" == -* ]]; then")
+			fmt.Fprintf(w, "            COMPREPLY=( $(compgen -W %q -- \"$cur\") )\n", strings.Join(flags, " "))
+			fmt.Fprintln(w, "            return")
+			fmt.Fprintln(w, "        fi")
+		}
+
+		if len(s.Args) > 0 {
+			fmt.Fprintln(w, "        case $pos in")
+			for i, kind := range s.Args {
+				if dir, ok := completionDirs[kind]; ok {
+					fmt.Fprintf(w, "        %d) COMPREPLY=( $(compgen -W \"$(_baja_dirs %s)\" -- \"$cur\") ) ;;\n", i, dir)
+				} else if words := kindWords(kind); words != "" {
+					fmt.Fprintf(w, "        %d) COMPREPLY=( $(compgen -W %q -- \"$cur\") ) ;;\n", i, words)
+				}
+			}
+			fmt.Fprintln(w, "        esac")
+		}
+		fmt.Fprintln(w, "        ;;")
+	}
+
+	fmt.Fprintln(w, "    esac")
+	fmt.Fprintln(w, "}")
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "complete -F _baja baja")
+}
+
+// zshEscape escapes a description for use inside a single quoted _arguments/_describe spec
+func zshEscape(s string) string {
+	r := strings.NewReplacer("'", "'\\''", ":", "\\:", "[", "\\[", "]", "\\]")
+	return r.Replace(s)
+}
+
+func writeZsh(w io.Writer, specs []commandSpec) {
+	fmt.Fprintln(w, "#compdef baja")
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "_baja_dirs() {")
+	fmt.Fprintln(w, "    local -a dirs")
+	fmt.Fprintln(w, "    dirs=( $1/*(N/:t) )")
+	fmt.Fprintln(w, "    compadd -a dirs")
+	fmt.Fprintln(w, "}")
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "_baja() {")
+	fmt.Fprintln(w, "    local -a commands")
+	fmt.Fprintln(w, "    commands=(")
+	for _, s := range specs {
+		fmt.Fprintf(w, "        '%s:%s'\n", s.Name, zshEscape(s.Help))
+	}
+	fmt.Fprintln(w, "    )")
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "    if (( CURRENT == 2 )); then")
+	fmt.Fprintln(w, "        _describe 'command' commands")
+	fmt.Fprintln(w, "        return")
+	fmt.Fprintln(w, "    fi")
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "    local cmd=$words[2]")
+	fmt.Fprintln(w, "    shift words")
+	fmt.Fprintln(w, "    (( CURRENT-- ))")
+	fmt.Fprintln(w, "    case $cmd in")
+
+	for _, s := range specs {
+		if len(s.Flags) == 0 && len(s.Args) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(w, "    %s)\n", s.Name)
+		fmt.Fprint(w, "        _arguments")
+		for _, f := range s.Flags {
+			if f.IsBool {
+				fmt.Fprintf(w, " \\\n            '--%s[%s]'", f.Name, zshEscape(f.Usage))
+				continue
+			}
+
+			action := ""
+			if f.Kind != "" {
+				action = "_baja_dirs " + completionDirs[f.Kind]
+			}
+			fmt.Fprintf(w, " \\\n            '--%s=[%s]:%s:%s'", f.Name, zshEscape(f.Usage), f.Name, action)
+		}
+		for i, kind := range s.Args {
+			action := ""
+			if dir, ok := completionDirs[kind]; ok {
+				action = "_baja_dirs " + dir
+			} else if words := kindWords(kind); words != "" {
+				action = "(" + words + ")"
+			}
+			fmt.Fprintf(w, " \\\n            '%d:%s:%s'", i+1, kind, action)
+		}
+		fmt.Fprintln(w, "")
+		fmt.Fprintln(w, "        ;;")
+	}
+
+	fmt.Fprintln(w, "    esac")
+	fmt.Fprintln(w, "}")
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "_baja \"$@\"")
+}
+
+// fishEscape escapes a description for use inside a single quoted fish string
+func fishEscape(s string) string {
+	r := strings.NewReplacer("\\", "\\\\", "'", "\\'")
+	return r.Replace(s)
+}
+
+func writeFish(w io.Writer, specs []commandSpec) {
+	fmt.Fprintln(w, "# fish completion for baja")
+	fmt.Fprintln(w, "function __baja_dirs")
+	fmt.Fprintln(w, "    for d in $argv[1]/*/")
+	fmt.Fprintln(w, "        basename $d")
+	fmt.Fprintln(w, "    end")
+	fmt.Fprintln(w, "end")
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "complete -c baja -f")
+
+	for _, s := range specs {
+		fmt.Fprintf(w, "complete -c baja -n __fish_use_subcommand -a %s -d '%s'\n", s.Name, fishEscape(s.Help))
+	}
+
+	for _, s := range specs {
+		cond := "__fish_seen_subcommand_from " + s.Name
+		for _, f := range s.Flags {
+			line := fmt.Sprintf("complete -c baja -n '%s' -l %s -d '%s'", cond, f.Name, fishEscape(f.Usage))
+			if !f.IsBool {
+				line += " -r"
+			}
+			if f.Kind != "" {
+				line += fmt.Sprintf(" -a '(__baja_dirs %s)'", completionDirs[f.Kind])
+			}
+			fmt.Fprintln(w, line)
+		}
+
+		// fish has no cheap way to know the argument position, so offer the first argument's values
+		if len(s.Args) > 0 {
+			kind := s.Args[0]
+			if dir, ok := completionDirs[kind]; ok {
+				fmt.Fprintf(w, "complete -c baja -n '%s' -a '(__baja_dirs %s)'\n", cond, dir)
+			} else if words := kindWords(kind); words != "" {
+				fmt.Fprintf(w, "complete -c baja -n '%s' -a '%s'\n", cond, words)
+			}
+		}
+	}
+}
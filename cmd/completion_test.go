@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/yeo/baja"
+)
+
+// publishCommand is a command with a flag of each kind completion knows about
+type publishCommand struct{}
+
+func (cmd *publishCommand) Run(site *baja.Site, args []string) int { return 0 }
+func (cmd *publishCommand) ArgDesc() string                        { return "section" }
+func (cmd *publishCommand) Help() string                           { return "Publish a section, it's quick" }
+func (cmd *publishCommand) CompleteArgs() []string                 { return []string{"section"} }
+
+func (cmd *publishCommand) Flags(fs *flag.FlagSet) {
+	fs.String("target", "s3", "where to publish")
+	fs.Bool("dry", false, "print what would be published")
+	fs.String("theme", "", "theme to publish with")
+}
+
+var _ = Describe("Completion", func() {
+	var specs []commandSpec
+
+	BeforeEach(func() {
+		registries := map[string]CmdRunner{"publish": &publishCommand{}}
+		registries["completion"] = &CompletionCommand{registries: registries}
+		specs = registries["completion"].(*CompletionCommand).specs()
+	})
+
+	It("completes the commands, their flags and arguments in bash", func() {
+		var b bytes.Buffer
+		writeBash(&b, specs)
+
+		Expect(b.String()).To(ContainSubstring(`compgen -W "completion publish"`))
+		Expect(b.String()).To(ContainSubstring("    publish)\n"))
+		Expect(b.String()).To(ContainSubstring("--dry"))
+		Expect(b.String()).To(ContainSubstring("--target"))
+		Expect(b.String()).To(ContainSubstring(`if [ "${COMP_WORDS[COMP_CWORD-1]}" = "--theme" ]; then`))
+		Expect(b.String()).To(ContainSubstring(`0) COMPREPLY=( $(compgen -W "$(_baja_dirs content)" -- "$cur") ) ;;`))
+		Expect(b.String()).To(ContainSubstring(`0) COMPREPLY=( $(compgen -W "bash zsh fish" -- "$cur") ) ;;`))
+	})
+
+	It("completes the commands, their flags and arguments in zsh", func() {
+		var b bytes.Buffer
+		writeZsh(&b, specs)
+
+		Expect(b.String()).To(ContainSubstring(`'publish:Publish a section, it'\''s quick'`))
+		Expect(b.String()).To(ContainSubstring(`'--target=[where to publish]:target:'`))
+		Expect(b.String()).To(ContainSubstring(`'--dry[print what would be published]'`))
+		Expect(b.String()).To(ContainSubstring(`'--theme=[theme to publish with]:theme:_baja_dirs themes'`))
+		Expect(b.String()).To(ContainSubstring(`'1:section:_baja_dirs content'`))
+		Expect(b.String()).To(ContainSubstring(`'1:shell:(bash zsh fish)'`))
+	})
+
+	It("completes the commands, their flags and arguments in fish", func() {
+		var b bytes.Buffer
+		writeFish(&b, specs)
+
+		Expect(b.String()).To(ContainSubstring(`complete -c baja -n __fish_use_subcommand -a publish -d 'Publish a section, it\'s quick'`))
+		Expect(b.String()).To(ContainSubstring(`complete -c baja -n '__fish_seen_subcommand_from publish' -l target -d 'where to publish' -r`))
+		Expect(b.String()).To(ContainSubstring(`complete -c baja -n '__fish_seen_subcommand_from publish' -l dry -d 'print what would be published'` + "\n"))
+		Expect(b.String()).To(ContainSubstring(`-l theme -d 'theme to publish with' -r -a '(__baja_dirs themes)'`))
+		Expect(b.String()).To(ContainSubstring(`complete -c baja -n '__fish_seen_subcommand_from publish' -a '(__baja_dirs content)'`))
+		Expect(b.String()).To(ContainSubstring(`complete -c baja -n '__fish_seen_subcommand_from completion' -a 'bash zsh fish'`))
+	})
+})
@@ -1,14 +1,22 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
 	"os"
+	"runtime/debug"
+	"sort"
 
 	"github.com/yeo/baja"
 	"github.com/yeo/baja/cleaner"
+	"github.com/yeo/baja/deploy"
+	"github.com/yeo/baja/doctor"
+	"github.com/yeo/baja/importer"
 	"github.com/yeo/baja/node"
 	"github.com/yeo/baja/render"
 	"github.com/yeo/baja/server"
+	"github.com/yeo/baja/stats"
 )
 
 type CmdRunner interface {
@@ -17,9 +25,32 @@ type CmdRunner interface {
 	Help() string
 }
 
+// FlagRunner is implemented by commands which accept flags. Flags are parsed
+// before Run and only the remaining positional arguments are passed to it
+type FlagRunner interface {
+	Flags(fs *flag.FlagSet)
+}
+
+// ArgCompleter is implemented by commands whose positional arguments can be
+// completed by the shell. Each entry is a completion kind, such as "section"
+type ArgCompleter interface {
+	CompleteArgs() []string
+}
+
+// ConfigDiagnoser is implemented by commands which still run when the config fails to load,
+// to report the problem. The load error is handed over and Run gets a nil site
+type ConfigDiagnoser interface {
+	DiagnoseConfig(err error)
+}
+
 var (
 	GitCommit  string
 	AppVersion string
+
+	// environment selects the config overlay, eg: baja.staging.yaml. It's a flag of every command
+	environment string
+	// baseURL overrides the config baseURL, eg: for preview deploys. It's a flag of every command
+	baseURL string
 )
 
 func printHelp(registries map[string]CmdRunner) {
@@ -27,14 +58,39 @@ func printHelp(registries map[string]CmdRunner) {
 	fmt.Println("  baja command [param 1]...[param N]")
 
 	fmt.Println("\n\nCommands:")
-	for name, cmd := range registries {
+	for _, name := range commandNames(registries) {
+		cmd := registries[name]
 		fmt.Printf("  %s %s: %s\n", name, cmd.ArgDesc(), cmd.Help())
 	}
 	//fmt.Println("  node path/to/content to create new node")
 }
 
+// commandNames returns the registered command names in a stable order
+func commandNames(registries map[string]CmdRunner) []string {
+	names := make([]string, 0, len(registries))
+	for name := range registries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// flagSet builds the flag set of a command: the site wide flags plus the command own flags
+func flagSet(name string, runner CmdRunner) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.StringVar(&environment, "environment", os.Getenv("BAJA_ENV"), "config overlay to merge over baja.yaml, eg: staging. Default to $BAJA_ENV")
+	fs.StringVar(&baseURL, "baseURL", os.Getenv("BAJA_BASEURL"), "override config baseURL, eg: for preview deploys. Default to $BAJA_BASEURL")
+
+	if f, ok := runner.(FlagRunner); ok {
+		f.Flags(fs)
+	}
+
+	return fs
+}
+
 func main() {
-	fmt.Printf("Baja %s. Rev %s\n\n", AppVersion, GitCommit)
+	fmt.Fprintf(os.Stderr, "Baja %s. Rev %s\n\n", AppVersion, GitCommit)
 
 	registries := make(map[string]CmdRunner)
 	registries["init"] = &baja.InitCommand{}
@@ -43,8 +99,28 @@ func main() {
 	registries["server"] = &server.ServerCommand{}
 	registries["serve"] = registries["server"]
 	registries["create"] = &node.CreateCommand{}
+	registries["new"] = &node.NewCommand{}
+	registries["render"] = &node.RenderCommand{}
+	registries["import"] = &importer.Command{}
+	registries["stats"] = &stats.Command{}
+	registries["deploy"] = &deploy.Command{}
+	registries["doctor"] = &doctor.Command{}
+	registries["completion"] = &CompletionCommand{registries: registries}
 
-	os.Exit(process(registries, os.Args[1:]))
+	os.Exit(run(registries, os.Args[1:]))
+}
+
+// run processes a command, reporting a crash with ExitInternalError rather than Go's panic exit code
+func run(registries map[string]CmdRunner, args []string) (code int) {
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Fprintln(os.Stderr, "baja crashed:", r)
+			fmt.Fprintln(os.Stderr, string(debug.Stack()))
+			code = baja.ExitInternalError
+		}
+	}()
+
+	return process(registries, args)
 }
 
 func process(registries map[string]CmdRunner, args []string) int {
@@ -65,7 +141,36 @@ func process(registries map[string]CmdRunner, args []string) int {
 		return 255
 	}
 
-	params := args[1:]
-	site := baja.LoadSite("./baja.yaml")
+	fs := flagSet(command, runner)
+	if err := fs.Parse(args[1:]); err != nil {
+		return 1
+	}
+
+	params := fs.Args()
+	baja.SetupLogger(&baja.Config{})
+	site, err := baja.LoadSite(baja.FindConfig("."), environment)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		d, ok := runner.(ConfigDiagnoser)
+		if !ok {
+			fmt.Println(err)
+			return baja.ExitConfigError
+		}
+		d.DiagnoseConfig(err)
+	}
+
+	if site != nil {
+		if err := baja.SetupLogger(site.Config); err != nil {
+			fmt.Println(err)
+			return baja.ExitConfigError
+		}
+	}
+
+	if site != nil && baseURL != "" {
+		if err := site.SetBaseURL(baseURL); err != nil {
+			fmt.Println(err)
+			return baja.ExitConfigError
+		}
+	}
+
 	return runner.Run(site, params)
 }
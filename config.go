@@ -1,15 +1,625 @@
 package baja
 
 import (
-	"gopkg.in/yaml.v2"
+	"fmt"
 	"io/ioutil"
-	"log"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/text/language"
 )
 
 type Config struct {
-	Theme string `"yaml":theme`
-	Site  string `"yaml":site`
-	path  string
+	Theme       string `yaml:"theme" toml:"theme" comment:"directory name of the theme under themes, or a .zip file"`
+	Site        string `yaml:"site" toml:"site" comment:"site name of older configs, use title"` // site name of older configs, use Title
+	Title       string `yaml:"title" toml:"title" comment:"site title, .Site.Title"`
+	Description string `yaml:"description" toml:"description" comment:"site description, .Site.Description"`
+	BaseURL     string `yaml:"baseURL" toml:"baseURL" comment:"absolute url the site is served at, eg: https://example.com/"`
+	// AssetBaseURL is the absolute url the asset template function links to, eg: a CDN, while
+	// pages stay on BaseURL. Unset, assets are served from BaseURL with the pages
+	AssetBaseURL string `yaml:"assetBaseURL" toml:"assetBaseURL" comment:"absolute url of assets, eg: https://cdn.example.com/, defaults to baseURL"`
+	Language     string `yaml:"language" toml:"language" comment:"BCP 47 tag of the site language, eg: en or pt-BR"` // BCP 47 tag, eg: en or pt-BR
+
+	// DefaultLanguage is the code of the main language of a multilingual site, it's used over
+	// Language when set
+	DefaultLanguage string `yaml:"defaultLanguage" toml:"defaultLanguage" comment:"code of the main language of a multilingual site, used over language"`
+
+	// Languages are the languages of the site keyed by code, with their title, path prefix and params
+	Languages map[string]*LanguageConfig `yaml:"languages" toml:"languages" comment:"languages keyed by code with their title, baseURL and params"`
+
+	// Timezone is the IANA name of the zone of front matter dates without an offset, and of dates
+	// shown on the site, eg: Asia/Ho_Chi_Minh. Default to the zone of the build machine
+	Timezone string `yaml:"timezone" toml:"timezone" comment:"IANA zone of dates without an offset, eg: Asia/Ho_Chi_Minh. Default to the build machine zone"`
+
+	// DateInputFormats are the Go layouts tried in order on a front matter date written as a
+	// string, eg: "Jan 2, 2006". Default to DefaultDateInputFormats
+	DateInputFormats []string `yaml:"dateInputFormats" toml:"dateInputFormats" comment:"Go layouts of front matter dates written as a string, eg: [\"Jan 2, 2006\"]"`
+
+	// DateFormats are Go layouts of displayed dates by name, eg: listing: "Jan 2". default is the
+	// one of DateFormatted, the dateFormat template function takes a name
+	DateFormats map[string]string `yaml:"dateFormats" toml:"dateFormats" comment:"Go layouts of displayed dates by name, default is the one of DateFormatted"`
+
+	// Author is the site owner, the default author of feeds and structured data of nodes
+	// without one. A plain string such as author: yeo is read as its name
+	Author SiteAuthor `yaml:"author" toml:"author" comment:"site owner, the byline of nodes without an author"`
+
+	// DefaultImage is the preview image of nodes without params.image in Open Graph and JSON-LD,
+	// a path of the site or an absolute url
+	DefaultImage string `yaml:"defaultImage" toml:"defaultImage" comment:"preview image of nodes without params.image, a site path or an absolute url"`
+
+	// Social are the profiles of the site, eg: GitHub or Mastodon, in the order themes list them.
+	// They're the twitter:site of Open Graph and the sameAs of JSON-LD
+	Social []SocialLink `yaml:"social" toml:"social" comment:"profiles of the site with name, url and icon, .Site.Social"`
+
+	// Authors are the profiles of node authors keyed by the id used in node metadata
+	Authors map[string]*AuthorProfile `yaml:"authors" toml:"authors" comment:"profiles of node authors keyed by id"`
+
+	// PrettyXML indents feed.xml and sitemap.xml so they're readable and diffable
+	PrettyXML bool `yaml:"prettyXML" toml:"prettyXML" comment:"indent feed.xml and sitemap.xml"`
+
+	// Encodings declares content files which aren't UTF-8, eg: "legacy/*.md": latin1.
+	// Patterns are matched against the path relative to content directory, the longest first
+	Encodings map[string]string `yaml:"encodings" toml:"encodings" comment:"content files which are not UTF-8 by glob, eg: \"legacy/*.md\": latin1"`
+
+	// PruneOrphans deletes files of public an incremental build no longer produces, eg: the page of
+	// a deleted node. Files coming from static directories and paths matching PruneProtect are kept
+	PruneOrphans bool `yaml:"pruneOrphans" toml:"pruneOrphans" comment:"delete files of public an incremental build no longer writes"`
+
+	// PruneProtect are glob patterns, relative to public, of files PruneOrphans never deletes
+	PruneProtect []string `yaml:"pruneProtect" toml:"pruneProtect" comment:"globs of public pruneOrphans never deletes, eg: [CNAME]"`
+
+	// Markup tunes the markdown renderer: extensions, raw html and code highlighting
+	Markup MarkupConfig `yaml:"markup" toml:"markup" comment:"markdown extensions, raw html (allow, escape or skip) and code highlighting"`
+
+	// Sanitize sets the html sanitize policy of sections, keyed by directory under content.
+	// A node uses the policy of its closest directory, "*" is the default. See SanitizeOff and others
+	Sanitize map[string]string `yaml:"sanitize" toml:"sanitize" comment:"html sanitize policy by section: off, ugc or strict, \"*\" is the default"`
+
+	// ReadRoot is the directory readFile and readDir template functions, and include shortcodes of
+	// markdown, read from, default to content. They can't reach outside of it
+	ReadRoot string `yaml:"readRoot" toml:"readRoot" default:"content" comment:"directory readFile, readDir and include read from"`
+
+	// Resources sets which files of a page bundle are copied next to its page: ResourcesAll, the
+	// default, or ResourcesReferenced to leave out the ones the page never names
+	Resources string `yaml:"resources" toml:"resources" default:"all" comment:"page bundle files copied next to the page: all or referenced"`
+
+	// IgnoreFiles are patterns of files and directories under content which are never parsed, eg:
+	// node_modules or re:\.draft\.md$. See CompileIgnore
+	IgnoreFiles []string `yaml:"ignoreFiles" toml:"ignoreFiles" comment:"content files never parsed, globs or re: regular expressions"`
+
+	// Env are the names of the environment variables templates can read as .Site.Env, eg:
+	// [COMMIT_SHA, BUILD_NUMBER]. Other variables are never exposed so secrets can't leak into pages
+	Env []string `yaml:"env" toml:"env" comment:"environment variables templates read as .Site.Env, eg: [COMMIT_SHA]"`
+
+	// EmbedScripts lets the built-in tweet and toot shortcodes load the scripts of their service.
+	// Off by default, they render a quote linking the post and nothing is loaded from another site
+	EmbedScripts bool `yaml:"embedScripts" toml:"embedScripts" comment:"let the tweet and toot shortcodes load the embed scripts of their service"`
+
+	// MaxContentSize is the size in bytes above which a content file is skipped with a warning,
+	// so an accidentally huge file can't stall the build. 0 is no limit
+	MaxContentSize int64 `yaml:"maxContentSize" toml:"maxContentSize" comment:"size in bytes of content files skipped with a warning, 0 is no limit"`
+
+	// RenderTimeout is how long the markdown of a node may take to render, eg: 10s. A node over it
+	// is an error of the build. Empty is no limit
+	RenderTimeout string `yaml:"renderTimeout" toml:"renderTimeout" comment:"longest markdown render of a node, eg: 10s. Empty is no limit"`
+
+	// Mounts are directories read as part of content, eg: a changelog of another repository
+	Mounts []Mount `yaml:"mounts" toml:"mounts" comment:"directories read as part of content, with source and target"`
+
+	// Defaults are front matter values of the nodes whose path relative to content matches a glob,
+	// eg: "notes/**": {draft: true}. The front matter of a node wins over them
+	Defaults map[string]map[string]interface{} `yaml:"defaults" toml:"defaults" comment:"front matter values by content glob, eg: \"notes/**\": {draft: true}"`
+
+	// Permalinks are url patterns of nodes keyed by section, the directory under content, eg:
+	// post: /:section/:year/:month/:slug/. Sections without one keep /<section>/<file name>/
+	Permalinks map[string]string `yaml:"permalinks" toml:"permalinks" comment:"url patterns by section, eg: post: /:section/:year/:slug/"`
+
+	// DuplicateSlugs is what happens when names of two nodes of a section slugify the same, eg:
+	// hello.md and Hello.html: DuplicateSlugsSuffix, the default, or DuplicateSlugsError
+	DuplicateSlugs string `yaml:"duplicateSlugs" toml:"duplicateSlugs" default:"suffix" comment:"nodes of a section with the same slug: suffix or error"`
+
+	// StaticDir is the directory of files copied as is into public, eg: favicon.ico, after the
+	// static directory of the theme so its files win. Default to static
+	StaticDir string `yaml:"staticDir" toml:"staticDir" default:"static" comment:"directory copied as is into public"`
+
+	// StaticIgnore are glob patterns of files and directories of the static directories never
+	// copied into public, matched against the path relative to the static directory or the name
+	// of the file, eg: [.git, node_modules, .DS_Store]
+	StaticIgnore []string `yaml:"staticIgnore" toml:"staticIgnore" comment:"globs of static files never copied, eg: [.git, node_modules, .DS_Store]"`
+
+	// FollowSymlinks reads the directories symlinks of content and of the static directories point
+	// to, eg: a media directory shared between sites. Off by default, a link then is skipped with a
+	// warning. A link to a directory it's in is never followed
+	FollowSymlinks bool `yaml:"followSymlinks" toml:"followSymlinks" comment:"read the directories symlinks of content and static point to"`
+
+	// MaxWorkers is the number of static files copied into public at once, eg: 2 on a slow disk.
+	// Default to the number of CPUs
+	MaxWorkers int `yaml:"maxWorkers" toml:"maxWorkers" comment:"static files copied at once, default to the number of CPUs"`
+
+	// CacheDir is the directory processed output is kept in across builds, eg: restored by CI so
+	// unchanged content isn't processed again. Default to .baja-cache
+	CacheDir string `yaml:"cacheDir" toml:"cacheDir" default:".baja-cache" comment:"directory of the processed output kept across builds"`
+
+	// CacheMaxSize bounds CacheDir in bytes, the least recently used entries are evicted after a
+	// build. Default to DefaultCacheMaxSize, -1 is no limit
+	CacheMaxSize int64 `yaml:"cacheMaxSize" toml:"cacheMaxSize" default:"268435456" comment:"size in bytes of cacheDir, least recently used entries are evicted first, -1 for no limit"`
+
+	// Archive is a .tar.gz, .tgz or .zip file baja build writes the output directory into once the
+	// build succeeds, eg: site.tar.gz for a deploy pipeline. --archive overrides it
+	Archive string `yaml:"archive" toml:"archive" comment:".tar.gz, .tgz or .zip file baja build writes public into"`
+
+	// Outputs are the formats generated for each page kind, eg: section: [html, rss]. Kinds it
+	// doesn't list use DefaultOutputs
+	Outputs map[string][]string `yaml:"outputs" toml:"outputs" comment:"formats by page kind, eg: section: [html, rss]"`
+
+	// CustomFormats are output formats of pages templated from the theme, eg: an ics calendar of
+	// each event, keyed by the name outputs lists them with. See OutputFormat
+	CustomFormats map[string]OutputFormat `yaml:"outputFormats" toml:"outputFormats" comment:"page formats templated from the theme, eg: ics: {suffix: ics, mediaType: text/calendar}"`
+
+	// MainSections are the directories of content the home index and site feed list, eg: blog
+	// without docs. Every section is listed when it's empty
+	MainSections []string `yaml:"mainSections" toml:"mainSections" comment:"sections the home index and site feed list, every one when empty"`
+
+	// RecentPosts is the number of newest nodes of the main sections in .Site.RecentPosts, eg: for
+	// a sidebar on every page. Default to DefaultRecentPosts
+	RecentPosts int `yaml:"recentPosts" toml:"recentPosts" default:"5" comment:"number of newest main section nodes of .Site.RecentPosts"`
+
+	// RelatedPosts is the number of nodes of .Related on a node page. Default to
+	// DefaultRelatedPosts
+	RelatedPosts int `yaml:"relatedPosts" toml:"relatedPosts" default:"5" comment:"number of nodes of .Related"`
+
+	// RelatedBy is how .Related is filled, in order: RelatedByTags, the nodes sharing the most tags,
+	// then RelatedBySection, the nodes of the same section closest by date, until RelatedPosts
+	// are found. Default to both in that order
+	RelatedBy []string `yaml:"relatedBy" toml:"relatedBy" default:"[tags, section]" comment:"how .Related is filled, in order: tags, section"`
+
+	// Paginate is the number of nodes per page of listings: sections, home, term and author pages.
+	// The next ones are on page/2/, page/3/... 0, the default, lists every node on one page
+	Paginate int `yaml:"paginate" toml:"paginate" comment:"nodes per listing page, 0 lists every node on one page"`
+
+	// SectionPaginate overrides Paginate by directory under content. A listing uses the size of
+	// its closest directory, eg: gallery: 24 also applies to gallery/2019
+	SectionPaginate map[string]int `yaml:"sectionPaginate" toml:"sectionPaginate" comment:"nodes per listing page by section, eg: gallery: 24"`
+
+	// PaginateNoIndex sets .Paginator.NoIndex on the listing pages after the first, for themes to
+	// keep them out of search engines with a robots meta. Every page is indexed by default
+	PaginateNoIndex bool `yaml:"paginateNoIndex" toml:"paginateNoIndex" comment:"noindex listing pages after the first"`
+
+	// SummaryLength is the length of .Summary of nodes, counted in SummaryUnit. Default to
+	// DefaultSummaryLength
+	SummaryLength int `yaml:"summaryLength" toml:"summaryLength" default:"70" comment:"length of node summaries, in summaryUnit"`
+
+	// SummaryUnit is what SummaryLength counts: SummaryWords, the default, or SummaryRunes for
+	// languages written without spaces such as Chinese or Japanese
+	SummaryUnit string `yaml:"summaryUnit" toml:"summaryUnit" default:"words" comment:"unit of summaryLength: words, or runes for languages without spaces"`
+
+	// OutputPaths is how node names are made safe for the directories of public:
+	// OutputPathsUnicode, the default, or OutputPathsASCII
+	OutputPaths string `yaml:"outputPaths" toml:"outputPaths" default:"unicode" comment:"how node names are made safe for public: unicode or ascii"`
+
+	// TrailingSlash is how baja serve answers a directory url without trailing slash, eg:
+	// /post/hello: TrailingSlashRedirect, the default, or TrailingSlashServe
+	TrailingSlash string `yaml:"trailingSlash" toml:"trailingSlash" default:"redirect" comment:"baja serve on a url without trailing slash: redirect or serve"`
+
+	// BuildReport writes ReportFile of render, counts, timings, feeds and problems of the build as
+	// json, into the output directory
+	BuildReport bool `yaml:"buildReport" toml:"buildReport" comment:"write .baja-report.json into public at the end of a build, for CI dashboards"`
+
+	// PreviewDir is where baja build --preview writes drafts, default to public-preview
+	PreviewDir string `yaml:"previewDir" toml:"previewDir" default:"public-preview" comment:"where baja build --preview writes drafts"`
+
+	// Params are free form settings of the theme, exposed to templates as .Site.Params.
+	// They override the defaults of the theme declared in its theme.toml
+	Params map[string]interface{} `yaml:"params" toml:"params" comment:"free form theme settings, .Site.Params"`
+
+	// LogLevel is the lowest level logged: debug, info, warn or error. Default to info
+	LogLevel string `yaml:"logLevel" toml:"logLevel" default:"info" comment:"lowest level logged: debug, info, warn or error"`
+
+	// LogFormat is console, human readable, or json
+	LogFormat string `yaml:"logFormat" toml:"logFormat" default:"console" comment:"console or json"`
+
+	// Taxonomies are the front matter keys grouping nodes into term pages, eg: cuisine, over
+	// DefaultTaxonomies. A taxonomy set to false, eg: categories: false, has no page
+	Taxonomies map[string]*Taxonomy `yaml:"taxonomies" toml:"taxonomies" comment:"front matter keys grouping nodes into term pages, eg: cuisine: {index: true}"`
+
+	// Menus are the menus of the site keyed by name, eg: main. Templates get them as a tree
+	// with .Site.Menus
+	Menus map[string][]*MenuEntry `yaml:"menus" toml:"menus" comment:"menus by name, eg: main, with name, url, weight and parent"`
+
+	// Deploy configures baja deploy
+	Deploy DeployConfig `yaml:"deploy" toml:"deploy" comment:"baja deploy github"`
+
+	path   string
+	format string          // ConfigFormatYAML or ConfigFormatTOML, WriteFile keeps it
+	unset  []UnsetVariable // ${VAR} of the file without a value, reported by Validate
+}
+
+// DeployConfig is where baja deploy commits the public directory
+type DeployConfig struct {
+	Branch  string `yaml:"branch" toml:"branch" default:"gh-pages"`             // default gh-pages
+	Remote  string `yaml:"remote" toml:"remote" default:"origin"`               // default origin
+	Message string `yaml:"message" toml:"message" default:"Deploy {{ .Date }}"` // commit message, {{ .Date }} is the build time
+}
+
+// SiteAuthor is the owner of the site, exposed to templates as .Site.Author
+type SiteAuthor struct {
+	Name  string `yaml:"name" toml:"name"`
+	Email string `yaml:"email" toml:"email"`
+	URL   string `yaml:"url" toml:"url"`
+	Image string `yaml:"image" toml:"image"` // avatar, a path of the site or an absolute url
+}
+
+// UnmarshalYAML reads author as a table, or as a name for configs older than the author table
+func (a *SiteAuthor) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var name string
+	if err := unmarshal(&name); err == nil {
+		*a = SiteAuthor{Name: name}
+		return nil
+	}
+
+	type author SiteAuthor
+	return unmarshal((*author)(a))
+}
+
+// UnmarshalTOML is UnmarshalYAML of toml configs
+func (a *SiteAuthor) UnmarshalTOML(v interface{}) error {
+	switch v := v.(type) {
+	case string:
+		*a = SiteAuthor{Name: v}
+	case map[string]interface{}:
+		for key, field := range map[string]*string{"name": &a.Name, "email": &a.Email, "url": &a.URL, "image": &a.Image} {
+			if value, ok := v[key]; ok {
+				s, ok := value.(string)
+				if !ok {
+					return fmt.Errorf("key author.%s: expected a string, got %T", key, value)
+				}
+				*field = s
+			}
+		}
+	default:
+		return fmt.Errorf("key author: expected a name or a table, got %T", v)
+	}
+
+	return nil
+}
+
+// AuthorProfile is the public profile of an author, rendered on its author page
+type AuthorProfile struct {
+	Name   string            `yaml:"name" toml:"name"`
+	Bio    string            `yaml:"bio" toml:"bio"`
+	Avatar string            `yaml:"avatar" toml:"avatar"`
+	Social map[string]string `yaml:"social" toml:"social"`
+}
+
+// Sanitize policies of node html
+const (
+	SanitizeOff    = "off"    // trusted, html is kept as is
+	SanitizeUGC    = "ugc"    // user generated content: formatting, links and images are kept, scripts and styles removed
+	SanitizeStrict = "strict" // every tag is removed
+)
+
+// ValidateSanitize checks the policies of Config.Sanitize
+func ValidateSanitize(policies map[string]string) error {
+	for dir, policy := range policies {
+		switch policy {
+		case SanitizeOff, SanitizeUGC, SanitizeStrict:
+		default:
+			return fmt.Errorf("invalid sanitize policy %q of %s: must be %s, %s or %s", policy, dir, SanitizeOff, SanitizeUGC, SanitizeStrict)
+		}
+	}
+
+	return nil
+}
+
+// Resources modes of page bundles
+const (
+	ResourcesAll        = "all"
+	ResourcesReferenced = "referenced"
+)
+
+// DuplicateSlugs modes
+const (
+	DuplicateSlugsSuffix = "suffix" // the later node by path gets a numeric suffix, eg: hello-2, with a warning
+	DuplicateSlugsError  = "error"  // the build fails, reporting both files
+)
+
+// TrailingSlash modes of baja serve
+const (
+	TrailingSlashRedirect = "redirect" // 301 to /post/hello/, like GitHub Pages and Netlify
+	TrailingSlashServe    = "serve"    // the index.html of the directory is served at both urls
+)
+
+// Location returns the zone of Timezone, the local zone when it's not set or invalid
+func (c *Config) Location() *time.Location {
+	if c.Timezone == "" {
+		return time.Local
+	}
+
+	loc, err := time.LoadLocation(c.Timezone)
+	if err != nil {
+		return time.Local
+	}
+
+	return loc
+}
+
+// RenderTimeoutDuration returns RenderTimeout parsed, 0 when it's not set or invalid
+func (c *Config) RenderTimeoutDuration() time.Duration {
+	d, err := time.ParseDuration(c.RenderTimeout)
+	if err != nil {
+		return 0
+	}
+
+	return d
+}
+
+// DefaultDateInputFormats are the layouts of front matter dates written as a string when config has
+// no dateInputFormats
+var DefaultDateInputFormats = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"Jan 2, 2006",
+	"January 2, 2006",
+	"2 Jan 2006",
+	"2 January 2006",
+}
+
+// DateInputLayouts returns DateInputFormats, or DefaultDateInputFormats when it's empty
+func (c *Config) DateInputLayouts() []string {
+	if len(c.DateInputFormats) == 0 {
+		return DefaultDateInputFormats
+	}
+
+	return c.DateInputFormats
+}
+
+// ParseDate parses a front matter date written as a string with the first of the DateInputLayouts
+// matching it. A date without offset is in loc
+func (c *Config) ParseDate(s string, loc *time.Location) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range c.DateInputLayouts() {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("cannot parse date %q, it matches none of %s", s, strings.Join(c.DateInputLayouts(), ", "))
+}
+
+// DateFormatDefault is the name of the date format of DateFormatted
+const DateFormatDefault = "default"
+
+// DefaultDateFormats are the named date formats config dateFormats adds to or overrides
+var DefaultDateFormats = map[string]string{
+	DateFormatDefault: "2006 Jan 02",
+	"rfc1123":         time.RFC1123Z,
+	"rfc3339":         time.RFC3339,
+}
+
+// ValidateDateFormats checks that every named format of config dateFormats has a layout. All
+// problems are returned as ValidationErrors
+func ValidateDateFormats(formats map[string]string) error {
+	var errs ValidationErrors
+
+	for _, name := range sortedNames(formats) {
+		if strings.TrimSpace(formats[name]) == "" {
+			errs = append(errs, fmt.Errorf("date format %q has no layout, eg: %s: \"Jan 2, 2006\"", name, name))
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return errs
+}
+
+// DateLayouts returns the named date formats, DefaultDateFormats merged with config dateFormats
+func (c *Config) DateLayouts() map[string]string {
+	layouts := make(map[string]string, len(DefaultDateFormats)+len(c.DateFormats))
+	for name, layout := range DefaultDateFormats {
+		layouts[name] = layout
+	}
+	for name, layout := range c.DateFormats {
+		layouts[name] = layout
+	}
+
+	return layouts
+}
+
+// DateFormat returns the layout of the named date format. An unknown name is an error listing the
+// available ones
+func (c *Config) DateFormat(name string) (string, error) {
+	layouts := c.DateLayouts()
+	if layout, ok := layouts[name]; ok {
+		return layout, nil
+	}
+
+	return "", fmt.Errorf("unknown date format %q, available: %s", name, strings.Join(sortedNames(layouts), ", "))
+}
+
+func sortedNames(m map[string]string) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// DefaultReadRoot is the directory of readFile and readDir when ReadRoot isn't set
+const DefaultReadRoot = "content"
+
+// DefaultStaticDir is the directory of site static files when StaticDir isn't set
+const DefaultStaticDir = "static"
+
+// StaticPath returns StaticDir, or DefaultStaticDir when it's not set
+func (c *Config) StaticPath() string {
+	if c.StaticDir == "" {
+		return DefaultStaticDir
+	}
+
+	return c.StaticDir
+}
+
+// DefaultCacheDir and DefaultCacheMaxSize are the cache of a config without cacheDir and
+// cacheMaxSize
+const (
+	DefaultCacheDir           = ".baja-cache"
+	DefaultCacheMaxSize int64 = 256 << 20
+)
+
+// CachePath returns CacheDir, or DefaultCacheDir when it's not set
+func (c *Config) CachePath() string {
+	if c.CacheDir == "" {
+		return DefaultCacheDir
+	}
+
+	return c.CacheDir
+}
+
+// CacheLimit returns CacheMaxSize, or DefaultCacheMaxSize when it's not set. It's 0, no limit
+// for utils.NewCache, when CacheMaxSize is -1
+func (c *Config) CacheLimit() int64 {
+	switch {
+	case c.CacheMaxSize == 0:
+		return DefaultCacheMaxSize
+	case c.CacheMaxSize < 0:
+		return 0
+	}
+
+	return c.CacheMaxSize
+}
+
+// PaginateSize returns the number of nodes per page of the listing at dir, eg: blog or tag/go,
+// from SectionPaginate of its closest directory, else Paginate. 0 is no pagination
+func (c *Config) PaginateSize(dir string) int {
+	for dir != "." && dir != "/" && dir != "" {
+		if size, ok := c.SectionPaginate[dir]; ok {
+			return size
+		}
+		dir = path.Dir(dir)
+	}
+
+	return c.Paginate
+}
+
+// Summary units
+const (
+	SummaryWords = "words" // runs of letters between spaces
+	SummaryRunes = "runes" // characters, an html entity counts as one
+)
+
+// DefaultSummaryLength is the length of .Summary when SummaryLength isn't set
+const DefaultSummaryLength = 70
+
+// SummaryLimit returns SummaryLength, or DefaultSummaryLength when it's not set, and its unit
+func (c *Config) SummaryLimit() (int, string) {
+	length, unit := c.SummaryLength, c.SummaryUnit
+	if length == 0 {
+		length = DefaultSummaryLength
+	}
+	if unit == "" {
+		unit = SummaryWords
+	}
+
+	return length, unit
+}
+
+// DefaultRecentPosts is the length of .Site.RecentPosts when RecentPosts isn't set
+const DefaultRecentPosts = 5
+
+// RecentPostsCount returns RecentPosts, or DefaultRecentPosts when it's not set
+func (c *Config) RecentPostsCount() int {
+	if c.RecentPosts == 0 {
+		return DefaultRecentPosts
+	}
+
+	return c.RecentPosts
+}
+
+// DefaultRelatedPosts is the length of .Related when RelatedPosts isn't set
+const DefaultRelatedPosts = 5
+
+// RelatedBy values, see Config.RelatedBy
+const (
+	RelatedByTags    = "tags"
+	RelatedBySection = "section"
+)
+
+// RelatedPostsCount returns RelatedPosts, or DefaultRelatedPosts when it's not set
+func (c *Config) RelatedPostsCount() int {
+	if c.RelatedPosts == 0 {
+		return DefaultRelatedPosts
+	}
+
+	return c.RelatedPosts
+}
+
+// RelatedOrder returns RelatedBy, or tags then section when it's not set
+func (c *Config) RelatedOrder() []string {
+	if len(c.RelatedBy) == 0 {
+		return []string{RelatedByTags, RelatedBySection}
+	}
+
+	return c.RelatedBy
+}
+
+// IsMainSection reports whether the nodes of content directory dir are listed on the home index
+// and site feed. A sub directory belongs to its main section
+func (c *Config) IsMainSection(dir string) bool {
+	if len(c.MainSections) == 0 {
+		return true
+	}
+
+	for _, section := range c.MainSections {
+		section = strings.Trim(section, "/")
+		if dir == section || strings.HasPrefix(dir, section+"/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Archive formats of Config.Archive
+const (
+	ArchiveTarGz = "tar.gz"
+	ArchiveZip   = "zip"
+)
+
+// ArchiveFormat returns the format of an archive from the extension of path
+func ArchiveFormat(path string) (string, error) {
+	switch {
+	case strings.HasSuffix(path, ".tar.gz"), strings.HasSuffix(path, ".tgz"):
+		return ArchiveTarGz, nil
+	case strings.HasSuffix(path, ".zip"):
+		return ArchiveZip, nil
+	default:
+		return "", fmt.Errorf("unknown archive format of %s, must end with .tar.gz, .tgz or .zip", path)
+	}
+}
+
+// DefaultPreviewDir is the output directory of drafts preview builds
+const DefaultPreviewDir = "public-preview"
+
+// PreviewPath returns PreviewDir, or DefaultPreviewDir when it's not set
+func (c *Config) PreviewPath() string {
+	if c.PreviewDir == "" {
+		return DefaultPreviewDir
+	}
+
+	return c.PreviewDir
 }
 
 var (
@@ -17,15 +627,159 @@ var (
 )
 
 func NewConfig(path string) *Config {
-	c := Config{path: path}
+	c := Config{path: path, format: DetectConfigFormat(path, nil)}
 	return &c
 }
 
-func (c *Config) WriteFile() {
-	d, err := yaml.Marshal(c)
+// ValidateBaseURL checks that a base url is absolute, eg: https://example.com/blog/
+func ValidateBaseURL(baseURL string) error {
+	return validateAbsURL("baseURL", baseURL)
+}
+
+// ValidateAssetBaseURL checks that the base url of assets is absolute, eg: https://cdn.example.com/
+func ValidateAssetBaseURL(assetBaseURL string) error {
+	return validateAbsURL("assetBaseURL", assetBaseURL)
+}
+
+// validateAbsURL checks that raw, the value of config key, is an absolute url
+func validateAbsURL(key, raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("invalid %s %q: %w", key, raw, err)
+	}
+
+	if u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("invalid %s %q: must be absolute with a scheme, eg: https://example.com/", key, raw)
+	}
+
+	return nil
+}
+
+// ValidateLanguage checks that language is a BCP 47 tag, eg: en-US
+func ValidateLanguage(lang string) error {
+	if _, err := language.Parse(lang); err != nil {
+		return fmt.Errorf("invalid language %q: must be a BCP 47 tag, eg: en or pt-BR", lang)
+	}
+
+	return nil
+}
+
+// AbsURL joins BaseURL and a site path such as a permalink
+func (c *Config) AbsURL(path string) string {
+	return strings.TrimRight(c.BaseURL, "/") + "/" + strings.TrimLeft(path, "/")
+}
+
+// AssetURL joins AssetBaseURL and the path of an asset. Without AssetBaseURL the path is kept,
+// the asset is served from BaseURL like the page linking to it
+func (c *Config) AssetURL(path string) string {
+	if c.AssetBaseURL == "" {
+		return path
+	}
+
+	return strings.TrimRight(c.AssetBaseURL, "/") + "/" + strings.TrimLeft(path, "/")
+}
+
+// WriteFile writes config into its file, in the format it was read from. Keys of the file which
+// aren't options of config, eg: settings of the author's own tooling, are kept
+func (c *Config) WriteFile() error {
+	d, err := marshalConfig(c.format, c)
+	if err != nil {
+		return err
+	}
+
+	if existing, err := ioutil.ReadFile(c.path); err == nil {
+		if d, err = keepUnknownKeys(c.format, d, existing); err != nil {
+			return err
+		}
+	}
+
+	return ioutil.WriteFile(c.path, d, 0644)
+}
+
+// keepUnknownKeys adds the top level keys of existing which config has no option for to d
+func keepUnknownKeys(format string, d, existing []byte) ([]byte, error) {
+	raw, err := DecodeConfigMap("baja."+format, existing)
 	if err != nil {
-		log.Fatalf("Cannot write config file file %v", err)
+		// nothing can be kept of a file which doesn't parse
+		return d, nil
+	}
+
+	options := map[string]bool{}
+	t := reflect.TypeOf(Config{})
+	for i := 0; i < t.NumField(); i++ {
+		options[tagKey(t.Field(i), format)] = true
+	}
+
+	unknown := map[string]interface{}{}
+	for k, v := range raw {
+		if !options[k] {
+			unknown[k] = v
+		}
+	}
+	if len(unknown) == 0 {
+		return d, nil
+	}
+
+	written, err := DecodeConfigMap("baja."+format, d)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range unknown {
+		written[k] = v
+	}
+
+	return marshalConfig(format, written)
+}
+
+// EnvironmentPath returns the overlay config file of an environment, eg: baja.staging.yaml for baja.yaml
+func EnvironmentPath(path, environment string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "." + environment + ext
+}
+
+// readConfig reads the config file at path. When environment is set, its overlay file is deep merged
+// over it: nested sections are merged key by key, any other value of the overlay replaces the base one.
+// The merged config is returned in the format of path. A missing overlay is an error, the
+// environment is most likely misspelled
+func readConfig(path, environment string) ([]byte, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil || environment == "" {
+		return data, err
+	}
+
+	overlayPath := EnvironmentPath(path, environment)
+	overlayData, err := ioutil.ReadFile(overlayPath)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("environment %s has no overlay file, create %s", environment, overlayPath)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	base, err := DecodeConfigMap(path, data)
+	if err != nil {
+		return nil, err
+	}
+
+	overlay, err := DecodeConfigMap(overlayPath, overlayData)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", overlayPath, err)
+	}
+
+	return marshalConfig(DetectConfigFormat(path, data), mergeMaps(base, overlay))
+}
+
+func mergeMaps(base, overlay map[string]interface{}) map[string]interface{} {
+	for k, v := range overlay {
+		if o, ok := v.(map[string]interface{}); ok {
+			if b, ok := base[k].(map[string]interface{}); ok {
+				base[k] = mergeMaps(b, o)
+				continue
+			}
+		}
+
+		base[k] = v
 	}
 
-	ioutil.WriteFile(c.path, d, 0644)
+	return base
 }
@@ -0,0 +1,120 @@
+package baja
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// requiredKeys are set in the default config file, every other option is commented out
+var requiredKeys = map[string]bool{"theme": true}
+
+// WriteDefault writes a config file at path, in the format of its extension, listing every option
+// with its default value and a comment. Only theme is set, the others are commented out
+func WriteDefault(path string) error {
+	return ioutil.WriteFile(path, DefaultConfigFile(DetectConfigFormat(path, nil)), 0644)
+}
+
+// DefaultConfigFile is the content of WriteDefault. It's generated from the comment and default
+// tags of Config so a new option can't be left out
+func DefaultConfigFile(format string) []byte {
+	var b, tables bytes.Buffer
+	b.WriteString("# baja config. Uncomment an option to change it, see README.md for details\n")
+
+	t := reflect.TypeOf(Config{})
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		key := tagKey(f, format)
+		if key == "" {
+			continue
+		}
+
+		// a toml table ends with the next one, so tables go after the top level keys
+		w := &b
+		if format == ConfigFormatTOML && f.Type.Kind() == reflect.Struct {
+			w = &tables
+		}
+
+		w.WriteString("\n")
+		if comment := f.Tag.Get("comment"); comment != "" {
+			fmt.Fprintf(w, "# %s\n", comment)
+		}
+
+		prefix := "# "
+		if requiredKeys[key] {
+			prefix = ""
+		}
+		writeOption(w, format, prefix, "", key, f)
+	}
+	b.Write(tables.Bytes())
+
+	return b.Bytes()
+}
+
+// writeOption writes key with its default value, the fields of a struct as a yaml mapping or a
+// toml table
+func writeOption(w *bytes.Buffer, format, prefix, indent, key string, f reflect.StructField) {
+	if f.Type.Kind() != reflect.Struct {
+		separator := ": "
+		if format == ConfigFormatTOML {
+			separator = " = "
+		}
+		fmt.Fprintf(w, "%s%s%s%s%s\n", prefix, indent, key, separator, defaultValue(f))
+		return
+	}
+
+	if format == ConfigFormatTOML {
+		fmt.Fprintf(w, "%s[%s]\n", prefix, key)
+	} else {
+		fmt.Fprintf(w, "%s%s%s:\n", prefix, indent, key)
+		indent += "  "
+	}
+
+	for i := 0; i < f.Type.NumField(); i++ {
+		field := f.Type.Field(i)
+		if name := tagKey(field, format); name != "" {
+			writeOption(w, format, prefix, indent, name, field)
+		}
+	}
+}
+
+// defaultValue is the default tag of f, or its zero value, written so yaml and toml read it alike
+func defaultValue(f reflect.StructField) string {
+	value := f.Tag.Get("default")
+
+	switch f.Type.Kind() {
+	case reflect.String:
+		return strconv.Quote(value)
+	case reflect.Slice:
+		return "[]"
+	case reflect.Map:
+		return "{}"
+	case reflect.Bool:
+		if value == "" {
+			return "false"
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if value == "" {
+			return "0"
+		}
+	}
+
+	return value
+}
+
+// tagKey is the key of a struct field in format, empty for fields which aren't config options
+func tagKey(f reflect.StructField, format string) string {
+	if f.PkgPath != "" {
+		return ""
+	}
+
+	key := strings.Split(f.Tag.Get(format), ",")[0]
+	if key == "-" {
+		return ""
+	}
+
+	return key
+}
@@ -1,10 +1,18 @@
 package baja_test
 
 import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
-	. "github.com/yeo/baja"
+	"github.com/yeo/baja"
+	. "github.com/yeo/baja/node"
 )
 
 var _ = Describe("Config", func() {
@@ -23,3 +31,555 @@ var _ = Describe("Config", func() {
 		})
 	})
 })
+
+var _ = Describe("LoadSite", func() {
+	var dir string
+
+	BeforeEach(func() {
+		dir, _ = ioutil.TempDir("", "baja")
+		ioutil.WriteFile(filepath.Join(dir, "baja.yaml"), []byte("theme: t\nbaseURL: https://example.com\n"), 0644)
+		ioutil.WriteFile(filepath.Join(dir, "baja.staging.yaml"), []byte("baseURL: https://staging.example.com\n"), 0644)
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("merges environment overlay over base config", func() {
+		site, err := baja.LoadSite(filepath.Join(dir, "baja.yaml"), "staging")
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(site.Environment).To(Equal("staging"))
+		Expect(site.Config.Theme).To(Equal("t"))
+		Expect(site.Config.BaseURL).To(Equal("https://staging.example.com"))
+	})
+
+	It("deep merges the nested sections of an overlay", func() {
+		ioutil.WriteFile(filepath.Join(dir, "baja.yaml"), []byte("theme: t\nmarkup:\n  rawHTML: escape\n  highlight: github\n"), 0644)
+		ioutil.WriteFile(filepath.Join(dir, "baja.staging.yaml"), []byte("markup:\n  highlight: monokai\n"), 0644)
+
+		site, err := baja.LoadSite(filepath.Join(dir, "baja.yaml"), "staging")
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(site.Config.Markup.RawHTML).To(Equal("escape"))
+		Expect(site.Config.Markup.Highlight).To(Equal("monokai"))
+	})
+
+	It("returns a config error naming the missing overlay of an environment", func() {
+		_, err := baja.LoadSite(filepath.Join(dir, "baja.yaml"), "prod")
+
+		Expect(baja.ExitCode(err)).To(Equal(baja.ExitConfigError))
+		Expect(err).To(MatchError(ContainSubstring("environment prod has no overlay file, create " + filepath.Join(dir, "baja.prod.yaml"))))
+	})
+
+	It("uses base config without environment", func() {
+		site, err := baja.LoadSite(filepath.Join(dir, "baja.yaml"), "")
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(site.Environment).To(Equal(""))
+		Expect(site.Config.BaseURL).To(Equal("https://example.com"))
+	})
+
+	It("returns a config error for invalid config", func() {
+		ioutil.WriteFile(filepath.Join(dir, "baja.yaml"), []byte("theme: [unclosed\n"), 0644)
+
+		_, err := baja.LoadSite(filepath.Join(dir, "baja.yaml"), "")
+
+		var configErr *baja.ConfigError
+		Expect(errors.As(err, &configErr)).To(Equal(true))
+		Expect(baja.ExitCode(err)).To(Equal(baja.ExitConfigError))
+	})
+
+	It("rejects a relative baseURL in config", func() {
+		ioutil.WriteFile(filepath.Join(dir, "baja.yaml"), []byte("baseURL: example.com\n"), 0644)
+
+		_, err := baja.LoadSite(filepath.Join(dir, "baja.yaml"), "")
+		Expect(baja.ExitCode(err)).To(Equal(baja.ExitConfigError))
+	})
+
+	It("overrides baseURL for a build", func() {
+		site, _ := baja.LoadSite(filepath.Join(dir, "baja.yaml"), "")
+
+		Expect(site.SetBaseURL("https://pr-1.preview.app")).To(Succeed())
+		Expect(site.Config.AbsURL("/post/a/")).To(Equal("https://pr-1.preview.app/post/a/"))
+
+		err := site.SetBaseURL("/relative")
+		Expect(baja.ExitCode(err)).To(Equal(baja.ExitConfigError))
+		Expect(site.Config.BaseURL).To(Equal("https://pr-1.preview.app"))
+	})
+
+	It("reloads config keeping environment and baseURL override", func() {
+		site, _ := baja.LoadSite(filepath.Join(dir, "baja.yaml"), "staging")
+		site.SetBaseURL("https://pr-1.preview.app")
+		Expect(site.ConfigPaths()).To(HaveLen(2))
+
+		ioutil.WriteFile(filepath.Join(dir, "baja.yaml"), []byte("theme: t2\nbaseURL: https://example.com\n"), 0644)
+		next, err := site.Reload()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(next.Environment).To(Equal("staging"))
+		Expect(next.Config.Theme).To(Equal("t2"))
+		Expect(next.Config.BaseURL).To(Equal("https://pr-1.preview.app"))
+
+		ioutil.WriteFile(filepath.Join(dir, "baja.yaml"), []byte("theme: [t\n"), 0644)
+		_, err = next.Reload()
+		Expect(baja.ExitCode(err)).To(Equal(baja.ExitConfigError))
+		Expect(next.Config.Theme).To(Equal("t2"))
+	})
+
+	It("exposes site settings to templates", func() {
+		ioutil.WriteFile(filepath.Join(dir, "baja.yaml"), []byte("title: Notes\ndescription: About things\nbaseURL: https://example.com\nlanguage: pt-BR\n"), 0644)
+		site, err := baja.LoadSite(filepath.Join(dir, "baja.yaml"), "")
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(site.Title()).To(Equal("Notes"))
+		Expect(site.Description()).To(Equal("About things"))
+		Expect(site.BaseURL()).To(Equal("https://example.com"))
+		Expect(site.Language()).To(Equal("pt-BR"))
+	})
+
+	It("falls back to site name for title", func() {
+		ioutil.WriteFile(filepath.Join(dir, "baja.yaml"), []byte("site: Old name\n"), 0644)
+		site, _ := baja.LoadSite(filepath.Join(dir, "baja.yaml"), "")
+
+		Expect(site.Title()).To(Equal("Old name"))
+	})
+
+	It("rejects an invalid language", func() {
+		ioutil.WriteFile(filepath.Join(dir, "baja.yaml"), []byte("language: not a language\n"), 0644)
+
+		_, err := baja.LoadSite(filepath.Join(dir, "baja.yaml"), "")
+		Expect(baja.ExitCode(err)).To(Equal(baja.ExitConfigError))
+	})
+
+	Describe("Params", func() {
+		var cwd string
+
+		BeforeEach(func() {
+			cwd, _ = os.Getwd()
+			Expect(os.Chdir(dir)).To(Succeed())
+			os.MkdirAll(filepath.Join(dir, "themes", "t"), os.ModePerm)
+		})
+
+		AfterEach(func() {
+			os.Chdir(cwd)
+		})
+
+		It("looks up params case insensitively", func() {
+			ioutil.WriteFile(filepath.Join(dir, "baja.yaml"), []byte("theme: t\nparams:\n  accentColor: red\n  Social:\n    Twitter: baja\n"), 0644)
+			site, err := baja.LoadSite(filepath.Join(dir, "baja.yaml"), "")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(site.Params().Get("ACCENTCOLOR")).To(Equal("red"))
+			Expect(site.Params().Get("social.twitter")).To(Equal("baja"))
+			Expect(site.Params()["accentcolor"]).To(Equal("red"))
+			Expect(site.Params().Get("social.mastodon")).To(BeNil())
+			Expect(site.Params().Get("accentColor.dark")).To(BeNil())
+		})
+
+		It("overrides theme defaults with site params", func() {
+			ioutil.WriteFile(filepath.Join(dir, "themes", "t", "theme.toml"), []byte("[params]\naccentColor = \"blue\"\nanalytics = \"UA-1\"\n[params.social]\ntwitter = \"theme\"\ngithub = \"theme\"\n"), 0644)
+			ioutil.WriteFile(filepath.Join(dir, "baja.yaml"), []byte("theme: t\nparams:\n  AccentColor: red\n  social:\n    twitter: site\n"), 0644)
+			site, err := baja.LoadSite(filepath.Join(dir, "baja.yaml"), "")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(site.Params().Get("accentColor")).To(Equal("red"))
+			Expect(site.Params().Get("analytics")).To(Equal("UA-1"))
+			Expect(site.Params().Get("social.twitter")).To(Equal("site"))
+			Expect(site.Params().Get("social.github")).To(Equal("theme"))
+		})
+
+		It("returns a config error for an invalid theme.toml", func() {
+			ioutil.WriteFile(filepath.Join(dir, "themes", "t", "theme.toml"), []byte("[params\n"), 0644)
+
+			_, err := baja.LoadSite(filepath.Join(dir, "baja.yaml"), "")
+			Expect(baja.ExitCode(err)).To(Equal(baja.ExitConfigError))
+		})
+	})
+
+	It("finds overlay file next to the base one", func() {
+		Expect(baja.EnvironmentPath("site/baja.yaml", "staging")).To(Equal("site/baja.staging.yaml"))
+	})
+})
+
+var _ = Describe("Validate", func() {
+	var cwd, dir string
+
+	BeforeEach(func() {
+		cwd, _ = os.Getwd()
+		dir, _ = ioutil.TempDir("", "baja")
+		os.MkdirAll(filepath.Join(dir, "themes", "t"), os.ModePerm)
+		Expect(os.Chdir(dir)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		os.Chdir(cwd)
+		os.RemoveAll(dir)
+	})
+
+	It("accepts a valid config", func() {
+		config := &baja.Config{Theme: "t", BaseURL: "https://example.com", Encodings: map[string]string{"legacy/*.md": "latin1"}}
+
+		Expect(config.Validate()).To(Succeed())
+	})
+
+	It("returns every problem at once", func() {
+		config := &baja.Config{
+			Theme:          "missing",
+			BaseURL:        "example.com",
+			Language:       "not a language",
+			Sanitize:       map[string]string{"*": "some"},
+			Encodings:      map[string]string{"legacy/*.md": "klingon"},
+			PruneProtect:   []string{"[unclosed"},
+			DuplicateSlugs: "rename",
+			TrailingSlash:  "strip",
+			Timezone:       "Mars/Olympus",
+			Defaults:       map[string]map[string]interface{}{"notes/[draft": {"draft": true}},
+			Languages:      map[string]*baja.LanguageConfig{"en": {}, "fr": {BaseURL: "fr"}},
+			Outputs:        map[string][]string{"term": {"json"}},
+			DateFormats:    map[string]string{"listing": ""},
+			StaticIgnore:   []string{"[unclosed"},
+			AssetBaseURL:   "cdn.example.com",
+			RecentPosts:    -1,
+			Paginate:       -1,
+			SummaryUnit:    "lines",
+		}
+
+		err := config.Validate()
+		Expect(baja.ExitCode(err)).To(Equal(baja.ExitConfigError))
+
+		var errs baja.ValidationErrors
+		Expect(errors.As(err, &errs)).To(Equal(true))
+		Expect(errs).To(HaveLen(18))
+		Expect(err.Error()).To(ContainSubstring(`theme "missing" not found`))
+	})
+
+	It("requires a theme", func() {
+		err := (&baja.Config{}).Validate()
+
+		Expect(err).To(MatchError(ContainSubstring("theme is not set")))
+	})
+
+	It("rejects a previewDir baja would remove the site with", func() {
+		os.MkdirAll("assets", os.ModePerm)
+		for _, previewDir := range []string{"public", "public/drafts", ".", "./", "..", "content", "assets", "assets/x", "themes", ".baja", filepath.Dir(dir)} {
+			config := &baja.Config{Theme: "t", PreviewDir: previewDir, StaticDir: "assets"}
+
+			Expect(config.Validate()).To(MatchError(ContainSubstring("invalid previewDir")), previewDir)
+		}
+
+		for _, previewDir := range []string{"", "drafts", "tmp/preview", "public-preview"} {
+			config := &baja.Config{Theme: "t", PreviewDir: previewDir}
+
+			Expect(config.Validate()).To(Succeed(), previewDir)
+		}
+	})
+
+	It("rejects a cacheDir baja would evict the site from", func() {
+		for _, cacheDir := range []string{"public", ".", "..", "content/cache", "themes", filepath.Dir(dir)} {
+			config := &baja.Config{Theme: "t", CacheDir: cacheDir}
+
+			Expect(config.Validate()).To(MatchError(ContainSubstring("invalid cacheDir")), cacheDir)
+		}
+
+		config := &baja.Config{Theme: "t", CacheDir: "tmp/drafts/cache", PreviewDir: "tmp/drafts"}
+		Expect(config.Validate()).To(MatchError(ContainSubstring(`invalid cacheDir "tmp/drafts/cache": it overlaps previewDir "tmp/drafts"`)))
+
+		for _, cacheDir := range []string{"", ".baja-cache", "tmp/cache"} {
+			config := &baja.Config{Theme: "t", CacheDir: cacheDir}
+
+			Expect(config.Validate()).To(Succeed(), cacheDir)
+		}
+	})
+
+	It("maps cacheMaxSize to the cache limit", func() {
+		Expect((&baja.Config{}).CacheLimit()).To(Equal(baja.DefaultCacheMaxSize))
+		Expect((&baja.Config{CacheMaxSize: 1 << 10}).CacheLimit()).To(Equal(int64(1 << 10)))
+		Expect((&baja.Config{CacheMaxSize: -1}).CacheLimit()).To(Equal(int64(0)))
+
+		Expect((&baja.Config{Theme: "t", CacheMaxSize: -1}).Validate()).To(Succeed())
+		Expect((&baja.Config{Theme: "t", CacheMaxSize: -2}).Validate()).To(MatchError(ContainSubstring("invalid cacheMaxSize -2")))
+	})
+
+	It("rejects a negative maxWorkers", func() {
+		Expect((&baja.Config{Theme: "t", MaxWorkers: 2}).Validate()).To(Succeed())
+		Expect((&baja.Config{Theme: "t", MaxWorkers: -1}).Validate()).To(MatchError(ContainSubstring("invalid maxWorkers -1")))
+	})
+
+	It("rejects an unknown relatedBy", func() {
+		err := (&baja.Config{Theme: "t", RelatedBy: []string{"tags", "date"}}).Validate()
+
+		Expect(err).To(MatchError(ContainSubstring(`invalid relatedBy "date": must be tags or section`)))
+	})
+
+	It("rejects an env name which isn't a variable name", func() {
+		err := (&baja.Config{Theme: "t", Env: []string{"COMMIT_SHA", "BUILD-NUMBER"}}).Validate()
+
+		Expect(err).To(MatchError(ContainSubstring(`invalid env name "BUILD-NUMBER"`)))
+
+		var errs baja.ValidationErrors
+		Expect(errors.As(err, &errs)).To(Equal(true))
+		Expect(errs).To(HaveLen(1))
+	})
+})
+
+var _ = Describe("Config formats", func() {
+	var dir string
+
+	BeforeEach(func() {
+		dir, _ = ioutil.TempDir("", "baja")
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	fullConfig := func(path string) *baja.Config {
+		c := baja.NewConfig(path)
+		c.Theme = "t"
+		c.Site = "Old"
+		c.Title = "Notes"
+		c.Description = "About things"
+		c.BaseURL = "https://example.com/"
+		c.Language = "en"
+		c.DefaultLanguage = "en"
+		c.DateInputFormats = []string{"2006-01-02", "Jan 2, 2006"}
+		c.DateFormats = map[string]string{"default": "January 2, 2006", "listing": "Jan 2"}
+		c.Languages = map[string]*baja.LanguageConfig{"en": {Title: "English", BaseURL: "/", Params: map[string]interface{}{"greeting": "Hi"}}}
+		c.Timezone = "Asia/Ho_Chi_Minh"
+		c.DefaultImage = "/img/default.png"
+		c.Taxonomies = map[string]*baja.Taxonomy{"cuisine": {Path: "cuisines", Index: true}, "categories": {Disabled: true}}
+		c.Menus = map[string][]*baja.MenuEntry{"main": {{Name: "Home", URL: "/", Weight: 1}, {Name: "Go", URL: "/go/", Identifier: "go", Parent: "Home"}}}
+		c.Author = baja.SiteAuthor{Name: "Yeo", Email: "yeo@example.com", URL: "https://yeo.example.com", Image: "/yeo.png"}
+		c.Social = []baja.SocialLink{{Name: "Mastodon", URL: "https://mastodon.social/@yeo", Icon: "mastodon"}}
+		c.Authors = map[string]*baja.AuthorProfile{"yeo": {Name: "Yeo", Bio: "Writes", Avatar: "/yeo.png", Social: map[string]string{"twitter": "yeo"}}}
+		c.PrettyXML = true
+		c.Encodings = map[string]string{"legacy/*.md": "latin1"}
+		c.PruneOrphans = true
+		c.PruneProtect = []string{"CNAME"}
+		c.Sanitize = map[string]string{"*": baja.SanitizeUGC}
+		c.Markup = baja.MarkupConfig{Extensions: []string{"tables", "footnotes"}, RawHTML: baja.RawHTMLEscape, Sanitize: baja.SanitizeStrict, Highlight: "github"}
+		c.ReadRoot = "examples"
+		c.PreviewDir = "preview"
+		c.TrailingSlash = baja.TrailingSlashServe
+		c.OutputPaths = baja.OutputPathsASCII
+		c.BuildReport = true
+		c.MainSections = []string{"blog"}
+		c.RecentPosts = 3
+		c.RelatedPosts = 4
+		c.RelatedBy = []string{"tags"}
+		c.Paginate = 10
+		c.SectionPaginate = map[string]int{"gallery": 24}
+		c.PaginateNoIndex = true
+		c.SummaryLength = 120
+		c.SummaryUnit = baja.SummaryRunes
+		c.Outputs = map[string][]string{"section": {"html", "rss"}, "page": {"html", "json", "ics"}}
+		c.CustomFormats = map[string]baja.OutputFormat{"ics": {Suffix: "ics", BaseName: "event", MediaType: "text/calendar"}}
+		c.StaticDir = "assets"
+		c.AssetBaseURL = "https://cdn.example.com/"
+		c.StaticIgnore = []string{".git", "node_modules"}
+		c.FollowSymlinks = true
+		c.MaxWorkers = 4
+		c.EmbedScripts = true
+		c.Env = []string{"COMMIT_SHA", "BUILD_NUMBER"}
+		c.Archive = "site.tar.gz"
+		c.CacheDir = "tmp/cache"
+		c.CacheMaxSize = 1 << 30
+		c.IgnoreFiles = []string{"node_modules", `re:\.bak$`}
+		c.MaxContentSize = 1 << 20
+		c.RenderTimeout = "10s"
+		c.Permalinks = map[string]string{"post": "/:section/:year/:slug/"}
+		c.Defaults = map[string]map[string]interface{}{"notes/**": {"draft": true}}
+		c.Mounts = []baja.Mount{{Source: "../changelog", Target: "changelog"}}
+		c.DuplicateSlugs = baja.DuplicateSlugsError
+		c.Params = map[string]interface{}{"accent": "red", "social": map[string]interface{}{"twitter": "yeo"}}
+		c.LogLevel = "debug"
+		c.LogFormat = baja.LogFormatJSON
+		c.Deploy = baja.DeployConfig{Branch: "pages", Remote: "upstream", Message: "Build {{ .Date }}"}
+		return c
+	}
+
+	for _, name := range []string{"baja.yaml", "baja.toml"} {
+		name := name
+
+		It("round trips every field through "+name, func() {
+			path := filepath.Join(dir, name)
+			config := fullConfig(path)
+			Expect(config.WriteFile()).To(Succeed())
+
+			data, _ := ioutil.ReadFile(path)
+			read, err := baja.ParseConfig(path, data)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(baja.NewParams(read.Params)).To(Equal(baja.NewParams(config.Params)))
+			read.Params, config.Params = nil, nil
+			Expect(read).To(Equal(config))
+		})
+
+		It("keeps keys of other tools when writing "+name, func() {
+			path := filepath.Join(dir, name)
+			fullConfig(path).WriteFile()
+			data, _ := ioutil.ReadFile(path)
+			if filepath.Ext(name) == ".toml" {
+				data = append([]byte("lintStrict = true\n"), data...)
+			} else {
+				data = append(data, "lint:\n  strict: true\n"...)
+			}
+			ioutil.WriteFile(path, data, 0644)
+
+			config, err := baja.ParseConfig(path, data)
+			Expect(err).ToNot(HaveOccurred())
+			config.Title = "Renamed"
+			Expect(config.WriteFile()).To(Succeed())
+
+			data, _ = ioutil.ReadFile(path)
+			raw, err := baja.DecodeConfigMap(path, data)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(raw["title"]).To(Equal("Renamed"))
+			if filepath.Ext(name) == ".toml" {
+				Expect(raw["lintStrict"]).To(Equal(true))
+			} else {
+				Expect(raw["lint"]).To(Equal(map[string]interface{}{"strict": true}))
+			}
+		})
+
+		It("writes every option commented with its default in "+name, func() {
+			path := filepath.Join(dir, name)
+			Expect(baja.WriteDefault(path)).To(Succeed())
+			data, _ := ioutil.ReadFile(path)
+
+			config, err := baja.ParseConfig(path, data)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config.Theme).To(BeEmpty())
+			Expect(config.PreviewDir).To(BeEmpty())
+
+			// uncommented, the options read back as their defaults
+			option := regexp.MustCompile(`(?m)^# (\s*\w+:|\w+ = |\[\w+\]$)`)
+			config, err = baja.ParseConfig(path, option.ReplaceAll(data, []byte("$1")))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config.PreviewDir).To(Equal(baja.DefaultPreviewDir))
+			Expect(config.TrailingSlash).To(Equal(baja.TrailingSlashRedirect))
+			Expect(config.Deploy.Branch).To(Equal("gh-pages"))
+			Expect(config.Markup.RawHTML).To(Equal(baja.RawHTMLAllow))
+		})
+	}
+
+	It("documents every option in the default config", func() {
+		t := reflect.TypeOf(baja.Config{})
+		data := string(baja.DefaultConfigFile(baja.ConfigFormatYAML))
+
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.PkgPath != "" {
+				continue
+			}
+			key := f.Tag.Get("yaml")
+			Expect(f.Tag.Get("comment")).ToNot(BeEmpty(), "comment tag of "+f.Name)
+			Expect(data).To(MatchRegexp(`(?m)^(# )?`+key+`:`), "option "+key)
+		}
+	})
+
+	It("loads a toml site with its environment overlay", func() {
+		ioutil.WriteFile(filepath.Join(dir, "baja.toml"), []byte("theme = \"t\"\nbaseURL = \"https://example.com\"\n[deploy]\nbranch = \"pages\"\nremote = \"upstream\"\n"), 0644)
+		ioutil.WriteFile(filepath.Join(dir, "baja.staging.toml"), []byte("[deploy]\nbranch = \"staging\"\n"), 0644)
+
+		Expect(baja.FindConfig(dir)).To(Equal(filepath.Join(dir, "baja.toml")))
+		site, err := baja.LoadSite(baja.FindConfig(dir), "staging")
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(site.Config.Theme).To(Equal("t"))
+		Expect(site.Config.Deploy.Branch).To(Equal("staging"))
+		Expect(site.Config.Deploy.Remote).To(Equal("upstream"))
+	})
+
+	It("detects the format by content when the extension is ambiguous", func() {
+		Expect(baja.DetectConfigFormat("site.conf", []byte("theme = \"t\"\n"))).To(Equal(baja.ConfigFormatTOML))
+		Expect(baja.DetectConfigFormat("site.conf", []byte("[deploy]\nbranch = \"x\"\n"))).To(Equal(baja.ConfigFormatTOML))
+		Expect(baja.DetectConfigFormat("site.conf", []byte("theme: t\n"))).To(Equal(baja.ConfigFormatYAML))
+		Expect(baja.DetectConfigFormat("baja.yml", []byte("theme = \"t\"\n"))).To(Equal(baja.ConfigFormatYAML))
+	})
+
+	It("reads author as a table or as a name", func() {
+		config, err := baja.ParseConfig("baja.yaml", []byte("author:\n  name: Yeo\n  email: yeo@example.com\n"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(config.Author).To(Equal(baja.SiteAuthor{Name: "Yeo", Email: "yeo@example.com"}))
+
+		config, err = baja.ParseConfig("baja.yaml", []byte("author: Yeo\n"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(config.Author).To(Equal(baja.SiteAuthor{Name: "Yeo"}))
+
+		config, err = baja.ParseConfig("baja.toml", []byte("author = \"Yeo\"\n"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(config.Author).To(Equal(baja.SiteAuthor{Name: "Yeo"}))
+
+		config, err = baja.ParseConfig("baja.toml", []byte("[author]\nname = \"Yeo\"\nurl = \"https://yeo.example.com\"\n"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(config.Author).To(Equal(baja.SiteAuthor{Name: "Yeo", URL: "https://yeo.example.com"}))
+
+		_, err = baja.ParseConfig("baja.toml", []byte("[author]\nname = 3\n"))
+		Expect(err).To(MatchError(ContainSubstring("key author.name: expected a string")))
+	})
+
+	It("names the key and expected type of a wrong value", func() {
+		_, err := baja.ParseConfig("baja.toml", []byte("prettyXML = \"yes\"\n"))
+		Expect(err).To(MatchError(`key prettyXML: expected a boolean, got a string "yes"`))
+
+		_, err = baja.ParseConfig("baja.yaml", []byte("authors:\n  yeo:\n    social: [a, b]\n"))
+		Expect(err).To(MatchError("key authors.yeo.social: expected a table, got a list"))
+
+		_, err = baja.ParseConfig("baja.yaml", []byte("pruneProtect: [CNAME, 3]\n"))
+		Expect(err).To(MatchError("key pruneProtect[1]: expected a string, got an integer"))
+	})
+})
+
+var _ = Describe("Date formats", func() {
+	It("adds named formats to the default ones", func() {
+		config := &baja.Config{DateFormats: map[string]string{"listing": "Jan 2"}}
+
+		Expect(config.DateFormat("listing")).To(Equal("Jan 2"))
+		Expect(config.DateFormat("default")).To(Equal("2006 Jan 02"))
+	})
+
+	It("overrides the default format", func() {
+		config := &baja.Config{DateFormats: map[string]string{"default": "January 2, 2006"}}
+
+		Expect(config.DateFormat("default")).To(Equal("January 2, 2006"))
+	})
+
+	It("lists the available names of an unknown one", func() {
+		_, err := (&baja.Config{DateFormats: map[string]string{"listing": "Jan 2"}}).DateFormat("short")
+
+		Expect(err).To(MatchError(`unknown date format "short", available: default, listing, rfc1123, rfc3339`))
+	})
+})
+
+var _ = Describe("Environment variables", func() {
+	BeforeEach(func() {
+		os.Setenv("BAJA_TEST_REMOTE", "upstream")
+		os.Setenv("BAJA_TEST_EMPTY", "")
+	})
+
+	AfterEach(func() {
+		os.Unsetenv("BAJA_TEST_REMOTE")
+		os.Unsetenv("BAJA_TEST_EMPTY")
+	})
+
+	It("are replaced in string values of config", func() {
+		config, err := baja.ParseConfig("baja.yaml", []byte("deploy:\n  remote: ${BAJA_TEST_REMOTE}\n  branch: ${BAJA_TEST_EMPTY:-pages}\n"+
+			"params:\n  analytics: id-${BAJA_TEST_REMOTE}\n  nested:\n    list: [\"${BAJA_TEST_MISSING:-none}\"]\n"))
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(config.Deploy.Remote).To(Equal("upstream"))
+		Expect(config.Deploy.Branch).To(Equal("pages"))
+		Expect(config.Params["analytics"]).To(Equal("id-upstream"))
+		Expect(config.Params["nested"]).To(Equal(map[interface{}]interface{}{"list": []interface{}{"none"}}))
+	})
+
+	It("reports every unset variable without a default", func() {
+		config, err := baja.ParseConfig("baja.toml", []byte("theme = \"${BAJA_TEST_THEME}\"\n[deploy]\nremote = \"${BAJA_TEST_BUCKET}\"\n"))
+		Expect(err).ToNot(HaveOccurred())
+
+		err = config.Validate()
+		Expect(err).To(MatchError(ContainSubstring("environment variable BAJA_TEST_BUCKET of deploy.remote is not set")))
+		Expect(err).To(MatchError(ContainSubstring("environment variable BAJA_TEST_THEME of theme is not set")))
+	})
+})
@@ -27,10 +27,14 @@ func NewContext(config *Config) *Context {
 
 // Current is a struct about various current state we pass to template to help us do some business logic depend on a context
 type Current struct {
-	IsHome bool
-	IsDir  bool
-	IsTag  bool
-	IsList bool
+	IsHome   bool
+	IsDir    bool
+	IsTag    bool // a term page of any taxonomy, Taxonomy is its front matter key
+	IsTerms  bool // the page listing every term of Taxonomy
+	IsAuthor bool
+	IsList   bool
+
+	Taxonomy string
 
 	CompiledAt time.Time
 }
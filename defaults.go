@@ -0,0 +1,95 @@
+package baja
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// globRegexp compiles a path glob: * and ? don't cross a /, ** matches any number of directories
+// and [abc] is a character class
+func globRegexp(pattern string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				b.WriteString(".*")
+				i++
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(pattern[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed character class")
+			}
+			class := pattern[i+1 : i+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+
+	return regexp.Compile(b.String())
+}
+
+// ValidateDefaults checks the patterns of Config.Defaults. All problems are returned as ValidationErrors
+func ValidateDefaults(defaults map[string]map[string]interface{}) error {
+	var errs ValidationErrors
+
+	for _, pattern := range defaultsPatterns(defaults) {
+		if _, err := globRegexp(pattern); err != nil {
+			errs = append(errs, fmt.Errorf("invalid defaults pattern %q: %w", pattern, err))
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return errs
+}
+
+// FrontMatterDefaults returns the front matter values of Config.Defaults whose pattern matches rel,
+// the path of a node relative to content such as notes/idea.md. When several patterns match, the
+// longer one wins key by key. Node front matter is decoded over them
+func (c *Config) FrontMatterDefaults(rel string) map[string]interface{} {
+	values := map[string]interface{}{}
+	for _, pattern := range defaultsPatterns(c.Defaults) {
+		re, err := globRegexp(pattern)
+		if err != nil || !re.MatchString(rel) {
+			continue
+		}
+		for k, v := range stringMap(c.Defaults[pattern]) {
+			values[k] = v
+		}
+	}
+
+	return values
+}
+
+// defaultsPatterns returns the patterns of defaults, shorter first
+func defaultsPatterns(defaults map[string]map[string]interface{}) []string {
+	patterns := make([]string, 0, len(defaults))
+	for pattern := range defaults {
+		patterns = append(patterns, pattern)
+	}
+	sort.Slice(patterns, func(i, j int) bool {
+		if len(patterns[i]) != len(patterns[j]) {
+			return len(patterns[i]) < len(patterns[j])
+		}
+		return patterns[i] < patterns[j]
+	})
+
+	return patterns
+}
@@ -0,0 +1,89 @@
+package deploy
+
+import (
+	"flag"
+
+	"github.com/fatih/color"
+
+	"github.com/yeo/baja"
+	"github.com/yeo/baja/render"
+)
+
+type Command struct {
+	branch  string
+	message string
+	push    bool
+}
+
+func (cmd *Command) ArgDesc() string {
+	return ""
+}
+
+func (cmd *Command) Help() string {
+	return "Build then commit public directory to a branch. The current branch and working tree are left alone"
+}
+
+func (cmd *Command) Flags(fs *flag.FlagSet) {
+	fs.StringVar(&cmd.branch, "branch", "", "branch to commit into, default to deploy.branch of config or gh-pages")
+	fs.StringVar(&cmd.message, "message", "", "commit message, default to deploy.message of config")
+	fs.BoolVar(&cmd.push, "push", false, "push the branch to deploy.remote after committing")
+}
+
+func (cmd *Command) Run(site *baja.Site, args []string) int {
+	if site == nil {
+		color.Red("Cannot find baja.yaml. Run baja init to create a new site")
+		return baja.ExitConfigError
+	}
+
+	if err := render.Build(site); err != nil {
+		color.Red("Build failed, nothing is deployed: %v", err)
+		return baja.ExitCode(err)
+	}
+
+	p := cmd.publisher(site.Config.Deploy)
+	hash, err := p.Publish()
+	switch {
+	case err == ErrUpToDate:
+		color.Yellow("%s is already up to date", p.Branch)
+	case err != nil:
+		color.Red("Cannot deploy: %v", err)
+		return baja.ExitInternalError
+	default:
+		color.Green("Committed %s into %s", hash.String()[:7], p.Branch)
+	}
+
+	if !cmd.push {
+		color.Yellow("Run with --push to push %s to %s", p.Branch, p.Remote)
+		return baja.ExitOK
+	}
+
+	if err := p.Push(); err != nil {
+		color.Red("Cannot push %s to %s: %v", p.Branch, p.Remote, err)
+		return baja.ExitInternalError
+	}
+	color.Green("Pushed %s to %s", p.Branch, p.Remote)
+
+	return baja.ExitOK
+}
+
+// publisher merges flags over deploy config and defaults
+func (cmd *Command) publisher(c baja.DeployConfig) *Publisher {
+	p := &Publisher{
+		Dir:     "public",
+		Branch:  firstOf(cmd.branch, c.Branch, DefaultBranch),
+		Remote:  firstOf(c.Remote, DefaultRemote),
+		Message: firstOf(cmd.message, c.Message, DefaultMessage),
+	}
+
+	return p
+}
+
+func firstOf(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,248 @@
+package deploy
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"text/template"
+	"time"
+
+	git "gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/config"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/filemode"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+const (
+	DefaultBranch  = "gh-pages"
+	DefaultRemote  = "origin"
+	DefaultMessage = "Deploy {{ .Date }}"
+)
+
+// emptyTree is the hash of a tree without entries, git doesn't keep empty directories
+var emptyTree = plumbing.NewHash("4b825dc642cb6eb9a060e54bf8d69288fbee4904")
+
+// ErrUpToDate is returned when the output is the same as the branch tip, nothing is committed
+var ErrUpToDate = errors.New("branch is already up to date")
+
+// Publisher commits a directory onto a branch of the git repository containing it.
+// The commit is written straight into the object store, the working tree, index and
+// current branch are never touched
+type Publisher struct {
+	Dir     string // directory to publish, its content becomes the root of the branch
+	Branch  string
+	Remote  string
+	Message string // text/template, {{ .Date }} is the time of the commit
+}
+
+// Publish commits Dir onto Branch and returns the new commit
+func (p *Publisher) Publish() (plumbing.Hash, error) {
+	repo, err := git.PlainOpenWithOptions(p.Dir, &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("cannot open git repository: %w", err)
+	}
+
+	refName := plumbing.NewBranchReferenceName(p.Branch)
+	if head, err := repo.Storer.Reference(plumbing.HEAD); err == nil && head.Target() == refName {
+		return plumbing.ZeroHash, fmt.Errorf("branch %s is checked out, switch to another branch before deploying", p.Branch)
+	}
+
+	treeHash, err := writeTree(repo, p.Dir)
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("cannot write %s into git: %w", p.Dir, err)
+	}
+
+	parents := []plumbing.Hash{}
+	if ref, err := repo.Reference(refName, true); err == nil {
+		parent, err := repo.CommitObject(ref.Hash())
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+		if parent.TreeHash == treeHash {
+			return ref.Hash(), ErrUpToDate
+		}
+		parents = append(parents, ref.Hash())
+	} else if err != plumbing.ErrReferenceNotFound {
+		return plumbing.ZeroHash, err
+	}
+
+	now := time.Now()
+	message, err := p.message(now)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	signature := signature(repo, now)
+	commit := &object.Commit{
+		Author:       signature,
+		Committer:    signature,
+		Message:      message,
+		TreeHash:     treeHash,
+		ParentHashes: parents,
+	}
+
+	hash, err := storeObject(repo, commit)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(refName, hash)); err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	return hash, nil
+}
+
+// Push pushes Branch to Remote. It's a fast forward push, a diverged remote branch is an error
+func (p *Publisher) Push() error {
+	repo, err := git.PlainOpenWithOptions(p.Dir, &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return fmt.Errorf("cannot open git repository: %w", err)
+	}
+
+	refName := plumbing.NewBranchReferenceName(p.Branch)
+	err = repo.Push(&git.PushOptions{
+		RemoteName: p.Remote,
+		RefSpecs:   []config.RefSpec{config.RefSpec(refName + ":" + refName)},
+	})
+	if err == git.NoErrAlreadyUpToDate {
+		return nil
+	}
+
+	return err
+}
+
+func (p *Publisher) message(now time.Time) (string, error) {
+	tmpl, err := template.New("message").Parse(p.Message)
+	if err != nil {
+		return "", fmt.Errorf("invalid deploy message: %w", err)
+	}
+
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, map[string]interface{}{"Date": now.Format(time.RFC3339)}); err != nil {
+		return "", fmt.Errorf("invalid deploy message: %w", err)
+	}
+
+	return out.String(), nil
+}
+
+// signature uses user.name and user.email of the repository config when they're set
+func signature(repo *git.Repository, now time.Time) object.Signature {
+	s := object.Signature{Name: "baja", Email: "baja@localhost", When: now}
+
+	if cfg, err := repo.Config(); err == nil {
+		user := cfg.Raw.Section("user")
+		if name := user.Option("name"); name != "" {
+			s.Name = name
+		}
+		if email := user.Option("email"); email != "" {
+			s.Email = email
+		}
+	}
+
+	return s
+}
+
+type encoder interface {
+	Encode(plumbing.EncodedObject) error
+}
+
+func storeObject(repo *git.Repository, o encoder) (plumbing.Hash, error) {
+	obj := repo.Storer.NewEncodedObject()
+	if err := o.Encode(obj); err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	return repo.Storer.SetEncodedObject(obj)
+}
+
+// writeTree stores dir recursively as blobs and trees and returns the hash of its tree
+func writeTree(repo *git.Repository, dir string) (plumbing.Hash, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	tree := &object.Tree{}
+	for _, f := range files {
+		path := filepath.Join(dir, f.Name())
+		if f.Mode()&os.ModeSymlink != 0 {
+			// follow symlinks so the branch has real files
+			if f, err = os.Stat(path); err != nil {
+				return plumbing.ZeroHash, err
+			}
+		}
+
+		var (
+			hash plumbing.Hash
+			mode = filemode.Regular
+		)
+
+		switch {
+		case f.IsDir():
+			mode = filemode.Dir
+			hash, err = writeTree(repo, path)
+		case f.Mode().IsRegular():
+			if f.Mode()&0111 != 0 {
+				mode = filemode.Executable
+			}
+			hash, err = writeBlob(repo, path)
+		default:
+			continue
+		}
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+		if hash == emptyTree {
+			continue
+		}
+
+		tree.Entries = append(tree.Entries, object.TreeEntry{Name: f.Name(), Mode: mode, Hash: hash})
+	}
+
+	// git sorts directory entries as if their name ended with a slash
+	sort.Slice(tree.Entries, func(i, j int) bool {
+		return entrySortName(tree.Entries[i]) < entrySortName(tree.Entries[j])
+	})
+
+	return storeObject(repo, tree)
+}
+
+func entrySortName(e object.TreeEntry) string {
+	if e.Mode == filemode.Dir {
+		return e.Name + "/"
+	}
+
+	return e.Name
+}
+
+func writeBlob(repo *git.Repository, path string) (plumbing.Hash, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	defer f.Close()
+
+	obj := repo.Storer.NewEncodedObject()
+	obj.SetType(plumbing.BlobObject)
+
+	w, err := obj.Writer()
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	if _, err := io.Copy(w, f); err != nil {
+		w.Close()
+		return plumbing.ZeroHash, err
+	}
+	if err := w.Close(); err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	return repo.Storer.SetEncodedObject(obj)
+}
@@ -0,0 +1,107 @@
+package deploy_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	git "gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+
+	. "github.com/yeo/baja/deploy"
+)
+
+var _ = Describe("Publisher", func() {
+	var (
+		dir       string
+		repo      *git.Repository
+		publisher *Publisher
+	)
+
+	write := func(name, content string) {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), os.ModePerm)
+		Expect(ioutil.WriteFile(path, []byte(content), 0644)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "baja-deploy")
+		Expect(err).ToNot(HaveOccurred())
+
+		repo, err = git.PlainInit(dir, false)
+		Expect(err).ToNot(HaveOccurred())
+
+		// a working branch with one commit
+		write("baja.yaml", "theme: t\n")
+		wt, _ := repo.Worktree()
+		wt.Add("baja.yaml")
+		_, err = wt.Commit("init", &git.CommitOptions{Author: &object.Signature{Name: "a", Email: "a@b"}})
+		Expect(err).ToNot(HaveOccurred())
+
+		write("public/index.html", "home")
+		write("public/post/one/index.html", "one")
+		os.MkdirAll(filepath.Join(dir, "public/empty"), os.ModePerm)
+
+		publisher = &Publisher{Dir: filepath.Join(dir, "public"), Branch: "gh-pages", Remote: "origin", Message: "Deploy {{ .Date }}"}
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("commits public directory as the root of the branch", func() {
+		hash, err := publisher.Publish()
+		Expect(err).ToNot(HaveOccurred())
+
+		ref, err := repo.Reference(plumbing.NewBranchReferenceName("gh-pages"), true)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ref.Hash()).To(Equal(hash))
+
+		commit, _ := repo.CommitObject(hash)
+		Expect(commit.Message).To(HavePrefix("Deploy "))
+		Expect(commit.NumParents()).To(Equal(0))
+
+		f, err := commit.File("post/one/index.html")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(f.Contents()).To(Equal("one"))
+
+		_, err = commit.File("baja.yaml")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("leaves the working branch alone", func() {
+		before, _ := repo.Head()
+		_, err := publisher.Publish()
+		Expect(err).ToNot(HaveOccurred())
+
+		after, _ := repo.Head()
+		Expect(after.Name()).To(Equal(plumbing.NewBranchReferenceName("master")))
+		Expect(after.Hash()).To(Equal(before.Hash()))
+	})
+
+	It("stacks deploys and skips unchanged output", func() {
+		first, err := publisher.Publish()
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = publisher.Publish()
+		Expect(err).To(Equal(ErrUpToDate))
+
+		write("public/index.html", "new home")
+		second, err := publisher.Publish()
+		Expect(err).ToNot(HaveOccurred())
+
+		commit, _ := repo.CommitObject(second)
+		Expect(commit.ParentHashes).To(Equal([]plumbing.Hash{first}))
+	})
+
+	It("refuses to commit into the checked out branch", func() {
+		publisher.Branch = "master"
+		_, err := publisher.Publish()
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,143 @@
+package baja
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sync"
+)
+
+// Exit codes of the cli, picked from the error a command gets from the library
+const (
+	ExitOK            = 0
+	ExitContentError  = 1
+	ExitConfigError   = 2
+	ExitInternalError = 3
+)
+
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// ConfigError is returned when the config file cannot be read, parsed or is invalid
+type ConfigError struct {
+	Path string
+	Err  error
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("config %s: %v", e.Path, e.Err)
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+// ContentError is returned by a build when some content or template files failed.
+// The build carries on with other files, Diagnostics has the detail of each of them
+type ContentError struct {
+	Diagnostics *Diagnostics
+}
+
+func (e *ContentError) Error() string {
+	return fmt.Sprintf("%d content or template errors", e.Diagnostics.Count(SeverityError))
+}
+
+// Diagnostic is an error or warning about a single file
+type Diagnostic struct {
+	Path     string `json:"path"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// Diagnostics collects per file errors and warnings of a build. It's safe for concurrent use
+type Diagnostics struct {
+	mu    sync.Mutex
+	Items []Diagnostic
+}
+
+func (d *Diagnostics) add(path, severity, message string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.Items = append(d.Items, Diagnostic{Path: path, Severity: severity, Message: message})
+}
+
+// AddError records that path failed to build
+func (d *Diagnostics) AddError(path string, err error) {
+	d.add(path, SeverityError, err.Error())
+}
+
+// AddWarning records a problem of path which didn't stop it from building
+func (d *Diagnostics) AddWarning(path, message string) {
+	d.add(path, SeverityWarning, message)
+}
+
+// Count returns the number of diagnostics of a severity
+func (d *Diagnostics) Count(severity string) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	total := 0
+	for _, item := range d.Items {
+		if item.Severity == severity {
+			total++
+		}
+	}
+
+	return total
+}
+
+// Err returns a ContentError when any error was recorded, nil otherwise
+func (d *Diagnostics) Err() error {
+	if d.Count(SeverityError) == 0 {
+		return nil
+	}
+
+	return &ContentError{Diagnostics: d}
+}
+
+// WriteReport writes the diagnostics as json into path, for CI annotation tools
+func (d *Diagnostics) WriteReport(path string) error {
+	report := struct {
+		Errors      int          `json:"errors"`
+		Warnings    int          `json:"warnings"`
+		Diagnostics []Diagnostic `json:"diagnostics"`
+	}{
+		Errors:      d.Count(SeverityError),
+		Warnings:    d.Count(SeverityWarning),
+		Diagnostics: []Diagnostic{},
+	}
+
+	d.mu.Lock()
+	report.Diagnostics = append(report.Diagnostics, d.Items...)
+	d.mu.Unlock()
+
+	out, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, out, 0644)
+}
+
+// ExitCode maps an error returned by the library to the exit code of the cli
+func ExitCode(err error) int {
+	var (
+		configErr  *ConfigError
+		contentErr *ContentError
+	)
+
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.As(err, &configErr):
+		return ExitConfigError
+	case errors.As(err, &contentErr):
+		return ExitContentError
+	default:
+		return ExitInternalError
+	}
+}
@@ -0,0 +1,82 @@
+package doctor
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/fatih/color"
+
+	"github.com/yeo/baja"
+)
+
+type Command struct {
+	json    bool
+	loadErr error
+}
+
+func (cmd *Command) ArgDesc() string {
+	return ""
+}
+
+func (cmd *Command) Help() string {
+	return "Check the site setup: config, theme templates and content layout, with a fix for each problem"
+}
+
+func (cmd *Command) Flags(fs *flag.FlagSet) {
+	fs.BoolVar(&cmd.json, "json", false, "print findings as json")
+}
+
+// DiagnoseConfig keeps the config load error so doctor runs and reports it rather than the cli exiting
+func (cmd *Command) DiagnoseConfig(err error) {
+	cmd.loadErr = err
+}
+
+func (cmd *Command) Run(site *baja.Site, args []string) int {
+	r := Diagnose(baja.FindConfig("."), cmd.loadErr)
+
+	code := baja.ExitOK
+	if r.Count(baja.SeverityError) > 0 {
+		code = baja.ExitConfigError
+	}
+
+	if cmd.json {
+		out, err := json.MarshalIndent(r, "", "  ")
+		if err != nil {
+			color.Red("Cannot encode findings %v", err)
+			return baja.ExitInternalError
+		}
+		fmt.Println(string(out))
+
+		return code
+	}
+
+	for _, f := range r.Findings {
+		mark := color.YellowString("warning")
+		if f.Severity == baja.SeverityError {
+			mark = color.RedString("error")
+		}
+
+		fmt.Fprintf(color.Output, "%s [%s] %s\n", mark, f.Check, f.Message)
+		if f.Path != "" && f.Path != f.Message {
+			fmt.Printf("    in %s\n", f.Path)
+		}
+		fmt.Printf("    fix: %s\n", f.Fix)
+	}
+
+	errors, warnings := r.Count(baja.SeverityError), r.Count(baja.SeverityWarning)
+	if errors+warnings == 0 {
+		color.Green("No problem found 🏖")
+		return code
+	}
+
+	fmt.Fprintln(os.Stdout)
+	summary := color.YellowString
+	if errors > 0 {
+		summary = color.RedString
+	}
+	fmt.Println(summary("%d errors, %d warnings", errors, warnings))
+
+	return code
+}
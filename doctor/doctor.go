@@ -0,0 +1,395 @@
+package doctor
+
+import (
+	"html/template"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/yeo/baja"
+	"github.com/yeo/baja/frontmatter"
+	"github.com/yeo/baja/node"
+	"github.com/yeo/baja/utils"
+)
+
+// Finding is a problem of the site setup and how to fix it
+type Finding struct {
+	Check    string `json:"check"`
+	Severity string `json:"severity"`
+	Path     string `json:"path,omitempty"`
+	Message  string `json:"message"`
+	Fix      string `json:"fix"`
+}
+
+// Report collects the findings of every check
+type Report struct {
+	Findings []Finding `json:"findings"`
+}
+
+func (r *Report) add(f Finding) {
+	r.Findings = append(r.Findings, f)
+}
+
+// Count returns the number of findings of a severity
+func (r *Report) Count(severity string) int {
+	total := 0
+	for _, f := range r.Findings {
+		if f.Severity == severity {
+			total++
+		}
+	}
+
+	return total
+}
+
+// requiredTemplates are the theme files every build reads
+var requiredTemplates = []string{"layout/default.html", "node.html", "index.html"}
+
+// skipDirs are never searched for misplaced content
+var skipDirs = map[string]bool{
+	"content": true, "themes": true, "theme": true, "public": true, "static": true, "node_modules": true,
+	node.ArchetypeDir: true, baja.DefaultPreviewDir: true,
+}
+
+// Diagnose runs every check against the site at the current directory. loadErr is the error
+// the cli got loading configPath, if any. Checks carry on after a finding so one run shows them all
+func Diagnose(configPath string, loadErr error) *Report {
+	r := &Report{}
+
+	config := checkConfig(r, configPath, loadErr)
+	checkLayout(r, config)
+	if config != nil && config.Theme != "" {
+		checkTheme(r, filepath.Join("themes", config.Theme))
+	}
+	checkContent(r)
+
+	return r
+}
+
+// checkConfig reports parse errors, unknown keys and missing settings. It returns the config
+// when it could be parsed
+func checkConfig(r *Report, path string, loadErr error) *baja.Config {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		r.add(Finding{Check: "config", Severity: baja.SeverityError, Path: path, Message: err.Error(),
+			Fix: "run baja from the site directory, or baja init to create a site"})
+		return nil
+	}
+
+	if loadErr != nil {
+		r.add(Finding{Check: "config", Severity: baja.SeverityError, Path: path, Message: loadErr.Error(),
+			Fix: "fix the config file, it must be valid yaml or toml"})
+	}
+
+	overlays, _ := filepath.Glob(strings.TrimSuffix(path, filepath.Ext(path)) + ".*" + filepath.Ext(path))
+	for _, p := range append([]string{path}, overlays...) {
+		content, err := ioutil.ReadFile(p)
+		if err != nil {
+			continue
+		}
+		raw, err := baja.DecodeConfigMap(p, content)
+		if err != nil {
+			continue
+		}
+		checkKeys(r, p, "", raw, reflect.TypeOf(baja.Config{}))
+	}
+
+	config, err := baja.ParseConfig(path, data)
+	if err != nil {
+		if loadErr == nil {
+			r.add(Finding{Check: "config", Severity: baja.SeverityError, Path: path, Message: err.Error(),
+				Fix: "fix the config file, it must be valid yaml or toml"})
+		}
+		return nil
+	}
+
+	if config.Theme == "" {
+		r.add(Finding{Check: "config", Severity: baja.SeverityError, Path: path, Message: "theme is not set",
+			Fix: "set theme to a directory name under themes"})
+	}
+	if config.BaseURL == "" {
+		r.add(Finding{Check: "config", Severity: baja.SeverityWarning, Path: path, Message: "baseURL is not set, feed and sitemap urls will be relative",
+			Fix: "set baseURL, eg: baseURL: https://example.com/"})
+	}
+
+	return config
+}
+
+// checkKeys reports keys of raw which don't map to a field of t, recursing into nested sections
+func checkKeys(r *Report, path, prefix string, raw map[string]interface{}, t reflect.Type) {
+	fields := configFields(t)
+
+	keys := []string{}
+	for k := range raw {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		v := raw[key]
+		field, ok := fields[key]
+		if !ok {
+			fix := "remove it, it's ignored"
+			if s := suggest(key, fields); s != "" {
+				fix = "did you mean " + prefix + s + "?"
+			}
+			r.add(Finding{Check: "config", Severity: baja.SeverityWarning, Path: path, Message: "unknown key " + prefix + key, Fix: fix})
+			continue
+		}
+
+		nested, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		switch {
+		case field.Kind() == reflect.Struct:
+			checkKeys(r, path, prefix+key+".", nested, field)
+		case field.Kind() == reflect.Map && elemStruct(field.Elem()) != nil:
+			for id, entry := range nested {
+				if m, ok := entry.(map[string]interface{}); ok {
+					checkKeys(r, path, prefix+key+"."+id+".", m, elemStruct(field.Elem()))
+				}
+			}
+		}
+	}
+}
+
+// configFields maps the config keys of a struct to their type, yaml and toml keys are the same
+func configFields(t reflect.Type) map[string]reflect.Type {
+	fields := map[string]reflect.Type{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := strings.Split(f.Tag.Get("yaml"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		fields[name] = f.Type
+	}
+
+	return fields
+}
+
+func elemStruct(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Struct {
+		return t
+	}
+
+	return nil
+}
+
+// suggest returns the known key closest to key, if one is close enough to be a typo
+func suggest(key string, fields map[string]reflect.Type) string {
+	best, bestDistance := "", 3
+	for name := range fields {
+		if strings.EqualFold(name, key) {
+			return name
+		}
+		if d := distance(strings.ToLower(name), strings.ToLower(key)); d < bestDistance {
+			best, bestDistance = name, d
+		}
+	}
+
+	return best
+}
+
+// distance is the levenshtein distance of a and b
+func distance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+
+	return prev[len(b)]
+}
+
+func min(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+
+	return m
+}
+
+// checkLayout verifies the directories the build reads exist, and looks for misnamed ones
+func checkLayout(r *Report, config *baja.Config) {
+	if !isDir("content") {
+		r.add(Finding{Check: "layout", Severity: baja.SeverityError, Path: "content", Message: "content directory is missing",
+			Fix: "create content and put your markdown files into it"})
+	}
+
+	if config == nil || config.Theme == "" {
+		return
+	}
+
+	themeDir := filepath.Join("themes", config.Theme)
+	if isDir(themeDir) || (baja.IsThemeArchive(config.Theme) && isFile(themeDir)) {
+		return
+	}
+
+	f := Finding{Check: "layout", Severity: baja.SeverityError, Path: themeDir, Message: "theme " + config.Theme + " is not found"}
+	switch candidate := findTheme(config.Theme); {
+	case candidate != "":
+		f.Fix = "rename " + candidate + " to " + themeDir
+	default:
+		themes := listDirs("themes")
+		if len(themes) > 0 {
+			f.Fix = "set theme to one of " + strings.Join(themes, ", ")
+		} else {
+			f.Fix = "create " + themeDir + " with layout/default.html, node.html and index.html"
+		}
+	}
+	r.add(f)
+}
+
+// findTheme looks for a theme directory in the wrong place or with a different case
+func findTheme(name string) string {
+	if p := filepath.Join("theme", name); isDir(p) {
+		return p
+	}
+
+	for _, root := range []string{"themes", "theme"} {
+		for _, d := range listDirs(root) {
+			if strings.EqualFold(d, name) {
+				return filepath.Join(root, d)
+			}
+		}
+	}
+
+	return ""
+}
+
+// checkTheme verifies required templates exist and every template parses with baja functions
+func checkTheme(r *Report, dir string) {
+	if !isDir(dir) {
+		return
+	}
+
+	for _, name := range requiredTemplates {
+		path := filepath.Join(dir, name)
+		if name == "node.html" && utils.HasFile(filepath.Join(dir, filepath.FromSlash(node.DefaultSingle))) {
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
+			r.add(Finding{Check: "theme", Severity: baja.SeverityError, Path: path, Message: name + " is missing",
+				Fix: "create " + path + ", every build needs it"})
+		}
+	}
+
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || filepath.Ext(path) != ".html" {
+			return nil
+		}
+
+		tpl, err := template.New(filepath.Base(path)).Funcs(baja.FuncMaps(nil)).ParseFiles(path)
+		if err != nil {
+			r.add(Finding{Check: "theme", Severity: baja.SeverityError, Path: path, Message: err.Error(), Fix: templateFix(err)})
+			return nil
+		}
+
+		if path == filepath.Join(dir, "layout", "default.html") && tpl.Lookup("layout") == nil {
+			r.add(Finding{Check: "theme", Severity: baja.SeverityError, Path: path, Message: "layout template is not defined",
+				Fix: `wrap the layout into {{ define "layout" }}...{{ end }}`})
+		}
+
+		return nil
+	})
+}
+
+func templateFix(err error) string {
+	if strings.Contains(err.Error(), "not defined") && strings.Contains(err.Error(), "function") {
+		names := []string{}
+		for name := range baja.FuncMaps(nil) {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		return "use one of the baja template functions: " + strings.Join(names, ", ")
+	}
+
+	return "fix the template syntax"
+}
+
+// checkContent looks for content files outside content directory, they're never built
+func checkContent(r *Report) {
+	filepath.Walk(".", func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+
+		if info.IsDir() {
+			if path != "." && (skipDirs[path] || strings.HasPrefix(info.Name(), ".")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		switch filepath.Ext(path) {
+		case ".md", ".markdown", ".html":
+		default:
+			return nil
+		}
+
+		if hasFrontMatter(path) {
+			r.add(Finding{Check: "content", Severity: baja.SeverityWarning, Path: path, Message: path + " is outside content directory and isn't built",
+				Fix: "move it into content"})
+		}
+
+		return nil
+	})
+}
+
+func hasFrontMatter(path string) bool {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return false
+	}
+
+	format, _, _, err := frontmatter.Split(content)
+	return err == nil && format != frontmatter.None
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+func isFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
+
+func listDirs(root string) []string {
+	files, _ := ioutil.ReadDir(root)
+
+	dirs := []string{}
+	for _, f := range files {
+		if f.IsDir() {
+			dirs = append(dirs, f.Name())
+		}
+	}
+
+	return dirs
+}
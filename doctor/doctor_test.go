@@ -0,0 +1,130 @@
+package doctor_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/yeo/baja"
+	. "github.com/yeo/baja/doctor"
+)
+
+var goodSite = map[string]string{
+	"baja.yaml":                    "theme: t\nbaseURL: https://example.com\n",
+	"content/post/one.md":          "+++\ntitle = \"One\"\n+++\nbody",
+	"themes/t/layout/default.html": `{{ define "layout" }}<link href="{{ asset "/app.css" }}">{{ template "content" . }}{{ end }}`,
+	"themes/t/node.html":           `{{ define "content" }}{{ .Body }}{{ end }}`,
+	"themes/t/index.html":          `{{ define "content" }}{{ range .Nodes }}{{ .Meta.Title }}{{ end }}{{ end }}`,
+}
+
+var _ = Describe("Diagnose", func() {
+	var (
+		cwd string
+		dir string
+	)
+
+	// site writes goodSite with files merged over it, an empty content removes the file
+	site := func(files map[string]string) {
+		for _, set := range []map[string]string{goodSite, files} {
+			for name, content := range set {
+				path := filepath.Join(dir, name)
+				if content == "" {
+					os.Remove(path)
+					continue
+				}
+				os.MkdirAll(filepath.Dir(path), os.ModePerm)
+				Expect(ioutil.WriteFile(path, []byte(content), 0644)).To(Succeed())
+			}
+		}
+	}
+
+	messages := func(r *Report) []string {
+		out := []string{}
+		for _, f := range r.Findings {
+			out = append(out, f.Message+" => "+f.Fix)
+		}
+		return out
+	}
+
+	BeforeEach(func() {
+		cwd, _ = os.Getwd()
+		dir, _ = ioutil.TempDir("", "baja-doctor")
+		Expect(os.Chdir(dir)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		os.Chdir(cwd)
+		os.RemoveAll(dir)
+	})
+
+	It("finds nothing on a good site", func() {
+		site(nil)
+		Expect(Diagnose("baja.yaml", nil).Findings).To(BeEmpty())
+	})
+
+	It("suggests the right config key", func() {
+		site(map[string]string{"baja.yaml": "theme: t\nbaseurl: https://example.com\ndeploy:\n  branhc: gh-pages\n"})
+
+		Expect(messages(Diagnose("baja.yaml", nil))).To(ContainElement("unknown key baseurl => did you mean baseURL?"))
+		Expect(messages(Diagnose("baja.yaml", nil))).To(ContainElement("unknown key deploy.branhc => did you mean deploy.branch?"))
+	})
+
+	It("suggests the right config key of a toml config", func() {
+		site(map[string]string{"baja.toml": "theme = \"t\"\nbaseurl = \"https://example.com\"\n[deploy]\nbranhc = \"gh-pages\"\n"})
+
+		Expect(messages(Diagnose("baja.toml", nil))).To(ContainElement("unknown key baseurl => did you mean baseURL?"))
+		Expect(messages(Diagnose("baja.toml", nil))).To(ContainElement("unknown key deploy.branhc => did you mean deploy.branch?"))
+	})
+
+	It("finds a misnamed theme directory", func() {
+		site(nil)
+		Expect(os.Rename("themes", "theme")).To(Succeed())
+
+		Expect(messages(Diagnose("baja.yaml", nil))).To(ContainElement("theme t is not found => rename theme/t to themes/t"))
+	})
+
+	It("reports every theme problem in one run", func() {
+		site(map[string]string{
+			"themes/t/layout/default.html": `{{ template "content" . }}`,
+			"themes/t/node.html":           `{{ define "content" }}{{ plainify .Body }}{{ end }}`,
+			"themes/t/index.html":          "",
+		})
+
+		r := Diagnose("baja.yaml", nil)
+		Expect(r.Count(baja.SeverityError)).To(Equal(3))
+		Expect(messages(r)).To(ContainElement(ContainSubstring("use one of the baja template functions: asset")))
+		Expect(messages(r)).To(ContainElement("index.html is missing => create themes/t/index.html, every build needs it"))
+		Expect(messages(r)).To(ContainElement(ContainSubstring("layout template is not defined")))
+	})
+
+	It("accepts _default/single.html in place of node.html", func() {
+		site(map[string]string{
+			"themes/t/node.html":            "",
+			"themes/t/_default/single.html": `{{ define "content" }}{{ .Body }}{{ end }}`,
+		})
+
+		Expect(Diagnose("baja.yaml", nil).Findings).To(BeEmpty())
+	})
+
+	It("finds content outside content directory", func() {
+		site(map[string]string{
+			"posts/two.md": "+++\ntitle = \"Two\"\n+++\nbody",
+			"README.md":    "# my blog",
+		})
+
+		r := Diagnose("baja.yaml", nil)
+		Expect(r.Findings).To(HaveLen(1))
+		Expect(r.Findings[0].Path).To(Equal("posts/two.md"))
+	})
+
+	It("carries on after a broken config", func() {
+		site(map[string]string{"baja.yaml": "theme: [t\n"})
+		os.RemoveAll("content")
+
+		r := Diagnose("baja.yaml", &baja.ConfigError{Path: "baja.yaml", Err: os.ErrInvalid})
+		Expect(r.Count(baja.SeverityError)).To(Equal(2))
+	})
+})
@@ -0,0 +1,105 @@
+package baja
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// envVariable matches ${VAR} and ${VAR:-fallback} in config values
+var envVariable = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// UnsetVariable is a ${VAR} of config without fallback whose variable isn't set
+type UnsetVariable struct {
+	Name string // the environment variable
+	Key  string // the config key using it, eg: deploy.remote
+}
+
+// expandEnv replaces the variables of s with their value. ${VAR:-fallback} is fallback when VAR is
+// unset or empty, ${VAR} unset is returned in unset
+func expandEnv(s string) (expanded string, unset []string) {
+	expanded = envVariable.ReplaceAllStringFunc(s, func(match string) string {
+		parts := envVariable.FindStringSubmatch(match)
+		value, ok := os.LookupEnv(parts[1])
+		if parts[2] != "" {
+			if value == "" {
+				return parts[3]
+			}
+			return value
+		}
+		if !ok {
+			unset = append(unset, parts[1])
+		}
+		return value
+	})
+
+	return expanded, unset
+}
+
+// interpolateEnv expands the environment variables of every string of config, in struct fields,
+// lists, maps and params. The variables without value are returned with the key using them
+func interpolateEnv(config *Config) []UnsetVariable {
+	var unset []UnsetVariable
+	interpolateValue(reflect.ValueOf(config).Elem(), "", &unset)
+
+	return unset
+}
+
+func interpolateValue(v reflect.Value, key string, unset *[]UnsetVariable) {
+	switch v.Kind() {
+	case reflect.String:
+		expanded, names := expandEnv(v.String())
+		for _, name := range names {
+			*unset = append(*unset, UnsetVariable{Name: name, Key: key})
+		}
+		if v.CanSet() {
+			v.SetString(expanded)
+		}
+	case reflect.Ptr:
+		if !v.IsNil() {
+			interpolateValue(v.Elem(), key, unset)
+		}
+	case reflect.Interface:
+		if v.IsNil() {
+			return
+		}
+		// the value of an interface can't be set in place
+		value := reflect.New(v.Elem().Type()).Elem()
+		value.Set(v.Elem())
+		interpolateValue(value, key, unset)
+		v.Set(value)
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			if !v.Field(i).CanSet() {
+				continue
+			}
+			name := strings.Split(t.Field(i).Tag.Get(ConfigFormatYAML), ",")[0]
+			if name == "" {
+				name = t.Field(i).Name
+			}
+			interpolateValue(v.Field(i), joinKey(key, name), unset)
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			interpolateValue(v.Index(i), fmt.Sprintf("%s[%d]", key, i), unset)
+		}
+	case reflect.Map:
+		for _, k := range v.MapKeys() {
+			value := reflect.New(v.Type().Elem()).Elem()
+			value.Set(v.MapIndex(k))
+			interpolateValue(value, joinKey(key, fmt.Sprint(k.Interface())), unset)
+			v.SetMapIndex(k, value)
+		}
+	}
+}
+
+func joinKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+
+	return prefix + "." + key
+}
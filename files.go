@@ -0,0 +1,73 @@
+package baja
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// safePath resolves path inside root. Absolute paths and paths escaping root, with .. or
+// a symlink, are refused
+func safePath(root, path string) (string, error) {
+	if filepath.IsAbs(path) {
+		return "", fmt.Errorf("%s: absolute paths aren't allowed, use a path relative to %s", path, root)
+	}
+
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return "", err
+	}
+	if resolved, err := filepath.EvalSymlinks(absRoot); err == nil {
+		absRoot = resolved
+	}
+
+	full := filepath.Join(absRoot, path)
+	if resolved, err := filepath.EvalSymlinks(full); err == nil {
+		full = resolved
+	}
+
+	rel, err := filepath.Rel(absRoot, full)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s: is outside of %s", path, root)
+	}
+
+	return full, nil
+}
+
+// readFile is the readFile template function
+func readFile(root, path string) (string, error) {
+	full, err := safePath(root, path)
+	if err != nil {
+		return "", err
+	}
+
+	content, err := ioutil.ReadFile(full)
+	if os.IsNotExist(err) {
+		return "", fmt.Errorf("readFile %s: file not found in %s", path, root)
+	}
+	if err != nil {
+		return "", fmt.Errorf("readFile %s: %w", path, err)
+	}
+
+	return string(content), nil
+}
+
+// readDir is the readDir template function
+func readDir(root, path string) ([]os.FileInfo, error) {
+	full, err := safePath(root, path)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := ioutil.ReadDir(full)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("readDir %s: directory not found in %s", path, root)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("readDir %s: %w", path, err)
+	}
+
+	return files, nil
+}
@@ -0,0 +1,67 @@
+package baja_test
+
+import (
+	"bytes"
+	"html/template"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/yeo/baja"
+)
+
+var _ = Describe("readFile and readDir", func() {
+	var (
+		dir  string
+		site *baja.Site
+	)
+
+	execute := func(source string) (string, error) {
+		tpl := template.Must(template.New("t").Funcs(baja.FuncMaps(site)).Parse(source))
+
+		var out bytes.Buffer
+		err := tpl.Execute(&out, nil)
+		return out.String(), err
+	}
+
+	BeforeEach(func() {
+		dir, _ = ioutil.TempDir("", "baja-files")
+		os.MkdirAll(filepath.Join(dir, "examples"), os.ModePerm)
+		ioutil.WriteFile(filepath.Join(dir, "examples", "main.go"), []byte("package main"), 0644)
+		ioutil.WriteFile(filepath.Join(dir, "examples", "util.go"), []byte("package util"), 0644)
+		ioutil.WriteFile(filepath.Join(dir, "secret.txt"), []byte("secret"), 0644)
+
+		site = &baja.Site{Config: &baja.Config{ReadRoot: filepath.Join(dir, "examples")}}
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("reads a file of the read root", func() {
+		Expect(execute(`{{ readFile "main.go" }}`)).To(Equal("package main"))
+	})
+
+	It("lists a directory of the read root", func() {
+		Expect(execute(`{{ range readDir "." }}{{ .Name }} {{ end }}`)).To(Equal("main.go util.go "))
+	})
+
+	It("refuses paths outside of the read root", func() {
+		for _, path := range []string{"../secret.txt", "/etc/passwd", "a/../../secret.txt"} {
+			_, err := execute(`{{ readFile "` + path + `" }}`)
+			Expect(err).To(HaveOccurred(), path)
+		}
+
+		os.Symlink(filepath.Join(dir, "secret.txt"), filepath.Join(dir, "examples", "link.txt"))
+		_, err := execute(`{{ readFile "link.txt" }}`)
+		Expect(err).To(MatchError(ContainSubstring("outside")))
+	})
+
+	It("reports missing files", func() {
+		_, err := execute(`{{ readFile "nope.go" }}`)
+		Expect(err).To(MatchError(ContainSubstring("readFile nope.go: file not found")))
+	})
+})
@@ -0,0 +1,277 @@
+package baja
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+)
+
+// Config file formats
+const (
+	ConfigFormatYAML = "yaml"
+	ConfigFormatTOML = "toml"
+)
+
+// ConfigFiles are the config file names a site is looked up with, in order
+var ConfigFiles = []string{"baja.yaml", "baja.yml", "baja.toml"}
+
+// FindConfig returns the config file of the site at dir, baja.yaml when there is none
+func FindConfig(dir string) string {
+	for _, name := range ConfigFiles {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+
+	return filepath.Join(dir, ConfigFiles[0])
+}
+
+// tomlLine matches a table header or a key = value line, which yaml never has
+var tomlLine = regexp.MustCompile(`(?m)^\s*(\[[^\]\n]+\]|[A-Za-z0-9_."-]+\s*=)`)
+
+// DetectConfigFormat returns the format of a config file from its extension, or from its content
+// when the extension is neither yaml nor toml
+func DetectConfigFormat(path string, data []byte) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".toml":
+		return ConfigFormatTOML
+	case ".yaml", ".yml":
+		return ConfigFormatYAML
+	}
+
+	if tomlLine.Match(data) {
+		return ConfigFormatTOML
+	}
+
+	return ConfigFormatYAML
+}
+
+func unmarshalConfig(format string, data []byte, v interface{}) error {
+	if format == ConfigFormatTOML {
+		_, err := toml.Decode(string(data), v)
+		return err
+	}
+
+	return yaml.Unmarshal(data, v)
+}
+
+func marshalConfig(format string, v interface{}) ([]byte, error) {
+	if format == ConfigFormatTOML {
+		var buf bytes.Buffer
+		if err := toml.NewEncoder(&buf).Encode(v); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	return yaml.Marshal(v)
+}
+
+// DecodeConfigMap decodes a config file into a map, whatever its format. Nested sections are
+// map[string]interface{} as well
+func DecodeConfigMap(path string, data []byte) (map[string]interface{}, error) {
+	raw := map[string]interface{}{}
+	if err := unmarshalConfig(DetectConfigFormat(path, data), data, &raw); err != nil {
+		return nil, err
+	}
+
+	return stringMap(raw), nil
+}
+
+func stringMap(m map[string]interface{}) map[string]interface{} {
+	for k, v := range m {
+		m[k] = stringKeys(v)
+	}
+
+	return m
+}
+
+// stringKeys turns the map[interface{}]interface{} of yaml into map[string]interface{}
+func stringKeys(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		return stringMap(v)
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, value := range v {
+			m[fmt.Sprint(k)] = value
+		}
+		return stringMap(m)
+	case []interface{}:
+		for i, value := range v {
+			v[i] = stringKeys(value)
+		}
+	case []map[string]interface{}:
+		list := make([]interface{}, len(v))
+		for i, value := range v {
+			list[i] = stringMap(value)
+		}
+		return list
+	}
+
+	return v
+}
+
+// ParseConfig decodes the content of config file path, in the format of DetectConfigFormat.
+// A value of the wrong type is reported with its key and the expected type. ${VAR} in string
+// values are replaced with environment variables, the unset ones are reported by Validate
+func ParseConfig(path string, data []byte) (*Config, error) {
+	format := DetectConfigFormat(path, data)
+
+	raw, err := DecodeConfigMap(path, data)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkTypes(format, "", raw, reflect.TypeOf(Config{})); err != nil {
+		return nil, err
+	}
+
+	config := &Config{path: path, format: format}
+	if err := unmarshalConfig(format, data, config); err != nil {
+		return nil, err
+	}
+	config.unset = interpolateEnv(config)
+
+	return config, nil
+}
+
+// checkTypes compares the values of raw with the fields of struct t, matched by their tag of format
+func checkTypes(format, prefix string, raw map[string]interface{}, t reflect.Type) error {
+	fields := map[string]reflect.Type{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := strings.Split(f.Tag.Get(format), ",")[0]
+		if name != "" && name != "-" {
+			fields[name] = f.Type
+		}
+	}
+
+	keys := make([]string, 0, len(raw))
+	for k := range raw {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if field, ok := fields[k]; ok {
+			if err := checkType(format, prefix+k, raw[k], field); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+var yamlUnmarshaler = reflect.TypeOf((*yaml.Unmarshaler)(nil)).Elem()
+
+func checkType(format, key string, v interface{}, t reflect.Type) error {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if v == nil || reflect.PtrTo(t).Implements(yamlUnmarshaler) {
+		// types decoding themselves, such as SiteAuthor, check their values on their own
+		return nil
+	}
+
+	mismatch := fmt.Errorf("key %s: expected %s, got %s", key, typeName(t), valueName(v))
+
+	switch t.Kind() {
+	case reflect.Interface:
+		return nil
+	case reflect.String:
+		if _, ok := v.(string); !ok {
+			return mismatch
+		}
+	case reflect.Bool:
+		if _, ok := v.(bool); !ok {
+			return mismatch
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		switch v.(type) {
+		case int, int64, uint64:
+		default:
+			return mismatch
+		}
+	case reflect.Float32, reflect.Float64:
+		switch v.(type) {
+		case int, int64, uint64, float64:
+		default:
+			return mismatch
+		}
+	case reflect.Slice:
+		list, ok := v.([]interface{})
+		if !ok {
+			return mismatch
+		}
+		for i, item := range list {
+			if err := checkType(format, fmt.Sprintf("%s[%d]", key, i), item, t.Elem()); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return mismatch
+		}
+		for k, item := range m {
+			if err := checkType(format, key+"."+k, item, t.Elem()); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return mismatch
+		}
+		return checkTypes(format, key+".", m, t)
+	}
+
+	return nil
+}
+
+func typeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice:
+		return "a list"
+	default:
+		return "a table"
+	}
+}
+
+func valueName(v interface{}) string {
+	switch v.(type) {
+	case string:
+		return fmt.Sprintf("a string %q", v)
+	case bool:
+		return "a boolean"
+	case int, int64, uint64:
+		return "an integer"
+	case float64:
+		return "a number"
+	case []interface{}:
+		return "a list"
+	case map[string]interface{}:
+		return "a table"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
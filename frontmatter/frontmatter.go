@@ -0,0 +1,142 @@
+// Package frontmatter splits a content file into its front matter and body, and decodes the front
+// matter whatever its format. Nodes and the Hugo importer share it so they read files the same way
+package frontmatter
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"regexp"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+
+	"github.com/yeo/baja"
+)
+
+// Front matter formats, None is a file without front matter
+const (
+	None = ""
+	TOML = "toml"
+	YAML = "yaml"
+	JSON = "json"
+)
+
+// fences open and close the front matter of a format
+var fences = map[string]string{"+++": TOML, "---": YAML}
+
+// jsonStart matches a json object starting a file, not a template action such as {{< include >}}
+var jsonStart = regexp.MustCompile(`^\{\s*["}]`)
+
+var bom = []byte{0xEF, 0xBB, 0xBF}
+
+// ErrNotClosed is the error of a front matter whose closing fence is missing
+var ErrNotClosed = errors.New("not enough header/body")
+
+// Split returns the format of the front matter of content, the front matter and the body after it.
+// The front matter is between a +++ line of toml or a --- line of yaml and the next same line, or
+// a json object. The body keeps the line break closing the fence. A leading UTF-8 BOM and blank
+// lines are dropped and CRLF line breaks read as LF. Content without front matter is all body,
+// of format None
+func Split(content []byte) (string, []byte, []byte, error) {
+	content = bytes.Replace(bytes.TrimPrefix(content, bom), []byte("\r\n"), []byte("\n"), -1)
+	text := strings.TrimLeft(string(content), " \t\n")
+
+	if jsonStart.MatchString(text) {
+		return splitJSON(text)
+	}
+
+	lines := strings.SplitAfter(text, "\n")
+	fence := strings.TrimSpace(lines[0])
+	format, ok := fences[fence]
+	if !ok {
+		return None, nil, content, nil
+	}
+
+	for i := 1; i < len(lines); i++ {
+		if strings.TrimSpace(lines[i]) == fence {
+			end := lines[i][strings.Index(lines[i], fence)+len(fence):]
+			return format, []byte(strings.Join(lines[1:i], "")), []byte(end + strings.Join(lines[i+1:], "")), nil
+		}
+	}
+
+	return format, nil, nil, fmt.Errorf("%w, metadata must be wrapped in %s", ErrNotClosed, fence)
+}
+
+// splitJSON is Split of a file starting with a json object
+func splitJSON(text string) (string, []byte, []byte, error) {
+	r := strings.NewReader(text)
+	d := json.NewDecoder(r)
+
+	var header json.RawMessage
+	if err := d.Decode(&header); err != nil {
+		return JSON, nil, nil, fmt.Errorf("invalid json front matter: %w", err)
+	}
+	body, _ := ioutil.ReadAll(io.MultiReader(d.Buffered(), r))
+
+	return JSON, header, body, nil
+}
+
+// Decode decodes header, a front matter of format, into v. Into a map, nested tables are
+// map[string]interface{} and integers are int64, as toml decodes them, whole json numbers too. The
+// front matter of format None decodes to nothing
+func Decode(header []byte, format string, v interface{}) error {
+	m, isMap := v.(*map[string]interface{})
+
+	switch format {
+	case None:
+		return nil
+	case TOML, YAML:
+		if !isMap {
+			if format == TOML {
+				_, err := toml.Decode(string(header), v)
+				return err
+			}
+			return yaml.Unmarshal(header, v)
+		}
+
+		decoded, err := baja.DecodeConfigMap("front."+format, header)
+		if err != nil {
+			return err
+		}
+		*m = int64s(decoded).(map[string]interface{})
+		return nil
+	case JSON:
+		if err := json.Unmarshal(header, v); err != nil {
+			return err
+		}
+		if isMap {
+			*m = int64s(*m).(map[string]interface{})
+		}
+		return nil
+	}
+
+	return fmt.Errorf("unknown front matter format %q", format)
+}
+
+// int64s turns the yaml ints and the float64 json numbers without a fraction of v into int64
+func int64s(v interface{}) interface{} {
+	switch v := v.(type) {
+	case int:
+		return int64(v)
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			return int64(v)
+		}
+	case map[string]interface{}:
+		for k, value := range v {
+			v[k] = int64s(value)
+		}
+	case []interface{}:
+		for i, value := range v {
+			v[i] = int64s(value)
+		}
+	}
+
+	return v
+}
@@ -0,0 +1,73 @@
+package frontmatter_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	"github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	"github.com/yeo/baja/frontmatter"
+)
+
+var _ = Describe("Split", func() {
+	table.DescribeTable("returns the format, front matter and body",
+		func(content, format, header, body string) {
+			f, h, b, err := frontmatter.Split([]byte(content))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(f).To(Equal(format))
+			Expect(string(h)).To(Equal(header))
+			Expect(string(b)).To(Equal(body))
+		},
+		table.Entry("toml", "+++\ntitle = \"A\"\n+++\nbody", frontmatter.TOML, "title = \"A\"\n", "\nbody"),
+		table.Entry("yaml", "---\ntitle: A\n---\nbody\n---\n", frontmatter.YAML, "title: A\n", "\nbody\n---\n"),
+		table.Entry("json", "{\"title\": \"A\"}\nbody", frontmatter.JSON, "{\"title\": \"A\"}", "\nbody"),
+		table.Entry("empty", "+++\n+++\nbody", frontmatter.TOML, "", "\nbody"),
+		table.Entry("a BOM", "\xEF\xBB\xBF+++\ntitle = \"A\"\n+++\nbody", frontmatter.TOML, "title = \"A\"\n", "\nbody"),
+		table.Entry("CRLF line breaks", "---\r\ntitle: A\r\n---\r\nbody\r\n", frontmatter.YAML, "title: A\n", "\nbody\n"),
+		table.Entry("leading blank lines", "\n \n+++\ntitle = \"A\"\n+++\nbody", frontmatter.TOML, "title = \"A\"\n", "\nbody"),
+		table.Entry("no front matter", "\n# Title\n\nbody", frontmatter.None, "", "\n# Title\n\nbody"),
+		table.Entry("a template action, not json", "{{< include \"a.md\" >}}", frontmatter.None, "", "{{< include \"a.md\" >}}"),
+	)
+
+	It("fails on a front matter not closed", func() {
+		_, _, _, err := frontmatter.Split([]byte("---\ntitle: A\nbody"))
+		Expect(errors.Is(err, frontmatter.ErrNotClosed)).To(BeTrue())
+		Expect(err).To(MatchError("not enough header/body, metadata must be wrapped in ---"))
+
+		_, _, _, err = frontmatter.Split([]byte("{\"title\": "))
+		Expect(err).To(MatchError(ContainSubstring("invalid json front matter")))
+	})
+})
+
+var _ = Describe("Decode", func() {
+	type meta struct {
+		Title  string
+		Weight int
+	}
+
+	table.DescribeTable("decodes every format into a struct and a map",
+		func(format, header string) {
+			var m meta
+			Expect(frontmatter.Decode([]byte(header), format, &m)).To(Succeed())
+			Expect(m).To(Equal(meta{Title: "A", Weight: 2}))
+
+			raw := map[string]interface{}{}
+			Expect(frontmatter.Decode([]byte(header), format, &raw)).To(Succeed())
+			Expect(raw).To(Equal(map[string]interface{}{
+				"title":  "A",
+				"weight": int64(2),
+				"params": map[string]interface{}{"accent": "red"},
+			}))
+		},
+		table.Entry("toml", frontmatter.TOML, "title = \"A\"\nweight = 2\n[params]\naccent = \"red\"\n"),
+		table.Entry("yaml", frontmatter.YAML, "title: A\nweight: 2\nparams:\n  accent: red\n"),
+		table.Entry("json", frontmatter.JSON, `{"title": "A", "weight": 2, "params": {"accent": "red"}}`),
+	)
+
+	It("decodes no front matter to nothing", func() {
+		raw := map[string]interface{}{}
+		Expect(frontmatter.Decode(nil, frontmatter.None, &raw)).To(Succeed())
+		Expect(raw).To(BeEmpty())
+	})
+})
@@ -0,0 +1,59 @@
+package baja
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// IgnoreRegexpPrefix marks a pattern of Config.IgnoreFiles as a regular expression, eg: re:\.bak$
+const IgnoreRegexpPrefix = "re:"
+
+// IgnoreFunc reports whether a path relative to content is ignored
+type IgnoreFunc func(rel string) bool
+
+// CompileIgnore compiles the patterns of Config.IgnoreFiles. A glob matches the path relative to
+// content or the name of the file, so node_modules matches at any depth. A pattern starting with
+// re: is a regular expression matched against the path. All invalid patterns are returned as
+// ValidationErrors
+func CompileIgnore(patterns []string) (IgnoreFunc, error) {
+	var errs ValidationErrors
+	matchers := []func(string) bool{}
+
+	for _, pattern := range patterns {
+		pattern := pattern
+		if strings.HasPrefix(pattern, IgnoreRegexpPrefix) {
+			re, err := regexp.Compile(strings.TrimPrefix(pattern, IgnoreRegexpPrefix))
+			if err != nil {
+				errs = append(errs, fmt.Errorf("invalid ignoreFiles pattern %q: %w", pattern, err))
+				continue
+			}
+			matchers = append(matchers, re.MatchString)
+			continue
+		}
+
+		if _, err := path.Match(pattern, ""); err != nil {
+			errs = append(errs, fmt.Errorf("invalid ignoreFiles pattern %q: %w", pattern, err))
+			continue
+		}
+		matchers = append(matchers, func(rel string) bool {
+			whole, _ := path.Match(pattern, rel)
+			name, _ := path.Match(pattern, path.Base(rel))
+			return whole || name
+		})
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	return func(rel string) bool {
+		for _, match := range matchers {
+			if match(rel) {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
@@ -0,0 +1,33 @@
+package baja_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/yeo/baja"
+)
+
+var _ = Describe("IgnoreFiles", func() {
+	It("matches globs against the path or the name, and regular expressions", func() {
+		ignored, err := baja.CompileIgnore([]string{"node_modules", "notes/*", `re:\.draft\.md$`})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(ignored("node_modules")).To(Equal(true))
+		Expect(ignored("post/node_modules")).To(Equal(true))
+		Expect(ignored("notes/todo.md")).To(Equal(true))
+		Expect(ignored("post/idea.draft.md")).To(Equal(true))
+
+		Expect(ignored("post/notes/todo.md")).To(Equal(false))
+		Expect(ignored("post/hello.md")).To(Equal(false))
+	})
+
+	It("returns every invalid pattern", func() {
+		_, err := baja.CompileIgnore([]string{"[unclosed", "re:(", "ok"})
+
+		var errs baja.ValidationErrors
+		Expect(errors.As(err, &errs)).To(Equal(true))
+		Expect(errs).To(HaveLen(2))
+	})
+})
@@ -0,0 +1,139 @@
+// Package imaging decodes, resizes, crops and encodes the images of page bundles with the standard
+// image packages, so templates can make responsive variants of a photo
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"math"
+)
+
+// Image formats which can be processed, named like image.Decode names them
+const (
+	JPEG = "jpeg"
+	PNG  = "png"
+	GIF  = "gif"
+)
+
+// JPEGQuality is the quality derived jpeg images are encoded with
+const JPEGQuality = 85
+
+// ErrUnsupported is the error of an image whose format can't be processed, eg: webp
+var ErrUnsupported = errors.New("unsupported image format, only jpeg, png and gif can be processed")
+
+// Decode reads data, the content of the image file at path, turned upright when it's a jpeg with
+// an EXIF orientation, and returns its format. An image of another format is ErrUnsupported
+// naming path
+func Decode(path string, data []byte) (image.Image, string, error) {
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err == image.ErrFormat {
+		return nil, "", fmt.Errorf("%s: %w", path, ErrUnsupported)
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("cannot decode image %s: %w", path, err)
+	}
+
+	if format == JPEG {
+		img = orient(img, Orientation(data))
+	}
+
+	return img, format, nil
+}
+
+// Encode writes img into w in format, one of JPEG, PNG or GIF
+func Encode(w io.Writer, img image.Image, format string) error {
+	switch format {
+	case JPEG:
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: JPEGQuality})
+	case PNG:
+		return png.Encode(w, img)
+	case GIF:
+		return gif.Encode(w, img, nil)
+	}
+
+	return fmt.Errorf("%s: %w", format, ErrUnsupported)
+}
+
+// Resize scales img to width pixels wide, its height keeps the aspect ratio
+func Resize(img image.Image, width int) image.Image {
+	b := img.Bounds()
+	height := int(math.Round(float64(b.Dy()) * float64(width) / float64(b.Dx())))
+	if height < 1 {
+		height = 1
+	}
+
+	return scale(img, b, width, height)
+}
+
+// Fill scales img to cover width by height pixels and crops what's over, keeping the center. The
+// crop keeps at least a pixel of a side, however far the aspect ratios are
+func Fill(img image.Image, width, height int) image.Image {
+	b := img.Bounds()
+	crop := b
+	if b.Dx()*height > b.Dy()*width {
+		w := int(math.Round(float64(b.Dy()) * float64(width) / float64(height)))
+		if w < 1 {
+			w = 1
+		}
+		crop.Min.X += (b.Dx() - w) / 2
+		crop.Max.X = crop.Min.X + w
+	} else {
+		h := int(math.Round(float64(b.Dx()) * float64(height) / float64(width)))
+		if h < 1 {
+			h = 1
+		}
+		crop.Min.Y += (b.Dy() - h) / 2
+		crop.Max.Y = crop.Min.Y + h
+	}
+
+	return scale(img, crop, width, height)
+}
+
+// scale resamples the rect area of img into a width by height image. Each pixel is the average of
+// the source pixels it covers, or the closest one when scaling up
+func scale(img image.Image, rect image.Rectangle, width, height int) image.Image {
+	src := image.NewRGBA(image.Rect(0, 0, rect.Dx(), rect.Dy()))
+	draw.Draw(src, src.Bounds(), img, rect.Min, draw.Src)
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0, y1 := span(y, height, rect.Dy())
+		for x := 0; x < width; x++ {
+			x0, x1 := span(x, width, rect.Dx())
+
+			var r, g, b, a, n int
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					p := row[sx*4 : sx*4+4]
+					r, g, b, a = r+int(p[0]), g+int(p[1]), b+int(p[2]), a+int(p[3])
+					n++
+				}
+			}
+
+			p := dst.Pix[y*dst.Stride+x*4:]
+			p[0], p[1], p[2], p[3] = uint8(r/n), uint8(g/n), uint8(b/n), uint8(a/n)
+		}
+	}
+
+	return dst
+}
+
+// span returns the source pixels [from, to) covered by pixel i of a side of size pixels scaled
+// from src pixels, never empty
+func span(i, size, src int) (int, int) {
+	from := i * src / size
+	to := ((i+1)*src + size - 1) / size
+	if to <= from {
+		to = from + 1
+	}
+
+	return from, to
+}
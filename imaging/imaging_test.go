@@ -0,0 +1,120 @@
+package imaging_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/yeo/baja/imaging"
+)
+
+// halves returns a width by height image, red on its left half and blue on its right half
+func halves(width, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			c := color.RGBA{255, 0, 0, 255}
+			if x >= width/2 {
+				c = color.RGBA{0, 0, 255, 255}
+			}
+			img.Set(x, y, c)
+		}
+	}
+
+	return img
+}
+
+// withOrientation returns a jpeg of img with an EXIF segment of orientation o
+func withOrientation(img image.Image, o uint16) []byte {
+	var b bytes.Buffer
+	Expect(jpeg.Encode(&b, img, &jpeg.Options{Quality: 100})).To(Succeed())
+	data := b.Bytes()
+
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08\x00\x01")
+	entry := make([]byte, 12)
+	binary.BigEndian.PutUint16(entry, 0x0112)
+	binary.BigEndian.PutUint16(entry[2:], 3)
+	binary.BigEndian.PutUint32(entry[4:], 1)
+	binary.BigEndian.PutUint16(entry[8:], o)
+	exif := append(append([]byte("Exif\x00\x00"), tiff...), append(entry, 0, 0, 0, 0)...)
+
+	segment := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(exif)+2))
+
+	return append(append(append([]byte{}, data[:2]...), append(segment, exif...)...), data[2:]...)
+}
+
+var _ = Describe("Imaging", func() {
+	It("resizes to a width keeping the aspect ratio", func() {
+		img := imaging.Resize(halves(300, 200), 150)
+		Expect(img.Bounds().Dx()).To(Equal(150))
+		Expect(img.Bounds().Dy()).To(Equal(100))
+
+		r, _, b, _ := img.At(10, 50).RGBA()
+		Expect(r >> 8).To(BeNumerically("==", 255))
+		Expect(b).To(BeZero())
+	})
+
+	It("fills a box cropping around the center", func() {
+		img := imaging.Fill(halves(400, 100), 50, 50)
+		Expect(img.Bounds().Size()).To(Equal(image.Pt(50, 50)))
+
+		r, _, _, _ := img.At(5, 25).RGBA()
+		_, _, b, _ := img.At(45, 25).RGBA()
+		Expect(r >> 8).To(BeNumerically("==", 255))
+		Expect(b >> 8).To(BeNumerically("==", 255))
+	})
+
+	It("fills a box of an aspect ratio far from the image one", func() {
+		wide := image.NewRGBA(image.Rect(0, 0, 1000, 10))
+		Expect(imaging.Fill(wide, 10, 1000).Bounds().Size()).To(Equal(image.Pt(10, 1000)))
+
+		tall := image.NewRGBA(image.Rect(0, 0, 10, 1000))
+		Expect(imaging.Fill(tall, 1000, 10).Bounds().Size()).To(Equal(image.Pt(1000, 10)))
+	})
+
+	It("reads the EXIF orientation of a jpeg", func() {
+		img := halves(40, 20)
+		Expect(imaging.Orientation(withOrientation(img, 6))).To(Equal(6))
+		Expect(imaging.Orientation(withOrientation(img, 1))).To(Equal(1))
+		Expect(imaging.Orientation([]byte("not a jpeg"))).To(Equal(1))
+	})
+
+	It("turns a jpeg upright", func() {
+		// rotated 90° clockwise, the red left half is on top
+		img, format, err := imaging.Decode("photo.jpg", withOrientation(halves(40, 20), 6))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(format).To(Equal(imaging.JPEG))
+		Expect(img.Bounds().Size()).To(Equal(image.Pt(20, 40)))
+
+		r, _, b, _ := img.At(10, 5).RGBA()
+		Expect(r >> 8).To(BeNumerically(">", 200))
+		Expect(b >> 8).To(BeNumerically("<", 50))
+	})
+
+	It("encodes in the format it decoded", func() {
+		var b bytes.Buffer
+		Expect(png.Encode(&b, halves(4, 4))).To(Succeed())
+		img, format, err := imaging.Decode("photo.png", b.Bytes())
+		Expect(err).ToNot(HaveOccurred())
+
+		var out bytes.Buffer
+		Expect(imaging.Encode(&out, imaging.Resize(img, 2), format)).To(Succeed())
+		_, format, err = image.DecodeConfig(&out)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(format).To(Equal(imaging.PNG))
+	})
+
+	It("names the file of an unsupported format", func() {
+		_, _, err := imaging.Decode("content/post/trip/photo.webp", []byte("RIFF\x00\x00\x00\x00WEBPVP8 "))
+		Expect(errors.Is(err, imaging.ErrUnsupported)).To(Equal(true))
+		Expect(err.Error()).To(ContainSubstring("content/post/trip/photo.webp"))
+	})
+})
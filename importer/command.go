@@ -0,0 +1,54 @@
+package importer
+
+import (
+	"flag"
+
+	"github.com/fatih/color"
+
+	"github.com/yeo/baja"
+)
+
+type Command struct {
+	from     string
+	section  string
+	download bool
+}
+
+func (cmd *Command) ArgDesc() string {
+	return "export.xml|dir"
+}
+
+func (cmd *Command) Help() string {
+	return "Import posts from a WordPress/RSS export, or a Hugo or Jekyll site, into content directory"
+}
+
+func (cmd *Command) Flags(fs *flag.FlagSet) {
+	fs.StringVar(&cmd.from, "from", FromWordPress, "format of the import: wordpress, hugo or jekyll")
+	fs.StringVar(&cmd.section, "section", "posts", "content directory to write imported posts into")
+	fs.BoolVar(&cmd.download, "download", true, "download uploaded files referenced in posts into static/")
+}
+
+func (cmd *Command) Run(site *baja.Site, args []string) int {
+	if len(args) < 1 {
+		color.Red("Usage: baja import [--from wordpress|hugo|jekyll] [--section posts] export.xml|dir")
+		return 1
+	}
+
+	var importer interface{ Import(string) error }
+	switch cmd.from {
+	case FromWordPress:
+		importer = &WordPress{Section: cmd.section, Download: cmd.download}
+	case FromHugo, FromJekyll:
+		importer = &Hugo{Jekyll: cmd.from == FromJekyll, Section: cmd.section}
+	default:
+		color.Red("Unknown --from %s, must be wordpress, hugo or jekyll", cmd.from)
+		return 1
+	}
+
+	if err := importer.Import(args[0]); err != nil {
+		color.Red("Cannot import %s: %v", args[0], err)
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,286 @@
+package importer
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/rs/zerolog/log"
+
+	"github.com/yeo/baja"
+	"github.com/yeo/baja/frontmatter"
+	"github.com/yeo/baja/utils"
+)
+
+// Sources of baja import --from
+const (
+	FromWordPress = "wordpress"
+	FromHugo      = "hugo"
+	FromJekyll    = "jekyll"
+)
+
+// hugoFrontMatter is what we write into the +++ section of a node imported from Hugo or Jekyll
+type hugoFrontMatter struct {
+	Title       string                 `toml:"title"`
+	Description string                 `toml:"description,omitempty"`
+	Date        *time.Time             `toml:"date,omitempty"` // nil when unset, the encoder writes every time
+	Lastmod     *time.Time             `toml:"lastmod,omitempty"`
+	Draft       bool                   `toml:"draft,omitempty"`
+	Tags        []string               `toml:"tags,omitempty"`
+	Categories  []string               `toml:"categories,omitempty"`
+	Author      string                 `toml:"author,omitempty"`
+	Authors     []string               `toml:"authors,omitempty"`
+	Aliases     []string               `toml:"aliases,omitempty"`
+	Params      map[string]interface{} `toml:"params,omitempty"`
+}
+
+// contentExts are the files converted, any other file such as an image of a bundle is copied as is
+var contentExts = map[string]bool{".md": true, ".markdown": true, ".html": true}
+
+// dateLayouts are the dates of Hugo and Jekyll front matter, Jekyll writes 2006-01-02 15:04:05 -0700
+var dateLayouts = append([]string{"2006-01-02 15:04:05 -0700", "2006-01-02 15:04:05 MST"}, baja.DefaultDateInputFormats...)
+
+// Hugo imports the content tree of a Hugo or Jekyll site into content directory. Front matter in
+// yaml, toml or json is rewritten as baja toml front matter, fields baja doesn't know are kept in
+// params and reported. Bodies are kept as is
+type Hugo struct {
+	// Jekyll reads _posts and _drafts of a Jekyll site into Section instead of a Hugo content tree
+	Jekyll  bool
+	Section string
+}
+
+// Import converts the site at dir. A Hugo site root is read from its content directory
+func (h *Hugo) Import(dir string) error {
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+
+	total := 0
+	for _, src := range h.sources(dir) {
+		err := filepath.Walk(src.dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() {
+				return err
+			}
+
+			rel, _ := filepath.Rel(src.dir, path)
+			dest := filepath.Join("content", src.target, rel)
+			if _, err := os.Stat(dest); err == nil {
+				log.Warn().Str("path", path).Str("dest", dest).Msg("Skip file, dest already exists")
+				return nil
+			}
+
+			if !contentExts[strings.ToLower(filepath.Ext(path))] {
+				return copyFile(path, dest)
+			}
+
+			if err := h.importFile(path, dest, src.draft); err != nil {
+				log.Error().Err(err).Str("path", path).Msg("Skip file")
+				return nil
+			}
+			total++
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	log.Info().Int("files", total).Msg("Import done")
+	return nil
+}
+
+// source is a directory of the imported site and the directory of content it's written into
+type source struct {
+	dir    string
+	target string
+	draft  bool
+}
+
+func (h *Hugo) sources(dir string) []source {
+	if h.Jekyll {
+		sources := []source{}
+		for _, s := range []source{{dir: "_posts", target: h.Section}, {dir: "_drafts", target: h.Section, draft: true}} {
+			s.dir = filepath.Join(dir, s.dir)
+			if _, err := os.Stat(s.dir); err == nil {
+				sources = append(sources, s)
+			}
+		}
+		return sources
+	}
+
+	if info, err := os.Stat(filepath.Join(dir, "content")); err == nil && info.IsDir() {
+		dir = filepath.Join(dir, "content")
+	}
+	return []source{{dir: dir}}
+}
+
+// importFile converts the front matter of content file path and writes it to dest
+func (h *Hugo) importFile(path, dest string, draft bool) error {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	raw, body, err := splitFrontMatter(content)
+	if err != nil {
+		return err
+	}
+
+	meta, unmapped := mapFrontMatter(raw)
+	meta.Draft = meta.Draft || draft
+	if name := filepath.Base(path); meta.Date == nil && h.Jekyll && len(name) > 10 {
+		// a Jekyll post is named after its date, eg: 2019-02-09-hello.md
+		if t, err := time.Parse("2006-01-02", name[:10]); err == nil {
+			meta.Date = &t
+		}
+	}
+	if len(unmapped) > 0 {
+		log.Warn().Str("path", path).Strs("fields", unmapped).Msg("Keep fields baja has no such field for in params")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dest), os.ModePerm); err != nil {
+		return err
+	}
+	file, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(file, "+++")
+	err = toml.NewEncoder(file).Encode(meta)
+	if err == nil {
+		_, err = fmt.Fprintf(file, "+++\n%s", body)
+	}
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		// a file half written would be skipped as existing by the next import
+		os.Remove(dest)
+		return err
+	}
+
+	log.Info().Str("path", dest).Msg("Import")
+	return nil
+}
+
+// splitFrontMatter returns the front matter of content, --- yaml, +++ toml or a json object, and
+// the body after it. A file without front matter has an empty one
+func splitFrontMatter(content []byte) (map[string]interface{}, []byte, error) {
+	format, header, body, err := frontmatter.Split(content)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	raw := map[string]interface{}{}
+	if err := frontmatter.Decode(header, format, &raw); err != nil {
+		return nil, nil, fmt.Errorf("invalid front matter: %w", err)
+	}
+	if format != frontmatter.None {
+		body = bytes.TrimLeft(body, "\n")
+	}
+
+	return raw, body, nil
+}
+
+// mapFrontMatter turns Hugo and Jekyll front matter into baja fields. The keys without a baja field
+// are kept in params and returned sorted
+func mapFrontMatter(raw map[string]interface{}) (*hugoFrontMatter, []string) {
+	meta := &hugoFrontMatter{Params: map[string]interface{}{}}
+	unmapped := []string{}
+
+	for key, v := range raw {
+		switch strings.ToLower(key) {
+		case "title":
+			meta.Title = fmt.Sprint(v)
+		case "description", "summary", "excerpt":
+			if meta.Description == "" {
+				meta.Description = fmt.Sprint(v)
+			}
+		case "date":
+			meta.Date = toTime(v)
+		case "publishdate", "pubdate":
+			if _, ok := raw["date"]; !ok {
+				meta.Date = toTime(v)
+			}
+		case "lastmod", "last_modified_at", "modified":
+			meta.Lastmod = toTime(v)
+		case "draft":
+			meta.Draft = meta.Draft || v == true
+		case "published":
+			// Jekyll
+			meta.Draft = meta.Draft || v == false
+		case "tags":
+			meta.Tags = toList(v)
+		case "categories", "category":
+			meta.Categories = append(meta.Categories, toList(v)...)
+		case "author":
+			meta.Author = fmt.Sprint(v)
+		case "authors":
+			meta.Authors = toList(v)
+		case "aliases", "redirect_from":
+			meta.Aliases = append(meta.Aliases, toList(v)...)
+		case "params":
+			if params, ok := v.(map[string]interface{}); ok {
+				for k, p := range params {
+					meta.Params[k] = p
+				}
+				continue
+			}
+			fallthrough
+		default:
+			meta.Params[key] = v
+			unmapped = append(unmapped, key)
+		}
+	}
+	sort.Strings(unmapped)
+
+	return meta, unmapped
+}
+
+// toTime reads a date of front matter, a time of toml or a string of yaml and json. It's nil when v
+// isn't a date
+func toTime(v interface{}) *time.Time {
+	switch v := v.(type) {
+	case time.Time:
+		return &v
+	case string:
+		for _, layout := range dateLayouts {
+			if t, err := time.Parse(layout, strings.TrimSpace(v)); err == nil {
+				return &t
+			}
+		}
+	}
+
+	return nil
+}
+
+// toList reads a list of front matter. Jekyll allows a space separated string of tags
+func toList(v interface{}) []string {
+	switch v := v.(type) {
+	case string:
+		return strings.Fields(v)
+	case []interface{}:
+		list := make([]string, len(v))
+		for i, item := range v {
+			list[i] = fmt.Sprint(item)
+		}
+		return list
+	}
+
+	return nil
+}
+
+func copyFile(src, dest string) error {
+	if err := os.MkdirAll(filepath.Dir(dest), os.ModePerm); err != nil {
+		return err
+	}
+
+	return utils.CopyFile(src, dest)
+}
@@ -0,0 +1,89 @@
+package importer_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	"github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	. "github.com/yeo/baja/importer"
+)
+
+// writeFiles writes files, keyed by their path, under dir
+func writeFiles(dir string, files map[string]string) {
+	for path, content := range files {
+		path = filepath.Join(dir, path)
+		Expect(os.MkdirAll(filepath.Dir(path), os.ModePerm)).To(Succeed())
+		Expect(ioutil.WriteFile(path, []byte(content), 0644)).To(Succeed())
+	}
+}
+
+var _ = Describe("Hugo", func() {
+	var cwd, dir string
+
+	BeforeEach(func() {
+		cwd, _ = os.Getwd()
+		dir, _ = ioutil.TempDir("", "baja-import")
+		Expect(os.Chdir(dir)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		os.Chdir(cwd)
+		os.RemoveAll(dir)
+	})
+
+	table.DescribeTable("rewrites front matter as baja toml",
+		func(content string) {
+			writeFiles("old", map[string]string{"content/post/hello.md": content})
+			Expect((&Hugo{}).Import("old")).To(Succeed())
+
+			meta, body := readImported("content/post/hello.md")
+			Expect(meta["title"]).To(Equal("Hello"))
+			Expect(meta["date"]).To(Equal("2019-02-09T00:00:00Z"))
+			Expect(meta["draft"]).To(Equal(true))
+			Expect(meta["tags"]).To(Equal([]interface{}{"go", "web"}))
+			Expect(meta["categories"]).To(Equal([]interface{}{"dev"}))
+			Expect(meta["aliases"]).To(Equal([]interface{}{"/old/hello/"}))
+			Expect(meta["params"]).To(Equal(map[string]interface{}{"slug": "hi", "accent": "red"}))
+			Expect(body).To(Equal("\nbody {{< youtube id >}}\n"))
+		},
+		table.Entry("yaml", "---\ntitle: Hello\ndate: 2019-02-09\ndraft: true\ntags: [go, web]\ncategories: [dev]\naliases: [/old/hello/]\nslug: hi\nparams:\n  accent: red\n---\nbody {{< youtube id >}}\n"),
+		table.Entry("toml", "+++\ntitle = \"Hello\"\ndate = 2019-02-09T00:00:00Z\ndraft = true\ntags = [\"go\", \"web\"]\ncategories = [\"dev\"]\naliases = [\"/old/hello/\"]\nslug = \"hi\"\n[params]\naccent = \"red\"\n+++\nbody {{< youtube id >}}\n"),
+		table.Entry("json", "{\"title\": \"Hello\", \"date\": \"2019-02-09\", \"draft\": true, \"tags\": [\"go\", \"web\"], \"categories\": [\"dev\"], \"aliases\": [\"/old/hello/\"], \"slug\": \"hi\", \"params\": {\"accent\": \"red\"}}\nbody {{< youtube id >}}\n"),
+	)
+
+	It("imports Jekyll posts and drafts", func() {
+		writeFiles("old", map[string]string{
+			"_posts/2019-02-09-hello.md": "---\ntitle: Hello\ntags: go web\ncategory: dev\nredirect_from:\n  - /2019/hello.html\n  - /hello/\n---\nbody\n",
+			"_posts/2019-02-10-later.md": "---\ntitle: Later\npublished: false\n---\nlater\n",
+			"_drafts/idea.md":            "---\ntitle: Idea\n---\nidea\n",
+		})
+		Expect((&Hugo{Jekyll: true, Section: "posts"}).Import("old")).To(Succeed())
+
+		meta, _ := readImported("content/posts/2019-02-09-hello.md")
+		Expect(meta["date"]).To(Equal("2019-02-09T00:00:00Z"))
+		Expect(meta).ToNot(HaveKey("draft"))
+		Expect(meta["tags"]).To(Equal([]interface{}{"go", "web"}))
+		Expect(meta["categories"]).To(Equal([]interface{}{"dev"}))
+		Expect(meta["aliases"]).To(Equal([]interface{}{"/2019/hello.html", "/hello/"}))
+
+		meta, _ = readImported("content/posts/2019-02-10-later.md")
+		Expect(meta["draft"]).To(Equal(true))
+
+		meta, _ = readImported("content/posts/idea.md")
+		Expect(meta["draft"]).To(Equal(true))
+	})
+
+	It("never overwrites a file", func() {
+		writeFiles("old", map[string]string{"content/post/hello.md": "---\ntitle: Hello\n---\nbody\n"})
+		writeFiles(".", map[string]string{"content/post/hello.md": "mine"})
+
+		Expect((&Hugo{}).Import("old")).To(Succeed())
+
+		content, _ := ioutil.ReadFile("content/post/hello.md")
+		Expect(string(content)).To(Equal("mine"))
+	})
+})
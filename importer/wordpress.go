@@ -0,0 +1,301 @@
+package importer
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/rs/zerolog/log"
+
+	"github.com/yeo/baja/utils"
+)
+
+// wxr maps the part of a WordPress export (WXR) or a plain RSS feed we care about
+type wxr struct {
+	Channel struct {
+		Items []wxrItem `xml:"item"`
+	} `xml:"channel"`
+}
+
+type wxrItem struct {
+	Title       string        `xml:"title"`
+	Link        string        `xml:"link"`
+	PubDate     string        `xml:"pubDate"`
+	Description string        `xml:"description"`
+	Content     string        `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	Categories  []wxrCategory `xml:"category"`
+
+	// WordPress specific fields, empty on a plain RSS feed
+	PostName string `xml:"post_name"`
+	PostDate string `xml:"post_date_gmt"`
+	PostType string `xml:"post_type"`
+	Status   string `xml:"status"`
+}
+
+type wxrCategory struct {
+	Domain string `xml:"domain,attr"`
+	Name   string `xml:",chardata"`
+}
+
+// frontMatter is what we write into the +++ section of an imported node
+type frontMatter struct {
+	Title      string    `toml:"title"`
+	Date       time.Time `toml:"date"`
+	Draft      bool      `toml:"draft"`
+	Type       string    `toml:"type,omitempty"`
+	Tags       []string  `toml:"tags"`
+	Categories []string  `toml:"categories"`
+	Aliases    []string  `toml:"aliases"`
+}
+
+var (
+	uploadRe  = regexp.MustCompile(`https?://[^"'\s()<>]+/wp-content/uploads/([^"'\s()<>]+)`)
+	blockRe   = regexp.MustCompile(`^<(p|div|h[1-6]|ul|ol|li|blockquote|pre|table|figure|img|iframe|hr|!--)[\s>/]`)
+	dateForms = []string{time.RFC1123Z, time.RFC1123, "2006-01-02 15:04:05"}
+)
+
+// WordPress imports a WXR or RSS export into content directory.
+// Item bodies are kept as HTML in a .html node so nothing is lost in a markdown conversion
+type WordPress struct {
+	Section  string // directory under content for posts. Pages are written directly into content
+	Download bool   // download wp-content/uploads files into static/uploads
+}
+
+func (w *WordPress) Import(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	export := wxr{}
+	if err := xml.NewDecoder(f).Decode(&export); err != nil {
+		return fmt.Errorf("invalid export file: %v", err)
+	}
+
+	total := 0
+	for _, item := range export.Channel.Items {
+		switch item.PostType {
+		case "", "post", "page":
+		default:
+			// attachments, menu items etc aren't content
+			continue
+		}
+
+		err := w.importItem(item)
+		if errors.Is(err, errExists) {
+			log.Warn().Err(err).Str("title", item.Title).Msg("Skip item")
+			continue
+		}
+		if err != nil {
+			log.Error().Err(err).Str("title", item.Title).Msg("Skip item")
+			continue
+		}
+		total++
+	}
+
+	log.Info().Int("items", total).Msg("Import done")
+	return nil
+}
+
+// errExists is the error of an item whose file is already in content, eg: imported before or
+// another item with the same slug. The file is never overwritten
+var errExists = errors.New("already exists")
+
+func (w *WordPress) importItem(item wxrItem) error {
+	meta := frontMatter{
+		Title:      item.Title,
+		Date:       itemDate(item),
+		Tags:       []string{},
+		Categories: []string{},
+		Aliases:    []string{},
+	}
+
+	switch item.Status {
+	case "draft", "private", "pending", "future":
+		meta.Draft = true
+	}
+
+	for _, c := range item.Categories {
+		switch c.Domain {
+		case "post_tag":
+			meta.Tags = append(meta.Tags, c.Name)
+		case "category", "":
+			meta.Categories = append(meta.Categories, c.Name)
+		}
+	}
+
+	if u, err := url.Parse(item.Link); err == nil && u.Path != "" && u.Path != "/" {
+		meta.Aliases = append(meta.Aliases, u.Path)
+	}
+
+	dir := filepath.Join("content", w.Section)
+	if item.PostType == "page" {
+		meta.Type = "page"
+		dir = "content"
+	}
+
+	// post_name is a slug when WordPress wrote the export, but it's used as a file name
+	slug := utils.Slugify(item.PostName)
+	if slug == "" {
+		slug = utils.Slugify(item.Title)
+	}
+	if slug == "" {
+		return fmt.Errorf("cannot find a slug")
+	}
+	if !meta.Date.IsZero() && item.PostType != "page" {
+		// filename with date in it to help sorting, same as baja create
+		slug = meta.Date.Format("2006-01-02") + "-" + slug
+	}
+
+	body := item.Content
+	if body == "" {
+		body = item.Description
+	}
+	if w.Download {
+		body = w.downloadUploads(body)
+	}
+
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+
+	dest := filepath.Join(dir, slug+".html")
+	file, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if os.IsExist(err) {
+		return fmt.Errorf("%s %w", dest, errExists)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(file, "+++")
+	err = toml.NewEncoder(file).Encode(meta)
+	if err == nil {
+		_, err = fmt.Fprintf(file, "+++\n%s", autop(body))
+	}
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(dest)
+		return err
+	}
+
+	log.Info().Str("path", dest).Msg("Import")
+	return nil
+}
+
+func itemDate(item wxrItem) time.Time {
+	for _, v := range []string{item.PubDate, item.PostDate} {
+		for _, layout := range dateForms {
+			if t, err := time.Parse(layout, strings.TrimSpace(v)); err == nil && !t.IsZero() && t.Year() > 1 {
+				return t
+			}
+		}
+	}
+
+	return time.Time{}
+}
+
+// downloadUploads fetches every wp-content/uploads file referenced in body into static/uploads
+// and rewrites the reference to the local copy. A failed download, or a file which would be
+// outside of static/uploads, keeps the original url
+func (w *WordPress) downloadUploads(body string) string {
+	return uploadRe.ReplaceAllStringFunc(body, func(src string) string {
+		rel, ok := uploadPath(uploadRe.FindStringSubmatch(src)[1])
+		if !ok {
+			log.Warn().Str("url", src).Msg("Skip download, the file would be outside of static/uploads")
+			return src
+		}
+		dest := filepath.Join("static", "uploads", filepath.FromSlash(rel))
+
+		if _, err := os.Stat(dest); err != nil {
+			if err := download(src, dest); err != nil && !os.IsExist(err) {
+				log.Error().Err(err).Str("url", src).Msg("Cannot download")
+				return src
+			}
+		}
+
+		return "/uploads/" + rel
+	})
+}
+
+// uploadPath returns the file under static/uploads of the path after wp-content/uploads/ of an
+// upload url, without its query, eg: 2019/02/a.jpg of 2019/02/a.jpg?w=300. It's false for a path
+// going up with .. or with a backslash
+func uploadPath(rel string) (string, bool) {
+	if i := strings.IndexAny(rel, "?#"); i >= 0 {
+		rel = rel[:i]
+	}
+
+	clean := path.Clean(rel)
+	if strings.Contains(rel, `\`) || clean == "." || clean == ".." || strings.HasPrefix(clean, "../") || path.IsAbs(clean) {
+		return "", false
+	}
+
+	return clean, true
+}
+
+func download(src, dest string) error {
+	resp, err := http.Get(src)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dest), os.ModePerm); err != nil {
+		return err
+	}
+
+	// never overwrite a file, eg: one put in static/uploads since the import started
+	f, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(f, resp.Body)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(dest)
+	}
+
+	return err
+}
+
+// autop wraps the loose paragraphs of a WordPress body into <p>. WordPress stores posts without them
+// and adds them at render time
+func autop(body string) string {
+	paragraphs := strings.Split(strings.Replace(body, "\r\n", "\n", -1), "\n\n")
+
+	out := []string{}
+	for _, p := range paragraphs {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+
+		if !blockRe.MatchString(p) {
+			p = "<p>" + strings.Replace(p, "\n", "<br />\n", -1) + "</p>"
+		}
+		out = append(out, p)
+	}
+
+	return "\n" + strings.Join(out, "\n\n") + "\n"
+}
@@ -0,0 +1,177 @@
+package importer_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/yeo/baja/frontmatter"
+	. "github.com/yeo/baja/importer"
+)
+
+// wxrExport is a WordPress export of a published post, a draft, a private page, an attachment
+// and a post whose slug is taken by the first one
+const wxrExport = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:content="http://purl.org/rss/1.0/modules/content/" xmlns:wp="http://wordpress.org/export/1.2/">
+<channel>
+	<item>
+		<title>Hello</title>
+		<link>https://blog.example.com/2019/02/hello/</link>
+		<pubDate>Sat, 09 Feb 2019 10:00:00 +0000</pubDate>
+		<category domain="category">Travel</category>
+		<category domain="post_tag">vietnam</category>
+		<category domain="post_tag">food</category>
+		<content:encoded><![CDATA[First line
+
+Second line]]></content:encoded>
+		<wp:post_name>hello</wp:post_name>
+		<wp:post_type>post</wp:post_type>
+		<wp:status>publish</wp:status>
+	</item>
+	<item>
+		<title>Later</title>
+		<link>https://blog.example.com/?p=2</link>
+		<pubDate>Sun, 10 Feb 2019 10:00:00 +0000</pubDate>
+		<content:encoded><![CDATA[<p>Soon</p>]]></content:encoded>
+		<wp:post_name>later</wp:post_name>
+		<wp:post_type>post</wp:post_type>
+		<wp:status>draft</wp:status>
+	</item>
+	<item>
+		<title>About</title>
+		<link>https://blog.example.com/about/</link>
+		<content:encoded><![CDATA[<p>Me</p>]]></content:encoded>
+		<wp:post_name>about</wp:post_name>
+		<wp:post_type>page</wp:post_type>
+		<wp:status>private</wp:status>
+	</item>
+	<item>
+		<title>photo.jpg</title>
+		<wp:post_type>attachment</wp:post_type>
+	</item>
+	<item>
+		<title>Hello again</title>
+		<link>https://blog.example.com/2019/02/hello-2/</link>
+		<pubDate>Sat, 09 Feb 2019 18:00:00 +0000</pubDate>
+		<content:encoded><![CDATA[<p>Duplicate</p>]]></content:encoded>
+		<wp:post_name>hello</wp:post_name>
+		<wp:post_type>post</wp:post_type>
+		<wp:status>publish</wp:status>
+	</item>
+</channel>
+</rss>
+`
+
+// uploadExport is a WordPress export of a post whose slug is a path and whose body references
+// uploads of server, one of them going up out of wp-content/uploads
+const uploadExport = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:content="http://purl.org/rss/1.0/modules/content/" xmlns:wp="http://wordpress.org/export/1.2/">
+<channel>
+	<item>
+		<title>Photos</title>
+		<content:encoded><![CDATA[<p><img src="%[1]s/wp-content/uploads/2019/02/a.jpg?w=300"> <img src="%[1]s/wp-content/uploads/../../../evil.txt"></p>]]></content:encoded>
+		<wp:post_name>../../photos</wp:post_name>
+		<wp:post_type>page</wp:post_type>
+		<wp:status>publish</wp:status>
+	</item>
+</channel>
+</rss>
+`
+
+// readImported returns the front matter and the body of an imported file
+func readImported(path string) (map[string]interface{}, string) {
+	content, err := ioutil.ReadFile(path)
+	Expect(err).ToNot(HaveOccurred())
+
+	format, header, body, err := frontmatter.Split(content)
+	Expect(err).ToNot(HaveOccurred())
+	meta := map[string]interface{}{}
+	Expect(frontmatter.Decode(header, format, &meta)).To(Succeed())
+
+	return meta, string(body)
+}
+
+var _ = Describe("WordPress", func() {
+	var cwd, dir string
+
+	BeforeEach(func() {
+		cwd, _ = os.Getwd()
+		dir, _ = ioutil.TempDir("", "baja-import")
+		Expect(os.Chdir(dir)).To(Succeed())
+		Expect(ioutil.WriteFile("export.xml", []byte(wxrExport), 0644)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		os.Chdir(cwd)
+		os.RemoveAll(dir)
+	})
+
+	It("imports posts and pages with their tags, categories and aliases", func() {
+		Expect((&WordPress{Section: "posts"}).Import("export.xml")).To(Succeed())
+
+		meta, body := readImported("content/posts/2019-02-09-hello.html")
+		Expect(meta["title"]).To(Equal("Hello"))
+		Expect(meta["draft"]).To(Equal(false))
+		Expect(meta["tags"]).To(Equal([]interface{}{"vietnam", "food"}))
+		Expect(meta["categories"]).To(Equal([]interface{}{"Travel"}))
+		Expect(meta["aliases"]).To(Equal([]interface{}{"/2019/02/hello/"}))
+		Expect(body).To(ContainSubstring("<p>First line</p>\n\n<p>Second line</p>"))
+
+		meta, _ = readImported("content/posts/2019-02-10-later.html")
+		Expect(meta["draft"]).To(Equal(true))
+		Expect(meta["aliases"]).To(BeEmpty())
+
+		meta, _ = readImported("content/about.html")
+		Expect(meta["draft"]).To(Equal(true))
+		Expect(meta["type"]).To(Equal("page"))
+
+		files, _ := filepath.Glob("content/posts/*")
+		Expect(files).To(HaveLen(2))
+	})
+
+	It("keeps downloads in static/uploads and names files after a slug", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, r.URL.Path)
+		}))
+		defer server.Close()
+		Expect(ioutil.WriteFile("export.xml", []byte(fmt.Sprintf(uploadExport, server.URL)), 0644)).To(Succeed())
+
+		Expect((&WordPress{Section: "posts", Download: true}).Import("export.xml")).To(Succeed())
+
+		content, err := ioutil.ReadFile("static/uploads/2019/02/a.jpg")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(content)).To(Equal("/wp-content/uploads/2019/02/a.jpg"))
+
+		_, body := readImported("content/photos.html")
+		Expect(body).To(ContainSubstring(`<img src="/uploads/2019/02/a.jpg">`))
+		Expect(body).To(ContainSubstring(server.URL + "/wp-content/uploads/../../../evil.txt"))
+
+		_, err = os.Stat(filepath.Join(dir, "..", "evil.txt"))
+		Expect(os.IsNotExist(err)).To(BeTrue())
+	})
+
+	It("never overwrites a file", func() {
+		Expect(os.MkdirAll("content", os.ModePerm)).To(Succeed())
+		Expect(ioutil.WriteFile("content/about.html", []byte("mine"), 0644)).To(Succeed())
+
+		Expect((&WordPress{Section: "posts"}).Import("export.xml")).To(Succeed())
+
+		content, _ := ioutil.ReadFile("content/about.html")
+		Expect(string(content)).To(Equal("mine"))
+
+		// Hello again has the slug of Hello, the first one is kept
+		_, body := readImported("content/posts/2019-02-09-hello.html")
+		Expect(body).To(ContainSubstring("First line"))
+		Expect(body).ToNot(ContainSubstring("Duplicate"))
+
+		Expect((&WordPress{Section: "posts"}).Import("export.xml")).To(Succeed())
+		_, body = readImported("content/posts/2019-02-09-hello.html")
+		Expect(body).To(ContainSubstring("First line"))
+	})
+})
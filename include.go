@@ -0,0 +1,72 @@
+package baja
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// includeRe matches the include shortcode of markdown bodies, eg: {{< include "snippets/install.md" >}}
+var includeRe = regexp.MustCompile(`\{\{<\s*include\s+"([^"]*)"\s*>\}\}`)
+
+// ExpandIncludes replaces each include shortcode of body, the markdown of content file source,
+// with the markdown of the file it names under root, so it's rendered in place like the rest of
+// the node. Included files can include others. A missing file, a path outside of root or an
+// include loop is an error naming the file with the shortcode
+func ExpandIncludes(root, source string, body []byte) ([]byte, error) {
+	full, err := filepath.Abs(source)
+	if err != nil {
+		return nil, err
+	}
+	if resolved, err := filepath.EvalSymlinks(full); err == nil {
+		full = resolved
+	}
+
+	return expandIncludes(root, source, body, []string{full}, []string{source})
+}
+
+// expandIncludes expands the includes of body, the file source. chain has the full paths of
+// the files being expanded, names their paths as written, to report a loop
+func expandIncludes(root, source string, body []byte, chain, names []string) ([]byte, error) {
+	var err error
+	expanded := includeRe.ReplaceAllFunc(body, func(shortcode []byte) []byte {
+		if err != nil {
+			return nil
+		}
+
+		path := string(includeRe.FindSubmatch(shortcode)[1])
+		full, e := safePath(root, path)
+		if e != nil {
+			err = fmt.Errorf("include in %s: %w", source, e)
+			return nil
+		}
+		name := filepath.Join(root, path)
+		for _, included := range chain {
+			if included == full {
+				err = fmt.Errorf("include in %s: loop %s", source, strings.Join(append(names, name), " > "))
+				return nil
+			}
+		}
+
+		content, e := ioutil.ReadFile(full)
+		if os.IsNotExist(e) {
+			err = fmt.Errorf("include in %s: %s not found in %s", source, path, root)
+			return nil
+		}
+		if e != nil {
+			err = fmt.Errorf("include in %s: %w", source, e)
+			return nil
+		}
+
+		content, err = expandIncludes(root, name, content, append(chain[:len(chain):len(chain)], full), append(names[:len(names):len(names)], name))
+		return content
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return expanded, nil
+}
@@ -0,0 +1,66 @@
+package baja_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/yeo/baja"
+)
+
+var _ = Describe("ExpandIncludes", func() {
+	var root string
+
+	write := func(path, content string) {
+		full := filepath.Join(root, path)
+		os.MkdirAll(filepath.Dir(full), os.ModePerm)
+		Expect(ioutil.WriteFile(full, []byte(content), 0644)).To(Succeed())
+	}
+
+	expand := func(body string) (string, error) {
+		out, err := baja.ExpandIncludes(root, filepath.Join(root, "page.md"), []byte(body))
+		return string(out), err
+	}
+
+	BeforeEach(func() {
+		root, _ = ioutil.TempDir("", "baja-include")
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(root)
+	})
+
+	It("inlines files, nested ones included", func() {
+		write("a.md", `A {{< include "sub/b.md" >}}`)
+		write("sub/b.md", "B")
+
+		Expect(expand(`before {{<include "a.md">}} after`)).To(Equal("before A B after"))
+	})
+
+	It("reports a missing file with the including file", func() {
+		write("a.md", `{{< include "missing.md" >}}`)
+
+		_, err := expand(`{{< include "a.md" >}}`)
+		Expect(err).To(MatchError(ContainSubstring("include in " + filepath.Join(root, "a.md") + ": missing.md not found")))
+	})
+
+	It("refuses files outside of root", func() {
+		_, err := expand(`{{< include "../secret.md" >}}`)
+		Expect(err).To(MatchError(ContainSubstring("outside")))
+	})
+
+	It("stops include loops", func() {
+		write("a.md", `{{< include "b.md" >}}`)
+		write("b.md", `{{< include "a.md" >}}`)
+		write("page.md", `{{< include "a.md" >}}`)
+
+		_, err := expand(`{{< include "a.md" >}}`)
+		Expect(err).To(MatchError(ContainSubstring("loop")))
+
+		_, err = expand(`{{< include "page.md" >}}`)
+		Expect(err).To(MatchError(ContainSubstring("loop")))
+	})
+})
@@ -1,12 +1,29 @@
 package baja
 
 import (
+	"errors"
+	"flag"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strconv"
+
+	"github.com/yeo/baja/utils"
 )
 
+// ErrSiteExists is returned by Setup when the target directory already has a site or other files in it
+var ErrSiteExists = errors.New("directory already exists and is not empty")
+
+const gitignore = `/public/
+/public-preview/
+/.baja/
+/.baja-cache/
+`
+
 type InitCommand struct {
+	force bool
+	mode  string
 }
 
 func (cmd *InitCommand) ArgDesc() string {
@@ -17,8 +34,29 @@ func (cmd *InitCommand) Help() string {
 	return "Setup skeleton for a new project"
 }
 
+func (cmd *InitCommand) Flags(fs *flag.FlagSet) {
+	fs.BoolVar(&cmd.force, "force", false, "scaffold into a non empty directory. Existing files are kept")
+	fs.StringVar(&cmd.mode, "mode", "0755", "octal mode of the created directories, before umask")
+}
+
 func (cmd *InitCommand) Run(s *Site, args []string) int {
-	if err := Create(s.Meta.Name); err == nil {
+	if len(args) < 1 {
+		fmt.Println("Usage: baja init [--force] site-name")
+		return 1
+	}
+
+	mode, err := strconv.ParseUint(cmd.mode, 8, 32)
+	if err != nil || mode > 0777 {
+		fmt.Println("Invalid --mode", cmd.mode, "must be an octal mode such as 0755")
+		return 1
+	}
+
+	if err := SetupWithMode(args[0], cmd.force, os.FileMode(mode)); err != nil {
+		if errors.Is(err, ErrSiteExists) {
+			fmt.Println(err, "\nUse --force to scaffold into it anyway")
+			return 1
+		}
+
 		fmt.Println("Error when creating site", err)
 		return 1
 	}
@@ -26,20 +64,73 @@ func (cmd *InitCommand) Run(s *Site, args []string) int {
 	return 0
 }
 
-// Initalize a new blog directory
-func Create(name string) error {
+// Setup initalizes a new blog directory. A directory which isn't empty is refused with ErrSiteExists
+// unless force is set, and even then existing files are never overwritten
+func Setup(name string, force bool) error {
+	return SetupWithMode(name, force, utils.DefaultDirMode)
+}
+
+// SetupWithMode is Setup creating the directories with mode, eg: 0750 for a site other users
+// can't read
+func SetupWithMode(name string, force bool, mode os.FileMode) error {
+	root := filepath.Join(".", name)
+
+	if !force {
+		if err := ensureEmpty(root); err != nil {
+			return err
+		}
+	}
+
 	path := []string{
-		filepath.Join(".", name),
-		filepath.Join(".", name, "content"),
-		filepath.Join(".", name, "theme/baja"),
-		filepath.Join(".", name, "public/asset"),
+		root,
+		filepath.Join(root, "content"),
+		filepath.Join(root, "theme/baja"),
+		filepath.Join(root, "public/asset"),
+		filepath.Join(root, "static"),
 	}
 
 	for _, p := range path {
-		os.MkdirAll(p, os.ModePerm)
+		if err := utils.EnsureDir(p, mode); err != nil {
+			return err
+		}
+	}
+
+	if !utils.HasFile(filepath.Join(root, ".gitignore")) {
+		if err := ioutil.WriteFile(filepath.Join(root, ".gitignore"), []byte(gitignore), 0644); err != nil {
+			return err
+		}
+	}
+
+	configPath := FindConfig(root)
+	if utils.HasFile(configPath) {
+		return nil
+	}
+
+	return WriteDefault(configPath)
+}
+
+// ensureEmpty returns ErrSiteExists when dir exists and has anything in it
+func ensureEmpty(dir string) error {
+	fi, err := os.Stat(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if !fi.IsDir() {
+		return fmt.Errorf("%s: %w", dir, ErrSiteExists)
+	}
+
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	if len(entries) > 0 {
+		return fmt.Errorf("%s: %w", dir, ErrSiteExists)
 	}
 
-	c := NewConfig("./" + name + "/baja.yaml")
-	c.WriteFile()
 	return nil
 }
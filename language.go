@@ -0,0 +1,132 @@
+package baja
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// LanguageConfig is a language of a multilingual site in config languages, keyed by its code
+type LanguageConfig struct {
+	Title   string                 `yaml:"title" toml:"title"`     // name of the language, eg: Français
+	BaseURL string                 `yaml:"baseURL" toml:"baseURL"` // path prefix of its pages, eg: /fr/
+	Params  map[string]interface{} `yaml:"params" toml:"params"`
+}
+
+// Language is a language of the site, exposed to templates as .Site.Languages
+type Language struct {
+	Code    string
+	Title   string
+	BaseURL string
+	Params  Params
+}
+
+// ValidateLanguages checks the codes and prefixes of languages, and that the default language is
+// one of them. All problems are returned as ValidationErrors
+func ValidateLanguages(defaultLanguage string, languages map[string]*LanguageConfig) error {
+	var errs ValidationErrors
+
+	if defaultLanguage != "" {
+		if err := ValidateLanguage(defaultLanguage); err != nil {
+			errs = append(errs, err)
+		} else if len(languages) > 0 && languages[defaultLanguage] == nil {
+			errs = append(errs, fmt.Errorf("defaultLanguage %q is not in languages", defaultLanguage))
+		}
+	}
+
+	codes := make([]string, 0, len(languages))
+	for code := range languages {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	for _, code := range codes {
+		if err := ValidateLanguage(code); err != nil {
+			errs = append(errs, err)
+		}
+		if l := languages[code]; l != nil && l.BaseURL != "" && !strings.HasPrefix(l.BaseURL, "/") {
+			errs = append(errs, fmt.Errorf("baseURL %q of language %s must be a path starting with /, eg: /%s/", l.BaseURL, code, code))
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return errs
+}
+
+// LanguageCode returns DefaultLanguage, or Language of a single language site
+func (c *Config) LanguageCode() string {
+	if c.DefaultLanguage != "" {
+		return c.DefaultLanguage
+	}
+
+	return c.Language
+}
+
+// SiteLanguages returns the languages of config sorted by code. A site without languages has
+// only its LanguageCode, when set
+func (c *Config) SiteLanguages() []*Language {
+	if len(c.Languages) == 0 {
+		if c.LanguageCode() == "" {
+			return []*Language{}
+		}
+		return []*Language{{Code: c.LanguageCode(), Title: c.LanguageCode(), BaseURL: "/", Params: Params{}}}
+	}
+
+	languages := make([]*Language, 0, len(c.Languages))
+	for code, l := range c.Languages {
+		if l == nil {
+			l = &LanguageConfig{}
+		}
+		language := &Language{Code: code, Title: l.Title, BaseURL: l.BaseURL, Params: NewParams(l.Params)}
+		if language.Title == "" {
+			language.Title = code
+		}
+		if language.BaseURL == "" {
+			language.BaseURL = "/"
+		}
+		languages = append(languages, language)
+	}
+	sort.Slice(languages, func(i, j int) bool { return languages[i].Code < languages[j].Code })
+
+	return languages
+}
+
+// Translations are the strings of a language keyed by id, read by the i18n template function
+type Translations map[string]string
+
+// ReadTranslations reads i18n/<lang>.yaml or .toml of the theme, then of the site so a site string
+// wins over the theme one. Missing files are skipped
+func ReadTranslations(theme *Theme, lang string) (Translations, error) {
+	translations := Translations{}
+	if lang == "" {
+		return translations, nil
+	}
+
+	for _, dir := range []string{theme.SubPath("i18n"), "i18n"} {
+		for _, ext := range []string{".yaml", ".yml", ".toml"} {
+			path := filepath.Join(dir, lang+ext)
+			data, err := theme.ReadFile(path)
+			if os.IsNotExist(err) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			raw, err := DecodeConfigMap(path, data)
+			if err != nil {
+				return nil, fmt.Errorf("invalid translations %s: %w", path, err)
+			}
+			for id, v := range raw {
+				translations[id] = fmt.Sprint(v)
+			}
+		}
+	}
+
+	return translations, nil
+}
@@ -0,0 +1,76 @@
+package baja_test
+
+import (
+	"bytes"
+	"html/template"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/yeo/baja"
+)
+
+var _ = Describe("Languages", func() {
+	It("lists the languages of config by code", func() {
+		config := &baja.Config{DefaultLanguage: "fr", Languages: map[string]*baja.LanguageConfig{
+			"fr": {Title: "Français", Params: map[string]interface{}{"Greeting": "Salut"}},
+			"en": {Title: "English", BaseURL: "/en/"},
+		}}
+
+		languages := config.SiteLanguages()
+		Expect(languages).To(HaveLen(2))
+		Expect(languages[0]).To(Equal(&baja.Language{Code: "en", Title: "English", BaseURL: "/en/", Params: baja.Params{}}))
+		Expect(languages[1]).To(Equal(&baja.Language{Code: "fr", Title: "Français", BaseURL: "/", Params: baja.Params{"greeting": "Salut"}}))
+		Expect(config.LanguageCode()).To(Equal("fr"))
+	})
+
+	It("has the language of a single language site", func() {
+		config := &baja.Config{Language: "en"}
+
+		Expect(config.SiteLanguages()).To(Equal([]*baja.Language{{Code: "en", Title: "en", BaseURL: "/", Params: baja.Params{}}}))
+	})
+
+	It("rejects a default language outside languages and invalid prefixes", func() {
+		err := baja.ValidateLanguages("de", map[string]*baja.LanguageConfig{"en": {BaseURL: "en"}, "not a tag": {}})
+
+		var errs baja.ValidationErrors
+		Expect(err).To(BeAssignableToTypeOf(errs))
+		Expect(err.Error()).To(ContainSubstring(`defaultLanguage "de" is not in languages`))
+		Expect(err.Error()).To(ContainSubstring(`baseURL "en" of language en must be a path`))
+		Expect(err.Error()).To(ContainSubstring(`invalid language "not a tag"`))
+	})
+
+	Describe("i18n", func() {
+		var cwd, dir string
+
+		BeforeEach(func() {
+			cwd, _ = os.Getwd()
+			dir, _ = ioutil.TempDir("", "baja-i18n")
+			os.MkdirAll(filepath.Join(dir, "themes", "t", "i18n"), os.ModePerm)
+			os.MkdirAll(filepath.Join(dir, "i18n"), os.ModePerm)
+			ioutil.WriteFile(filepath.Join(dir, "themes", "t", "i18n", "fr.toml"), []byte("readMore = \"Lire\"\nnext = \"Suivant\"\n"), 0644)
+			ioutil.WriteFile(filepath.Join(dir, "i18n", "fr.yaml"), []byte("readMore: Lire la suite\n"), 0644)
+			ioutil.WriteFile(filepath.Join(dir, "baja.yaml"), []byte("theme: t\ndefaultLanguage: fr\nlanguages:\n  fr:\n    title: Français\n"), 0644)
+			os.Chdir(dir)
+		})
+
+		AfterEach(func() {
+			os.Chdir(cwd)
+			os.RemoveAll(dir)
+		})
+
+		It("translates with the site strings over the theme ones", func() {
+			site, err := baja.LoadSite("baja.yaml", "")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(site.Language()).To(Equal("fr"))
+
+			tpl := template.Must(template.New("t").Funcs(baja.FuncMaps(site)).Parse(`{{ i18n "readMore" }}, {{ i18n "next" }}, {{ i18n "unknown" }}`))
+			var out bytes.Buffer
+			Expect(tpl.Execute(&out, nil)).To(Succeed())
+			Expect(out.String()).To(Equal("Lire la suite, Suivant, unknown"))
+		})
+	})
+})
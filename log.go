@@ -0,0 +1,70 @@
+package baja
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// Log formats of Config.LogFormat
+const (
+	LogFormatConsole = "console" // human readable, the default
+	LogFormatJSON    = "json"    // one json object per line, for log collectors
+)
+
+// DefaultLogLevel is used when Config.LogLevel isn't set
+const DefaultLogLevel = "info"
+
+// ValidateLog checks a log level such as debug or warn, and a log format. Empty values use defaults
+func ValidateLog(level, format string) error {
+	if level != "" {
+		if _, err := zerolog.ParseLevel(level); err != nil {
+			return fmt.Errorf("invalid logLevel %q: must be debug, info, warn or error", level)
+		}
+	}
+
+	switch format {
+	case "", LogFormatConsole, LogFormatJSON:
+	default:
+		return fmt.Errorf("invalid logFormat %q: must be %s or %s", format, LogFormatConsole, LogFormatJSON)
+	}
+
+	return nil
+}
+
+// NewLogger returns a logger writing to w with the level and format of config. Each line is a
+// single write to w, so lines of concurrent builds never interleave
+func NewLogger(config *Config, w io.Writer) (zerolog.Logger, error) {
+	if err := ValidateLog(config.LogLevel, config.LogFormat); err != nil {
+		return zerolog.Nop(), err
+	}
+
+	level := config.LogLevel
+	if level == "" {
+		level = DefaultLogLevel
+	}
+	l, _ := zerolog.ParseLevel(level)
+
+	w = zerolog.SyncWriter(w)
+	if config.LogFormat != LogFormatJSON {
+		w = zerolog.ConsoleWriter{Out: w, TimeFormat: time.Kitchen, NoColor: color.NoColor}
+	}
+
+	return zerolog.New(w).Level(l).With().Timestamp().Logger(), nil
+}
+
+// SetupLogger makes the logger of config the global one every package logs to
+func SetupLogger(config *Config) error {
+	logger, err := NewLogger(config, os.Stderr)
+	if err != nil {
+		return err
+	}
+
+	log.Logger = logger
+	return nil
+}
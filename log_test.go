@@ -0,0 +1,51 @@
+package baja_test
+
+import (
+	"bytes"
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/yeo/baja"
+)
+
+var _ = Describe("NewLogger", func() {
+	var out *bytes.Buffer
+
+	BeforeEach(func() {
+		out = &bytes.Buffer{}
+	})
+
+	It("writes json lines with their context", func() {
+		logger, err := baja.NewLogger(&baja.Config{LogFormat: baja.LogFormatJSON}, out)
+		Expect(err).ToNot(HaveOccurred())
+
+		logger.Info().Str("path", "content/post/a.md").Msg("Build node")
+
+		line := map[string]interface{}{}
+		Expect(json.Unmarshal(out.Bytes(), &line)).To(Succeed())
+		Expect(line["level"]).To(Equal("info"))
+		Expect(line["path"]).To(Equal("content/post/a.md"))
+		Expect(line["message"]).To(Equal("Build node"))
+	})
+
+	It("defaults to console format at info level", func() {
+		logger, _ := baja.NewLogger(&baja.Config{}, out)
+
+		logger.Debug().Msg("hidden")
+		logger.Info().Str("path", "a.md").Msg("shown")
+
+		Expect(out.String()).ToNot(ContainSubstring("hidden"))
+		Expect(out.String()).To(ContainSubstring("shown"))
+		Expect(out.String()).To(ContainSubstring("a.md"))
+	})
+
+	It("rejects unknown level and format", func() {
+		_, err := baja.NewLogger(&baja.Config{LogLevel: "loud"}, out)
+		Expect(err).To(MatchError(ContainSubstring("invalid logLevel")))
+
+		_, err = baja.NewLogger(&baja.Config{LogFormat: "xml"}, out)
+		Expect(err).To(MatchError(ContainSubstring("invalid logFormat")))
+	})
+})
@@ -0,0 +1,255 @@
+package baja
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/russross/blackfriday"
+)
+
+// MarkupConfig tunes how markdown content is turned into html, the markup table of config
+type MarkupConfig struct {
+	// Extensions are the markdown extensions in use, names of MarkdownExtensions. Default to
+	// DefaultMarkdownExtensions
+	Extensions []string `yaml:"extensions" toml:"extensions"`
+
+	// RawHTML is what happens to html written in markdown: RawHTMLAllow, the default, RawHTMLEscape
+	// or RawHTMLSkip
+	RawHTML string `yaml:"rawHTML" toml:"rawHTML" default:"allow"`
+
+	// Sanitize is the sanitize policy of sections without one in the sanitize table, off when unset
+	Sanitize string `yaml:"sanitize" toml:"sanitize"`
+
+	// Highlight is the code highlighting style, eg: github. Code blocks are rendered with a
+	// language-<lang> class and themes load the style with .Site.Config.Markup.Highlight
+	Highlight string `yaml:"highlight" toml:"highlight"`
+}
+
+// RawHTML modes of markdown
+const (
+	RawHTMLAllow  = "allow"  // html is kept, then sanitized with the policy of the section
+	RawHTMLEscape = "escape" // html is shown as text
+	RawHTMLSkip   = "skip"   // html is removed
+)
+
+// MarkdownExtensions are the markdown extensions which can be turned on in config
+var MarkdownExtensions = map[string]blackfriday.Extensions{
+	"noIntraEmphasis":    blackfriday.NoIntraEmphasis,
+	"tables":             blackfriday.Tables,
+	"fencedCode":         blackfriday.FencedCode,
+	"autolink":           blackfriday.Autolink,
+	"strikethrough":      blackfriday.Strikethrough,
+	"laxHTMLBlocks":      blackfriday.LaxHTMLBlocks,
+	"spaceHeadings":      blackfriday.SpaceHeadings,
+	"hardLineBreak":      blackfriday.HardLineBreak,
+	"footnotes":          blackfriday.Footnotes,
+	"headingIDs":         blackfriday.HeadingIDs,
+	"titleblock":         blackfriday.Titleblock,
+	"autoHeadingIDs":     blackfriday.AutoHeadingIDs,
+	"backslashLineBreak": blackfriday.BackslashLineBreak,
+	"definitionLists":    blackfriday.DefinitionLists,
+	"taskLists":          TaskLists,
+}
+
+// TaskLists renders list items starting with [ ] or [x] as a disabled checkbox, unchecked or
+// checked. It's done by baja on top of blackfriday, with a bit blackfriday doesn't use
+const TaskLists blackfriday.Extensions = 1 << 30
+
+// DefaultMarkdownExtensions are the extensions of a site without markup.extensions
+var DefaultMarkdownExtensions = []string{
+	"noIntraEmphasis", "tables", "fencedCode", "autolink", "strikethrough",
+	"spaceHeadings", "headingIDs", "backslashLineBreak", "definitionLists", "taskLists",
+}
+
+// sanitizePolicies are built once, a bluemonday policy is safe for concurrent use
+var sanitizePolicies = map[string]*bluemonday.Policy{
+	SanitizeUGC:    bluemonday.UGCPolicy(),
+	SanitizeStrict: bluemonday.StrictPolicy(),
+}
+
+// ValidateMarkup checks the markup table of config. All problems are returned as ValidationErrors
+func ValidateMarkup(m MarkupConfig) error {
+	var errs ValidationErrors
+
+	for _, name := range m.Extensions {
+		if _, ok := MarkdownExtensions[name]; !ok {
+			errs = append(errs, fmt.Errorf("unknown markup extension %q, must be one of %s", name, strings.Join(markupExtensionNames(), ", ")))
+		}
+	}
+
+	switch m.RawHTML {
+	case "", RawHTMLAllow, RawHTMLEscape, RawHTMLSkip:
+	default:
+		errs = append(errs, fmt.Errorf("invalid markup rawHTML %q: must be %s, %s or %s", m.RawHTML, RawHTMLAllow, RawHTMLEscape, RawHTMLSkip))
+	}
+
+	if m.Sanitize != "" {
+		if err := ValidateSanitize(map[string]string{"markup": m.Sanitize}); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return errs
+}
+
+// Markup turns markdown into html with the markup settings of config. Node bodies, section
+// intros and the markdownify template function all go through it, so the html of the site is
+// produced the same way everywhere
+type Markup struct {
+	config     *Config
+	extensions blackfriday.Extensions
+	flags      blackfriday.HTMLFlags
+	escape     bool
+}
+
+// NewMarkup creates the markup renderer of config, which can be nil for the defaults
+func NewMarkup(config *Config) *Markup {
+	if config == nil {
+		config = &Config{}
+	}
+
+	m := &Markup{config: config, flags: blackfriday.CommonHTMLFlags | blackfriday.FootnoteReturnLinks}
+
+	names := config.Markup.Extensions
+	if len(names) == 0 {
+		names = DefaultMarkdownExtensions
+	}
+	for _, name := range names {
+		m.extensions |= MarkdownExtensions[name]
+	}
+
+	switch config.Markup.RawHTML {
+	case RawHTMLSkip:
+		m.flags |= blackfriday.SkipHTML
+	case RawHTMLEscape:
+		m.escape = true
+	}
+
+	return m
+}
+
+// Markdown renders markdown body into html, without sanitizing it
+func (m *Markup) Markdown(body []byte) []byte {
+	return m.MarkdownWithID(body, "")
+}
+
+// MarkdownWithID is Markdown with footnote anchors prefixed by id, eg: fn:post-hello-1, so the
+// footnotes of several nodes listed on one page don't link to each other
+func (m *Markup) MarkdownWithID(body []byte, id string) []byte {
+	params := blackfriday.HTMLRendererParameters{Flags: m.flags, FootnoteReturnLinkContents: "&#8617;"}
+	if id != "" {
+		params.FootnoteAnchorPrefix = id + "-"
+	}
+
+	renderer := &htmlRenderer{blackfriday.NewHTMLRenderer(params), m.escape, m.extensions&TaskLists != 0}
+
+	return blackfriday.Run(body, blackfriday.WithExtensions(m.extensions&^TaskLists), blackfriday.WithRenderer(renderer))
+}
+
+// SanitizePolicy returns the sanitize policy of a directory under content. The setting of the
+// closest directory wins: with travel set, travel/asia uses it unless it has its own. "*" of the
+// sanitize table, then markup.sanitize, are the default of every directory, off when unset
+func (m *Markup) SanitizePolicy(dir string) string {
+	for dir != "." && dir != "/" && dir != "" {
+		if policy, ok := m.config.Sanitize[dir]; ok {
+			return policy
+		}
+		dir = path.Dir(dir)
+	}
+
+	if policy, ok := m.config.Sanitize["*"]; ok {
+		return policy
+	}
+	if m.config.Markup.Sanitize != "" {
+		return m.config.Markup.Sanitize
+	}
+
+	return SanitizeOff
+}
+
+// Sanitize cleans html with the policy of directory dir
+func (m *Markup) Sanitize(dir string, html []byte) []byte {
+	p, ok := sanitizePolicies[m.SanitizePolicy(dir)]
+	if !ok {
+		return html
+	}
+
+	return p.SanitizeBytes(html)
+}
+
+// HTML renders markdown body of directory dir into sanitized html
+func (m *Markup) HTML(dir string, body []byte) []byte {
+	return m.Sanitize(dir, m.Markdown(body))
+}
+
+// htmlRenderer is the blackfriday renderer with the markup settings blackfriday doesn't have:
+// raw html of markdown rendered as text with escape, and task list checkboxes with tasks
+type htmlRenderer struct {
+	*blackfriday.HTMLRenderer
+	escape bool
+	tasks  bool
+}
+
+func (r *htmlRenderer) RenderNode(w io.Writer, node *blackfriday.Node, entering bool) blackfriday.WalkStatus {
+	switch {
+	case r.escape && node.Type == blackfriday.HTMLBlock:
+		io.WriteString(w, "<p>"+html.EscapeString(string(node.Literal))+"</p>\n")
+		return blackfriday.GoToNext
+	case r.escape && node.Type == blackfriday.HTMLSpan:
+		io.WriteString(w, html.EscapeString(string(node.Literal)))
+		return blackfriday.GoToNext
+	case r.tasks && entering && isTaskItem(node):
+		if node.Literal[1] == ' ' {
+			io.WriteString(w, `<input type="checkbox" disabled> `)
+		} else {
+			io.WriteString(w, `<input type="checkbox" checked disabled> `)
+		}
+		node.Literal = node.Literal[len("[ ] "):]
+	}
+
+	return r.HTMLRenderer.RenderNode(w, node, entering)
+}
+
+// isTaskItem reports whether node is the text starting a list item with [ ], [x] or [X]
+func isTaskItem(node *blackfriday.Node) bool {
+	if node.Type != blackfriday.Text || node.Prev != nil {
+		return false
+	}
+
+	paragraph := node.Parent
+	if paragraph == nil || paragraph.Type != blackfriday.Paragraph || paragraph.Prev != nil {
+		return false
+	}
+	item := paragraph.Parent
+	if item == nil || item.Type != blackfriday.Item || item.ListData.RefLink != nil ||
+		item.ListFlags&(blackfriday.ListTypeDefinition|blackfriday.ListTypeTerm) != 0 {
+		return false
+	}
+
+	for _, marker := range []string{"[ ] ", "[x] ", "[X] "} {
+		if strings.HasPrefix(string(node.Literal), marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// markupExtensionNames returns the names of MarkdownExtensions, sorted
+func markupExtensionNames() []string {
+	names := make([]string, 0, len(MarkdownExtensions))
+	for name := range MarkdownExtensions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
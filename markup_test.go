@@ -0,0 +1,89 @@
+package baja_test
+
+import (
+	"bytes"
+	"errors"
+	"html/template"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/yeo/baja"
+)
+
+var _ = Describe("Markup", func() {
+	render := func(markup baja.MarkupConfig, body string) string {
+		return string(baja.NewMarkup(&baja.Config{Markup: markup}).HTML("post", []byte(body)))
+	}
+
+	It("uses the common extensions by default", func() {
+		html := render(baja.MarkupConfig{}, "| a |\n|---|\n| b |\n\n~~gone~~")
+
+		Expect(html).To(ContainSubstring("<table>"))
+		Expect(html).To(ContainSubstring("<del>gone</del>"))
+	})
+
+	It("turns on the configured extensions only", func() {
+		html := render(baja.MarkupConfig{Extensions: []string{"footnotes"}}, "Note[^1]\n\n[^1]: text\n\n~~kept~~")
+
+		Expect(html).To(ContainSubstring(`class="footnotes"`))
+		Expect(html).To(ContainSubstring("~~kept~~"))
+	})
+
+	It("renders task lists and definition lists", func() {
+		html := render(baja.MarkupConfig{}, "- [ ] write *docs*\n- [x] ship\n- [link](/a) item\n\nTerm\n: Definition\n")
+
+		Expect(html).To(ContainSubstring(`<li><input type="checkbox" disabled> write <em>docs</em></li>`))
+		Expect(html).To(ContainSubstring(`<li><input type="checkbox" checked disabled> ship</li>`))
+		Expect(html).To(ContainSubstring(`<li><a href="/a">link</a> item</li>`))
+		Expect(html).To(ContainSubstring("<dl>\n<dt>Term</dt>\n<dd>Definition</dd>\n</dl>"))
+	})
+
+	It("leaves task markers as text without taskLists", func() {
+		html := render(baja.MarkupConfig{Extensions: []string{"tables"}}, "- [ ] write docs\n")
+
+		Expect(html).To(ContainSubstring("<li>[ ] write docs</li>"))
+		Expect(html).ToNot(ContainSubstring("<input"))
+	})
+
+	It("keeps, escapes or skips raw html", func() {
+		body := "<div>block</div>\n\nsome <b>bold</b>"
+
+		Expect(render(baja.MarkupConfig{}, body)).To(ContainSubstring("<b>bold</b>"))
+
+		escaped := render(baja.MarkupConfig{RawHTML: baja.RawHTMLEscape}, body)
+		Expect(escaped).To(ContainSubstring("&lt;div&gt;block&lt;/div&gt;"))
+		Expect(escaped).To(ContainSubstring("&lt;b&gt;bold&lt;/b&gt;"))
+
+		skipped := render(baja.MarkupConfig{RawHTML: baja.RawHTMLSkip}, body)
+		Expect(skipped).ToNot(ContainSubstring("block"))
+		Expect(skipped).ToNot(ContainSubstring("<b>"))
+	})
+
+	It("sanitizes with markup.sanitize when the section has no policy", func() {
+		config := &baja.Config{Markup: baja.MarkupConfig{Sanitize: baja.SanitizeUGC}, Sanitize: map[string]string{"docs": baja.SanitizeOff}}
+		markup := baja.NewMarkup(config)
+
+		Expect(markup.SanitizePolicy("post")).To(Equal(baja.SanitizeUGC))
+		Expect(markup.SanitizePolicy("docs/api")).To(Equal(baja.SanitizeOff))
+		Expect(string(markup.HTML("post", []byte("hi <script>x</script>")))).ToNot(ContainSubstring("<script>"))
+	})
+
+	It("renders markdownify like node bodies", func() {
+		site := &baja.Site{Config: &baja.Config{Markup: baja.MarkupConfig{RawHTML: baja.RawHTMLEscape}}}
+		tpl := template.Must(template.New("t").Funcs(baja.FuncMaps(site)).Parse(`{{ markdownify . }}`))
+
+		var out bytes.Buffer
+		Expect(tpl.Execute(&out, "*hi* <i>x</i>")).To(Succeed())
+		Expect(out.String()).To(Equal("<p><em>hi</em> &lt;i&gt;x&lt;/i&gt;</p>\n"))
+	})
+
+	It("rejects unknown extensions and modes", func() {
+		err := baja.ValidateMarkup(baja.MarkupConfig{Extensions: []string{"tables", "mermaid"}, RawHTML: "strip", Sanitize: "lax"})
+
+		var errs baja.ValidationErrors
+		Expect(errors.As(err, &errs)).To(Equal(true))
+		Expect(errs).To(HaveLen(3))
+		Expect(errs[0]).To(MatchError(ContainSubstring(`unknown markup extension "mermaid"`)))
+	})
+})
@@ -0,0 +1,148 @@
+package baja
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// MenuEntry is a link of a menu in config, eg: [[menus.main]] of baja.toml
+type MenuEntry struct {
+	Name       string `yaml:"name" toml:"name"`
+	URL        string `yaml:"url" toml:"url"` // a path of the site such as /about/, or an absolute url
+	Weight     int    `yaml:"weight" toml:"weight"`
+	Identifier string `yaml:"identifier" toml:"identifier"` // default to Name
+	Parent     string `yaml:"parent" toml:"parent"`         // identifier of the parent entry
+
+	// Children are the entries whose parent is this one, sorted by weight
+	Children []*MenuEntry `yaml:"-" toml:"-"`
+}
+
+// ID returns the identifier of the entry, its name when it has none
+func (e *MenuEntry) ID() string {
+	if e.Identifier != "" {
+		return e.Identifier
+	}
+
+	return e.Name
+}
+
+// HasChildren is true when the entry has sub entries, for templates
+func (e *MenuEntry) HasChildren() bool {
+	return len(e.Children) > 0
+}
+
+// ValidateMenus checks the entries of every menu: identifiers are unique, parents exist and
+// urls are paths or absolute urls. All problems are returned as ValidationErrors
+func ValidateMenus(menus map[string][]*MenuEntry) error {
+	var errs ValidationErrors
+
+	names := make([]string, 0, len(menus))
+	for name := range menus {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		entries := map[string]*MenuEntry{}
+		for _, e := range menus[name] {
+			if e.ID() == "" {
+				errs = append(errs, fmt.Errorf("menu %s: an entry has no name", name))
+				continue
+			}
+			if _, ok := entries[e.ID()]; ok {
+				errs = append(errs, fmt.Errorf("menu %s: duplicate identifier %q", name, e.ID()))
+			}
+			entries[e.ID()] = e
+
+			if err := validateMenuURL(e.URL); err != nil {
+				errs = append(errs, fmt.Errorf("menu %s: entry %q: %w", name, e.ID(), err))
+			}
+		}
+
+		for _, e := range menus[name] {
+			if e.Parent == "" {
+				continue
+			}
+			if _, ok := entries[e.Parent]; !ok {
+				errs = append(errs, fmt.Errorf("menu %s: entry %q has unknown parent %q", name, e.ID(), e.Parent))
+				continue
+			}
+
+			// a parent chain coming back to the entry would never reach the top of the menu
+			seen := map[string]bool{e.ID(): true}
+			for p := entries[e.Parent]; p != nil; p = entries[p.Parent] {
+				if seen[p.ID()] {
+					errs = append(errs, fmt.Errorf("menu %s: entry %q is its own ancestor", name, e.ID()))
+					break
+				}
+				seen[p.ID()] = true
+			}
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return errs
+}
+
+func validateMenuURL(s string) error {
+	u, err := url.Parse(s)
+	switch {
+	case s == "":
+		return fmt.Errorf("url is not set")
+	case err != nil || strings.ContainsAny(s, " \t\n"):
+		return fmt.Errorf("invalid url %q", s)
+	case strings.HasPrefix(s, "/"), u.Scheme != "" && (u.Host != "" || u.Opaque != ""):
+		return nil
+	default:
+		return fmt.Errorf("invalid url %q: must be a path such as /about/ or an absolute url", s)
+	}
+}
+
+// BuildMenus assembles the entries of each menu into a tree of top level entries with their
+// children, sorted by weight then name at every level. Config entries are left untouched
+func BuildMenus(menus map[string][]*MenuEntry) map[string][]*MenuEntry {
+	trees := map[string][]*MenuEntry{}
+
+	for name, entries := range menus {
+		byID := map[string]*MenuEntry{}
+		copies := make([]*MenuEntry, len(entries))
+		for i, e := range entries {
+			c := *e
+			c.Children = nil
+			copies[i] = &c
+			byID[c.ID()] = &c
+		}
+
+		roots := []*MenuEntry{}
+		for _, e := range copies {
+			if parent, ok := byID[e.Parent]; ok && e.Parent != "" {
+				parent.Children = append(parent.Children, e)
+				continue
+			}
+			roots = append(roots, e)
+		}
+
+		sortMenu(roots)
+		trees[name] = roots
+	}
+
+	return trees
+}
+
+func sortMenu(entries []*MenuEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Weight != entries[j].Weight {
+			return entries[i].Weight < entries[j].Weight
+		}
+		return entries[i].Name < entries[j].Name
+	})
+
+	for _, e := range entries {
+		sortMenu(e.Children)
+	}
+}
@@ -0,0 +1,112 @@
+package baja_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/yeo/baja"
+)
+
+var _ = Describe("Menus", func() {
+	var dir string
+
+	load := func(config string) (*baja.Site, error) {
+		path := filepath.Join(dir, "baja.toml")
+		ioutil.WriteFile(path, []byte(config), 0644)
+		return baja.LoadSite(path, "")
+	}
+
+	BeforeEach(func() {
+		dir, _ = ioutil.TempDir("", "baja")
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("assembles entries into a tree sorted by weight", func() {
+		site, err := load(`
+[[menus.main]]
+name = "Posts"
+url = "/post/"
+weight = 2
+
+[[menus.main]]
+name = "Home"
+url = "/"
+weight = 1
+
+[[menus.main]]
+name = "Rust"
+url = "/tag/rust/"
+parent = "posts"
+weight = 2
+
+[[menus.main]]
+name = "Go"
+url = "/tag/go/"
+parent = "posts"
+weight = 1
+
+[[menus.main]]
+name = "Posts menu"
+identifier = "posts"
+url = "https://example.com/post/"
+parent = "Posts"
+`)
+		Expect(err).ToNot(HaveOccurred())
+
+		main := site.Menus()["main"]
+		Expect(main).To(HaveLen(2))
+		Expect(main[0].Name).To(Equal("Home"))
+		Expect(main[1].Name).To(Equal("Posts"))
+
+		nested := main[1].Children[0]
+		Expect(nested.ID()).To(Equal("posts"))
+		Expect(nested.Children).To(HaveLen(2))
+		Expect(nested.Children[0].Name).To(Equal("Go"))
+		Expect(nested.Children[1].Name).To(Equal("Rust"))
+		Expect(nested.Children[1].HasChildren()).To(Equal(false))
+
+		Expect(site.Config.Menus["main"][1].Children).To(BeEmpty())
+	})
+
+	It("reports every menu problem at load time", func() {
+		_, err := load(`
+[[menus.main]]
+name = "Home"
+url = "/"
+
+[[menus.main]]
+name = "Home"
+url = "about page"
+
+[[menus.main]]
+name = "Orphan"
+url = "/orphan/"
+parent = "missing"
+`)
+		Expect(baja.ExitCode(err)).To(Equal(baja.ExitConfigError))
+
+		var errs baja.ValidationErrors
+		Expect(errors.As(err, &errs)).To(Equal(true))
+		Expect(errs).To(HaveLen(3))
+		Expect(err.Error()).To(ContainSubstring(`duplicate identifier "Home"`))
+		Expect(err.Error()).To(ContainSubstring(`invalid url "about page"`))
+		Expect(err.Error()).To(ContainSubstring(`unknown parent "missing"`))
+	})
+
+	It("rejects a parent cycle", func() {
+		err := baja.ValidateMenus(map[string][]*baja.MenuEntry{"main": {
+			{Name: "A", URL: "/a/", Parent: "B"},
+			{Name: "B", URL: "/b/", Parent: "A"},
+		}})
+
+		Expect(err).To(MatchError(ContainSubstring("is its own ancestor")))
+	})
+})
@@ -0,0 +1,38 @@
+package baja
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Mount is a directory outside content whose files are nodes of a directory of content
+type Mount struct {
+	Source string `yaml:"source" toml:"source"` // directory of the files, eg: ../changelog
+	Target string `yaml:"target" toml:"target"` // directory of content they are in, eg: changelog
+}
+
+// ValidateMounts checks that the source of every mount is a directory and its target a directory
+// inside content. All problems are returned as ValidationErrors
+func ValidateMounts(mounts []Mount) error {
+	var errs ValidationErrors
+
+	for _, m := range mounts {
+		if info, err := os.Stat(m.Source); err != nil || !info.IsDir() {
+			errs = append(errs, fmt.Errorf("source %q of mount %q is not a directory", m.Source, m.Target))
+		}
+
+		target := filepath.ToSlash(m.Target)
+		if path.IsAbs(target) || path.Clean(target) == ".." || strings.HasPrefix(path.Clean(target), "../") {
+			errs = append(errs, fmt.Errorf("target %q of mount %s must be a directory inside content, eg: changelog", m.Target, m.Source))
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return errs
+}
@@ -0,0 +1,44 @@
+package node
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"text/template"
+)
+
+// ArchetypeDir holds front matter templates of new nodes, one per section plus default.md
+const ArchetypeDir = "archetypes"
+
+// defaultArchetype is used when a site has no archetypes/default.md
+const defaultArchetype = `+++
+date = "{{ .Date }}"
+title = "{{ .Title }}"
+draft = true
+
+tags = []
++++
+`
+
+// Archetype returns the template of a new node in section: archetypes/<section>.md,
+// archetypes/default.md or the built in one
+func Archetype(section string) (*template.Template, error) {
+	source := defaultArchetype
+
+	for _, path := range []string{filepath.Join(ArchetypeDir, section+".md"), filepath.Join(ArchetypeDir, "default.md")} {
+		if content, err := ioutil.ReadFile(path); err == nil {
+			source = string(content)
+			break
+		}
+	}
+
+	return template.New("archetype").Parse(source)
+}
+
+// DefaultArchetype returns the content of archetypes/default.md, or the built in one when missing
+func DefaultArchetype() []byte {
+	if content, err := ioutil.ReadFile(filepath.Join(ArchetypeDir, "default.md")); err == nil {
+		return content
+	}
+
+	return []byte(defaultArchetype)
+}
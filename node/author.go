@@ -0,0 +1,126 @@
+package node
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/yeo/baja"
+	"github.com/yeo/baja/utils"
+)
+
+// AuthorDir is the directory of author pages in public
+const AuthorDir = "authors"
+
+// Author is an author of a node with a link to its author page
+type Author struct {
+	ID        string
+	Name      string
+	Permalink string
+	Profile   *baja.AuthorProfile // empty when config has no profile for this author
+}
+
+// NewAuthor looks up author id in config authors
+func NewAuthor(site *baja.Site, id string) *Author {
+	a := &Author{
+		ID:        id,
+		Name:      id,
+		Permalink: "/" + AuthorDir + "/" + AuthorSlug(id) + "/",
+		Profile:   &baja.AuthorProfile{},
+	}
+
+	if site != nil && site.Config != nil {
+		if p, ok := site.Config.Authors[id]; ok && p != nil {
+			a.Profile = p
+			if p.Name != "" {
+				a.Name = p.Name
+			}
+		}
+	}
+
+	return a
+}
+
+// AuthorSlug returns the url path segment of an author id
+func AuthorSlug(id string) string {
+	return utils.Slugify(id)
+}
+
+// checkAuthors finds author ids whose slugs are the same, eg: Bob Smith and bob-smith, which would
+// write their author pages and feeds to one directory. The first id in sort order keeps it, each
+// node of the others is an error of the build
+func (db *NodeDB) checkAuthors() {
+	sources := map[string][]*Node{}
+	for _, n := range db.NodeList {
+		for _, a := range n.Authors() {
+			if nodes := sources[a.ID]; len(nodes) == 0 || nodes[len(nodes)-1] != n {
+				sources[a.ID] = append(nodes, n)
+			}
+		}
+	}
+
+	ids := make([]string, 0, len(sources))
+	for id := range sources {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	taken := map[string]string{}
+	for _, id := range ids {
+		slug := AuthorSlug(id)
+		first, ok := taken[slug]
+		if !ok {
+			taken[slug] = id
+			continue
+		}
+
+		err := fmt.Errorf("author %q has the slug %q of author %q, use one of them or rename it", id, slug, first)
+		for _, n := range sources[id] {
+			n.Logger().Error().Str("author", id).Str("other", first).Msg("Duplicate author slug")
+			db.Site.Diagnostics.AddError(n.source(), err)
+		}
+	}
+}
+
+// Authors returns the authors of a node from both author and authors metadata
+func (n *Node) Authors() []*Author {
+	authors := []*Author{}
+	if n.Meta == nil {
+		return authors
+	}
+
+	ids := n.Meta.Authors
+	if n.Meta.Author != "" {
+		ids = append([]string{n.Meta.Author}, ids...)
+	}
+
+	for _, id := range ids {
+		authors = append(authors, NewAuthor(n.site, id))
+	}
+
+	return authors
+}
+
+// Author returns the byline of the node: params.author, then its first author, then the site owner.
+// It's nil when none is set
+func (n *Node) Author() *baja.SiteAuthor {
+	if n.Meta != nil {
+		if name, ok := n.Meta.Params["author"].(string); ok && name != "" {
+			return &baja.SiteAuthor{Name: name}
+		}
+	}
+
+	if authors := n.Authors(); len(authors) > 0 {
+		a := authors[0]
+		author := &baja.SiteAuthor{Name: a.Name, Image: a.Profile.Avatar}
+		if n.site != nil {
+			author.URL = n.site.Config.AbsURL(a.Permalink)
+		}
+		return author
+	}
+
+	if n.site != nil && n.site.Config.Author.Name != "" {
+		return n.site.Author()
+	}
+
+	return nil
+}
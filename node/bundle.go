@@ -0,0 +1,169 @@
+package node
+
+import (
+	"errors"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/yeo/baja"
+	"github.com/yeo/baja/utils"
+)
+
+// BundleFiles are the index files which make a directory a page bundle: one node, rendered at the
+// url of the directory, with the other files of the directory as its resources
+var BundleFiles = []string{"index.md", "index.html"}
+
+// bundleIndex returns the index file of a page bundle directory, empty when dir isn't a bundle
+func bundleIndex(dir string) string {
+	for _, name := range BundleFiles {
+		path := filepath.Join(dir, name)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+	}
+
+	return ""
+}
+
+// NewBundle creates the node of page bundle dir, eg: content/post/trip/index.md is rendered at
+// /post/trip/ and content/post/trip/map.png is copied next to it
+func NewBundle(site *baja.Site, dir string) (*Node, error) {
+	return newBundle(site, dir, strings.Join(strings.Split(filepath.Dir(dir), "/")[1:], "/"))
+}
+
+// newBundle creates the node of page bundle dir, in directory base of content
+func newBundle(site *baja.Site, dir, base string) (*Node, error) {
+	n, err := newNode(site, bundleIndex(dir), path.Join(base, filepath.Base(dir)))
+	if err != nil {
+		return nil, err
+	}
+
+	n.Bundle = dir
+	n.Name = filepath.Base(dir)
+	n.BaseDirectory = base
+	n.Meta.Category = n.BaseDirectory
+	n.FindTheme(site)
+
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || path == n.Path {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		n.Resources = append(n.Resources, filepath.ToSlash(rel))
+		return nil
+	})
+	sort.Strings(n.Resources)
+
+	return n, err
+}
+
+// BundleResource is a file of a page bundle as templates see it in .Resources
+type BundleResource struct {
+	Name string // path relative to the bundle, eg: photos/beach.jpg
+	URL  string // url relative to the page, eg: photos/beach.jpg or my%20map.png
+	Size int64  // bytes
+
+	node *Node
+	path string // file on disk, in the bundle or for a derived image in the output directory
+}
+
+// BundleResourceList is the resources of a bundle, .Resources of a template
+type BundleResourceList []*BundleResource
+
+// Get returns the resource named name, eg: photos/beach.jpg, nil when the bundle has none
+func (l BundleResourceList) Get(name string) *BundleResource {
+	for _, r := range l {
+		if r.Name == name {
+			return r
+		}
+	}
+
+	return nil
+}
+
+// BundleResources returns the resources of the bundle with their url and size, in the order of
+// Resources. Empty for a node which isn't a bundle
+func (n *Node) BundleResources() BundleResourceList {
+	resources := BundleResourceList{}
+	for _, r := range n.Resources {
+		resource := &BundleResource{Name: r, URL: (&url.URL{Path: r}).String(), node: n, path: filepath.Join(n.Bundle, filepath.FromSlash(r))}
+		if info, err := os.Stat(resource.path); err == nil {
+			resource.Size = info.Size()
+		}
+		resources = append(resources, resource)
+	}
+
+	return resources
+}
+
+// RelPermalink returns the site path of the resource, eg: /post/trip/photos/beach.jpg
+func (r *BundleResource) RelPermalink() string {
+	return resolveURL(r.node.Permalink(), r.URL)
+}
+
+// ReferencedResources returns the resources of the bundle whose file name appears in page
+func (n *Node) ReferencedResources(page []byte) []string {
+	referenced := []string{}
+	for _, r := range n.Resources {
+		if strings.Contains(string(page), filepath.Base(r)) {
+			referenced = append(referenced, r)
+		}
+	}
+
+	return referenced
+}
+
+// compileResources copies the resources of a bundle into directory, the output directory of its
+// page. With config resources set to referenced, the ones page doesn't name are left out
+func (n *Node) compileResources(directory string, page []byte) {
+	if len(n.Resources) == 0 {
+		return
+	}
+
+	logger := n.Logger()
+	resources := n.Resources
+	if n.site.Config.Resources == baja.ResourcesReferenced {
+		resources = n.ReferencedResources(page)
+		for _, r := range n.Resources {
+			if !contains(resources, r) {
+				logger.Info().Str("resource", r).Msg("Prune unreferenced resource")
+			}
+		}
+	}
+
+	for _, r := range resources {
+		target := filepath.Join(directory, filepath.FromSlash(r))
+		if err := os.MkdirAll(filepath.Dir(target), os.ModePerm); err != nil {
+			logger.Error().Err(err).Str("resource", r).Msg("Cannot create resource directory")
+			continue
+		}
+
+		err := utils.CopyFile(filepath.Join(n.Bundle, filepath.FromSlash(r)), target)
+		var timeErr *utils.ModTimeError
+		if errors.As(err, &timeErr) {
+			logger.Warn().Err(err).Str("resource", r).Msg("Cannot keep resource time")
+		} else if err != nil {
+			logger.Error().Err(err).Str("resource", r).Msg("Cannot copy resource")
+			continue
+		}
+		n.site.Outputs.Add(target)
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
@@ -1,7 +1,6 @@
 package node
 
 import (
-	"fmt"
 	"os"
 	"regexp"
 	"strings"
@@ -22,6 +21,10 @@ func (cmd *CreateCommand) Help() string {
 	return "Create a new post, directory is post type. title should wrap in quote"
 }
 
+func (cmd *CreateCommand) CompleteArgs() []string {
+	return []string{"section"}
+}
+
 func (cmd *CreateCommand) Run(site *baja.Site, args []string) int {
 	if len(args) < 2 {
 		color.Red("Usage: baja create node-type file-name")
@@ -37,6 +40,9 @@ func Create(site *baja.Site, dir, title string) int {
 	slug := strings.Replace(title, " ", "-", -1)
 
 	current_time := time.Now()
+	if site != nil {
+		current_time = site.Now()
+	}
 
 	slug = strings.ToLower(slug)
 	slug = re.ReplaceAllString(slug, "-")
@@ -52,14 +58,20 @@ func Create(site *baja.Site, dir, title string) int {
 
 	defer file.Close()
 
-	content := `+++
-date = "%s"
-title = "%s"
-draft = true
+	archetype, err := Archetype(dir)
+	if err != nil {
+		color.Red("Invalid archetype for %s: %v", dir, err)
+		return 1
+	}
 
-tags = []
-+++`
-	fmt.Fprintf(file, fmt.Sprintf(content, time.Now().Format(time.RFC3339), title))
+	err = archetype.Execute(file, map[string]string{
+		"Date":  current_time.Format(time.RFC3339),
+		"Title": title,
+	})
+	if err != nil {
+		color.Red("Cannot write %s: %v", slug, err)
+		return 1
+	}
 	color.Green("Create file %s", slug)
 
 	return 0
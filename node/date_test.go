@@ -0,0 +1,45 @@
+package node_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/yeo/baja"
+)
+
+var _ = Describe("Date input formats", func() {
+	var site *baja.Site
+
+	BeforeEach(func() {
+		site = &baja.Site{Config: &baja.Config{Timezone: "Asia/Bangkok"}, Diagnostics: &baja.Diagnostics{}}
+	})
+
+	It("parses human dates in the site zone", func() {
+		n := parseNode(site, "content/post/day.md", "+++\ntitle = \"Day\"\ndate = \"2024-01-02\"\nlastmod = \"Jan 5, 2024\"\n+++\nbody")
+
+		Expect(n.Meta.Title).To(Equal("Day"))
+		Expect(n.Meta.Date.Format(time.RFC3339)).To(Equal("2024-01-02T00:00:00+07:00"))
+		Expect(n.Meta.Lastmod.Format(time.RFC3339)).To(Equal("2024-01-05T00:00:00+07:00"))
+		Expect(n.Meta.DateFormatted).To(Equal("2024 Jan 02"))
+		Expect(site.Diagnostics.Items).To(BeEmpty())
+	})
+
+	It("tries the formats of config in order", func() {
+		site.Config.DateInputFormats = []string{"02/01/2006"}
+		n := parseNode(site, "content/post/day.md", "+++\ntitle = \"Day\"\ndate = \"03/02/2024\"\n+++\nbody")
+
+		Expect(n.Meta.Date.Format("2006-01-02")).To(Equal("2024-02-03"))
+	})
+
+	It("warns about a date it can't parse", func() {
+		n := parseNode(site, "content/post/day.md", "+++\ntitle = \"Day\"\ndate = \"someday\"\n+++\nbody")
+
+		Expect(n.Meta.Date.IsZero()).To(Equal(true))
+		Expect(site.Diagnostics.Items).To(HaveLen(1))
+		Expect(site.Diagnostics.Items[0].Path).To(Equal("content/post/day.md"))
+		Expect(site.Diagnostics.Items[0].Severity).To(Equal(baja.SeverityWarning))
+		Expect(site.Diagnostics.Items[0].Message).To(ContainSubstring(`date: cannot parse date "someday"`))
+	})
+})
@@ -1,18 +1,26 @@
 package node
 
 import (
+	"errors"
+	"fmt"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 
-	"github.com/fatih/color"
+	"github.com/rs/zerolog/log"
 
 	"github.com/yeo/baja"
+	"github.com/yeo/baja/utils"
 )
 
 // NodeDB is the in-memory database of all the page
 type NodeDB struct {
 	NodeList      []*Node
 	DirectoryList []string
+	Sections      map[string]*Section
 	Total         int
 	Site          *baja.Site
 }
@@ -22,6 +30,23 @@ func (db *NodeDB) Append(n *Node) {
 	db.Total = len(db.NodeList)
 }
 
+// AddSection registers the metadata of a directory, parsed from its _index.md
+func (db *NodeDB) AddSection(s *Section) {
+	if db.Sections == nil {
+		db.Sections = make(map[string]*Section)
+	}
+	db.Sections[s.Dir] = s
+}
+
+// Section returns the metadata of a directory. Directory without an _index.md get a default one
+func (db *NodeDB) Section(dir string) *Section {
+	if s, ok := db.Sections[dir]; ok {
+		return s
+	}
+
+	return DefaultSection(dir)
+}
+
 func (db *NodeDB) All() []*Node {
 	return db.NodeList
 }
@@ -31,6 +56,10 @@ func (db *NodeDB) ByCategory() map[string][]*Node {
 	categoryNodes := make(map[string][]*Node)
 
 	for _, node := range db.NodeList {
+		if node.Meta.Unlisted {
+			continue
+		}
+
 		if node.BaseDirectory == "" {
 			// Those are node directly under content/ without any subdirectory
 			// they are only appear in / index page and not in subdirectory page
@@ -47,34 +76,43 @@ func (db *NodeDB) ByCategory() map[string][]*Node {
 
 // ByTag groups node by tag
 func (db *NodeDB) ByTag() map[string][]*Node {
-	tagsNode := make(map[string][]*Node)
+	return db.ByTaxonomy("tags")
+}
+
+// ByAuthor groups node by author id
+func (db *NodeDB) ByAuthor() map[string][]*Node {
+	authorNodes := make(map[string][]*Node)
 	for _, node := range db.NodeList {
-		if len(node.Meta.Tags) > 0 {
-			for _, tag := range node.Meta.Tags {
-				if tagsNode[tag] == nil {
-					tagsNode[tag] = []*Node{node}
-				} else {
-					tagsNode[tag] = append(tagsNode[tag], node)
-				}
-			}
+		if node.Meta.Unlisted {
+			continue
+		}
+
+		for _, author := range node.Authors() {
+			authorNodes[author.ID] = append(authorNodes[author.ID], node)
 		}
 	}
 
-	return tagsNode
+	return authorNodes
 }
 
-// Publishable returns a list of node that can be publish, as in non-draft mode or non page
+// Publishable returns a list of node that can be publish, as in non-draft mode, non page or non unlisted.
+// Drafts and future dated nodes are kept in a preview build
 func (db *NodeDB) Publishable() []*Node {
 	nodes := []*Node{}
 
+	now := db.Site.Now()
 	for _, node := range db.NodeList {
 		if node.IsPage() {
-			color.Red("\tignore %s because it's a standalone page", node.Name)
+			node.Logger().Debug().Msg("Ignore standalone page in listings")
 			continue
 		}
 
-		if node.Meta.Draft {
-			color.Red("\tignore %s because it's in draft mode ", node.Name)
+		if !node.IsPublished(now) && !db.Site.Preview {
+			node.Logger().Debug().Msg("Ignore draft or future node in listings")
+			continue
+		}
+
+		if node.Meta.Unlisted {
 			continue
 		}
 
@@ -84,23 +122,176 @@ func (db *NodeDB) Publishable() []*Node {
 	return nodes
 }
 
-type visitor func(path string, f os.FileInfo, err error) error
+// MainNodes returns the publishable nodes of the main sections of config, listed by the home index
+// and site feed
+func (db *NodeDB) MainNodes() []*Node {
+	nodes := []*Node{}
+	for _, node := range db.Publishable() {
+		if db.Site.Config.IsMainSection(node.BaseDirectory) {
+			nodes = append(nodes, node)
+		}
+	}
 
-func visit(db *NodeDB) filepath.WalkFunc {
+	return nodes
+}
+
+// RecentCount is the number of nodes of Recent on the home page
+const RecentCount = 10
+
+// Recent returns the count newest publishable nodes of every section
+func (db *NodeDB) Recent(count int) []*Node {
+	return newest(db.Publishable(), count)
+}
 
+// RecentMain returns the count newest nodes of the main sections, .Site.RecentPosts
+func (db *NodeDB) RecentMain(count int) []*Node {
+	return newest(db.MainNodes(), count)
+}
+
+// newest sorts nodes by date, newest first, and keeps count of them
+func newest(nodes []*Node, count int) []*Node {
+	sort.SliceStable(nodes, func(i, j int) bool { return nodes[i].Meta.Date.After(nodes[j].Meta.Date) })
+	if len(nodes) > count {
+		nodes = nodes[:count]
+	}
+
+	return nodes
+}
+
+// Preview returns a db of the draft and future dated nodes only, for a drafts preview build
+func (db *NodeDB) Preview(now time.Time) *NodeDB {
+	preview := &NodeDB{
+		NodeList:      []*Node{},
+		DirectoryList: db.DirectoryList,
+		Sections:      db.Sections,
+		Site:          db.Site,
+	}
+
+	for _, n := range db.NodeList {
+		if !n.IsPublished(now) {
+			preview.Append(n)
+		}
+	}
+
+	return preview
+}
+
+type visitor func(path string, f os.FileInfo, err error) error
+
+// visit parses the content files under root. A page bundle directory is a single node. Files of a
+// content mount are in its target directory, mount is nil for content
+func visit(db *NodeDB, root string, mount *baja.Mount) filepath.WalkFunc {
 	return func(path string, f os.FileInfo, err error) error {
-		color.Green("\t%s", path)
+		log.Debug().Str("path", path).Msg("Scan")
+
+		if errors.Is(err, utils.ErrSymlinkSkipped) || errors.Is(err, utils.ErrSymlinkCycle) {
+			log.Warn().Err(err).Str("path", path).Msg("Skip symlink")
+			db.Site.Diagnostics.AddWarning(path, err.Error())
+			return nil
+		}
+		if err != nil {
+			db.Site.Diagnostics.AddError(path, err)
+			return nil
+		}
+
+		rel, _ := filepath.Rel(root, path)
+		if path != root && db.Site.Ignored(filepath.ToSlash(rel)) {
+			log.Debug().Str("path", path).Msg("Skip ignored file")
+			if f.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
 
 		if f.IsDir() {
+			if path == root || bundleIndex(path) == "" {
+				return nil
+			}
+
+			n, err := newMountedBundle(db.Site, path, rel, mount)
+			if errors.Is(err, ErrContentTooLarge) {
+				skipLarge(db, path, err)
+				return filepath.SkipDir
+			}
+			if err != nil {
+				log.Error().Err(err).Str("path", path).Msg("Cannot parse bundle")
+				db.Site.Diagnostics.AddError(path, err)
+				return filepath.SkipDir
+			}
+
+			db.Append(n)
+			return filepath.SkipDir
+		}
+
+		n, err := newMountedNode(db.Site, path, rel, mount)
+		if errors.Is(err, ErrContentTooLarge) {
+			skipLarge(db, path, err)
+			return nil
+		}
+		if err != nil {
+			log.Error().Err(err).Str("path", path).Msg("Cannot parse node")
+			db.Site.Diagnostics.AddError(path, err)
 			return nil
 		}
 
-		db.Append(NewNode(db.Site, path))
+		if f.Name() == SectionFile {
+			db.AddSection(NewSection(n))
+			return nil
+		}
+
+		db.Append(n)
 
 		return nil
 	}
 }
 
+// newMountedNode creates the node of content file path, rel is its path relative to the root of
+// content or of mount
+func newMountedNode(site *baja.Site, path, rel string, mount *baja.Mount) (*Node, error) {
+	if mount == nil {
+		return NewNode(site, path)
+	}
+
+	n, err := newNode(site, path, mountDir(mount, filepath.Dir(rel)))
+	if err != nil {
+		return nil, err
+	}
+	n.Mount = mount.Source
+
+	return n, nil
+}
+
+// newMountedBundle is newMountedNode of a page bundle directory
+func newMountedBundle(site *baja.Site, dir, rel string, mount *baja.Mount) (*Node, error) {
+	if mount == nil {
+		return NewBundle(site, dir)
+	}
+
+	n, err := newBundle(site, dir, mountDir(mount, filepath.Dir(rel)))
+	if err != nil {
+		return nil, err
+	}
+	n.Mount = mount.Source
+
+	return n, nil
+}
+
+// mountDir returns the content directory of dir, a directory relative to the source of mount
+func mountDir(mount *baja.Mount, dir string) string {
+	dir = path.Join(mount.Target, filepath.ToSlash(dir))
+	if dir == "." {
+		return ""
+	}
+
+	return strings.Trim(dir, "/")
+}
+
+// skipLarge reports a content file over maxContentSize, which is left out of the build
+func skipLarge(db *NodeDB, path string, err error) {
+	log.Warn().Err(err).Str("path", path).Msg("Skip large content file")
+	db.Site.Diagnostics.AddWarning(path, "skipped, "+err.Error())
+}
+
 // BuildDB calculate a tree to represent all of node
 // This tree can be query/group/filter
 func BuildDB(site *baja.Site, ctx *baja.Context) *NodeDB {
@@ -108,7 +299,77 @@ func BuildDB(site *baja.Site, ctx *baja.Context) *NodeDB {
 		NodeList: []*Node{},
 		Site:     site,
 	}
-	color.Green("Scan content")
-	_ = filepath.Walk("./content", visit(db))
+	log.Info().Msg("Scan content")
+	_ = utils.Walk("./content", site.Config.FollowSymlinks, visit(db, "./content", nil))
+	for i := range site.Config.Mounts {
+		mount := &site.Config.Mounts[i]
+		log.Info().Str("source", mount.Source).Str("target", mount.Target).Msg("Scan content mount")
+		_ = utils.Walk(mount.Source, site.Config.FollowSymlinks, visit(db, mount.Source, mount))
+	}
+	db.checkMounts()
+	db.resolveSlugs()
+	db.checkAuthors()
+	db.checkPaths()
+	db.linkSeries()
+	db.linkRelated()
+	site.RecentPosts = db.RecentMain(site.Config.RecentPostsCount())
 	return db
 }
+
+// checkPaths reports the nodes whose permalink had characters unsafe in a file name, eg: a control
+// character or with outputPaths ascii an emoji, so authors know their url isn't the name they wrote
+func (db *NodeDB) checkPaths() {
+	for _, n := range db.NodeList {
+		raw, permalink := n.rawPermalink(), n.Permalink()
+		if raw == permalink {
+			continue
+		}
+
+		n.Logger().Warn().Str("permalink", permalink).Msg("Unsafe characters in output path")
+		db.Site.Diagnostics.AddWarning(n.source(), fmt.Sprintf("%q has characters unsafe in a file path, written to %s", raw, permalink))
+	}
+}
+
+// checkMounts finds nodes of different content mounts at the same permalink. The first one, content
+// then mounts in config order, is built, the others are errors of the build
+func (db *NodeDB) checkMounts() {
+	if len(db.Site.Config.Mounts) == 0 {
+		return
+	}
+
+	permalinks := map[string]*Node{}
+	nodes := []*Node{}
+	for _, n := range db.NodeList {
+		permalink := n.Permalink()
+		first, ok := permalinks[permalink]
+		if ok && first.Mount != n.Mount {
+			err := fmt.Errorf("permalink %s of %s is also the permalink of %s", permalink, n.source(), first.source())
+			n.Logger().Error().Str("other", first.source()).Msg("Duplicate permalink")
+			db.Site.Diagnostics.AddError(n.source(), err)
+			continue
+		}
+		if !ok {
+			permalinks[permalink] = n
+		}
+		nodes = append(nodes, n)
+	}
+
+	db.NodeList = nodes
+	db.Total = len(nodes)
+}
+
+// ByTaxonomy groups listed nodes by their terms of a taxonomy, eg: cuisine
+func (db *NodeDB) ByTaxonomy(key string) map[string][]*Node {
+	termNodes := make(map[string][]*Node)
+	for _, node := range db.NodeList {
+		if node.Meta.Unlisted {
+			continue
+		}
+
+		for _, term := range node.Terms(key) {
+			termNodes[term] = append(termNodes[term], node)
+		}
+	}
+
+	return termNodes
+}
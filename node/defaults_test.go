@@ -0,0 +1,44 @@
+package node_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/yeo/baja"
+)
+
+var _ = Describe("Defaults", func() {
+	site := &baja.Site{Config: &baja.Config{Defaults: map[string]map[string]interface{}{
+		"notes/**":       {"draft": true, "tags": []interface{}{"note"}, "params": map[interface{}]interface{}{"toc": true, "color": "grey"}},
+		"notes/public/*": {"draft": false},
+		"*":              {"author": "yeo"},
+	}}}
+
+	It("applies the defaults matching the node path", func() {
+		n := parseNode(site, "content/notes/idea.md", "+++\ntitle = \"Idea\"\n+++\nbody")
+
+		Expect(n.Meta.Draft).To(Equal(true))
+		Expect(n.Meta.Tags).To(Equal([]string{"note"}))
+		Expect(n.Meta.Author).To(Equal(""))
+	})
+
+	It("lets front matter and longer patterns win", func() {
+		n := parseNode(site, "content/notes/public/idea.md", "+++\ntitle = \"Idea\"\ntags = [\"go\"]\n[params]\ncolor = \"red\"\n+++\nbody")
+
+		Expect(n.Meta.Draft).To(Equal(false))
+		Expect(n.Meta.Tags).To(Equal([]string{"go"}))
+		Expect(n.Meta.Params).To(Equal(map[string]interface{}{"toc": true, "color": "red"}))
+	})
+
+	It("matches top level files with *", func() {
+		n := parseNode(site, "content/about.md", "+++\ntitle = \"About\"\n+++\nbody")
+
+		Expect(n.Meta.Author).To(Equal("yeo"))
+		Expect(n.Meta.Draft).To(Equal(false))
+	})
+
+	It("rejects invalid patterns", func() {
+		err := baja.ValidateDefaults(map[string]map[string]interface{}{"notes/[draft": {"draft": true}})
+		Expect(err).To(MatchError(ContainSubstring(`invalid defaults pattern "notes/[draft"`)))
+	})
+})
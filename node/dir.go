@@ -1,17 +1,18 @@
 package node
 
 import (
-	"bufio"
+	"bytes"
 	"fmt"
 	"html/template"
-	"log"
+	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
-	"os"
-	"sort"
+	"github.com/rs/zerolog/log"
 
 	"github.com/yeo/baja"
+	"github.com/yeo/baja/utils"
 )
 
 // ListPage is an index page, it isn't constructed from a markdown file but from a list of related markdown such as tag or category
@@ -19,18 +20,29 @@ type ListPage struct {
 	Current   *baja.Current
 	Title     string
 	Permalink string
-	Nodes     []map[string]interface{}
+	Nodes     []map[string]interface{} // the nodes of this page of the listing
+	Paginator *Paginator
+	Section   *Section
+	Author    *Author                  // set on author pages
+	Terms     []*Term                  // set on the terms index page of a taxonomy
+	Recent    []map[string]interface{} // set on the home page, the newest nodes of every section
+	Site      *baja.Site
 }
 
 type IndexNode struct {
 	Dir     string
 	Nodes   []*Node
+	Section *Section
+	Author  *Author
+	Terms   []*Term
+	Recent  []*Node
 	Current *baja.Current
 }
 
-func NewIndex(dir string, nodes []*Node) *IndexNode {
+func NewIndex(dir string, section *Section, nodes []*Node) *IndexNode {
 	n := &IndexNode{
-		Dir: dir,
+		Dir:     dir,
+		Section: section,
 		Current: &baja.Current{
 			IsHome:     false,
 			IsDir:      false,
@@ -44,8 +56,8 @@ func NewIndex(dir string, nodes []*Node) *IndexNode {
 		n.Current.IsHome = true
 	}
 
-	if strings.HasPrefix(dir, "tag/") {
-		n.Current.IsTag = true
+	if strings.HasPrefix(dir, AuthorDir+"/") {
+		n.Current.IsAuthor = true
 	} else {
 		n.Current.IsDir = true
 	}
@@ -53,58 +65,78 @@ func NewIndex(dir string, nodes []*Node) *IndexNode {
 	return n
 }
 
-func (n *IndexNode) Compile(site *baja.Site) {
+// Compile renders the index page into public, with page/2/, page/3/... after it when the
+// listing has more nodes than the paginate size of its directory
+func (n *IndexNode) Compile(site *baja.Site) error {
 	theme := site.Theme
+	n.Current.CompiledAt = n.Current.CompiledAt.In(site.Location())
 
-	targetDirectory := "public/" + n.Dir
-	os.MkdirAll(targetDirectory, os.ModePerm)
+	sort.Slice(n.Nodes, func(i, j int) bool { return n.Nodes[i].Meta.Date.After(n.Nodes[j].Meta.Date) })
 
-	f, err := os.Create(targetDirectory + "/index.html")
-	if err != nil {
-		fmt.Println("Cannot create index.html in", targetDirectory, ". error", err)
+	recentData := make([]map[string]interface{}, len(n.Recent))
+	for i, n := range n.Recent {
+		recentData[i] = n.data()
 	}
 
-	w := bufio.NewWriter(f)
-
-	sort.Slice(n.Nodes, func(i, j int) bool { return n.Nodes[i].Meta.Date.After(n.Nodes[j].Meta.Date) })
-	nodeData := make([]map[string]interface{}, len(n.Nodes))
-
-	for i, n := range n.Nodes {
-		nodeData[i] = n.data()
+	tpl, err := theme.ParseFiles(template.New("layout").Funcs(baja.FuncMaps(site)), theme.LayoutPath("default"), theme.NodePath("index"))
+	if err != nil {
+		return fmt.Errorf("cannot parse template: %w", err)
 	}
 
-	data := ListPage{
-		n.Current,
-		n.Dir,
-		n.Dir,
-		nodeData,
+	log.Debug().Str("dir", n.Dir).Str("template", theme.SubPath(n.Dir+".html")).Msg("Build index")
+	overrides := []string{theme.SubPath(n.Dir + ".html"), theme.Path() + n.Dir + "/index.html"}
+	if n.Current.IsHome {
+		// the home page has its own template, index.html of the theme root is already parsed
+		overrides = []string{theme.NodePath("home")}
+	}
+	if n.Current.IsAuthor {
+		overrides = append(overrides, theme.NodePath("author"))
+	}
+	if n.Current.IsTerms {
+		overrides = append(overrides, theme.NodePath("terms"))
 	}
 
-	tpl, err := template.New("layout").Funcs(baja.FuncMaps()).ParseFiles(theme.LayoutPath("default"))
-	tpl, err = tpl.ParseFiles(theme.NodePath("index"))
+	for _, override := range overrides {
+		if !theme.Exists(override) {
+			continue
+		}
 
-	log.Println("Build index", n.Dir, theme.SubPath(n.Dir+".html"))
-	if _, err := os.Stat(theme.SubPath(n.Dir + ".html")); err == nil {
-		tpl, err = tpl.ParseFiles(theme.SubPath(n.Dir + ".html"))
+		if tpl, err = theme.ParseFiles(tpl, override); err != nil {
+			return fmt.Errorf("cannot parse template: %w", err)
+		}
 	}
 
-	if _, err := os.Stat(theme.Path() + n.Dir + "/index.html"); err == nil {
-		tpl, err = tpl.ParseFiles(theme.Path() + n.Dir + "/index.html")
-	}
+	pagers, pages := paginate(site.Config, n.Dir, n.Nodes)
+	for i, pager := range pagers {
+		nodeData := make([]map[string]interface{}, len(pages[i]))
+		for j, n := range pages[i] {
+			nodeData[j] = n.data()
+		}
 
-	if n.Current.IsHome {
-		if _, err := os.Stat(theme.NodePath("home")); err == nil {
-			tpl, err = tpl.ParseFiles(theme.NodePath("home"))
+		data := ListPage{
+			Current:   n.Current,
+			Title:     n.Dir,
+			Permalink: n.Dir,
+			Nodes:     nodeData,
+			Paginator: pager,
+			Section:   n.Section,
+			Author:    n.Author,
+			Terms:     n.Terms,
+			Recent:    recentData,
+			Site:      site,
 		}
-	}
 
-	if tpl == nil {
-		fmt.Println("Cannot create template render")
-		return
-	}
+		var page bytes.Buffer
+		if err := tpl.Execute(&page, data); err != nil {
+			return fmt.Errorf("fail to render. Check your template for syntax, wrong tag: %w", err)
+		}
 
-	if err := tpl.Execute(w, data); err != nil {
-		fmt.Println("Fail to render. Check your template for syntax, wrong tag", err)
+		path := filepath.Join(site.OutputDir(), filepath.FromSlash(pagePermalink(n.Dir, pager.PageNumber)), "index.html")
+		if err := utils.WriteFileAtomic(path, &page, 0644); err != nil {
+			return fmt.Errorf("cannot write index.html in %s: %w", filepath.Dir(path), err)
+		}
+		site.Outputs.Add(path)
 	}
-	w.Flush()
+
+	return nil
 }
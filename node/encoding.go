@@ -0,0 +1,70 @@
+package node
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/htmlindex"
+
+	"github.com/yeo/baja"
+)
+
+var bom = []byte{0xEF, 0xBB, 0xBF}
+
+// toUTF8 converts the raw content of a node to UTF-8 before it's split into header and body.
+// Files declared in config Encodings are transcoded, a leading UTF-8 BOM is dropped
+func toUTF8(site *baja.Site, path string, content []byte) ([]byte, error) {
+	if name := declaredEncoding(site, path); name != "" {
+		enc, err := htmlindex.Get(name)
+		if err != nil {
+			return nil, fmt.Errorf("unknown encoding %s: %w", name, err)
+		}
+
+		if content, err = enc.NewDecoder().Bytes(content); err != nil {
+			return nil, fmt.Errorf("cannot decode as %s: %w", name, err)
+		}
+	}
+
+	content = bytes.TrimPrefix(content, bom)
+
+	if !utf8.Valid(content) && site != nil && site.Diagnostics != nil {
+		site.Diagnostics.AddWarning(path, "content is not valid UTF-8, declare its encoding in config encodings")
+	}
+
+	return content, nil
+}
+
+// declaredEncoding returns the encoding config Encodings declares for path. When patterns overlap,
+// eg: legacy/*.md and legacy/2009-*.md, the longest one, likely the most specific, wins
+func declaredEncoding(site *baja.Site, path string) string {
+	if site == nil || site.Config == nil {
+		return ""
+	}
+
+	rel, err := filepath.Rel("content", path)
+	if err != nil {
+		return ""
+	}
+
+	patterns := make([]string, 0, len(site.Config.Encodings))
+	for pattern := range site.Config.Encodings {
+		patterns = append(patterns, pattern)
+	}
+	sort.Slice(patterns, func(i, j int) bool {
+		if len(patterns[i]) != len(patterns[j]) {
+			return len(patterns[i]) > len(patterns[j])
+		}
+		return patterns[i] < patterns[j]
+	})
+
+	for _, pattern := range patterns {
+		if ok, _ := filepath.Match(pattern, filepath.ToSlash(rel)); ok {
+			return site.Config.Encodings[pattern]
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,49 @@
+package node_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/yeo/baja"
+)
+
+var _ = Describe("Content encoding", func() {
+	It("drops a UTF-8 BOM", func() {
+		site := &baja.Site{Config: &baja.Config{}}
+		n := parseNode(site, "content/post/bom.md", "\xEF\xBB\xBF+++\ntitle = \"Café\"\n+++\nbody")
+
+		Expect(n.Meta.Title).To(Equal("Café"))
+	})
+
+	It("transcodes files with a declared encoding", func() {
+		site := &baja.Site{Config: &baja.Config{Encodings: map[string]string{"legacy/*.md": "latin1"}}}
+		n := parseNode(site, "content/legacy/old.md", "+++\ntitle = \"Caf\xE9\"\n+++\nna\xEFve")
+
+		Expect(n.Meta.Title).To(Equal("Café"))
+		Expect(string(n.Body)).To(Equal("\nnaïve"))
+	})
+
+	It("uses the longest of overlapping patterns", func() {
+		site := &baja.Site{Config: &baja.Config{Encodings: map[string]string{
+			"legacy/*.md":      "latin1",
+			"legacy/2009-*.md": "windows-1251",
+			"*/2009-*.md":      "utf-8",
+		}}}
+
+		// run it a few times, a map is ranged in a random order
+		for i := 0; i < 10; i++ {
+			n := parseNode(site, "content/legacy/2009-old.md", "+++\ntitle = \"\xC4\xE0\"\n+++\nbody")
+			Expect(n.Meta.Title).To(Equal("Да"))
+
+			n = parseNode(site, "content/legacy/old.md", "+++\ntitle = \"Caf\xE9\"\n+++\nbody")
+			Expect(n.Meta.Title).To(Equal("Café"))
+		}
+	})
+
+	It("warns about undeclared invalid UTF-8", func() {
+		site := &baja.Site{Config: &baja.Config{}, Diagnostics: &baja.Diagnostics{}}
+		parseNode(site, "content/post/old.md", "+++\ntitle = \"x\"\n+++\nna\xEFve")
+
+		Expect(site.Diagnostics.Count(baja.SeverityWarning)).To(Equal(1))
+	})
+})
@@ -0,0 +1,114 @@
+package node
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"time"
+
+	"github.com/yeo/baja/utils"
+)
+
+// ExportFile is the file name of the json export of a node, written where its index.html would be
+const ExportFile = "index.json"
+
+// Export is the json of a node for headless usage, the fields a theme would use to render it
+type Export struct {
+	Permalink   string                 `json:"permalink"`
+	URL         string                 `json:"url,omitempty"` // absolute, when config has a baseURL
+	Title       string                 `json:"title"`
+	Description string                 `json:"description,omitempty"`
+	Date        time.Time              `json:"date"`
+	Lastmod     time.Time              `json:"lastmod"`
+	Draft       bool                   `json:"draft"`
+	Unlisted    bool                   `json:"unlisted"`
+	Type        string                 `json:"type,omitempty"`
+	Category    string                 `json:"category"`
+	Tags        []string               `json:"tags"`
+	Categories  []string               `json:"categories"`
+	Series      string                 `json:"series,omitempty"`
+	Authors     []string               `json:"authors"`
+	Aliases     []string               `json:"aliases"`
+	Params      map[string]interface{} `json:"params,omitempty"`
+	HasContent  bool                   `json:"hasContent"`
+	WordCount   int                    `json:"wordCount"`
+	ReadingTime int                    `json:"readingTime"`
+	Resources   []string               `json:"resources"`      // urls of the page bundle files
+	HTML        string                 `json:"html,omitempty"` // rendered body, left out of the index
+}
+
+// Export returns the json export of the node. The body is rendered and sanitized like an html build
+func (n *Node) Export() *Export {
+	e := &Export{
+		Permalink:   n.Permalink(),
+		Title:       n.Meta.Title,
+		Description: n.Meta.Description,
+		Date:        n.Meta.Date,
+		Lastmod:     n.Meta.Lastmod,
+		Draft:       n.Meta.Draft,
+		Unlisted:    n.Meta.Unlisted,
+		Type:        n.Meta.Type,
+		Category:    n.Meta.Category,
+		Tags:        nonNil(n.Meta.Tags),
+		Categories:  nonNil(n.Meta.Categories),
+		Series:      n.Meta.Series,
+		Authors:     []string{},
+		Aliases:     nonNil(n.Meta.Aliases),
+		Resources:   []string{},
+		Params:      n.Meta.Params,
+		HasContent:  n.HasContent(),
+		WordCount:   n.WordCount(),
+		ReadingTime: n.ReadingTime(),
+		HTML:        string(n.HTML()),
+	}
+
+	for _, r := range n.Resources {
+		e.Resources = append(e.Resources, e.Permalink+r)
+	}
+
+	for _, a := range n.Authors() {
+		e.Authors = append(e.Authors, a.ID)
+	}
+
+	if n.site != nil && n.site.Config.BaseURL != "" {
+		e.URL = n.site.Config.AbsURL(e.Permalink)
+	}
+
+	return e
+}
+
+// CompileJSON writes the json export of the node into its directory in public, in place of Compile
+func (n *Node) CompileJSON() error {
+	if _, err := n.markdown(); err != nil {
+		return err
+	}
+
+	directory := filepath.Join(n.site.OutputDir(), filepath.FromSlash(n.Permalink()))
+	if err := utils.EnsureDir(directory, utils.DefaultDirMode); err != nil {
+		return err
+	}
+
+	e := n.Export()
+	out, err := json.MarshalIndent(e, "", "  ")
+	if err != nil {
+		return fmt.Errorf("cannot encode node: %w", err)
+	}
+
+	path := filepath.Join(directory, ExportFile)
+	if err := ioutil.WriteFile(path, out, 0644); err != nil {
+		return fmt.Errorf("cannot write %s: %w", path, err)
+	}
+	n.site.Outputs.Add(path)
+
+	n.compileResources(directory, []byte(e.HTML))
+	return nil
+}
+
+func nonNil(list []string) []string {
+	if list == nil {
+		return []string{}
+	}
+
+	return list
+}
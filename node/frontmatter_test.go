@@ -0,0 +1,84 @@
+package node_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/yeo/baja"
+	. "github.com/yeo/baja/node"
+)
+
+var _ = Describe("Front matter", func() {
+	site := &baja.Site{Config: &baja.Config{Defaults: map[string]map[string]interface{}{"post/*": {"author": "yeo"}}}}
+
+	It("can be empty, the node gets the defaults and its body", func() {
+		n := parseNode(site, "content/post/empty.md", "+++\n+++\nbody")
+
+		Expect(n.Meta.Title).To(Equal(""))
+		Expect(n.Meta.Date.IsZero()).To(Equal(true))
+		Expect(n.Meta.Author).To(Equal("yeo"))
+		Expect(n.Meta.Category).To(Equal("post"))
+		Expect(string(n.Body)).To(Equal("\nbody"))
+	})
+
+	It("can be the whole file, the node has no content", func() {
+		for path, content := range map[string]string{
+			"content/event/launch.md": "+++\ntitle = \"Launch\"\n+++",
+			"content/event/meetup.md": "+++\ntitle = \"Meetup\"\n+++\n\n  \n",
+			"content/event/yaml.md":   "---\ntitle: Yaml\n---\n",
+		} {
+			n := parseNode(site, path, content)
+
+			Expect(n.Meta.Title).ToNot(BeEmpty(), path)
+			Expect(n.HasContent()).To(Equal(false), path)
+			Expect(string(n.HTML())).To(BeEmpty(), path)
+			Expect(n.WordCount()).To(Equal(0), path)
+		}
+
+		Expect(parseNode(site, "content/event/talk.md", "+++\ntitle = \"Talk\"\n+++\nslides").HasContent()).To(Equal(true))
+	})
+
+	It("keeps +++ written in the body", func() {
+		n := parseNode(site, "content/post/plus.md", "+++\ntitle = \"Plus\"\n+++\na\n+++\nb +++ c")
+
+		Expect(n.Meta.Title).To(Equal("Plus"))
+		Expect(string(n.Body)).To(Equal("\na\n+++\nb +++ c"))
+	})
+
+	It("can be yaml or json, dates read as in toml", func() {
+		for path, content := range map[string]string{
+			"content/post/yaml.md": "---\ntitle: Yaml\ndate: 2019-02-03\ntags: [go]\nweight: 2\n---\nbody",
+			"content/post/json.md": "{\"title\": \"Yaml\", \"date\": \"2019-02-03\", \"tags\": [\"go\"], \"weight\": 2}\nbody",
+		} {
+			n := parseNode(site, path, content)
+
+			Expect(n.Meta.Title).To(Equal("Yaml"))
+			Expect(n.Meta.Date.Format("2006-01-02")).To(Equal("2019-02-03"))
+			Expect(n.Meta.Tags).To(Equal([]string{"go"}))
+			Expect(n.Meta.Weight).To(Equal(2))
+			Expect(string(n.Body)).To(Equal("\nbody"))
+		}
+	})
+
+	It("can be left out, the whole file is the body", func() {
+		n := parseNode(site, "content/post/bare.md", "# Bare\r\n\r\nbody")
+
+		Expect(n.Meta.Title).To(Equal(""))
+		Expect(n.Meta.Author).To(Equal("yeo"))
+		Expect(string(n.Body)).To(Equal("# Bare\n\nbody"))
+	})
+
+	It("must be closed", func() {
+		dir, _ := ioutil.TempDir("", "baja-node")
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "open.md")
+		Expect(ioutil.WriteFile(path, []byte("+++\ntitle = \"Open\"\nbody"), 0644)).To(Succeed())
+
+		_, err := NewNode(site, path)
+		Expect(err).To(MatchError(ContainSubstring("metadata must be wrapped in +++")))
+	})
+})
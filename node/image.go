@@ -0,0 +1,44 @@
+package node
+
+import (
+	"html"
+	"path"
+	"regexp"
+	"strings"
+)
+
+var imgSrcRe = regexp.MustCompile(`(?i)<img\s[^>]*?\bsrc\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s>]+))`)
+
+// FirstImage returns the image of the node for card listings: params.image, or the first image of
+// its rendered body. A relative src such as map.png of a page bundle is resolved against the
+// permalink, so it's a site path like Permalink. Empty when the node has no image
+func (n *Node) FirstImage() string {
+	image := ""
+	if n.Meta != nil {
+		image, _ = n.Meta.Params["image"].(string)
+	}
+
+	if image == "" {
+		match := imgSrcRe.FindStringSubmatch(string(n.HTML()))
+		if match == nil {
+			return ""
+		}
+		image = html.UnescapeString(match[1] + match[2] + match[3])
+	}
+
+	return resolveURL(n.Permalink(), image)
+}
+
+// resolveURL resolves ref against base, a site path. Site paths and urls are kept as is
+func resolveURL(base, ref string) string {
+	if ref == "" || strings.HasPrefix(ref, "/") || strings.Contains(ref, ":") {
+		return ref
+	}
+
+	resolved := path.Join(base, ref)
+	if strings.HasSuffix(ref, "/") {
+		resolved += "/"
+	}
+
+	return resolved
+}
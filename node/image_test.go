@@ -0,0 +1,37 @@
+package node_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/yeo/baja"
+)
+
+var _ = Describe("FirstImage", func() {
+	var site *baja.Site
+
+	BeforeEach(func() {
+		site = &baja.Site{Config: &baja.Config{}}
+	})
+
+	It("is the first image of the body resolved against the permalink", func() {
+		n := parseNode(site, "content/post/trip.md", "+++\ntitle = \"Trip\"\n+++\nIntro\n\n![map](img/map.png?w=1&h=2)\n\n![sea](/img/sea.png)")
+		Expect(n.FirstImage()).To(Equal("/post/trip/img/map.png?w=1&h=2"))
+
+		n = parseNode(site, "content/post/raw.html", `+++
+title = "Raw"
++++
+<p><IMG alt="x" SRC='https://cdn.example.com/a.png'></p>`)
+		Expect(n.FirstImage()).To(Equal("https://cdn.example.com/a.png"))
+	})
+
+	It("prefers params.image", func() {
+		n := parseNode(site, "content/post/cover.md", "+++\ntitle = \"Cover\"\n[params]\nimage = \"/img/cover.png\"\n+++\n![map](map.png)")
+		Expect(n.FirstImage()).To(Equal("/img/cover.png"))
+	})
+
+	It("is empty without image", func() {
+		n := parseNode(site, "content/post/plain.md", "+++\ntitle = \"Plain\"\n+++\nbody")
+		Expect(n.FirstImage()).To(BeEmpty())
+	})
+})
@@ -0,0 +1,118 @@
+package node
+
+import (
+	"encoding/json"
+	"html/template"
+	"strings"
+	"time"
+
+	"github.com/yeo/baja"
+)
+
+// JSONLD returns schema.org Article, or BlogPosting for posts, structured data of the node
+// wrapped in its script tag. Its publisher is the site with the sameAs of config social. Fields
+// without a value are left out
+func (n *Node) JSONLD() template.HTML {
+	if n.site == nil || n.Meta == nil {
+		return ""
+	}
+	config := n.site.Config
+
+	ld := map[string]interface{}{
+		"@context":         "https://schema.org",
+		"@type":            "BlogPosting",
+		"headline":         n.Meta.Title,
+		"mainEntityOfPage": n.Canonical(),
+	}
+
+	if n.IsPage() {
+		ld["@type"] = "Article"
+	}
+
+	if n.Meta.Description != "" {
+		ld["description"] = n.Meta.Description
+	}
+
+	if !n.Meta.Date.IsZero() {
+		ld["datePublished"] = n.Meta.Date.Format(time.RFC3339)
+	}
+
+	if !n.Meta.Lastmod.IsZero() {
+		ld["dateModified"] = n.Meta.Lastmod.Format(time.RFC3339)
+	}
+
+	if author := n.Author(); author != nil {
+		person := map[string]string{"@type": "Person", "name": author.Name}
+		if author.URL != "" {
+			person["url"] = author.URL
+		}
+		if author.Image != "" {
+			person["image"] = absURL(config, author.Image)
+		}
+		ld["author"] = person
+	}
+
+	if image := n.Image(); image != "" {
+		ld["image"] = image
+	}
+
+	if sameAs := config.SameAs(); len(sameAs) > 0 {
+		// the site, with the same profiles as the links themes show from .Site.Social
+		publisher := map[string]interface{}{"@type": "Organization", "name": n.site.Title(), "url": config.AbsURL("/"), "sameAs": sameAs}
+		if config.Author.Name != "" {
+			publisher["@type"] = "Person"
+			publisher["name"] = config.Author.Name
+		}
+		ld["publisher"] = publisher
+	}
+
+	out, err := json.Marshal(ld)
+	if err != nil {
+		return ""
+	}
+
+	return template.HTML(`<script type="application/ld+json">` + string(out) + `</script>`)
+}
+
+// Image returns the absolute url of params.image of the node, or of the site default image
+func (n *Node) Image() string {
+	if n.site == nil {
+		return ""
+	}
+	config := n.site.Config
+
+	image := config.DefaultImage
+	if n.Meta != nil {
+		if i, ok := n.Meta.Params["image"].(string); ok && i != "" {
+			image = i
+		}
+	}
+	if image == "" {
+		return ""
+	}
+
+	return absURL(config, image)
+}
+
+// Canonical returns the absolute url of meta canonical, for content published elsewhere first, or
+// the absolute permalink of the node. Feed and sitemap always use the permalink
+func (n *Node) Canonical() string {
+	if n.site == nil {
+		return n.Permalink()
+	}
+
+	if n.Meta != nil && n.Meta.Canonical != "" {
+		return absURL(n.site.Config, n.Meta.Canonical)
+	}
+
+	return n.site.Config.AbsURL(n.Permalink())
+}
+
+// absURL makes a site path absolute, urls are kept as is
+func absURL(config *baja.Config, path string) string {
+	if strings.Contains(path, "://") {
+		return path
+	}
+
+	return config.AbsURL(path)
+}
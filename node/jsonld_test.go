@@ -0,0 +1,131 @@
+package node_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/yeo/baja"
+	. "github.com/yeo/baja/node"
+)
+
+// parseNode writes content at path, relative to a temporary site root, and parses it into a node
+func parseNode(site *baja.Site, path, content string) *Node {
+	cwd, _ := os.Getwd()
+	dir, err := ioutil.TempDir("", "baja-node")
+	Expect(err).ToNot(HaveOccurred())
+	defer os.RemoveAll(dir)
+
+	os.MkdirAll(filepath.Join(dir, filepath.Dir(path)), os.ModePerm)
+	Expect(ioutil.WriteFile(filepath.Join(dir, path), []byte(content), 0644)).To(Succeed())
+
+	os.Chdir(dir)
+	defer os.Chdir(cwd)
+
+	n, err := NewNode(site, path)
+	Expect(err).ToNot(HaveOccurred())
+
+	return n
+}
+
+func jsonLD(n *Node) map[string]interface{} {
+	script := string(n.JSONLD())
+	Expect(script).To(HavePrefix(`<script type="application/ld+json">`))
+
+	ld := map[string]interface{}{}
+	body := strings.TrimSuffix(strings.TrimPrefix(script, `<script type="application/ld+json">`), "</script>")
+	Expect(json.Unmarshal([]byte(body), &ld)).To(Succeed())
+
+	return ld
+}
+
+var _ = Describe("JSONLD", func() {
+	var site *baja.Site
+
+	BeforeEach(func() {
+		site = &baja.Site{Config: &baja.Config{BaseURL: "https://example.com/", Author: baja.SiteAuthor{Name: "Site Owner"}}}
+	})
+
+	It("describes a post", func() {
+		n := parseNode(site, "content/post/hello.md", `+++
+title = "Hello"
+date = 2019-02-09T10:00:00Z
+lastmod = 2019-03-01T10:00:00Z
+[params]
+image = "/img/cover.png"
++++
+body`)
+
+		ld := jsonLD(n)
+		Expect(ld["@type"]).To(Equal("BlogPosting"))
+		Expect(ld["headline"]).To(Equal("Hello"))
+		Expect(ld["datePublished"]).To(Equal("2019-02-09T10:00:00Z"))
+		Expect(ld["dateModified"]).To(Equal("2019-03-01T10:00:00Z"))
+		Expect(ld["image"]).To(Equal("https://example.com/img/cover.png"))
+		Expect(ld["mainEntityOfPage"]).To(Equal("https://example.com/post/hello/"))
+		Expect(ld["author"]).To(Equal(map[string]interface{}{"@type": "Person", "name": "Site Owner"}))
+	})
+
+	It("prefers node author and omits unset fields", func() {
+		n := parseNode(site, "content/about.md", `+++
+title = "About"
+type = "page"
+[params]
+author = "Guest"
++++
+body`)
+
+		ld := jsonLD(n)
+		Expect(ld["@type"]).To(Equal("Article"))
+		Expect(ld["author"]).To(Equal(map[string]interface{}{"@type": "Person", "name": "Guest"}))
+		Expect(ld).ToNot(HaveKey("datePublished"))
+		Expect(ld).ToNot(HaveKey("dateModified"))
+		Expect(ld).ToNot(HaveKey("image"))
+	})
+
+	It("describes the site author", func() {
+		site.Config.Author = baja.SiteAuthor{Name: "Site Owner", URL: "https://owner.example.com", Image: "/owner.png"}
+		n := parseNode(site, "content/post/hello.md", "+++\ntitle = \"Hello\"\n+++\nbody")
+
+		Expect(jsonLD(n)["author"]).To(Equal(map[string]interface{}{
+			"@type": "Person", "name": "Site Owner", "url": "https://owner.example.com", "image": "https://example.com/owner.png",
+		}))
+	})
+
+	It("points to the canonical url of syndicated content", func() {
+		n := parseNode(site, "content/post/copy.md", "+++\ntitle = \"Copy\"\ncanonical = \"https://medium.com/@yeo/copy\"\n+++\nbody")
+
+		Expect(n.Canonical()).To(Equal("https://medium.com/@yeo/copy"))
+		Expect(jsonLD(n)["mainEntityOfPage"]).To(Equal("https://medium.com/@yeo/copy"))
+		Expect(string(n.OpenGraph())).To(ContainSubstring(`<meta property="og:url" content="https://medium.com/@yeo/copy">`))
+		Expect(n.Permalink()).To(Equal("/post/copy/"))
+
+		n = parseNode(site, "content/post/own.md", "+++\ntitle = \"Own\"\n+++\nbody")
+		Expect(n.Canonical()).To(Equal("https://example.com/post/own/"))
+	})
+
+	It("links the site profiles of config social", func() {
+		site.Config.Social = []baja.SocialLink{{Name: "GitHub", URL: "https://github.com/yeo"}, {Name: "Mastodon", URL: "https://mastodon.social/@yeo"}}
+		n := parseNode(site, "content/post/hello.md", "+++\ntitle = \"Hello\"\n+++\nbody")
+
+		Expect(jsonLD(n)["publisher"]).To(Equal(map[string]interface{}{
+			"@type": "Person", "name": "Site Owner", "url": "https://example.com/",
+			"sameAs": []interface{}{"https://github.com/yeo", "https://mastodon.social/@yeo"},
+		}))
+	})
+
+	It("falls back to the site default image", func() {
+		site.Config.DefaultImage = "/img/default.png"
+
+		n := parseNode(site, "content/post/plain.md", "+++\ntitle = \"Plain\"\n+++\nbody")
+		Expect(jsonLD(n)["image"]).To(Equal("https://example.com/img/default.png"))
+
+		n = parseNode(site, "content/post/cover.md", "+++\ntitle = \"Cover\"\n[params]\nimage = \"https://cdn.example.com/cover.png\"\n+++\nbody")
+		Expect(jsonLD(n)["image"]).To(Equal("https://cdn.example.com/cover.png"))
+	})
+})
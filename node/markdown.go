@@ -0,0 +1,117 @@
+package node
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/yeo/baja"
+	"github.com/yeo/baja/utils"
+)
+
+// ErrContentTooLarge is returned by NewNode for a content file over config maxContentSize
+var ErrContentTooLarge = errors.New("content file is over maxContentSize")
+
+// markdown returns the body of the node rendered into html, once, with its shortcodes expanded,
+// then its includes from config readRoot. Rendering is bound by config renderTimeout, the offending node
+// gets an error. The html is kept in the site cache, a body rendered by a previous build with the
+// same markup config isn't rendered again
+func (n *Node) markdown() ([]byte, error) {
+	if n.IsHTML() {
+		return []byte(n.Body), nil
+	}
+
+	if n.rendered == nil && n.renderErr == nil {
+		var timeout time.Duration
+		root := baja.DefaultReadRoot
+		if n.site != nil && n.site.Config != nil {
+			timeout = n.site.Config.RenderTimeoutDuration()
+			if n.site.Config.ReadRoot != "" {
+				root = n.site.Config.ReadRoot
+			}
+		}
+
+		body, err := n.expandShortcodes([]byte(n.Body))
+		if err != nil {
+			n.renderErr = err
+			return nil, err
+		}
+		body, err = baja.ExpandIncludes(root, n.Path, body)
+		if err != nil {
+			n.renderErr = err
+			return nil, err
+		}
+		n.rendered, n.renderErr = n.renderCached(body, timeout)
+	}
+
+	return n.rendered, n.renderErr
+}
+
+// renderCached is renderMarkdown of body through the site cache
+func (n *Node) renderCached(body []byte, timeout time.Duration) ([]byte, error) {
+	var cache *utils.Cache
+	markup := ""
+	if n.site != nil && n.site.Config != nil {
+		cache = n.site.Cache()
+		markup = fmt.Sprintf("%+v", n.site.Config.Markup)
+	}
+
+	id := n.footnoteID()
+	key := utils.CacheKey("markdown", []byte(markup), []byte(id), body)
+	if html, ok := cache.Get(key); ok {
+		return html, nil
+	}
+
+	html, err := renderMarkdown(n.markup(), body, id, timeout)
+	if err != nil {
+		return nil, err
+	}
+	if err := cache.Put(key, html); err != nil {
+		n.Logger().Warn().Err(err).Msg("Cannot cache rendered markdown")
+	}
+
+	return html, nil
+}
+
+// markup returns the markdown renderer of the site of the node
+func (n *Node) markup() *baja.Markup {
+	if n.site == nil || n.site.Config == nil {
+		return baja.NewMarkup(nil)
+	}
+
+	return n.site.Markup()
+}
+
+// footnoteID prefixes the footnote anchors of the node, its permalink as a slug
+func (n *Node) footnoteID() string {
+	return utils.Slugify(n.Permalink())
+}
+
+// HasFootnotes returns true when the body of the node ends with footnotes, for a theme to title
+// them
+func (n *Node) HasFootnotes() bool {
+	html, _ := n.markdown()
+
+	return bytes.Contains(html, []byte(`<div class="footnotes">`))
+}
+
+// renderMarkdown renders body with m, id prefixes its footnote anchors. Past timeout it gives up, blackfriday can't be stopped so
+// it finishes in the background and its result is dropped
+func renderMarkdown(m *baja.Markup, body []byte, id string, timeout time.Duration) ([]byte, error) {
+	if timeout <= 0 {
+		return m.MarkdownWithID(body, id), nil
+	}
+
+	done := make(chan []byte, 1)
+	go func() {
+		done <- m.MarkdownWithID(body, id)
+	}()
+
+	select {
+	case html := <-done:
+		return html, nil
+	case <-time.After(timeout):
+		return nil, fmt.Errorf("markdown took over renderTimeout %s to render", timeout)
+	}
+}
@@ -0,0 +1,125 @@
+package node
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fatih/color"
+
+	"github.com/yeo/baja"
+	"github.com/yeo/baja/utils"
+)
+
+// ErrSectionExists is returned by CreateSection when any file of the section is already there
+var ErrSectionExists = errors.New("section already exists")
+
+const sectionIndex = `+++
+title = "%s"
+description = ""
++++
+Introduction of %s, shown on its index page.
+`
+
+const sectionTemplate = `{{/* Index page of %s. Change "content" to the block your layout renders */}}
+{{ define "content" }}
+<h1>{{ .Section.Meta.Title }}</h1>
+{{ .Section.Body }}
+{{ range .Nodes }}<a href="{{ .Permalink }}">{{ .Meta.Title }}</a>{{ end }}
+{{ end }}
+`
+
+type NewCommand struct {
+	themeStub bool
+}
+
+func (cmd *NewCommand) ArgDesc() string {
+	return "section name"
+}
+
+func (cmd *NewCommand) Help() string {
+	return "Create a new section with its _index.md and archetype"
+}
+
+func (cmd *NewCommand) Flags(fs *flag.FlagSet) {
+	fs.BoolVar(&cmd.themeStub, "theme-stub", false, "also create an index template of the section in the theme")
+}
+
+func (cmd *NewCommand) CompleteArgs() []string {
+	return []string{"new"}
+}
+
+func (cmd *NewCommand) Run(site *baja.Site, args []string) int {
+	if len(args) < 2 || args[0] != "section" {
+		color.Red("Usage: baja new [--theme-stub] section name")
+		return 1
+	}
+
+	created, err := CreateSection(site, args[1], cmd.themeStub)
+	if err != nil {
+		color.Red("Cannot create section %s: %v", args[1], err)
+		return 1
+	}
+
+	for _, path := range created {
+		color.Green("Create %s", path)
+	}
+
+	return 0
+}
+
+// CreateSection scaffolds content/<name>/_index.md and archetypes/<name>.md, copied from the default
+// archetype, plus an index template in the theme when themeStub is set. Nothing is written when any
+// of those files exists. It returns the created files
+func CreateSection(site *baja.Site, name string, themeStub bool) ([]string, error) {
+	name = strings.Trim(filepath.ToSlash(name), "/")
+	if name == "" || strings.Contains(name, "..") {
+		return nil, fmt.Errorf("invalid section name %q", name)
+	}
+
+	files := map[string][]byte{
+		filepath.Join("content", name, SectionFile): []byte(fmt.Sprintf(sectionIndex, DefaultSection(name).Meta.Title, name)),
+		filepath.Join(ArchetypeDir, name+".md"):     DefaultArchetype(),
+	}
+	paths := []string{filepath.Join("content", name, SectionFile), filepath.Join(ArchetypeDir, name+".md")}
+
+	if themeStub {
+		if site == nil || site.Theme == nil || site.Theme.Name == "" {
+			return nil, errors.New("theme stub needs a theme in config")
+		}
+		if site.Theme.IsArchive() {
+			return nil, errors.New("theme stub needs a theme directory, not an archive")
+		}
+
+		stub := filepath.Join("themes", site.Theme.Name, name+".html")
+		files[stub] = []byte(fmt.Sprintf(sectionTemplate, name))
+		paths = append(paths, stub)
+	}
+
+	if _, err := os.Stat(filepath.Join("content", name)); err == nil {
+		return nil, &utils.PathError{Op: "create section", Path: filepath.Join("content", name), Err: ErrSectionExists}
+	}
+	for _, path := range paths {
+		if _, err := os.Stat(path); err == nil {
+			return nil, &utils.PathError{Op: "create section", Path: path, Err: ErrSectionExists}
+		}
+	}
+
+	created := []string{}
+	for _, path := range paths {
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			return created, err
+		}
+
+		if err := ioutil.WriteFile(path, files[path], 0644); err != nil {
+			return created, err
+		}
+		created = append(created, path)
+	}
+
+	return created, nil
+}
@@ -0,0 +1,69 @@
+package node_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/yeo/baja"
+	. "github.com/yeo/baja/node"
+	"github.com/yeo/baja/utils"
+)
+
+var _ = Describe("CreateSection", func() {
+	var (
+		cwd, dir string
+		site     *baja.Site
+	)
+
+	BeforeEach(func() {
+		cwd, _ = os.Getwd()
+		dir, _ = ioutil.TempDir("", "baja-section")
+		os.Chdir(dir)
+
+		site = &baja.Site{Config: &baja.Config{Theme: "t"}, Theme: &baja.Theme{Name: "t"}}
+	})
+
+	AfterEach(func() {
+		os.Chdir(cwd)
+		os.RemoveAll(dir)
+	})
+
+	It("scaffolds section files and returns them", func() {
+		created, err := CreateSection(site, "travel-notes", true)
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(created).To(Equal([]string{"content/travel-notes/_index.md", "archetypes/travel-notes.md", "themes/t/travel-notes.html"}))
+
+		index, _ := ioutil.ReadFile("content/travel-notes/_index.md")
+		Expect(string(index)).To(ContainSubstring(`title = "Travel notes"`))
+	})
+
+	It("copies the site default archetype", func() {
+		os.MkdirAll("archetypes", os.ModePerm)
+		ioutil.WriteFile("archetypes/default.md", []byte("+++\ntitle = \"{{ .Title }}\"\n+++\n"), 0644)
+
+		_, err := CreateSection(site, "notes", false)
+		Expect(err).ToNot(HaveOccurred())
+
+		archetype, _ := ioutil.ReadFile("archetypes/notes.md")
+		Expect(string(archetype)).To(Equal("+++\ntitle = \"{{ .Title }}\"\n+++\n"))
+	})
+
+	It("refuses an existing section", func() {
+		os.MkdirAll("content/notes", os.ModePerm)
+
+		created, err := CreateSection(site, "notes", false)
+
+		Expect(errors.Is(err, ErrSectionExists)).To(Equal(true))
+		var pathErr *utils.PathError
+		Expect(errors.As(err, &pathErr)).To(Equal(true))
+		Expect(pathErr.Path).To(Equal("content/notes"))
+		Expect(created).To(BeEmpty())
+		_, statErr := os.Stat("archetypes/notes.md")
+		Expect(os.IsNotExist(statErr)).To(Equal(true))
+	})
+})
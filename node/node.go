@@ -1,8 +1,12 @@
 package node
 
 import (
-	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"html"
 	"html/template"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -10,11 +14,12 @@ import (
 	"time"
 
 	"github.com/BurntSushi/toml"
+	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
-	"github.com/russross/blackfriday"
-	//"github.com/microcosm-cc/bluemonday"
 
 	"github.com/yeo/baja"
+	"github.com/yeo/baja/frontmatter"
+	"github.com/yeo/baja/utils"
 )
 
 const (
@@ -25,13 +30,25 @@ const (
 // NodeMeta is meta data of a node, usually map directly to node toml metadata section
 type NodeMeta struct {
 	Title         string
+	Description   string
 	Draft         bool
+	Unlisted      bool // built and reachable by its url but left out of indexes, feed, sitemap and tags
+	NoIndex       bool // built and listed but left out of the sitemap, themes add a robots noindex meta
 	Date          time.Time
+	Lastmod       time.Time
 	DateFormatted string
 	Tags          []string
+	Categories    []string
+	Series        string // name of the series the node is a part of, eg: a multi-part tutorial
+	Weight        int    // order in the series, lower first. Nodes of the same weight are ordered by date
+	Author        string
+	Authors       []string // ids of authors, profiles come from config authors
 	Category      string
-	Type          string // node type. Eg page or post
-	Theme         string // a custom template file inside theme directory without extension
+	Type          string   // node type. Eg page or post
+	Theme         string   // a custom template file inside theme directory without extension
+	Aliases       []string // old urls of this node, a redirect page is generated for each of them
+	Canonical     string   // url of the original of syndicated content, a site path or an absolute url
+	Params        map[string]interface{}
 }
 
 // Node hold information of a specifc page we are rendering
@@ -39,59 +56,278 @@ type Node struct {
 	Meta *NodeMeta
 	Body template.HTML
 
-	Raw           string // raw text content
-	Path          string // full absolute path to markdown file
-	BaseDirectory string // the directory without /content part
-	Name          string // the filename without extension
-
+	Raw           string   // raw text content
+	Path          string   // full absolute path to markdown file
+	BaseDirectory string   // the directory without /content part
+	Name          string   // the filename without extension
+	Bundle        string   // directory of a page bundle, empty when the node is a single file
+	Mount         string   // source directory of the content mount of the node, empty for content
+	Resources     []string // files of the bundle other than its index, relative to Bundle
+
+	frontMatter   map[string]interface{} // raw metadata, for taxonomy keys which aren't a NodeMeta field
+	rendered      []byte                 // html of a markdown body, see markdown
+	renderErr     error
+	bodyStart     int      // line of the content file the body starts on
 	templatePaths []string // a list of template files that are discovered for this node. These templates are used to render content
+	templateMiss  []string // the templates FindTheme looked for and didn't find
+	site          *baja.Site
+
+	series      []*Node // listed nodes of Meta.Series in order, set once the walk is done
+	seriesIndex int     // position of the node in series
+	related     []*Node // see Related, set once the walk is done
 }
 
 // NewNode creates a Node object from a path
-func NewNode(site *baja.Site, path string) *Node {
-	n := Node{Path: path}
-
+func NewNode(site *baja.Site, path string) (*Node, error) {
 	// Remove content from path to get base directory
-	n.BaseDirectory = strings.Join(strings.Split(filepath.Dir(path), "/")[1:], "/")
+	return newNode(site, path, strings.Join(strings.Split(filepath.Dir(path), "/")[1:], "/"))
+}
+
+// newNode creates the node of file path, in directory dir of content
+func newNode(site *baja.Site, path, dir string) (*Node, error) {
+	n := Node{Path: path, site: site}
+	n.BaseDirectory = dir
 
 	filename := filepath.Base(path)
 	dotPosition := strings.LastIndex(filename, ".")
 	n.Name = filename[0:dotPosition]
 
-	n.Parse()
+	if err := n.Parse(); err != nil {
+		return nil, err
+	}
 	n.FindTheme(site)
 
-	return &n
+	return &n, nil
 }
 
 // Parse reads the markdown and parse metadata and generate html
-func (n *Node) Parse() {
+func (n *Node) Parse() error {
+	if n.site != nil && n.site.Config != nil && n.site.Config.MaxContentSize > 0 {
+		info, err := os.Stat(n.Path)
+		if err != nil {
+			return err
+		}
+		if info.Size() > n.site.Config.MaxContentSize {
+			return fmt.Errorf("%w: %d bytes, limit is %d", ErrContentTooLarge, info.Size(), n.site.Config.MaxContentSize)
+		}
+	}
+
 	content, err := ioutil.ReadFile(n.Path)
 	if err != nil {
-		log.Error().Err(err).Str("Node", n.Path).Str("Message", "Cannot read node")
+		return err
+	}
 
-		return
+	if content, err = toUTF8(n.site, n.Path, content); err != nil {
+		return err
 	}
 
-	part := strings.Split(string(content), "+++")
-	if len(part) < 3 {
-		log.Fatal().Str("path", n.Path).Msg("Not enough header/body")
+	format, header, body, err := frontmatter.Split(content)
+	if err != nil {
+		return err
+	}
+	front, err := tomlFrontMatter(format, header)
+	if err != nil {
+		return fmt.Errorf("invalid metadata: %w", err)
 	}
 
 	n.Meta = &NodeMeta{}
-	toml.Decode(string(part[1]), n.Meta)
+	n.frontMatter = map[string]interface{}{}
+	if err := n.applyDefaults(); err != nil {
+		return err
+	}
+
+	metadata, dates, err := stringDates(front)
+	if err != nil {
+		return fmt.Errorf("invalid metadata: %w", err)
+	}
+	if _, err := toml.Decode(metadata, n.Meta); err != nil {
+		return fmt.Errorf("invalid metadata: %w", err)
+	}
+	toml.Decode(front, &n.frontMatter)
 
-	n.Meta.DateFormatted = n.Meta.Date.Format("2006 Jan 02")
+	if n.site != nil && n.site.Config != nil {
+		loc := n.site.Location()
+		n.Meta.Date = inZone(n.Meta.Date, n.frontMatterValue("date"), loc)
+		n.Meta.Lastmod = inZone(n.Meta.Lastmod, n.frontMatterValue("lastmod"), loc)
+		n.parseDates(dates, loc)
+	}
+	layout := baja.DefaultDateFormats[baja.DateFormatDefault]
+	if n.site != nil && n.site.Config != nil {
+		layout, _ = n.site.Config.DateFormat(baja.DateFormatDefault)
+	}
+	n.Meta.DateFormatted = n.Meta.Date.Format(layout)
 	n.Meta.Category = n.BaseDirectory
 
-	n.Body = template.HTML(part[2])
+	n.Body = template.HTML(body)
+	n.bodyStart = strings.Count(string(content), "\n") - strings.Count(string(body), "\n") + 1
+
+	return nil
+}
+
+// tomlFrontMatter returns header, a front matter of format, as toml: yaml and json front matter
+// is decoded and encoded again so NodeMeta and its dates are read the same way
+func tomlFrontMatter(format string, header []byte) (string, error) {
+	if format == frontmatter.TOML || format == frontmatter.None {
+		return string(header), nil
+	}
+
+	raw := map[string]interface{}{}
+	if err := frontmatter.Decode(header, format, &raw); err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(raw); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// dateKeys are the NodeMeta dates, they can be written as a string in a format of dateInputFormats
+var dateKeys = []string{"date", "lastmod"}
+
+// stringDates takes the dates written as a string out of the front matter, toml only decodes
+// RFC 3339 strings into a time. It returns the front matter left and these dates by key
+func stringDates(metadata string) (string, map[string]string, error) {
+	raw := map[string]interface{}{}
+	if _, err := toml.Decode(metadata, &raw); err != nil {
+		return "", nil, err
+	}
+
+	dates := map[string]string{}
+	for k, v := range raw {
+		for _, key := range dateKeys {
+			if s, ok := v.(string); ok && strings.EqualFold(k, key) {
+				dates[key] = s
+				delete(raw, k)
+			}
+		}
+	}
+	if len(dates) == 0 {
+		return metadata, dates, nil
+	}
+
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(raw); err != nil {
+		return "", nil, err
+	}
+
+	return buf.String(), dates, nil
+}
+
+// parseDates sets the dates written as a string with the dateInputFormats of config. A date which
+// can't be parsed is left unset with a warning
+func (n *Node) parseDates(dates map[string]string, loc *time.Location) {
+	for key, s := range dates {
+		t, err := n.site.Config.ParseDate(s, loc)
+		if err != nil {
+			n.Logger().Warn().Err(err).Str("key", key).Msg("Invalid date")
+			if n.site.Diagnostics != nil {
+				n.site.Diagnostics.AddWarning(n.Path, key+": "+err.Error())
+			}
+			continue
+		}
+
+		switch key {
+		case "date":
+			n.Meta.Date = t
+		case "lastmod":
+			n.Meta.Lastmod = t
+		}
+	}
+}
+
+// inZone moves t into loc. A date written without offset, raw as decoded into the front matter map
+// is in the local zone of the build machine, it's read again as the same wall clock in loc. The
+// decoder gives the local zone to an offset equal to the one of the machine too, those can't be
+// told apart
+func inZone(t time.Time, raw interface{}, loc *time.Location) time.Time {
+	if t.IsZero() {
+		return t
+	}
+	if local, ok := raw.(time.Time); ok && local.Location() == time.Local {
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), loc)
+	}
+
+	return t.In(loc)
+}
+
+// applyDefaults decodes the config defaults matching the node into its metadata, before its own
+// front matter is decoded over them
+func (n *Node) applyDefaults() error {
+	if n.site == nil || n.site.Config == nil || len(n.site.Config.Defaults) == 0 {
+		return nil
+	}
+
+	rel := strings.TrimPrefix(n.BaseDirectory+"/"+filepath.Base(n.source()), "/")
+	defaults := n.site.Config.FrontMatterDefaults(rel)
+	if len(defaults) == 0 {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(defaults); err != nil {
+		return fmt.Errorf("invalid defaults: %w", err)
+	}
+	if _, err := toml.Decode(buf.String(), n.Meta); err != nil {
+		return fmt.Errorf("invalid defaults: %w", err)
+	}
+	toml.Decode(buf.String(), &n.frontMatter)
+
+	return nil
+}
+
+// frontMatterValue returns the value of a front matter key, matched case insensitively like the
+// fields of NodeMeta
+func (n *Node) frontMatterValue(key string) interface{} {
+	for k, v := range n.frontMatter {
+		if strings.EqualFold(k, key) {
+			return v
+		}
+	}
+
+	return nil
+}
+
+// IsHTML returns true when node body is already html and doesn't need markdown rendering
+func (n *Node) IsHTML() bool {
+	return filepath.Ext(n.Path) == ".html"
 }
 
 func (n *Node) IsPage() bool {
 	return n.Meta.Type == NodeTypePage
 }
 
+// IsPublished reports whether n is out at now: neither a draft nor dated after now. Only a
+// preview build has the others
+func (n *Node) IsPublished(now time.Time) bool {
+	return !n.Meta.Draft && !n.Meta.Date.After(now)
+}
+
+// Permalink is the path of the node page, made from the permalink pattern of its section in config
+// when there's one. It's safe as a directory of public, see baja.SafePath
 func (n *Node) Permalink() string {
+	return baja.SafePath(n.rawPermalink(), n.outputPaths())
+}
+
+// outputPaths returns the config outputPaths mode of file names in public
+func (n *Node) outputPaths() string {
+	if n.site != nil && n.site.Config != nil && n.site.Config.OutputPaths != "" {
+		return n.site.Config.OutputPaths
+	}
+
+	return baja.OutputPathsUnicode
+}
+
+// rawPermalink is Permalink with the name of the node as is
+func (n *Node) rawPermalink() string {
+	if n.site != nil && n.site.Config != nil {
+		if permalink := n.site.Permalink(n.BaseDirectory); permalink != nil {
+			return permalink(baja.PermalinkParts{Section: n.BaseDirectory, Slug: filepath.Base(n.Name), Title: n.Meta.Title, Date: n.Meta.Date})
+		}
+	}
+
 	if n.BaseDirectory == "" {
 		return "/" + filepath.Base(n.Name) + "/"
 	} else {
@@ -99,30 +335,69 @@ func (n *Node) Permalink() string {
 	}
 }
 
-func (n *Node) data() map[string]interface{} {
-	html := blackfriday.Run([]byte(n.Body))
+// HTML returns the rendered body of the node, sanitized with the policy of its section
+func (n *Node) HTML() template.HTML {
+	// a render error is reported by Compile, the page is never written
+	html, _ := n.markdown()
+
+	return template.HTML(n.markup().Sanitize(n.BaseDirectory, html))
+}
 
+func (n *Node) data() map[string]interface{} {
 	return map[string]interface{}{
-		"Meta":      n.Meta,
-		"Body":      template.HTML(html),
-		"Permalink": n.Permalink(),
+		"Meta":         n.Meta,
+		"Params":       n.Meta.Params,
+		"Body":         n.HTML(),
+		"HasContent":   n.HasContent(),
+		"Summary":      n.Summary(),
+		"Truncated":    n.Truncated(),
+		"WordCount":    n.WordCount(),
+		"ReadingTime":  n.ReadingTime(),
+		"Permalink":    n.Permalink(),
+		"Site":         n.site,
+		"JSONLD":       n.JSONLD(),
+		"OpenGraph":    n.OpenGraph(),
+		"Authors":      n.Authors(),
+		"FirstImage":   n.FirstImage(),
+		"Canonical":    n.Canonical(),
+		"HasFootnotes": n.HasFootnotes(),
+		"NoIndex":      n.Meta.NoIndex,
+		"Series":       n.Series(),
+		"SeriesIndex":  n.SeriesIndex(),
+		"SeriesPrev":   n.SeriesPrev(),
+		"SeriesNext":   n.SeriesNext(),
+		"Related":      n.Related(),
+		"Resources":    n.BundleResources(),
 	}
 }
 
+// Logger returns the global logger with the path and section of the node attached to every line
+func (n *Node) Logger() *zerolog.Logger {
+	logger := log.With().Str("path", n.Path).Str("section", n.BaseDirectory).Logger()
+	return &logger
+}
+
+// DefaultSingle is the template of the theme a node is rendered with when no node.html applies
+// to it and it names no theme
+const DefaultSingle = "_default/single.html"
+
+// ErrNoNodeTemplate is returned by Render for a node the theme has no template for
+var ErrNoNodeTemplate = errors.New("no node template")
+
 func (n *Node) FindTheme(site *baja.Site) {
-	theme := site.Theme
-	c := site.Config
+	theme := n.theme()
 
 	pathComponents := strings.Split(n.BaseDirectory, "/")
-	n.templatePaths = []string{"themes/" + c.Theme + "/layout/default.html"}
-	lookupPath := "themes/" + c.Theme
+	n.templatePaths = []string{theme.LayoutPath("default")}
+	n.templateMiss = []string{}
+	lookupPath := strings.TrimSuffix(theme.Path(), "/")
 	for _, p := range pathComponents {
-		if _, err := os.Stat(lookupPath + "/node.html"); err == nil {
-			n.templatePaths = append(n.templatePaths, lookupPath+"/node.html")
-		}
-
-		if _, err := os.Stat(lookupPath + "/" + n.Name + ".html"); err == nil {
-			n.templatePaths = append(n.templatePaths, lookupPath+"/"+n.Name+".html")
+		for _, candidate := range []string{lookupPath + "/node.html", lookupPath + "/" + n.Name + ".html"} {
+			if theme.Exists(candidate) {
+				n.templatePaths = append(n.templatePaths, candidate)
+			} else {
+				n.templateMiss = append(n.templateMiss, candidate)
+			}
 		}
 
 		lookupPath = lookupPath + "/" + p
@@ -131,27 +406,151 @@ func (n *Node) FindTheme(site *baja.Site) {
 	if n.Meta.Theme != "" {
 		n.templatePaths = append(n.templatePaths, theme.NodePath(n.Meta.Theme))
 	}
+
+	// the layout alone doesn't know how to render a page
+	if len(n.templatePaths) == 1 {
+		if single := theme.SubPath(DefaultSingle); theme.Exists(single) {
+			n.templatePaths = append(n.templatePaths, single)
+		} else {
+			n.templateMiss = append(n.templateMiss, single)
+		}
+	}
 }
 
-func (n *Node) Compile() {
-	directory := "public/" + n.BaseDirectory + "/" + n.Name
-	os.MkdirAll(directory, os.ModePerm)
-	f, err := os.Create(directory + "/index.html")
-	if err != nil {
-		log.Error().Err(err).Str("Directory", directory).Msg("Cannot create index file in directory")
+// theme returns the theme of the site, loaded from config for a site without one
+func (n *Node) theme() *baja.Theme {
+	if n.site.Theme == nil {
+		return baja.NewThemeFromConfig(n.site.Config)
 	}
 
-	w := bufio.NewWriter(f)
+	return n.site.Theme
+}
+
+// Compile renders the node into its directory in public
+func (n *Node) Compile() error {
+	directory := filepath.Join(n.site.OutputDir(), filepath.FromSlash(n.Permalink()))
+	path := filepath.Join(directory, "index.html")
 
-	tpl := template.New("layout").Funcs(baja.FuncMaps())
-	tpl, err = tpl.ParseFiles(n.templatePaths...)
+	// the page is kept to find the bundle resources it references
+	var page bytes.Buffer
+	if err := n.Render(&page); err != nil {
+		return err
+	}
+
+	if err := utils.WriteFileAtomic(path, bytes.NewReader(page.Bytes()), 0644); err != nil {
+		return fmt.Errorf("cannot write index file in %s: %w", directory, err)
+	}
+	n.site.Outputs.Add(path)
+
+	n.compileAliases()
+	n.compileResources(directory, page.Bytes())
+	return nil
+}
+
+// Render executes the node templates found by FindTheme into w
+func (n *Node) Render(w io.Writer) error {
+	if _, err := n.markdown(); err != nil {
+		return err
+	}
+	if len(n.templatePaths) == 1 {
+		return fmt.Errorf("%w for %s, searched %s", ErrNoNodeTemplate, n.Path, strings.Join(n.templateMiss, ", "))
+	}
+
+	tpl, err := n.theme().ParseFiles(template.New("layout").Funcs(baja.FuncMaps(n.site)), n.templatePaths...)
 	if err != nil {
-		log.Panic().Err(err)
+		return fmt.Errorf("cannot parse template: %w", err)
 	}
 
 	if err := tpl.Execute(w, n.data()); err != nil {
-		log.Panic().Str("Node", n.Name).Err(err).Msg("Fail to render node")
+		return fmt.Errorf("fail to render node: %w", err)
+	}
+
+	return nil
+}
+
+// compileAliases writes a redirect page at each alias of the node so old links keep working. An
+// alias outside public or at its root is an error of the node, see aliasPath
+func (n *Node) compileAliases() {
+	permalink := html.EscapeString(n.Permalink())
+	logger := n.Logger()
+
+	for _, alias := range n.Meta.Aliases {
+		rel, err := aliasPath(alias, n.outputPaths())
+		if err != nil {
+			n.site.Diagnostics.AddError(n.Path, err)
+			continue
+		}
+
+		directory := filepath.Join(n.site.OutputDir(), filepath.FromSlash(rel))
+		if err := utils.EnsureDir(directory, utils.DefaultDirMode); err != nil {
+			logger.Error().Err(err).Str("alias", alias).Msg("Cannot create alias directory")
+			continue
+		}
+
+		page := fmt.Sprintf(aliasTemplate, permalink, permalink, permalink, permalink)
+		if err := utils.WriteFileAtomic(filepath.Join(directory, "index.html"), strings.NewReader(page), 0644); err != nil {
+			logger.Error().Err(err).Str("alias", alias).Msg("Cannot write alias page")
+			continue
+		}
+		n.site.Outputs.Add(filepath.Join(directory, "index.html"))
+	}
+}
+
+// aliasPath returns the directory of public alias is written in, its segments made safe like
+// permalinks in mode. An alias with a . or .. segment, which could reach outside public, or of
+// the site root, whose page is the home page, is an error
+func aliasPath(alias, mode string) (string, error) {
+	rel := strings.Trim(alias, "/")
+	if rel == "" {
+		return "", fmt.Errorf("invalid alias %q: it's the home page", alias)
+	}
+	for _, segment := range strings.Split(strings.Replace(rel, `\`, "/", -1), "/") {
+		if segment == "." || segment == ".." {
+			return "", fmt.Errorf("invalid alias %q: . and .. segments are not allowed", alias)
+		}
+	}
+
+	return baja.SafePath(rel, mode), nil
+}
+
+const aliasTemplate = `<!DOCTYPE html>
+<html>
+<head>
+<title>%s</title>
+<link rel="canonical" href="%s">
+<meta http-equiv="refresh" content="0; url=%s">
+</head>
+<body><a href="%s">Moved</a></body>
+</html>
+`
+
+// Terms returns the terms of the node in a taxonomy. tags and categories are NodeMeta fields, other
+// taxonomies are read from the front matter key of the same name, a string or a list of strings
+func (n *Node) Terms(key string) []string {
+	switch key {
+	case "tags":
+		return n.Meta.Tags
+	case "categories":
+		return n.Meta.Categories
+	case SeriesKey:
+		if n.Meta.Series == "" {
+			return nil
+		}
+		return []string{n.Meta.Series}
+	}
+
+	switch v := n.frontMatter[key].(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		terms := []string{}
+		for _, term := range v {
+			if s, ok := term.(string); ok {
+				terms = append(terms, s)
+			}
+		}
+		return terms
 	}
 
-	w.Flush()
+	return nil
 }
@@ -4,7 +4,7 @@ import (
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
-	. "github.com/yeo/baja"
+	. "github.com/yeo/baja/node"
 )
 
 var _ = Describe("Baja", func() {
@@ -0,0 +1,44 @@
+package node
+
+import (
+	"html"
+	"html/template"
+	"strings"
+)
+
+// OpenGraph returns the Open Graph meta tags of the node for link previews, with twitter:site from
+// config social. Tags without a value are left out
+func (n *Node) OpenGraph() template.HTML {
+	if n.site == nil || n.Meta == nil {
+		return ""
+	}
+
+	ogType := "article"
+	if n.IsPage() {
+		ogType = "website"
+	}
+
+	tags := [][2]string{
+		{"og:title", n.Meta.Title},
+		{"og:type", ogType},
+		{"og:url", n.Canonical()},
+		{"og:description", n.Meta.Description},
+		{"og:image", n.Image()},
+		{"og:site_name", n.site.Title()},
+	}
+
+	var b strings.Builder
+	for _, tag := range tags {
+		if tag[1] == "" {
+			continue
+		}
+		b.WriteString(`<meta property="` + tag[0] + `" content="` + html.EscapeString(tag[1]) + `">` + "\n")
+	}
+
+	// Twitter cards read name, not property
+	if handle := n.site.Config.TwitterSite(); handle != "" {
+		b.WriteString(`<meta name="twitter:site" content="` + html.EscapeString(handle) + `">` + "\n")
+	}
+
+	return template.HTML(b.String())
+}
@@ -0,0 +1,41 @@
+package node_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/yeo/baja"
+)
+
+var _ = Describe("OpenGraph", func() {
+	var site *baja.Site
+
+	BeforeEach(func() {
+		site = &baja.Site{Config: &baja.Config{BaseURL: "https://example.com/", Title: "Notes", DefaultImage: "/img/default.png"}}
+	})
+
+	It("describes a post with the site default image", func() {
+		n := parseNode(site, "content/post/hello.md", "+++\ntitle = \"Hello & bye\"\n+++\nbody")
+
+		og := string(n.OpenGraph())
+		Expect(og).To(ContainSubstring(`<meta property="og:title" content="Hello &amp; bye">`))
+		Expect(og).To(ContainSubstring(`<meta property="og:type" content="article">`))
+		Expect(og).To(ContainSubstring(`<meta property="og:url" content="https://example.com/post/hello/">`))
+		Expect(og).To(ContainSubstring(`<meta property="og:image" content="https://example.com/img/default.png">`))
+		Expect(og).To(ContainSubstring(`<meta property="og:site_name" content="Notes">`))
+		Expect(og).ToNot(ContainSubstring("og:description"))
+	})
+
+	It("names the twitter account of config social", func() {
+		site.Config.Social = []baja.SocialLink{{Name: "GitHub", URL: "https://github.com/yeo"}, {Name: "X", URL: "https://x.com/yeo"}}
+		n := parseNode(site, "content/post/hello.md", "+++\ntitle = \"Hello\"\n+++\nbody")
+
+		Expect(string(n.OpenGraph())).To(ContainSubstring(`<meta name="twitter:site" content="@yeo">`))
+	})
+
+	It("prefers the node image", func() {
+		n := parseNode(site, "content/post/cover.md", "+++\ntitle = \"Cover\"\n[params]\nimage = \"/img/cover.png\"\n+++\nbody")
+
+		Expect(string(n.OpenGraph())).To(ContainSubstring(`<meta property="og:image" content="https://example.com/img/cover.png">`))
+	})
+})
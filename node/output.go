@@ -0,0 +1,87 @@
+package node
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/yeo/baja"
+	"github.com/yeo/baja/utils"
+)
+
+// CompileFormat writes the file of the node in custom output format name of config
+// outputFormats, eg: public/events/launch/index.ics. It's templated with node.<suffix> of the
+// theme, the one of the closest directory of the node wins, eg: events/node.ics gives the format
+// to the nodes of events only. Without a template the node has no such file. It's a text
+// template, the file isn't html and nothing is escaped
+func (n *Node) CompileFormat(name string) error {
+	format, ok := n.site.Config.CustomFormat(name)
+	if !ok {
+		return fmt.Errorf("unknown output format %s", name)
+	}
+
+	path := n.formatTemplate(format.Suffix)
+	if path == "" {
+		n.Logger().Debug().Str("format", name).Msg("No template of output format")
+		return nil
+	}
+
+	theme := n.theme()
+	source, err := theme.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	tpl, err := template.New(filepath.Base(path)).Funcs(template.FuncMap(baja.FuncMaps(n.site))).Parse(string(source))
+	if err != nil {
+		return fmt.Errorf("cannot parse %s: %w", path, err)
+	}
+
+	data := map[string]interface{}{
+		"Meta":      n.Meta,
+		"Params":    n.Meta.Params,
+		"Raw":       string(n.Body),
+		"Permalink": n.Permalink(),
+		"Site":      n.site,
+	}
+	if format.Markdown {
+		if _, err := n.markdown(); err != nil {
+			return err
+		}
+		data = n.data()
+		data["Raw"] = string(n.Body)
+	}
+
+	var out bytes.Buffer
+	if err := tpl.Execute(&out, data); err != nil {
+		return fmt.Errorf("cannot render %s: %w", path, err)
+	}
+
+	file := filepath.Join(n.site.OutputDir(), filepath.FromSlash(n.Permalink()), format.FileName())
+	if err := utils.WriteFileAtomic(file, &out, 0644); err != nil {
+		return fmt.Errorf("cannot write %s: %w", file, err)
+	}
+	n.site.Outputs.Add(file)
+
+	return nil
+}
+
+// formatTemplate returns the node.<suffix> template of the theme closest to the node, empty when
+// there is none
+func (n *Node) formatTemplate(suffix string) string {
+	theme := n.theme()
+
+	found := ""
+	lookupPath := strings.TrimSuffix(theme.Path(), "/")
+	for _, p := range append([]string{""}, strings.Split(n.BaseDirectory, "/")...) {
+		if p != "" {
+			lookupPath += "/" + p
+		}
+		if path := lookupPath + "/node." + suffix; theme.Exists(path) {
+			found = path
+		}
+	}
+
+	return found
+}
@@ -0,0 +1,99 @@
+package node
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/yeo/baja"
+)
+
+// Paginator is the page of a listing being rendered, .Paginator of index templates. A listing
+// with no pagination is one page
+type Paginator struct {
+	PageNumber int // from 1
+	TotalPages int
+	PageSize   int // nodes per page, 0 when the listing isn't paginated
+	TotalNodes int
+
+	// permalinks of pages, Prev and Next are empty on the first and last page
+	First string
+	Prev  string
+	Next  string
+	Last  string
+
+	// absolute urls of this page and its neighbours, for the canonical, prev and next links of
+	// the page head. Every page is its own canonical, they list different nodes
+	Canonical string
+	PrevURL   string
+	NextURL   string
+
+	// NoIndex is set after the first page with config paginateNoIndex, for a robots noindex meta
+	NoIndex bool
+}
+
+// HasPrev is true on every page but the first
+func (p *Paginator) HasPrev() bool {
+	return p.Prev != ""
+}
+
+// HasNext is true on every page but the last
+func (p *Paginator) HasNext() bool {
+	return p.Next != ""
+}
+
+// paginate splits nodes of the listing at dir into pages of the paginate size of config, one
+// page when it's 0
+func paginate(config *baja.Config, dir string, nodes []*Node) ([]*Paginator, [][]*Node) {
+	size := config.PaginateSize(dir)
+	total := 1
+	if size > 0 && len(nodes) > size {
+		total = (len(nodes) + size - 1) / size
+	}
+
+	pagers := make([]*Paginator, total)
+	pages := make([][]*Node, total)
+	for i := range pagers {
+		pagers[i] = &Paginator{
+			PageNumber: i + 1,
+			TotalPages: total,
+			PageSize:   size,
+			TotalNodes: len(nodes),
+			First:      pagePermalink(dir, 1),
+			Last:       pagePermalink(dir, total),
+		}
+		pagers[i].Canonical = config.AbsURL(pagePermalink(dir, i+1))
+		if i > 0 {
+			pagers[i].Prev = pagePermalink(dir, i)
+			pagers[i].PrevURL = config.AbsURL(pagers[i].Prev)
+			pagers[i].NoIndex = config.PaginateNoIndex
+		}
+		if i < total-1 {
+			pagers[i].Next = pagePermalink(dir, i+2)
+			pagers[i].NextURL = config.AbsURL(pagers[i].Next)
+		}
+
+		pages[i] = nodes
+		if total > 1 {
+			end := (i + 1) * size
+			if end > len(nodes) {
+				end = len(nodes)
+			}
+			pages[i] = nodes[i*size : end]
+		}
+	}
+
+	return pagers, pages
+}
+
+// pagePermalink returns the permalink of page number of the listing at dir, eg: /blog/page/2/.
+// The first page is the listing itself
+func pagePermalink(dir string, number int) string {
+	if number > 1 {
+		dir = path.Join(dir, "page", fmt.Sprint(number))
+	}
+	if dir == "" {
+		return "/"
+	}
+
+	return "/" + dir + "/"
+}
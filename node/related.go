@@ -0,0 +1,117 @@
+package node
+
+import (
+	"sort"
+
+	"github.com/yeo/baja"
+)
+
+// linkRelated gives each listed node its related nodes, see Related. It runs once the walk is
+// done, related nodes can be anywhere in content
+func (db *NodeDB) linkRelated() {
+	count, order := baja.DefaultRelatedPosts, []string{baja.RelatedByTags, baja.RelatedBySection}
+	if db.Site != nil && db.Site.Config != nil {
+		count, order = db.Site.Config.RelatedPostsCount(), db.Site.Config.RelatedOrder()
+	}
+
+	nodes := db.Publishable()
+	for _, n := range nodes {
+		n.related = []*Node{}
+		seen := map[*Node]bool{n: true}
+		for _, by := range order {
+			var candidates []*Node
+			switch by {
+			case baja.RelatedByTags:
+				candidates = relatedByTags(n, nodes)
+			case baja.RelatedBySection:
+				candidates = relatedBySection(n, nodes)
+			}
+
+			for _, c := range candidates {
+				if len(n.related) == count {
+					break
+				}
+				if !seen[c] {
+					seen[c] = true
+					n.related = append(n.related, c)
+				}
+			}
+		}
+	}
+}
+
+// relatedByTags returns the nodes sharing tags with n, the most shared first, then the newest
+func relatedByTags(n *Node, nodes []*Node) []*Node {
+	tags := map[string]bool{}
+	for _, tag := range n.Meta.Tags {
+		tags[tag] = true
+	}
+
+	shared := map[*Node]int{}
+	related := []*Node{}
+	for _, other := range nodes {
+		for _, tag := range other.Meta.Tags {
+			if tags[tag] {
+				shared[other]++
+			}
+		}
+		if shared[other] > 0 && other != n {
+			related = append(related, other)
+		}
+	}
+
+	sort.SliceStable(related, func(i, j int) bool {
+		a, b := related[i], related[j]
+		if shared[a] != shared[b] {
+			return shared[a] > shared[b]
+		}
+		if !a.Meta.Date.Equal(b.Meta.Date) {
+			return a.Meta.Date.After(b.Meta.Date)
+		}
+		return a.Path < b.Path
+	})
+
+	return related
+}
+
+// relatedBySection returns the other nodes of the section of n, the closest by date first and the
+// undated ones last. For an undated n they are the newest first
+func relatedBySection(n *Node, nodes []*Node) []*Node {
+	related := []*Node{}
+	for _, other := range nodes {
+		if other != n && other.BaseDirectory == n.BaseDirectory {
+			related = append(related, other)
+		}
+	}
+
+	// in seconds, a time.Duration overflows between a date and the zero time
+	distance := func(other *Node) int64 {
+		d := other.Meta.Date.Unix() - n.Meta.Date.Unix()
+		if d < 0 {
+			return -d
+		}
+		return d
+	}
+	sort.SliceStable(related, func(i, j int) bool {
+		a, b := related[i], related[j]
+		if a.Meta.Date.IsZero() != b.Meta.Date.IsZero() {
+			return b.Meta.Date.IsZero()
+		}
+		if !n.Meta.Date.IsZero() && distance(a) != distance(b) {
+			return distance(a) < distance(b)
+		}
+		if !a.Meta.Date.Equal(b.Meta.Date) {
+			return a.Meta.Date.After(b.Meta.Date)
+		}
+		return a.Path < b.Path
+	})
+
+	return related
+}
+
+// Related returns the nodes a page of n can suggest next, config relatedPosts of them filled by
+// config relatedBy: the ones sharing tags with n, then the others of its section closest by
+// date. Empty for a node which isn't listed
+func (n *Node) Related() []*Node {
+	return n.related
+}
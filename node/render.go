@@ -0,0 +1,73 @@
+package node
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fatih/color"
+
+	"github.com/yeo/baja"
+)
+
+// RenderCommand renders a single node to stdout, to debug a template without a full build
+type RenderCommand struct{}
+
+func (cmd *RenderCommand) ArgDesc() string {
+	return "content-path"
+}
+
+func (cmd *RenderCommand) Help() string {
+	return "Render one node to stdout with its theme templates. Nothing is written to public directory"
+}
+
+func (cmd *RenderCommand) Run(site *baja.Site, args []string) int {
+	// keep stdout for the rendered html only
+	color.Output = os.Stderr
+
+	if site == nil {
+		color.Red("Cannot find baja.yaml. Run baja init to create a new site")
+		return baja.ExitConfigError
+	}
+
+	if len(args) < 1 {
+		color.Red("Usage: baja render content/path/to/node.md")
+		return 1
+	}
+
+	path := ContentPath(args[0])
+	if _, err := os.Stat(path); err != nil {
+		color.Red("Cannot find %s: %v", path, err)
+		return 1
+	}
+
+	n, err := NewNode(site, path)
+	if err != nil {
+		color.Red("%s: %v", path, err)
+		return baja.ExitContentError
+	}
+
+	color.Yellow("Templates: %s", strings.Join(n.templatePaths, ", "))
+
+	// render fully before writing so a template error doesn't print half a page
+	var out bytes.Buffer
+	if err := n.Render(&out); err != nil {
+		color.Red("%s: %v", path, err)
+		return baja.ExitContentError
+	}
+
+	os.Stdout.Write(out.Bytes())
+	return baja.ExitOK
+}
+
+// ContentPath returns path relative to the site root, in the form NewNode expects: content/post/a.md.
+// path can be relative to content directory or to the site root
+func ContentPath(path string) string {
+	path = filepath.ToSlash(filepath.Clean(path))
+	if strings.HasPrefix(path, "content/") {
+		return path
+	}
+
+	return "content/" + strings.TrimPrefix(path, "/")
+}
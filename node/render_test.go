@@ -0,0 +1,87 @@
+package node_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/yeo/baja"
+	. "github.com/yeo/baja/node"
+)
+
+var _ = Describe("Render", func() {
+	var (
+		cwd string
+		dir string
+	)
+
+	write := func(name, content string) {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), os.ModePerm)
+		Expect(ioutil.WriteFile(path, []byte(content), 0644)).To(Succeed())
+	}
+
+	render := func() (string, error) {
+		site, err := baja.LoadSite("baja.yaml", "")
+		Expect(err).ToNot(HaveOccurred())
+
+		n, err := NewNode(site, ContentPath("post/one.md"))
+		Expect(err).ToNot(HaveOccurred())
+
+		var out bytes.Buffer
+		err = n.Render(&out)
+		return out.String(), err
+	}
+
+	BeforeEach(func() {
+		cwd, _ = os.Getwd()
+		dir, _ = ioutil.TempDir("", "baja-render")
+		Expect(os.Chdir(dir)).To(Succeed())
+
+		write("baja.yaml", "theme: t\n")
+		write("themes/t/layout/default.html", `{{ define "layout" }}<main>{{ template "content" . }}</main>{{ end }}`)
+		write("themes/t/node.html", `{{ define "content" }}<h1>{{ .Meta.Title }}</h1>{{ end }}`)
+		write("content/post/one.md", "+++\ntitle = \"One\"\n+++\nbody")
+	})
+
+	AfterEach(func() {
+		os.Chdir(cwd)
+		os.RemoveAll(dir)
+	})
+
+	It("renders a node with its theme templates", func() {
+		Expect(render()).To(Equal("<main><h1>One</h1></main>"))
+	})
+
+	It("renders footnotes prefixed by the node slug with back references", func() {
+		write("baja.yaml", "theme: t\nmarkup:\n  extensions: [footnotes]\n")
+		write("themes/t/node.html", `{{ define "content" }}{{ if .HasFootnotes }}notes{{ end }}{{ .Body }}{{ end }}`)
+		write("content/post/one.md", "+++\ntitle = \"One\"\n+++\nbody[^a]\n\n[^a]: a note\n")
+
+		out, err := render()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(out).To(HavePrefix("<main>notes"))
+		Expect(out).To(ContainSubstring(`id="fnref:post-one-a"`))
+		Expect(out).To(ContainSubstring(`id="fn:post-one-a"`))
+		Expect(out).To(ContainSubstring(`href="#fnref:post-one-a"`))
+	})
+
+	It("returns template errors", func() {
+		write("themes/t/node.html", `{{ define "content" }}{{ .Meta.Nope }}{{ end }}`)
+
+		_, err := render()
+		Expect(err).To(MatchError(ContainSubstring("fail to render node")))
+	})
+})
+
+var _ = Describe("ContentPath", func() {
+	It("accepts paths relative to site root or content directory", func() {
+		Expect(ContentPath("content/post/a.md")).To(Equal("content/post/a.md"))
+		Expect(ContentPath("post/a.md")).To(Equal("content/post/a.md"))
+		Expect(ContentPath("./post/../post/a.md")).To(Equal("content/post/a.md"))
+	})
+})
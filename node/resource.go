@@ -0,0 +1,89 @@
+package node
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"io/ioutil"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/yeo/baja/imaging"
+	"github.com/yeo/baja/utils"
+)
+
+// Resize returns the image resource scaled to width pixels wide, eg: {{ (.Resize 800).RelPermalink }}.
+// It's written next to the page as the name of the resource suffixed with its width, eg:
+// beach_800w.jpg
+func (r *BundleResource) Resize(width int) (*BundleResource, error) {
+	if width < 1 {
+		return nil, fmt.Errorf("cannot resize %s: width %d must be over 0", r.path, width)
+	}
+
+	return r.process(fmt.Sprintf("resize %d", width), fmt.Sprintf("%dw", width), func(img image.Image) image.Image {
+		return imaging.Resize(img, width)
+	})
+}
+
+// Fill returns the image resource scaled to cover width by height pixels and cropped around its
+// center, written next to the page as eg: beach_800x600.jpg
+func (r *BundleResource) Fill(width, height int) (*BundleResource, error) {
+	if width < 1 || height < 1 {
+		return nil, fmt.Errorf("cannot fill %s: %dx%d must be over 0", r.path, width, height)
+	}
+
+	return r.process(fmt.Sprintf("fill %dx%d", width, height), fmt.Sprintf("%dx%d", width, height), func(img image.Image) image.Image {
+		return imaging.Fill(img, width, height)
+	})
+}
+
+// process derives an image from the resource with fn, operation op, into the output directory of
+// the node under the name of the resource with suffix. The derived image is kept in the site
+// cache by the content of the resource and op, an unchanged photo isn't processed again
+func (r *BundleResource) process(op, suffix string, fn func(image.Image) image.Image) (*BundleResource, error) {
+	data, err := ioutil.ReadFile(r.path)
+	if err != nil {
+		return nil, err
+	}
+
+	cache := r.node.site.Cache()
+	key := utils.CacheKey("image", []byte(op), data)
+	derived, ok := cache.Get(key)
+	if !ok {
+		img, format, err := imaging.Decode(r.path, data)
+		if err != nil {
+			return nil, err
+		}
+
+		var b bytes.Buffer
+		if err := imaging.Encode(&b, fn(img), format); err != nil {
+			return nil, fmt.Errorf("cannot encode %s: %w", r.path, err)
+		}
+		derived = b.Bytes()
+
+		if err := cache.Put(key, derived); err != nil {
+			r.node.Logger().Warn().Err(err).Str("resource", r.Name).Msg("Cannot cache image")
+		}
+	}
+
+	ext := path.Ext(r.Name)
+	name := strings.TrimSuffix(r.Name, ext) + "_" + suffix + ext
+	target := filepath.Join(r.node.site.OutputDir(), filepath.FromSlash(r.node.Permalink()), filepath.FromSlash(name))
+	if err := utils.EnsureDir(filepath.Dir(target), utils.DefaultDirMode); err != nil {
+		return nil, err
+	}
+	if err := utils.WriteFileAtomic(target, bytes.NewReader(derived), 0644); err != nil {
+		return nil, fmt.Errorf("cannot write %s: %w", target, err)
+	}
+	r.node.site.Outputs.Add(target)
+
+	return &BundleResource{
+		Name: name,
+		URL:  (&url.URL{Path: name}).String(),
+		Size: int64(len(derived)),
+		node: r.node,
+		path: target,
+	}, nil
+}
@@ -0,0 +1,10 @@
+package node
+
+import (
+	"github.com/yeo/baja"
+)
+
+// SanitizePolicy returns the sanitize policy of a directory under content, see baja.Markup
+func SanitizePolicy(config *baja.Config, dir string) string {
+	return baja.NewMarkup(config).SanitizePolicy(dir)
+}
@@ -0,0 +1,42 @@
+package node_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/yeo/baja"
+	. "github.com/yeo/baja/node"
+)
+
+var _ = Describe("Sanitize", func() {
+	config := &baja.Config{Sanitize: map[string]string{
+		"*":         baja.SanitizeUGC,
+		"posts":     baja.SanitizeOff,
+		"community": baja.SanitizeUGC,
+	}}
+
+	It("resolves the policy of the closest directory", func() {
+		Expect(SanitizePolicy(config, "posts")).To(Equal(baja.SanitizeOff))
+		Expect(SanitizePolicy(config, "posts/2019")).To(Equal(baja.SanitizeOff))
+		Expect(SanitizePolicy(config, "community/guest")).To(Equal(baja.SanitizeUGC))
+		Expect(SanitizePolicy(config, "other")).To(Equal(baja.SanitizeUGC))
+		Expect(SanitizePolicy(&baja.Config{}, "posts")).To(Equal(baja.SanitizeOff))
+	})
+
+	It("sanitizes node html of untrusted sections only", func() {
+		site := &baja.Site{Config: config}
+		body := "+++\ntitle = \"A\"\n+++\nhi <script>alert(1)</script><b>there</b>"
+
+		trusted := parseNode(site, "content/posts/a.md", body)
+		Expect(string(trusted.HTML())).To(ContainSubstring("<script>"))
+
+		untrusted := parseNode(site, "content/community/a.md", body)
+		Expect(string(untrusted.HTML())).ToNot(ContainSubstring("<script>"))
+		Expect(string(untrusted.HTML())).To(ContainSubstring("<b>there</b>"))
+	})
+
+	It("rejects unknown policies", func() {
+		Expect(baja.ValidateSanitize(map[string]string{"posts": "ugc"})).To(Succeed())
+		Expect(baja.ValidateSanitize(map[string]string{"posts": "lax"})).ToNot(Succeed())
+	})
+})
@@ -0,0 +1,58 @@
+package node
+
+import (
+	"html/template"
+	"path"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// SectionFile holds the metadata of the directory it's in instead of being a node
+const SectionFile = "_index.md"
+
+// Section is the metadata of a content directory, passed to its index page as .Section.
+// It's authored in the directory's _index.md or derived from the directory name
+type Section struct {
+	Dir  string // the directory without /content part
+	Meta *NodeMeta
+	Body template.HTML // rendered intro text of _index.md
+}
+
+// NewSection creates a Section from the node parsed from an _index.md
+func NewSection(n *Node) *Section {
+	s := Section{
+		Dir:  n.BaseDirectory,
+		Meta: n.Meta,
+		Body: n.HTML(),
+	}
+
+	if s.Meta.Title == "" {
+		s.Meta.Title = DefaultSection(s.Dir).Meta.Title
+	}
+	if s.Meta.Params == nil {
+		s.Meta.Params = map[string]interface{}{}
+	}
+
+	return &s
+}
+
+// DefaultSection creates the metadata of a directory without _index.md. Its title is made from
+// the directory name, eg: travel-notes becomes Travel notes
+func DefaultSection(dir string) *Section {
+	title := strings.NewReplacer("-", " ", "_", " ").Replace(path.Base(dir))
+	if dir == "" {
+		title = ""
+	} else if title != "" {
+		r, size := utf8.DecodeRuneInString(title)
+		title = string(unicode.ToTitle(r)) + title[size:]
+	}
+
+	return &Section{
+		Dir: dir,
+		Meta: &NodeMeta{
+			Title:  title,
+			Params: map[string]interface{}{},
+		},
+	}
+}
@@ -0,0 +1,41 @@
+package node_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/yeo/baja/node"
+)
+
+var _ = Describe("Section", func() {
+	Describe("DefaultSection", func() {
+		It("derives title from directory name", func() {
+			s := DefaultSection("notes/travel-notes")
+
+			Expect(s.Dir).To(Equal("notes/travel-notes"))
+			Expect(s.Meta.Title).To(Equal("Travel notes"))
+			Expect(s.Meta.Params).ToNot(BeNil())
+		})
+
+		It("capitalizes the first letter of a non ascii directory name", func() {
+			Expect(DefaultSection("élan-vital").Meta.Title).To(Equal("Élan vital"))
+			Expect(DefaultSection("ảnh").Meta.Title).To(Equal("Ảnh"))
+			Expect(DefaultSection("日記").Meta.Title).To(Equal("日記"))
+		})
+
+		It("has no title for home", func() {
+			Expect(DefaultSection("").Meta.Title).To(Equal(""))
+		})
+	})
+
+	Describe("NodeDB.Section", func() {
+		It("returns authored section when _index.md exists", func() {
+			db := &NodeDB{}
+			authored := &Section{Dir: "blog", Meta: &NodeMeta{Title: "My Blog", Description: "Words"}}
+			db.AddSection(authored)
+
+			Expect(db.Section("blog")).To(Equal(authored))
+			Expect(db.Section("photo").Meta.Title).To(Equal("Photo"))
+		})
+	})
+})
@@ -0,0 +1,63 @@
+package node
+
+import (
+	"sort"
+)
+
+// SeriesKey is the taxonomy of NodeMeta.Series, its term pages list the parts of each series
+const SeriesKey = "series"
+
+// linkSeries orders the listed nodes of each series by weight then date and gives each of them
+// its place. It runs once the walk is done, a part can be anywhere in content
+func (db *NodeDB) linkSeries() {
+	for _, nodes := range db.ByTaxonomy(SeriesKey) {
+		sort.SliceStable(nodes, func(i, j int) bool {
+			a, b := nodes[i].Meta, nodes[j].Meta
+			if a.Weight != b.Weight {
+				return a.Weight < b.Weight
+			}
+			if !a.Date.Equal(b.Date) {
+				return a.Date.Before(b.Date)
+			}
+			return nodes[i].Path < nodes[j].Path
+		})
+
+		for i, n := range nodes {
+			n.series = nodes
+			n.seriesIndex = i
+		}
+	}
+}
+
+// Series returns the nodes of the series of n in order, n included. Empty outside a series
+func (n *Node) Series() []*Node {
+	return n.series
+}
+
+// SeriesIndex returns the position of n in its series starting at 1, eg: part 2 of 5. 0 outside
+// a series
+func (n *Node) SeriesIndex() int {
+	if n.series == nil {
+		return 0
+	}
+
+	return n.seriesIndex + 1
+}
+
+// SeriesPrev returns the part of the series before n, nil for the first one
+func (n *Node) SeriesPrev() *Node {
+	if n.series == nil || n.seriesIndex == 0 {
+		return nil
+	}
+
+	return n.series[n.seriesIndex-1]
+}
+
+// SeriesNext returns the part of the series after n, nil for the last one
+func (n *Node) SeriesNext() *Node {
+	if n.seriesIndex+1 >= len(n.series) {
+		return nil
+	}
+
+	return n.series[n.seriesIndex+1]
+}
@@ -0,0 +1,190 @@
+package node
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/yeo/baja"
+)
+
+// ShortcodeDir is the directory of the site shortcodes, a shortcode there overrides the one of
+// the theme with the same name
+const ShortcodeDir = "shortcodes"
+
+// shortcodeRe matches a shortcode tag of a markdown body, eg: {{< youtube dQw4w9WgXcQ >}} or the
+// closing {{< /note >}} of a paired one
+var shortcodeRe = regexp.MustCompile(`\{\{<\s*(/?)\s*([A-Za-z0-9_-]+)([^\n]*?)\s*>\}\}`)
+
+// shortcodeArgRe matches an argument of a shortcode: a name=value pair or a value, either quoted
+var shortcodeArgRe = regexp.MustCompile(`(?:([A-Za-z0-9_-]+)=)?(?:"((?:[^"\\]|\\.)*)"|(\S+))`)
+
+// Shortcode is the data a shortcode template executes with
+type Shortcode struct {
+	Name   string
+	Params []string          // positional arguments, eg: dQw4w9WgXcQ of {{< youtube dQw4w9WgXcQ >}}
+	Named  map[string]string // name=value arguments, eg: type of {{< note type="warning" >}}
+	Inner  template.HTML     // markdown between a paired shortcode and its closing tag, expanded
+	Page   *Node             // the node the shortcode is in
+	Site   *baja.Site
+}
+
+// Get returns the positional argument at index key when it's an int, else the named argument
+// key. Empty when there is none
+func (s *Shortcode) Get(key interface{}) string {
+	switch k := key.(type) {
+	case int:
+		if k >= 0 && k < len(s.Params) {
+			return s.Params[k]
+		}
+	case string:
+		return s.Named[k]
+	}
+
+	return ""
+}
+
+// shortcodeTag is a shortcode tag found in a body, start and end are its byte offsets
+type shortcodeTag struct {
+	name       string
+	args       string
+	closing    bool
+	start, end int
+}
+
+// expandShortcodes replaces each shortcode of body with its template executed:
+// shortcodes/<name>.html of the site, else of the theme. A paired shortcode gets the markdown up
+// to its closing tag as Inner, shortcodes in it expanded first. include is left for
+// baja.ExpandIncludes. An unknown shortcode or a closing tag without its opening one is an error
+// naming the node and the line
+func (n *Node) expandShortcodes(body []byte) ([]byte, error) {
+	var b strings.Builder
+	if err := n.expandShortcodesAt(&b, string(body), 0); err != nil {
+		return nil, err
+	}
+
+	return []byte(b.String()), nil
+}
+
+// expandShortcodesAt expands the shortcodes of text, which starts at byte offset of the body,
+// into b
+func (n *Node) expandShortcodesAt(b *strings.Builder, text string, offset int) error {
+	tags := []shortcodeTag{}
+	for _, m := range shortcodeRe.FindAllStringSubmatchIndex(text, -1) {
+		tag := shortcodeTag{name: text[m[4]:m[5]], args: text[m[6]:m[7]], closing: m[3] > m[2], start: m[0], end: m[1]}
+		if tag.name != "include" {
+			tags = append(tags, tag)
+		}
+	}
+
+	// a closing tag pairs with the last opening tag of its name, the ones opened after it aren't
+	// paired
+	pairs := map[int]int{}
+	open := []int{}
+	for i, tag := range tags {
+		if !tag.closing {
+			open = append(open, i)
+			continue
+		}
+
+		j := len(open) - 1
+		for j >= 0 && tags[open[j]].name != tag.name {
+			j--
+		}
+		if j < 0 {
+			return fmt.Errorf("%s:%d: closing shortcode %q without its opening one", n.Path, n.bodyLine(offset+tag.start), tag.name)
+		}
+		pairs[open[j]] = i
+		open = open[:j]
+	}
+
+	pos := 0
+	for i := 0; i < len(tags); i++ {
+		tag := tags[i]
+		b.WriteString(text[pos:tag.start])
+
+		var inner strings.Builder
+		pos = tag.end
+		if c, ok := pairs[i]; ok {
+			if err := n.expandShortcodesAt(&inner, text[tag.end:tags[c].start], offset+tag.end); err != nil {
+				return err
+			}
+			pos = tags[c].end
+			i = c
+		}
+
+		html, err := n.renderShortcode(tag, inner.String(), n.bodyLine(offset+tag.start))
+		if err != nil {
+			return err
+		}
+		b.WriteString(html)
+	}
+	b.WriteString(text[pos:])
+
+	return nil
+}
+
+// renderShortcode executes the template of tag, at line of the node, with inner as its Inner
+func (n *Node) renderShortcode(tag shortcodeTag, inner string, line int) (string, error) {
+	tpl, err := n.shortcodeTemplate(tag.name)
+	if errors.Is(err, ErrUnknownShortcode) {
+		return "", fmt.Errorf("%s:%d: %w", n.Path, line, err)
+	}
+	if err != nil {
+		return "", fmt.Errorf("%s:%d: cannot parse shortcode %q: %w", n.Path, line, tag.name, err)
+	}
+
+	data := &Shortcode{Name: tag.name, Params: []string{}, Named: map[string]string{}, Inner: template.HTML(inner), Page: n, Site: n.site}
+	for _, arg := range shortcodeArgRe.FindAllStringSubmatch(tag.args, -1) {
+		value := arg[3]
+		if value == "" {
+			value = strings.NewReplacer(`\"`, `"`, `\\`, `\`).Replace(arg[2])
+		}
+		if arg[1] != "" {
+			data.Named[arg[1]] = value
+		} else {
+			data.Params = append(data.Params, value)
+		}
+	}
+
+	var b bytes.Buffer
+	if err := tpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("%s:%d: cannot render shortcode %q: %w", n.Path, line, tag.name, err)
+	}
+
+	return b.String(), nil
+}
+
+// ErrUnknownShortcode is the error of a shortcode with no template
+var ErrUnknownShortcode = errors.New("unknown shortcode")
+
+// shortcodeTemplate parses the template of shortcode name, of the site, the theme or else built
+// in. A shortcode none of them has is ErrUnknownShortcode naming the files looked for
+func (n *Node) shortcodeTemplate(name string) (*template.Template, error) {
+	theme := n.theme()
+	file := name + ".html"
+	tpl := template.New(file).Funcs(baja.FuncMaps(n.site))
+
+	project := filepath.Join(ShortcodeDir, file)
+	themed := theme.SubPath(ShortcodeDir + "/" + file)
+	for _, path := range []string{project, themed} {
+		if theme.Exists(path) {
+			return theme.ParseFiles(tpl, path)
+		}
+	}
+
+	if builtin, ok := builtinShortcodes[name]; ok {
+		return tpl.Parse(builtin)
+	}
+
+	return nil, fmt.Errorf("%w %q, create %s or %s", ErrUnknownShortcode, name, project, themed)
+}
+
+// bodyLine returns the line in the content file of byte offset of the body
+func (n *Node) bodyLine(offset int) int {
+	return n.bodyStart + strings.Count(string(n.Body)[:offset], "\n")
+}
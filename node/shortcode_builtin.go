@@ -0,0 +1,31 @@
+package node
+
+// builtinShortcodes are the templates of the shortcodes baja ships, by name. A shortcode of the
+// same name of the site or the theme overrides them
+var builtinShortcodes = map[string]string{
+	// {{< figure src="map.png" alt="Map" caption="The route" link="map-large.png" class="wide" >}}
+	"figure": `<figure{{ with .Get "class" }} class="{{ . }}"{{ end }}>
+{{- with .Get "link" }}<a href="{{ . }}">{{ end -}}
+<img src="{{ .Get "src" }}"{{ with .Get "alt" }} alt="{{ . }}"{{ end }}>
+{{- if .Get "link" }}</a>{{ end -}}
+{{ with .Get "caption" }}<figcaption>{{ . }}</figcaption>{{ end }}</figure>`,
+
+	// {{< gist user 0123abcd >}}, or a single file of it: {{< gist user 0123abcd main.go >}}
+	"gist": `<script src="https://gist.github.com/{{ .Get 0 }}/{{ .Get 1 }}.js{{ with .Get 2 }}?file={{ . }}{{ end }}"></script>`,
+
+	// {{< tweet user 1234 >}}, the text of the tweet can be given between {{< tweet >}} and
+	// {{< /tweet >}}
+	"tweet": `<blockquote class="twitter-tweet">
+{{- with .Inner }}<p>{{ . }}</p>{{ end -}}
+<a href="https://twitter.com/{{ .Get 0 }}/status/{{ .Get 1 }}">https://twitter.com/{{ .Get 0 }}/status/{{ .Get 1 }}</a></blockquote>
+{{- if .Site.Config.EmbedScripts }}<script async src="https://platform.twitter.com/widgets.js" charset="utf-8"></script>{{ end }}`,
+
+	// {{< toot https://mastodon.social/@user/1234 >}}
+	"toot": `{{ if .Site.Config.EmbedScripts -}}
+<iframe src="{{ .Get 0 }}/embed" class="mastodon-embed" style="max-width: 100%; border: 0" width="400" allowfullscreen="allowfullscreen"></iframe>
+{{- else -}}
+<blockquote class="mastodon-toot">
+{{- with .Inner }}<p>{{ . }}</p>{{ end -}}
+<a href="{{ .Get 0 }}">{{ .Get 0 }}</a></blockquote>
+{{- end }}`,
+}
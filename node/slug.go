@@ -0,0 +1,74 @@
+package node
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"github.com/yeo/baja"
+	"github.com/yeo/baja/utils"
+)
+
+// resolveSlugs finds the nodes of a section whose names slugify the same, eg: hello.md and
+// Hello.html, or Hello World.md and hello-world.md. The first one by path keeps its name, the
+// others get a numeric suffix such as hello-2. With config duplicateSlugs set to error every
+// one of them is an error of the build instead
+func (db *NodeDB) resolveSlugs() {
+	sections := map[string][]*Node{}
+	for _, n := range db.NodeList {
+		sections[n.BaseDirectory] = append(sections[n.BaseDirectory], n)
+	}
+
+	for _, nodes := range sections {
+		sort.SliceStable(nodes, func(i, j int) bool { return nodes[i].source() < nodes[j].source() })
+
+		reported := map[*Node]bool{}
+		taken := map[string]*Node{}
+		for _, n := range nodes {
+			taken[utils.Slugify(n.Name)] = nil
+		}
+
+		for _, n := range nodes {
+			slug := utils.Slugify(n.Name)
+			first := taken[slug]
+			if first == nil {
+				taken[slug] = n
+				continue
+			}
+
+			if db.Site.Config.DuplicateSlugs == baja.DuplicateSlugsError {
+				err := fmt.Errorf("slug %q of %s is also the slug of %s, rename one of them", slug, n.source(), first.source())
+				n.Logger().Error().Str("other", first.source()).Msg("Duplicate slug")
+				db.Site.Diagnostics.AddError(n.source(), err)
+				if !reported[first] {
+					db.Site.Diagnostics.AddError(first.source(), err)
+					reported[first] = true
+				}
+				continue
+			}
+
+			name := slug
+			for i := 2; ; i++ {
+				name = fmt.Sprintf("%s-%d", slug, i)
+				if _, ok := taken[name]; !ok {
+					break
+				}
+			}
+			taken[name] = n
+
+			message := fmt.Sprintf("slug %q is also the slug of %s, renamed to %s", slug, first.source(), name)
+			n.Logger().Warn().Str("other", first.source()).Str("name", name).Msg("Duplicate slug")
+			db.Site.Diagnostics.AddWarning(n.source(), message)
+			n.Name = name
+		}
+	}
+}
+
+// source is the content file of a node, or the directory of a page bundle
+func (n *Node) source() string {
+	if n.Bundle != "" {
+		return filepath.ToSlash(n.Bundle)
+	}
+
+	return filepath.ToSlash(n.Path)
+}
@@ -0,0 +1,135 @@
+package node
+
+import (
+	"html/template"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/yeo/baja"
+)
+
+// entityRe matches an html entity at the start of text, eg: &amp; or &#8217;
+var entityRe = regexp.MustCompile(`^&(#[0-9]+|#x[0-9a-fA-F]+|[a-zA-Z][a-zA-Z0-9]*);`)
+
+// voidTags are the html elements with no closing tag
+var voidTags = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "link": true, "meta": true, "source": true, "track": true, "wbr": true,
+}
+
+// Summary returns the start of the rendered body, config summaryLength words or runes long.
+// The cut never splits a character or an entity and the tags left open are closed
+func (n *Node) Summary() template.HTML {
+	length, unit := baja.DefaultSummaryLength, baja.SummaryWords
+	if n.site != nil && n.site.Config != nil {
+		length, unit = n.site.Config.SummaryLimit()
+	}
+
+	summary, _ := truncateHTML(string(n.HTML()), length, unit)
+	return template.HTML(summary)
+}
+
+// Truncated is true when Summary is shorter than the body, for a theme to add a read more link
+func (n *Node) Truncated() bool {
+	length, unit := baja.DefaultSummaryLength, baja.SummaryWords
+	if n.site != nil && n.site.Config != nil {
+		length, unit = n.site.Config.SummaryLimit()
+	}
+
+	_, truncated := truncateHTML(string(n.HTML()), length, unit)
+	return truncated
+}
+
+// truncateHTML keeps the first limit words or runes, see baja.SummaryWords, of the text of
+// html. When it cuts, an ellipsis follows the last kept text, the tags after it are dropped and
+// the ones still open are closed
+func truncateHTML(html string, limit int, unit string) (string, bool) {
+	var b strings.Builder
+	open := []string{}
+	count := 0
+	inWord := false
+
+	// where the last kept text ends, and the tags open there
+	kept, keptOpen := 0, []string{}
+
+	pos := 0
+	tags := tagRe.FindAllStringIndex(html, -1)
+	for i := 0; i <= len(tags); i++ {
+		end := len(html)
+		if i < len(tags) {
+			end = tags[i][0]
+		}
+
+		// text up to the next tag, entities and runes taken whole
+		for text := html[pos:end]; text != ""; {
+			size := len(entityRe.FindString(text))
+			r := rune('&')
+			if size == 0 {
+				r, size = utf8.DecodeRuneInString(text)
+			}
+
+			space := unicode.IsSpace(r)
+			if unit == baja.SummaryRunes && !space || unit != baja.SummaryRunes && !space && !inWord {
+				if count == limit {
+					return closeTags(b.String()[:kept]+"…", keptOpen), true
+				}
+				count++
+			}
+			inWord = !space
+
+			b.WriteString(text[:size])
+			text = text[size:]
+			if !space {
+				kept, keptOpen = b.Len(), append(keptOpen[:0], open...)
+			}
+		}
+
+		if i == len(tags) {
+			break
+		}
+		tag := html[tags[i][0]:tags[i][1]]
+		b.WriteString(tag)
+		pos = tags[i][1]
+
+		name := tagName(tag)
+		switch {
+		case name == "", voidTags[name], strings.HasSuffix(tag, "/>"):
+		case strings.HasPrefix(tag, "</"):
+			for j := len(open) - 1; j >= 0; j-- {
+				if open[j] == name {
+					open = open[:j]
+					break
+				}
+			}
+		default:
+			open = append(open, name)
+		}
+	}
+
+	return html, false
+}
+
+// tagName returns the lower case element name of tag, eg: p of <p class="x"> or </p>. It's
+// empty for a comment or a doctype
+func tagName(tag string) string {
+	name := strings.TrimPrefix(strings.TrimPrefix(tag, "<"), "/")
+	if i := strings.IndexFunc(name, func(r rune) bool { return unicode.IsSpace(r) || r == '>' || r == '/' }); i >= 0 {
+		name = name[:i]
+	}
+	if strings.HasPrefix(name, "!") || strings.HasPrefix(name, "?") {
+		return ""
+	}
+
+	return strings.ToLower(name)
+}
+
+// closeTags appends the closing tags of open, innermost first, to html
+func closeTags(html string, open []string) string {
+	for i := len(open) - 1; i >= 0; i-- {
+		html += "</" + open[i] + ">"
+	}
+
+	return html
+}
@@ -0,0 +1,45 @@
+package node_test
+
+import (
+	"html/template"
+	"unicode/utf8"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/yeo/baja"
+)
+
+var _ = Describe("Summary", func() {
+	It("keeps summaryLength words and closes the open tags", func() {
+		site := &baja.Site{Config: &baja.Config{SummaryLength: 4}}
+		n := parseNode(site, "content/post/one.md", "+++\ntitle = \"One\"\n+++\nSome *very important* words here.\n\nNext paragraph.")
+
+		Expect(n.Summary()).To(Equal(template.HTML("<p>Some <em>very important</em> words…</p>")))
+		Expect(n.Truncated()).To(BeTrue())
+	})
+
+	It("keeps a short body whole", func() {
+		site := &baja.Site{Config: &baja.Config{}}
+		n := parseNode(site, "content/post/one.md", "+++\ntitle = \"One\"\n+++\nShort & sweet.")
+
+		Expect(n.Summary()).To(Equal(template.HTML("<p>Short &amp; sweet.</p>\n")))
+		Expect(n.Truncated()).To(BeFalse())
+	})
+
+	It("counts runes of CJK text without cutting a character", func() {
+		site := &baja.Site{Config: &baja.Config{SummaryLength: 5, SummaryUnit: baja.SummaryRunes}}
+		n := parseNode(site, "content/post/ja.md", "+++\ntitle = \"Ja\"\n+++\n日本語の**文章**を要約します。")
+
+		summary := string(n.Summary())
+		Expect(summary).To(Equal("<p>日本語の<strong>文…</strong></p>"))
+		Expect(utf8.ValidString(summary)).To(BeTrue())
+	})
+
+	It("takes an entity as one rune and drops the tags after the cut", func() {
+		site := &baja.Site{Config: &baja.Config{SummaryLength: 3, SummaryUnit: baja.SummaryRunes}}
+		n := parseNode(site, "content/post/one.md", "+++\ntitle = \"One\"\n+++\na&b\n\n![x](x.png)cd")
+
+		Expect(n.Summary()).To(Equal(template.HTML("<p>a&amp;b…</p>")))
+	})
+})
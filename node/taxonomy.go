@@ -0,0 +1,38 @@
+package node
+
+import (
+	"sort"
+)
+
+// Term is a term of a taxonomy, listed on the terms index page
+type Term struct {
+	Name      string
+	Permalink string
+	Count     int // number of listed nodes with the term
+}
+
+// NewTermIndex creates the page of a term, eg: the nodes tagged go at tag/go
+func NewTermIndex(taxonomy, dir string, nodes []*Node) *IndexNode {
+	n := NewIndex(dir, DefaultSection(dir), nodes)
+	n.Current.IsDir = false
+	n.Current.IsTag = true
+	n.Current.Taxonomy = taxonomy
+
+	return n
+}
+
+// NewTermsIndex creates the page at dir listing every term of a taxonomy, sorted by name.
+// Themes can render it with a terms.html template
+func NewTermsIndex(taxonomy, dir string, terms map[string][]*Node) *IndexNode {
+	n := NewIndex(dir, DefaultSection(dir), []*Node{})
+	n.Current.IsDir = false
+	n.Current.IsTerms = true
+	n.Current.Taxonomy = taxonomy
+
+	for name, nodes := range terms {
+		n.Terms = append(n.Terms, &Term{Name: name, Permalink: "/" + dir + "/" + name + "/", Count: len(nodes)})
+	}
+	sort.Slice(n.Terms, func(i, j int) bool { return n.Terms[i].Name < n.Terms[j].Name })
+
+	return n
+}
@@ -0,0 +1,33 @@
+package node_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/yeo/baja"
+)
+
+var _ = Describe("Timezone", func() {
+	site := &baja.Site{Config: &baja.Config{Timezone: "Asia/Bangkok"}}
+
+	It("reads dates without offset in the site zone", func() {
+		n := parseNode(site, "content/post/late.md", "+++\ntitle = \"Late\"\ndate = 2019-02-09T23:00:00\n+++\nbody")
+
+		Expect(n.Meta.DateFormatted).To(Equal("2019 Feb 09"))
+		Expect(n.Meta.Date.UTC()).To(Equal(time.Date(2019, 2, 9, 16, 0, 0, 0, time.UTC)))
+	})
+
+	It("shows dates with an offset in the site zone", func() {
+		n := parseNode(site, "content/post/utc.md", "+++\ntitle = \"UTC\"\ndate = 2019-02-09T20:00:00Z\n+++\nbody")
+
+		Expect(n.Meta.DateFormatted).To(Equal("2019 Feb 10"))
+		Expect(n.Meta.Date.Format(time.RFC3339)).To(Equal("2019-02-10T03:00:00+07:00"))
+	})
+
+	It("rejects unknown zones", func() {
+		Expect(baja.ValidateTimezone("Asia/Bangkok")).To(Succeed())
+		Expect(baja.ValidateTimezone("Mars/Olympus")).To(MatchError(ContainSubstring(`unknown timezone "Mars/Olympus"`)))
+	})
+})
@@ -0,0 +1,32 @@
+package node
+
+import (
+	"regexp"
+	"strings"
+)
+
+// wordsPerMinute is the reading speed used for ReadingTime
+const wordsPerMinute = 200
+
+var tagRe = regexp.MustCompile(`<[^>]*>`)
+
+// HasContent is false for a node whose body is empty or blank, eg: an event page made only of
+// front matter and rendered by its template
+func (n *Node) HasContent() bool {
+	return strings.TrimSpace(string(n.Body)) != ""
+}
+
+// WordCount returns the number of words of the rendered body, markup excluded
+func (n *Node) WordCount() int {
+	return len(strings.Fields(tagRe.ReplaceAllString(string(n.HTML()), " ")))
+}
+
+// ReadingTime returns the estimated number of minutes to read the node, at least 1
+func (n *Node) ReadingTime() int {
+	minutes := (n.WordCount() + wordsPerMinute - 1) / wordsPerMinute
+	if minutes < 1 {
+		return 1
+	}
+
+	return minutes
+}
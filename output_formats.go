@@ -0,0 +1,180 @@
+package baja
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Page kinds of Config.Outputs
+const (
+	KindHome     = "home"     // the site index
+	KindSection  = "section"  // index of a content directory
+	KindTaxonomy = "taxonomy" // page listing the terms of a taxonomy
+	KindTerm     = "term"     // index of the nodes of a term, eg: a tag
+	KindAuthor   = "author"   // index of the nodes of an author
+	KindPage     = "page"     // a node
+)
+
+// Output formats of Config.Outputs
+const (
+	OutputHTML = "html" // index.html
+	OutputRSS  = "rss"  // feed.xml
+	OutputJSON = "json" // index.json
+)
+
+// OutputKinds are the formats each page kind can be generated in
+var OutputKinds = map[string][]string{
+	KindHome:     {OutputHTML, OutputRSS, OutputJSON},
+	KindSection:  {OutputHTML, OutputRSS},
+	KindTaxonomy: {OutputHTML},
+	KindTerm:     {OutputHTML, OutputRSS},
+	KindAuthor:   {OutputHTML, OutputRSS},
+	KindPage:     {OutputHTML, OutputJSON},
+}
+
+// DefaultOutputs are the formats of the page kinds config outputs doesn't list
+var DefaultOutputs = map[string][]string{
+	KindHome:     {OutputHTML, OutputRSS},
+	KindSection:  {OutputHTML},
+	KindTaxonomy: {OutputHTML},
+	KindTerm:     {OutputHTML},
+	KindAuthor:   {OutputHTML, OutputRSS},
+	KindPage:     {OutputHTML},
+}
+
+// OutputFormat is a format of config outputFormats: a file of each page templated with
+// node.<suffix> of the theme, eg: node.ics for the ics calendar of an event. Pages list it by its
+// name in outputs
+type OutputFormat struct {
+	Suffix    string `yaml:"suffix" toml:"suffix"`       // file extension, default to the name of the format
+	BaseName  string `yaml:"baseName" toml:"baseName"`   // file name without suffix, default to index
+	MediaType string `yaml:"mediaType" toml:"mediaType"` // eg: text/calendar, the type baja serve sends the file with
+	Markdown  bool   `yaml:"markdown" toml:"markdown"`   // template the rendered body, .Raw markdown only otherwise
+}
+
+// DefaultBaseName is the file name of an OutputFormat without one
+const DefaultBaseName = "index"
+
+// formatName matches the name, suffix and base name of an OutputFormat
+var formatName = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// FileName returns the name of the file of the format, eg: index.ics
+func (f OutputFormat) FileName() string {
+	return f.BaseName + "." + f.Suffix
+}
+
+// CustomFormat returns the format name of config outputFormats with its defaults set, false when
+// there is none
+func (c *Config) CustomFormat(name string) (OutputFormat, bool) {
+	f, ok := c.CustomFormats[name]
+	if !ok {
+		return f, false
+	}
+
+	if f.Suffix == "" {
+		f.Suffix = name
+	}
+	if f.BaseName == "" {
+		f.BaseName = DefaultBaseName
+	}
+
+	return f, true
+}
+
+// ValidateOutputs checks the kinds and formats of config outputs, and the custom formats of
+// config outputFormats, which pages can list. All problems are returned as ValidationErrors
+func ValidateOutputs(outputs map[string][]string, custom map[string]OutputFormat) error {
+	var errs ValidationErrors
+
+	names := make([]string, 0, len(custom))
+	for name := range custom {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		f := custom[name]
+		switch {
+		case name == OutputHTML || name == OutputRSS || name == OutputJSON:
+			errs = append(errs, fmt.Errorf("output format %s is built in, pick another name", name))
+		case !formatName.MatchString(name):
+			errs = append(errs, fmt.Errorf("invalid output format name %q: letters, digits, - and _ only", name))
+		}
+		if f.Suffix != "" && !formatName.MatchString(f.Suffix) {
+			errs = append(errs, fmt.Errorf("invalid suffix %q of output format %s, eg: ics", f.Suffix, name))
+		}
+		if f.BaseName != "" && !formatName.MatchString(f.BaseName) {
+			errs = append(errs, fmt.Errorf("invalid baseName %q of output format %s, eg: event", f.BaseName, name))
+		}
+		if f.MediaType != "" && !strings.Contains(f.MediaType, "/") {
+			errs = append(errs, fmt.Errorf("invalid mediaType %q of output format %s, eg: text/calendar", f.MediaType, name))
+		}
+	}
+
+	kinds := make([]string, 0, len(outputs))
+	for kind := range outputs {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+
+	for _, kind := range kinds {
+		formats, ok := OutputKinds[kind]
+		if !ok {
+			errs = append(errs, fmt.Errorf("unknown page kind %q of outputs, must be one of %s", kind, strings.Join(sortedKeys(OutputKinds), ", ")))
+			continue
+		}
+
+		if kind == KindPage {
+			formats = append(append([]string{}, formats...), names...)
+		}
+
+		for _, format := range outputs[kind] {
+			if !contains(formats, format) {
+				errs = append(errs, fmt.Errorf("output format %q of %s is not supported, must be one of %s", format, kind, strings.Join(formats, ", ")))
+			}
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return errs
+}
+
+// OutputFormats returns the formats pages of kind are generated in, from config outputs or
+// DefaultOutputs
+func (c *Config) OutputFormats(kind string) []string {
+	if formats, ok := c.Outputs[kind]; ok {
+		return formats
+	}
+
+	return DefaultOutputs[kind]
+}
+
+// HasOutput reports whether pages of kind are generated in format
+func (c *Config) HasOutput(kind, format string) bool {
+	return contains(c.OutputFormats(kind), format)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
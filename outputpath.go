@@ -0,0 +1,65 @@
+package baja
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// OutputPaths modes, how names of nodes are made safe for a directory of public
+const (
+	OutputPathsUnicode = "unicode" // NFC normalized, control and reserved characters removed
+	OutputPathsASCII   = "ascii"   // accents transliterated, eg: é to e, other non ASCII such as emoji removed
+)
+
+// maxSegment is the longest file name in bytes of common file systems
+const maxSegment = 255
+
+// reservedChars can't be in a file name on Windows, / separates segments
+const reservedChars = `<>:"\|?*`
+
+// SafePath makes each segment of the slash path p a valid file name on common file systems, in
+// OutputPathsUnicode or OutputPathsASCII mode. A segment left empty becomes a dash
+func SafePath(p, mode string) string {
+	segments := strings.Split(p, "/")
+	for i, segment := range segments {
+		if segment == "" {
+			continue
+		}
+		segments[i] = safeSegment(segment, mode)
+	}
+
+	return strings.Join(segments, "/")
+}
+
+func safeSegment(s, mode string) string {
+	if mode == OutputPathsASCII {
+		s = norm.NFD.String(s)
+	} else {
+		s = norm.NFC.String(s)
+	}
+
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case unicode.IsControl(r), strings.ContainsRune(reservedChars, r):
+		case mode == OutputPathsASCII && r > unicode.MaxASCII:
+		default:
+			b.WriteRune(r)
+		}
+	}
+
+	// Windows drops trailing dots and spaces
+	s = strings.TrimRight(strings.TrimSpace(b.String()), ". ")
+	if len(s) > maxSegment {
+		// a rune cut in half decodes as RuneError
+		s = strings.TrimRightFunc(s[:maxSegment], func(r rune) bool { return r == unicode.ReplacementChar })
+	}
+
+	if s == "" {
+		return "-"
+	}
+
+	return s
+}
@@ -0,0 +1,30 @@
+package baja_test
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/yeo/baja"
+)
+
+var _ = Describe("SafePath", func() {
+	It("normalizes to NFC and removes control and reserved characters", func() {
+		Expect(baja.SafePath("/post/café/", baja.OutputPathsUnicode)).To(Equal("/post/café/"))
+		Expect(baja.SafePath("/post/what?\x07 🎉./", baja.OutputPathsUnicode)).To(Equal("/post/what 🎉/"))
+		Expect(baja.SafePath("/post/hello/", baja.OutputPathsUnicode)).To(Equal("/post/hello/"))
+	})
+
+	It("transliterates accents and removes other characters in ascii mode", func() {
+		Expect(baja.SafePath("/post/Crème brûlée 🍮/", baja.OutputPathsASCII)).To(Equal("/post/Creme brulee/"))
+		Expect(baja.SafePath("/post/🎉/", baja.OutputPathsASCII)).To(Equal("/post/-/"))
+	})
+
+	It("truncates long names on a rune boundary", func() {
+		name := baja.SafePath(strings.Repeat("é", 200), baja.OutputPathsUnicode)
+
+		Expect(len(name)).To(BeNumerically("<=", 255))
+		Expect(name).To(Equal(strings.Repeat("é", 127)))
+	})
+})
@@ -0,0 +1,51 @@
+package baja
+
+import (
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// Outputs records the files a build writes into public, so the next build can find the ones
+// it no longer produces. It's safe for concurrent use, a nil Outputs records nothing
+type Outputs struct {
+	mu    sync.Mutex
+	paths map[string]bool
+}
+
+// Add records a written file
+func (o *Outputs) Add(path string) {
+	if o == nil {
+		return
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.paths == nil {
+		o.paths = map[string]bool{}
+	}
+	o.paths[filepath.ToSlash(filepath.Clean(path))] = true
+}
+
+// Has reports whether path was recorded
+func (o *Outputs) Has(path string) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return o.paths[filepath.ToSlash(filepath.Clean(path))]
+}
+
+// List returns the recorded files sorted
+func (o *Outputs) List() []string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	paths := make([]string, 0, len(o.paths))
+	for p := range o.paths {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	return paths
+}
@@ -0,0 +1,116 @@
+package baja
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Params are free form settings of themes, eg: accent color or analytics id. Keys are lower cased so
+// lookups are case insensitive, nested tables become Params as well.
+// Templates read them with {{ .Site.Params.accentcolor }} or {{ .Site.Params.Get "Social.Twitter" }}
+type Params map[string]interface{}
+
+// NewParams normalizes the params decoded from yaml or toml
+func NewParams(m map[string]interface{}) Params {
+	p := Params{}
+	for k, v := range m {
+		p[strings.ToLower(k)] = normalizeParam(v)
+	}
+
+	return p
+}
+
+func normalizeParam(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		return NewParams(v)
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, value := range v {
+			m[fmt.Sprint(k)] = value
+		}
+		return NewParams(m)
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		for i, value := range v {
+			list[i] = normalizeParam(value)
+		}
+		return list
+	case []map[string]interface{}:
+		list := make([]interface{}, len(v))
+		for i, value := range v {
+			list[i] = NewParams(value)
+		}
+		return list
+	}
+
+	return v
+}
+
+// Get returns the param at key, nested params are reached with a dotted key such as social.twitter.
+// It returns nil when there is no such param
+func (p Params) Get(key string) interface{} {
+	var v interface{} = p
+	for _, k := range strings.Split(strings.ToLower(key), ".") {
+		m, ok := v.(Params)
+		if !ok {
+			return nil
+		}
+
+		if v, ok = m[k]; !ok {
+			return nil
+		}
+	}
+
+	return v
+}
+
+// mergeParams returns params with overrides applied over defaults. Nested params are merged key by key
+func mergeParams(defaults, overrides Params) Params {
+	merged := Params{}
+	for k, v := range defaults {
+		merged[k] = v
+	}
+
+	for k, v := range overrides {
+		if o, ok := v.(Params); ok {
+			if d, ok := merged[k].(Params); ok {
+				merged[k] = mergeParams(d, o)
+				continue
+			}
+		}
+
+		merged[k] = v
+	}
+
+	return merged
+}
+
+// themeConfig is themes/<theme>/theme.toml
+type themeConfig struct {
+	Params map[string]interface{} `toml:"params"`
+}
+
+// ReadParams returns the default params declared in the [params] table of theme.toml.
+// A theme without theme.toml has no defaults
+func (t *Theme) ReadParams() (Params, error) {
+	path := t.SubPath("theme.toml")
+
+	data, err := t.ReadFile(path)
+	if os.IsNotExist(err) {
+		return Params{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var c themeConfig
+	if _, err := toml.Decode(string(data), &c); err != nil {
+		return nil, fmt.Errorf("invalid theme config %s: %w", path, err)
+	}
+
+	return NewParams(c.Params), nil
+}
@@ -0,0 +1,102 @@
+package baja
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/yeo/baja/utils"
+)
+
+var (
+	permalinkTokenRe = regexp.MustCompile(`:[a-z]+`)
+)
+
+// PermalinkParts are the values of a node a permalink pattern is made of
+type PermalinkParts struct {
+	Section string // directory under content, eg: post
+	Slug    string // file name without extension, or directory of a page bundle
+	Title   string
+	Date    time.Time
+}
+
+// PermalinkFunc returns the permalink of a node, a path such as /post/2019/hello/
+type PermalinkFunc func(PermalinkParts) string
+
+// permalinkTokens are the tokens of a permalink pattern with their value
+var permalinkTokens = map[string]func(PermalinkParts) string{
+	":year":    func(p PermalinkParts) string { return p.Date.Format("2006") },
+	":month":   func(p PermalinkParts) string { return p.Date.Format("01") },
+	":day":     func(p PermalinkParts) string { return p.Date.Format("02") },
+	":section": func(p PermalinkParts) string { return p.Section },
+	":slug":    func(p PermalinkParts) string { return p.Slug },
+	":title": func(p PermalinkParts) string {
+		if title := utils.Slugify(p.Title); title != "" {
+			return title
+		}
+		return p.Slug
+	},
+}
+
+// CompilePermalink parses a pattern such as /:section/:year/:slug/ into a PermalinkFunc. Every
+// token must be known, and :slug or :title is required so two nodes can't share a permalink
+func CompilePermalink(pattern string) (PermalinkFunc, error) {
+	tokens := permalinkTokenRe.FindAllString(pattern, -1)
+	unique := false
+	for _, token := range tokens {
+		if _, ok := permalinkTokens[token]; !ok {
+			return nil, fmt.Errorf("unknown token %s, must be one of :year, :month, :day, :section, :slug or :title", token)
+		}
+		unique = unique || token == ":slug" || token == ":title"
+	}
+	if !unique {
+		return nil, fmt.Errorf("no :slug or :title token, nodes of the section would have the same permalink")
+	}
+
+	// the literal parts of the pattern, between tokens
+	literals := permalinkTokenRe.Split(strings.Trim(pattern, "/"), -1)
+
+	return func(p PermalinkParts) string {
+		var b strings.Builder
+		b.WriteString("/")
+		for i, literal := range literals {
+			b.WriteString(literal)
+			if i < len(tokens) {
+				b.WriteString(permalinkTokens[tokens[i]](p))
+			}
+		}
+		b.WriteString("/")
+
+		return b.String()
+	}, nil
+}
+
+// CompilePermalinks compiles the permalink patterns of Config.Permalinks keyed by section. All
+// invalid patterns are returned as ValidationErrors
+func CompilePermalinks(patterns map[string]string) (map[string]PermalinkFunc, error) {
+	var errs ValidationErrors
+
+	sections := make([]string, 0, len(patterns))
+	for section := range patterns {
+		sections = append(sections, section)
+	}
+	sort.Strings(sections)
+
+	funcs := map[string]PermalinkFunc{}
+	for _, section := range sections {
+		f, err := CompilePermalink(patterns[section])
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid permalink %q of %s: %w", patterns[section], section, err))
+			continue
+		}
+		funcs[section] = f
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	return funcs, nil
+}
@@ -0,0 +1,70 @@
+package baja_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/yeo/baja"
+)
+
+var _ = Describe("Permalinks", func() {
+	parts := baja.PermalinkParts{
+		Section: "post",
+		Slug:    "2019-02-09-hello",
+		Title:   "Hello, World!",
+		Date:    time.Date(2019, 2, 9, 10, 0, 0, 0, time.UTC),
+	}
+
+	It("fills the tokens of a pattern", func() {
+		permalink, err := baja.CompilePermalink("/:section/:year/:month/:day/:title/")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(permalink(parts)).To(Equal("/post/2019/02/09/hello-world/"))
+
+		permalink, err = baja.CompilePermalink("blog/:year-:slug")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(permalink(parts)).To(Equal("/blog/2019-2019-02-09-hello/"))
+	})
+
+	It("uses the slug when the title has no letter or digit", func() {
+		permalink, _ := baja.CompilePermalink("/:title/")
+		parts.Title = "!!"
+
+		Expect(permalink(parts)).To(Equal("/2019-02-09-hello/"))
+	})
+
+	It("rejects unknown tokens and patterns without slug or title", func() {
+		_, err := baja.CompilePermalinks(map[string]string{
+			"post": "/:section/:name/",
+			"news": "/news/:year/",
+			"note": "/:slug/",
+		})
+
+		var errs baja.ValidationErrors
+		Expect(errors.As(err, &errs)).To(Equal(true))
+		Expect(errs).To(HaveLen(2))
+		Expect(errs[0]).To(MatchError(ContainSubstring(`invalid permalink "/news/:year/" of news: no :slug or :title token`)))
+		Expect(errs[1]).To(MatchError(ContainSubstring("unknown token :name")))
+	})
+
+	It("is checked when config is loaded", func() {
+		dir, _ := ioutil.TempDir("", "baja")
+		defer os.RemoveAll(dir)
+
+		path := filepath.Join(dir, "baja.yaml")
+		ioutil.WriteFile(path, []byte("permalinks:\n  post: /:section/:year/\n"), 0644)
+		_, err := baja.LoadSite(path, "")
+		Expect(baja.ExitCode(err)).To(Equal(baja.ExitConfigError))
+
+		ioutil.WriteFile(path, []byte("permalinks:\n  post: /:year/:slug/\n"), 0644)
+		site, err := baja.LoadSite(path, "")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(site.Permalink("post")(parts)).To(Equal("/2019/2019-02-09-hello/"))
+		Expect(site.Permalink("news")).To(BeNil())
+	})
+})
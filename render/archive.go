@@ -0,0 +1,128 @@
+package render
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/yeo/baja"
+)
+
+// archiveTime is the time of every archive entry, so the same output gives the same archive.
+// zip can't go before 1980
+var archiveTime = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// WriteArchive writes the files of directory dir into path, a .tar.gz, .tgz or .zip archive.
+// Entries are sorted with fixed times and modes so the archive of the same files is byte for byte
+// the same, eg: to verify a build is reproducible
+func WriteArchive(dir, path string) error {
+	format, err := baja.ArchiveFormat(path)
+	if err != nil {
+		return err
+	}
+
+	abs, _ := filepath.Abs(path)
+	files := []string{}
+	err = filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			if a, _ := filepath.Abs(p); a != abs {
+				files = append(files, p)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("cannot list %s: %w", dir, err)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("cannot create archive: %w", err)
+	}
+	defer f.Close()
+
+	if format == baja.ArchiveZip {
+		err = writeZip(f, dir, files)
+	} else {
+		err = writeTarGz(f, dir, files)
+	}
+	if err != nil {
+		return fmt.Errorf("cannot write archive %s: %w", path, err)
+	}
+
+	log.Info().Str("path", path).Int("files", len(files)).Msg("Write archive")
+	return f.Close()
+}
+
+func writeTarGz(w io.Writer, dir string, files []string) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	for _, path := range files {
+		rel, _ := filepath.Rel(dir, path)
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+
+		header := &tar.Header{
+			Name:     filepath.ToSlash(rel),
+			Mode:     0644,
+			Size:     info.Size(),
+			ModTime:  archiveTime,
+			Typeflag: tar.TypeReg,
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if err := copyInto(tw, path); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func writeZip(w io.Writer, dir string, files []string) error {
+	zw := zip.NewWriter(w)
+
+	for _, path := range files {
+		rel, _ := filepath.Rel(dir, path)
+		header := &zip.FileHeader{Name: filepath.ToSlash(rel), Method: zip.Deflate, Modified: archiveTime}
+		header.SetMode(0644)
+
+		entry, err := zw.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		if err := copyInto(entry, path); err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+func copyInto(w io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(w, f)
+	return err
+}
@@ -0,0 +1,85 @@
+package render_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/yeo/baja"
+	. "github.com/yeo/baja/render"
+)
+
+// benchSite writes a site whose static directory has many small files and a few large ones, like
+// one of photos, and returns its directory and number of static files
+func benchSite(b *testing.B) (string, int) {
+	dir, err := ioutil.TempDir("", "baja-bench")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	files := map[string][]byte{}
+	for name, content := range fixtureTheme {
+		files[name] = []byte(content)
+	}
+	small := make([]byte, 4<<10)
+	large := make([]byte, 16<<20)
+	for i := 0; i < 500; i++ {
+		files[fmt.Sprintf("static/small/%02d/%d.txt", i%20, i)] = small
+	}
+	for i := 0; i < 4; i++ {
+		files[fmt.Sprintf("static/photos/%d.jpg", i)] = large
+	}
+
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), os.ModePerm)
+		if err := ioutil.WriteFile(path, content, 0644); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	return dir, 504
+}
+
+// BenchmarkCompileAsset copies the static files of a build with one worker, then with maxWorkers
+// unset
+func BenchmarkCompileAsset(b *testing.B) {
+	dir, files := benchSite(b)
+	defer os.RemoveAll(dir)
+	cwd, _ := os.Getwd()
+	defer os.Chdir(cwd)
+	if err := os.Chdir(dir); err != nil {
+		b.Fatal(err)
+	}
+
+	for _, workers := range []int{1, 0} {
+		name := "serial"
+		if workers == 0 {
+			name = "workers"
+		}
+
+		b.Run(name, func(b *testing.B) {
+			config := fmt.Sprintf("theme: t\nmaxWorkers: %d\n", workers)
+			if err := ioutil.WriteFile("baja.yaml", []byte(config), 0644); err != nil {
+				b.Fatal(err)
+			}
+			site, err := baja.LoadSite("baja.yaml", "")
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			for i := 0; i < b.N; i++ {
+				counts := CompileAsset(site)
+				if err := site.Diagnostics.Err(); err != nil || counts.Copied != files {
+					b.Fatalf("copied %d of %d files: %v", counts.Copied, files, err)
+				}
+
+				b.StopTimer()
+				os.RemoveAll("public")
+				b.StartTimer()
+			}
+		})
+	}
+}
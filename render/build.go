@@ -1,80 +1,531 @@
 package render
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
-	"github.com/fatih/color"
+	"github.com/rs/zerolog/log"
 
 	"github.com/yeo/baja"
 	"github.com/yeo/baja/node"
 	"github.com/yeo/baja/utils"
 )
 
-// Build executes template and content to generate our real static conent
-func Build(site *baja.Site) int {
-	ctx := baja.NewContext(site.Config)
+// Build executes template and content to generate our real static conent.
+// Content and template failures don't stop the build, they're collected in site.Diagnostics
+// and returned as a *baja.ContentError at the end
+func Build(site *baja.Site) error {
+	return BuildWithOptions(site, Options{})
+}
+
+// Options tunes a build
+type Options struct {
+	// Incremental keeps public from the previous build and only rewrites feeds and sitemap
+	// when a node was added, removed or had its listing metadata (title, date, draft...) changed.
+	// A body edit keeps the previous feeds, which is fine for the dev serve loop
+	Incremental bool
+
+	// Format is FormatHTML, the default, or FormatJSON to export nodes as json for headless usage
+	Format string
+
+	// Only is a content path, eg: content/blog. The whole content is still scanned so links and
+	// listings are right, but only the nodes under it are rendered, with the indexes and feeds
+	// listing them. Other files of public are kept, nothing is pruned and assets aren't copied
+	Only string
 
-	os.RemoveAll("./public")
-	db := node.BuildDB(site, ctx)
+	// Since is a git ref, eg: origin/main. Like Only, but the scope is the content files added or
+	// changed since the ref, and the pages of the deleted ones are removed from public
+	Since string
+
+	// Report is a file the Report of the build is written into, besides ReportFile of the output
+	// directory with config buildReport
+	Report string
+}
+
+// BuildPreview builds only draft and future dated nodes, plus the indexes to navigate them, into
+// Config.PreviewDir with site.Preview set. It has its own manifest, public and the cache of
+// normal builds are never touched
+func BuildPreview(site *baja.Site, opts Options) error {
+	output, err := filepath.Abs(site.Config.PreviewPath())
+	if err != nil {
+		return err
+	}
 
-	CompileAsset(ctx)
-	CompileNodes(db)
+	if site.Path == nil {
+		site.Path = &baja.SitePath{}
+	}
+	site.Path.Output = output
+	site.Preview = true
 
-	return 0
+	return BuildWithOptions(site, opts)
 }
 
-// CompileAsset copy asset from theme or static into public and also generate a hash version of those file
-func CompileAsset(ctx *baja.Context) {
-	utils.CopyDir(ctx.Theme.SubPath("static/"), "public")
-	utils.CopyDir("static", "public")
+// BuildWithOptions is Build tuned with opts
+func BuildWithOptions(site *baja.Site, opts Options) (err error) {
+	site.Diagnostics = &baja.Diagnostics{}
+	site.Outputs = &baja.Outputs{}
+	utils.ResetHashCache()
+
+	// the report is written whatever happens, a failed build is what CI wants to know about
+	var db *node.NodeDB
+	report := newReport()
+	defer func() {
+		report.finish(site, db, err)
+		for _, path := range reportPaths(site, opts) {
+			if err := report.Write(path); err != nil {
+				log.Error().Err(err).Str("path", path).Msg("Cannot write build report")
+			}
+		}
+	}()
+
+	if err := site.Config.Validate(); err != nil {
+		return err
+	}
+
+	ctx := baja.NewContext(site.Config)
+	if opts.Only != "" && opts.Since != "" {
+		return &baja.ConfigError{Path: "--since", Err: errors.New("--since cannot be used with --only")}
+	}
+	scope, err := onlyScope(opts.Only)
+	if opts.Since != "" {
+		scope, err = sinceScope(opts.Since)
+	}
+	if err != nil {
+		return err
+	}
+
+	if !opts.Incremental && scope == nil {
+		os.RemoveAll(site.OutputDir())
+	}
+	db = node.BuildDB(site, ctx)
+	if site.Preview {
+		db = db.Preview(site.Now())
+	}
+	report.phase("scan")
 
-	// Now generate hash
-	err := filepath.Walk("./public", func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			color.Red("Error while access %q: %v\n", path, err)
+	if opts.Format == FormatJSON {
+		if err := CompileExport(db); err != nil {
+			return err
+		}
+	} else {
+		if err := compileNodes(db, scope); err != nil {
 			return err
 		}
+	}
+	report.phase("nodes")
 
-		if info.IsDir() {
-			return nil
+	manifest := NewManifest(db)
+	prev, _ := LoadManifest(manifestPath(site))
+	switch {
+	case site.Preview, opts.Format == FormatJSON:
+		// a private preview or a headless export has no feed or sitemap
+	case opts.Incremental && !manifest.ListingChanged(prev) && feedsExist(db):
+		log.Info().Msg("Feed and sitemap are up to date")
+		for _, path := range feedPaths(db) {
+			site.Outputs.Add(path)
 		}
+	default:
+		if err := compileFeeds(db, scope); err != nil {
+			return err
+		}
+	}
+	report.phase("feeds")
 
-		color.Green("Generate hash for %s", path)
-		utils.CopyFileWithHash(path)
+	// static files are copied last so they win over a generated page of the same path
+	if opts.Format != FormatJSON && scope == nil {
+		report.Assets = CompileAsset(site)
+	}
+	report.phase("assets")
 
-		return nil
-	})
+	if site.Config.BuildReport {
+		// written once the build is done, recorded so it's never pruned
+		site.Outputs.Add(filepath.Join(site.OutputDir(), ReportFile))
+	}
 
+	manifest.Outputs = outputList(site)
+	if scope != nil && prev != nil {
+		// files of other sections are still in public from the previous build
+		manifest.Outputs = mergeOutputs(prev.Outputs, manifest.Outputs)
+		for _, path := range removeDeleted(site, scope, prev, manifest) {
+			log.Info().Str("path", path).Msg("Remove page of deleted content")
+		}
+	}
+	if opts.Incremental && scope == nil && site.Config.PruneOrphans {
+		for _, path := range PruneOrphans(site, prev, manifest) {
+			log.Info().Str("path", path).Msg("Remove orphan")
+		}
+	}
+
+	if err := manifest.Save(manifestPath(site)); err != nil {
+		return fmt.Errorf("cannot write build manifest: %w", err)
+	}
+	report.phase("manifest")
+
+	if removed, err := site.Cache().Evict(); err != nil {
+		log.Warn().Err(err).Str("dir", site.Config.CachePath()).Msg("Cannot evict cache")
+	} else if removed > 0 {
+		log.Info().Int("removed", removed).Msg("Evict cache")
+	}
+
+	reportDone(site.Diagnostics)
+	return site.Diagnostics.Err()
+}
+
+// CompileAsset copies the static directory of the theme, extracted from its archive for a zip
+// theme, then the site StaticDir, into public with a hash version of each file, through
+// utils.CopyDir. Site files win over theme files of the same path. A file unchanged since the
+// previous build isn't copied again, all of them are recorded in site.Outputs. Files and
+// directories matching config staticIgnore are left out. A file which can't be read or copied is
+// an error of site.Diagnostics, the others are copied anyway. It returns what was copied and
+// skipped
+func CompileAsset(site *baja.Site) AssetCounts {
+	diagnostics := site.Diagnostics
+	counts := AssetCounts{}
+	start := time.Now()
+
+	symlinks := utils.SymlinkFiles
+	if site.Config.FollowSymlinks {
+		symlinks = utils.SymlinkFollow
+	}
+	progress := newProgressLog("Copy static files", progressInterval)
+	opts := utils.CopyDirOpts{
+		Existing:    utils.CopySync,
+		Exclude:     site.Config.StaticIgnore,
+		Symlinks:    symlinks,
+		Fingerprint: true,
+		Workers:     site.Config.MaxWorkers,
+		Warn: func(err error) {
+			log.Warn().Err(err).Msg("Cannot copy static file as is")
+			diagnostics.AddWarning(warningPath(err), err.Error())
+		},
+		Progress: func(p utils.CopyProgress) {
+			progress.report(p)
+			if p.Dest != "" {
+				site.Outputs.Add(p.Dest)
+				site.Outputs.Add(p.Hashed)
+			}
+		},
+	}
+
+	theme, err := themeStatic(site)
 	if err != nil {
-		color.Red("error compile asser%v", err)
-		return
+		log.Error().Err(err).Str("theme", site.Theme.Path()).Msg("Cannot extract static files")
+		diagnostics.AddError(site.Theme.Path(), fmt.Errorf("cannot extract static files: %w", err))
+	}
+	static := site.Config.StaticPath()
+	for _, src := range []string{theme, static} {
+		if src == "" || !utils.HasFile(src) {
+			continue
+		}
+
+		opts.Include = nil
+		if src == theme {
+			opts.Include = func(rel string) bool { return !utils.HasFile(filepath.Join(static, rel)) }
+		}
+		result, err := utils.CopyDir(src, site.OutputDir(), opts)
+		counts.Copied += result.Files
+		counts.Skipped += result.Skipped
+		counts.Dirs += result.Dirs
+		counts.Bytes += result.Bytes
+
+		var errs utils.CopyErrors
+		if errors.As(err, &errs) {
+			for _, e := range errs {
+				log.Error().Err(e.Err).Str("path", e.Path).Msg("Cannot copy asset")
+				diagnostics.AddError(e.Path, fmt.Errorf("cannot copy asset: %w", e.Err))
+			}
+		} else if err != nil {
+			log.Error().Err(err).Str("dir", src).Msg("Cannot compile assets")
+			diagnostics.AddError(src, fmt.Errorf("cannot copy static files: %w", err))
+		}
 	}
+
+	counts.Duration = time.Since(start).Milliseconds()
+	log.Info().Int("copied", counts.Copied).Int("skipped", counts.Skipped).Int("dirs", counts.Dirs).Int64("bytes", counts.Bytes).Int64("durationMs", counts.Duration).Msg("Copy static files")
+	return counts
+}
+
+// themeStatic returns the static directory of the theme. The one of a zip theme is extracted
+// into .baja, its files keep the time of their archive entry so unchanged ones aren't copied
+// again into public
+func themeStatic(site *baja.Site) (string, error) {
+	if !site.Theme.IsArchive() {
+		return site.Theme.SubPath("static/"), nil
+	}
+
+	dir := filepath.Join(filepath.Dir(manifestPath(site)), "theme", "static")
+	if err := os.RemoveAll(dir); err != nil {
+		return "", err
+	}
+	if err := utils.EnsureDir(dir, utils.DefaultDirMode); err != nil {
+		return "", err
+	}
+	for rel, file := range site.Theme.ArchiveFiles("static") {
+		if _, err := site.Theme.SyncFile(file, filepath.Join(dir, rel)); err != nil {
+			return "", err
+		}
+	}
+
+	return dir, nil
+}
+
+// warningPath is the path of a warning of utils.CopyDir
+func warningPath(err error) string {
+	var linkErr *utils.SymlinkError
+	if errors.As(err, &linkErr) {
+		return linkErr.Path
+	}
+	var timeErr *utils.ModTimeError
+	if errors.As(err, &timeErr) {
+		return timeErr.Path
+	}
+
+	return ""
+}
+
+// AssetCounts are the static files CompileAsset copied, and the ones it skipped as unchanged
+type AssetCounts struct {
+	Copied   int   `json:"copied"`
+	Skipped  int   `json:"skipped"`
+	Dirs     int   `json:"dirs"`  // directories of the static directories, empty ones included
+	Bytes    int64 `json:"bytes"` // size of the copied files
+	Duration int64 `json:"durationMs"`
 }
 
-func CompileNodes(db *node.NodeDB) {
-	color.Yellow("Build individual page")
-	for i, node := range db.All() {
-		color.Yellow("\t%d/%d:  %s\n", i+1, db.Total, node.Path)
-		node.Compile()
+func CompileNodes(db *node.NodeDB) error {
+	return compileNodes(db, nil)
+}
+
+// compileNodes is CompileNodes limited to the nodes of scope, and the indexes listing at least
+// one of them. A nil scope is the whole site
+func compileNodes(db *node.NodeDB, scope *buildScope) error {
+	diagnostics := db.Site.Diagnostics
+	affected := func(nodes []*node.Node) bool { return anyInScope(nodes, scope) }
+
+	config := db.Site.Config
+
+	log.Info().Int("total", db.Total).Msg("Build individual page")
+	now := db.Site.Now()
+	for _, node := range db.All() {
+		if !inScope(node, scope) {
+			continue
+		}
+		if !db.Site.Preview && !node.IsPublished(now) {
+			// drafts and future nodes only land in the preview directory
+			node.Logger().Debug().Msg("Skip draft or future node")
+			continue
+		}
+
+		logger := node.Logger()
+		logger.Debug().Msg("Build node")
+		if config.HasOutput(baja.KindPage, baja.OutputHTML) {
+			if err := node.Compile(); err != nil {
+				logger.Error().Err(err).Msg("Cannot build node")
+				diagnostics.AddError(node.Path, err)
+			}
+		}
+		if config.HasOutput(baja.KindPage, baja.OutputJSON) {
+			if err := node.CompileJSON(); err != nil {
+				logger.Error().Err(err).Msg("Cannot export node")
+				diagnostics.AddError(node.Path, err)
+			}
+		}
+		for _, format := range config.OutputFormats(baja.KindPage) {
+			if _, ok := config.CustomFormat(format); !ok {
+				continue
+			}
+			if err := node.CompileFormat(format); err != nil {
+				logger.Error().Err(err).Str("format", format).Msg("Cannot build node output format")
+				diagnostics.AddError(node.Path, err)
+			}
+		}
 	}
 
-	indexNode := node.NewIndex("", db.Publishable())
-	indexNode.Compile(db.Site)
+	if affected(db.MainNodes()) {
+		if config.HasOutput(baja.KindHome, baja.OutputHTML) {
+			indexNode := node.NewIndex("", db.Section(""), db.MainNodes())
+			indexNode.Recent = db.Recent(node.RecentCount)
+			compileIndex(db, indexNode)
+		}
+		if config.HasOutput(baja.KindHome, baja.OutputJSON) {
+			if err := compileExportIndex(db); err != nil {
+				return err
+			}
+		}
+	}
 
-	color.Cyan("Build category")
+	log.Info().Msg("Build category")
 	for dir, nodes := range db.ByCategory() {
-		color.Cyan("    %s ", dir)
-		indexNode := node.NewIndex(dir, nodes)
-		indexNode.Compile(db.Site)
+		if !affected(nodes) || !config.HasOutput(baja.KindSection, baja.OutputHTML) {
+			continue
+		}
+		log.Debug().Str("section", dir).Msg("Build category index")
+		indexNode := node.NewIndex(dir, db.Section(dir), nodes)
+		compileIndex(db, indexNode)
+	}
+
+	log.Info().Msg("Build taxonomies")
+	taxonomies := db.Site.Config.EnabledTaxonomies()
+	for _, key := range db.Site.Config.TaxonomyKeys() {
+		taxonomy := taxonomies[key]
+		terms := db.ByTaxonomy(key)
+		changed := false
+		for term, nodes := range terms {
+			if !affected(nodes) {
+				continue
+			}
+			changed = true
+			if !config.HasOutput(baja.KindTerm, baja.OutputHTML) {
+				continue
+			}
+			log.Debug().Str("taxonomy", key).Str("term", term).Msg("Build term index")
+			compileIndex(db, node.NewTermIndex(key, taxonomy.Path+"/"+term, nodes))
+		}
+
+		if taxonomy.Index && (changed || scope == nil) && config.HasOutput(baja.KindTaxonomy, baja.OutputHTML) {
+			compileIndex(db, node.NewTermsIndex(key, taxonomy.Path, terms))
+		}
+	}
+
+	log.Info().Msg("Build author")
+	for id, nodes := range db.ByAuthor() {
+		if !affected(nodes) || !config.HasOutput(baja.KindAuthor, baja.OutputHTML) {
+			continue
+		}
+		author := node.NewAuthor(db.Site, id)
+		dir := node.AuthorDir + "/" + node.AuthorSlug(id)
+		log.Debug().Str("author", id).Msg("Build author index")
+
+		section := node.DefaultSection(dir)
+		section.Meta.Title = author.Name
+		section.Meta.Description = author.Profile.Bio
+
+		indexNode := node.NewIndex(dir, section, nodes)
+		indexNode.Author = author
+		compileIndex(db, indexNode)
+	}
+
+	return nil
+}
+
+// CompileFeeds writes the site feed, author feeds and sitemap
+func CompileFeeds(db *node.NodeDB) error {
+	return compileFeeds(db, nil)
+}
+
+// compileFeeds is CompileFeeds skipping the feeds with no node of scope. The site feed and sitemap
+// list every section, they're always written
+func compileFeeds(db *node.NodeDB, scope *buildScope) error {
+	log.Info().Msg("Build feed and sitemap")
+	for _, f := range listFeeds(db) {
+		if f.dir != "" && !anyInScope(f.nodes, scope) {
+			continue
+		}
+		if err := compileFeed(db, f.dir, f.title, f.nodes); err != nil {
+			return fmt.Errorf("cannot build feed of %s: %w", f.name, err)
+		}
 	}
 
-	color.Cyan("Build tag")
-	for tag, nodes := range db.ByTag() {
-		color.Cyan("    %s ", tag)
-		indexNode := node.NewIndex("tag/"+tag, nodes)
-		indexNode.Compile(db.Site)
+	if err := CompileSitemap(db); err != nil {
+		return fmt.Errorf("cannot build sitemap: %w", err)
 	}
 
-	color.Green("💥 Done! Enjoy. 🏖")
+	return nil
+}
+
+// feed is an RSS feed of the build, in public/dir/feed.xml
+type feed struct {
+	name  string // what it's about in logs, eg: section post
+	dir   string
+	title string
+	nodes []*node.Node
+}
+
+// listFeeds returns the feeds of the page kinds with the rss output: the site feed, and feeds of
+// sections, terms and authors
+func listFeeds(db *node.NodeDB) []feed {
+	config := db.Site.Config
+	feeds := []feed{}
+
+	if config.HasOutput(baja.KindHome, baja.OutputRSS) {
+		feeds = append(feeds, feed{name: "site", title: db.Site.Title(), nodes: db.MainNodes()})
+	}
+
+	if config.HasOutput(baja.KindSection, baja.OutputRSS) {
+		for dir, nodes := range db.ByCategory() {
+			feeds = append(feeds, feed{name: "section " + dir, dir: dir, title: db.Section(dir).Meta.Title, nodes: nodes})
+		}
+	}
+
+	if config.HasOutput(baja.KindTerm, baja.OutputRSS) {
+		taxonomies := config.EnabledTaxonomies()
+		for _, key := range config.TaxonomyKeys() {
+			for term, nodes := range db.ByTaxonomy(key) {
+				feeds = append(feeds, feed{name: key + " " + term, dir: taxonomies[key].Path + "/" + term, title: term, nodes: nodes})
+			}
+		}
+	}
+
+	if config.HasOutput(baja.KindAuthor, baja.OutputRSS) {
+		for id, nodes := range db.ByAuthor() {
+			feeds = append(feeds, feed{name: "author " + id, dir: node.AuthorDir + "/" + node.AuthorSlug(id), title: node.NewAuthor(db.Site, id).Name, nodes: nodes})
+		}
+	}
+
+	return feeds
+}
+
+// feedPaths are the files CompileFeeds writes
+func feedPaths(db *node.NodeDB) []string {
+	output := db.Site.OutputDir()
+
+	paths := []string{filepath.Join(output, "sitemap.xml")}
+	for _, f := range listFeeds(db) {
+		paths = append(paths, filepath.Join(output, filepath.FromSlash(f.dir), "feed.xml"))
+	}
+
+	return paths
+}
+
+// outputList returns the files written by the build relative to its output directory
+func outputList(site *baja.Site) []string {
+	paths := []string{}
+	for _, path := range site.Outputs.List() {
+		if rel, err := filepath.Rel(site.OutputDir(), path); err == nil {
+			paths = append(paths, filepath.ToSlash(rel))
+		}
+	}
+
+	return paths
+}
+
+// feedsExist reports whether the previous build left every feed and sitemap in public
+func feedsExist(db *node.NodeDB) bool {
+	for _, path := range feedPaths(db) {
+		if _, err := os.Stat(path); err != nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+func reportDone(diagnostics *baja.Diagnostics) {
+	if total := diagnostics.Count(baja.SeverityError); total > 0 {
+		log.Error().Int("errors", total).Msg("Done with errors")
+		return
+	}
+
+	log.Info().Msg("💥 Done! Enjoy. 🏖")
+}
+
+func compileIndex(db *node.NodeDB, indexNode *node.IndexNode) {
+	if err := indexNode.Compile(db.Site); err != nil {
+		path := filepath.Join(db.Site.OutputDir(), indexNode.Dir, "index.html")
+		log.Error().Err(err).Str("path", path).Msg("Cannot build index")
+		db.Site.Diagnostics.AddError(path, err)
+	}
 }
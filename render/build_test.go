@@ -0,0 +1,1825 @@
+package render_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	git "gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+
+	"github.com/yeo/baja"
+	"github.com/yeo/baja/node"
+	. "github.com/yeo/baja/render"
+	"github.com/yeo/baja/utils"
+)
+
+// fixture files of a minimal site, relative to its root
+var fixtureTheme = map[string]string{
+	"baja.yaml":                    "theme: t\nbaseURL: https://example.com\n",
+	"themes/t/layout/default.html": `{{ define "layout" }}{{ template "content" . }}{{ end }}`,
+	"themes/t/node.html":           `{{ define "content" }}<h1>{{ .Meta.Title }}</h1>{{ .Body }}{{ end }}`,
+	"themes/t/index.html":          `{{ define "content" }}{{ range .Nodes }}<a href="{{ .Permalink }}">{{ .Meta.Title }}</a>{{ end }}{{ end }}`,
+}
+
+// withSite writes files into a temporary directory and runs the spec from inside it
+func withSite(files map[string]string) (cleanup func()) {
+	cwd, _ := os.Getwd()
+	dir, err := ioutil.TempDir("", "baja-site")
+	Expect(err).ToNot(HaveOccurred())
+
+	for _, set := range []map[string]string{fixtureTheme, files} {
+		for name, content := range set {
+			path := filepath.Join(dir, name)
+			os.MkdirAll(filepath.Dir(path), os.ModePerm)
+			Expect(ioutil.WriteFile(path, []byte(content), 0644)).To(Succeed())
+		}
+	}
+
+	Expect(os.Chdir(dir)).To(Succeed())
+
+	return func() {
+		os.Chdir(cwd)
+		os.RemoveAll(dir)
+	}
+}
+
+func loadSite() *baja.Site {
+	site, err := baja.LoadSite("baja.yaml", "")
+	Expect(err).ToNot(HaveOccurred())
+
+	return site
+}
+
+// zipTheme moves the directory of theme into themes/<theme>.zip, inside a directory like zip -r does
+func zipTheme(theme string) {
+	f, err := os.Create(filepath.Join("themes", theme+".zip"))
+	Expect(err).ToNot(HaveOccurred())
+	defer f.Close()
+
+	w := zip.NewWriter(f)
+	filepath.Walk(filepath.Join("themes", theme), func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, _ := filepath.Rel("themes", path)
+		entry, err := w.Create(filepath.ToSlash(rel))
+		Expect(err).ToNot(HaveOccurred())
+		content, _ := ioutil.ReadFile(path)
+		entry.Write(content)
+		return nil
+	})
+	Expect(w.Close()).To(Succeed())
+	Expect(os.RemoveAll(filepath.Join("themes", theme))).To(Succeed())
+}
+
+func readPublic(path string) string {
+	content, err := ioutil.ReadFile(filepath.Join("public", path))
+	Expect(err).ToNot(HaveOccurred())
+
+	return string(content)
+}
+
+var _ = Describe("Build", func() {
+	var cleanup func()
+
+	AfterEach(func() {
+		cleanup()
+	})
+
+	Describe("unlisted node", func() {
+		BeforeEach(func() {
+			cleanup = withSite(map[string]string{
+				"content/post/shown.md":  "+++\ntitle = \"Shown\"\ntags = [\"go\"]\n+++\nbody",
+				"content/post/hidden.md": "+++\ntitle = \"Hidden\"\nunlisted = true\ntags = [\"go\"]\n+++\nsecret body",
+			})
+
+			Expect(Build(loadSite())).To(Succeed())
+		})
+
+		It("is compiled", func() {
+			Expect(readPublic("post/hidden/index.html")).To(ContainSubstring("secret body"))
+		})
+
+		It("is absent from indexes, tags, feed and sitemap", func() {
+			for _, page := range []string{"index.html", "post/index.html", "tag/go/index.html", "feed.xml", "sitemap.xml"} {
+				content := readPublic(page)
+
+				Expect(content).To(ContainSubstring("/post/shown/"), page)
+				Expect(content).ToNot(ContainSubstring("/post/hidden/"), page)
+			}
+		})
+	})
+
+	Describe("noindex node", func() {
+		BeforeEach(func() {
+			cleanup = withSite(map[string]string{
+				"themes/t/node.html":     `{{ define "content" }}{{ if .NoIndex }}<meta name="robots" content="noindex">{{ end }}{{ .Body }}{{ end }}`,
+				"content/post/shown.md":  "+++\ntitle = \"Shown\"\n+++\nbody",
+				"content/post/thanks.md": "+++\ntitle = \"Thanks\"\nnoindex = true\n+++\nthank you",
+			})
+
+			Expect(Build(loadSite())).To(Succeed())
+		})
+
+		It("is compiled with the robots meta and listed", func() {
+			Expect(readPublic("post/thanks/index.html")).To(ContainSubstring(`<meta name="robots" content="noindex">`))
+			Expect(readPublic("post/shown/index.html")).ToNot(ContainSubstring("robots"))
+			Expect(readPublic("post/index.html")).To(ContainSubstring("/post/thanks/"))
+		})
+
+		It("is absent from the sitemap", func() {
+			sitemap := readPublic("sitemap.xml")
+			Expect(sitemap).To(ContainSubstring("/post/shown/"))
+			Expect(sitemap).ToNot(ContainSubstring("/post/thanks/"))
+		})
+	})
+
+	Describe("main sections", func() {
+		BeforeEach(func() {
+			cleanup = withSite(map[string]string{
+				"baja.yaml":               "theme: t\nbaseURL: https://example.com\nmainSections: [blog]\n",
+				"content/blog/hello.md":   "+++\ntitle = \"Hello\"\n+++\nbody",
+				"content/blog/go/tips.md": "+++\ntitle = \"Tips\"\n+++\nbody",
+				"content/docs/install.md": "+++\ntitle = \"Install\"\n+++\nbody",
+			})
+
+			Expect(Build(loadSite())).To(Succeed())
+		})
+
+		It("limits the home index and site feed to them", func() {
+			for _, page := range []string{"index.html", "feed.xml"} {
+				content := readPublic(page)
+
+				Expect(content).To(ContainSubstring("/blog/hello/"), page)
+				Expect(content).To(ContainSubstring("/blog/go/tips/"), page)
+				Expect(content).ToNot(ContainSubstring("/docs/install/"), page)
+			}
+		})
+
+		It("keeps other sections in their own index and the sitemap", func() {
+			Expect(readPublic("docs/index.html")).To(ContainSubstring("/docs/install/"))
+			Expect(readPublic("sitemap.xml")).To(ContainSubstring("/docs/install/"))
+		})
+	})
+
+	Describe("recent posts", func() {
+		BeforeEach(func() {
+			cleanup = withSite(map[string]string{
+				"baja.yaml":               "theme: t\nmainSections: [blog]\nrecentPosts: 2\n",
+				"themes/t/node.html":      `{{ define "content" }}<aside>{{ range .Site.RecentPosts }}{{ .Meta.Title }};{{ end }}</aside>{{ end }}`,
+				"content/blog/one.md":     "+++\ntitle = \"One\"\ndate = 2019-01-01T00:00:00Z\n+++\nbody",
+				"content/blog/two.md":     "+++\ntitle = \"Two\"\ndate = 2019-02-01T00:00:00Z\n+++\nbody",
+				"content/blog/three.md":   "+++\ntitle = \"Three\"\ndate = 2019-03-01T00:00:00Z\n+++\nbody",
+				"content/blog/draft.md":   "+++\ntitle = \"Draft\"\ndraft = true\ndate = 2019-04-01T00:00:00Z\n+++\nbody",
+				"content/docs/install.md": "+++\ntitle = \"Install\"\ndate = 2019-05-01T00:00:00Z\n+++\nbody",
+			})
+
+			Expect(Build(loadSite())).To(Succeed())
+		})
+
+		It("lists the newest nodes of the main sections on every page, drafts left out", func() {
+			for _, page := range []string{"blog/one/index.html", "docs/install/index.html"} {
+				Expect(readPublic(page)).To(ContainSubstring("<aside>Three;Two;</aside>"), page)
+			}
+		})
+	})
+
+	Describe("pagination", func() {
+		BeforeEach(func() {
+			files := map[string]string{
+				"baja.yaml":           "theme: t\npaginate: 2\nsectionPaginate:\n  gallery: 3\n",
+				"themes/t/index.html": `{{ define "content" }}{{ range .Nodes }}{{ .Meta.Title }};{{ end }}|{{ .Paginator.PageNumber }}/{{ .Paginator.TotalPages }}|{{ .Paginator.Prev }}|{{ .Paginator.Next }}{{ end }}`,
+			}
+			for i := 1; i <= 5; i++ {
+				files[fmt.Sprintf("content/blog/b%d.md", i)] = fmt.Sprintf("+++\ntitle = \"B%d\"\ndate = 2019-01-0%dT00:00:00Z\n+++\nbody", i, i)
+				files[fmt.Sprintf("content/gallery/g%d.md", i)] = fmt.Sprintf("+++\ntitle = \"G%d\"\ndate = 2019-01-0%dT00:00:00Z\n+++\nbody", i, i)
+			}
+			cleanup = withSite(files)
+
+			Expect(Build(loadSite())).To(Succeed())
+		})
+
+		It("splits a section at the global size", func() {
+			Expect(readPublic("blog/index.html")).To(Equal("B5;B4;|1/3||/blog/page/2/"))
+			Expect(readPublic("blog/page/2/index.html")).To(Equal("B3;B2;|2/3|/blog/|/blog/page/3/"))
+			Expect(readPublic("blog/page/3/index.html")).To(Equal("B1;|3/3|/blog/page/2/|"))
+		})
+
+		It("splits a section at its own size", func() {
+			Expect(readPublic("gallery/index.html")).To(Equal("G5;G4;G3;|1/2||/gallery/page/2/"))
+			Expect(readPublic("gallery/page/2/index.html")).To(Equal("G2;G1;|2/2|/gallery/|"))
+
+			_, err := os.Stat("public/gallery/page/3")
+			Expect(os.IsNotExist(err)).To(BeTrue())
+		})
+
+		It("links canonical, prev and next and marks later pages noindex when configured", func() {
+			Expect(ioutil.WriteFile("baja.yaml", []byte("theme: t\nbaseURL: https://example.com/\npaginate: 2\npaginateNoIndex: true\n"), 0644)).To(Succeed())
+			Expect(ioutil.WriteFile("themes/t/index.html", []byte(`{{ define "content" }}{{ with .Paginator }}{{ .Canonical }}|{{ .PrevURL }}|{{ .NextURL }}|{{ .NoIndex }}{{ end }}{{ end }}`), 0644)).To(Succeed())
+			Expect(Build(loadSite())).To(Succeed())
+
+			Expect(readPublic("blog/index.html")).To(Equal("https://example.com/blog/||https://example.com/blog/page/2/|false"))
+			Expect(readPublic("blog/page/2/index.html")).To(Equal("https://example.com/blog/page/2/|https://example.com/blog/|https://example.com/blog/page/3/|true"))
+		})
+	})
+
+	Describe("partials", func() {
+		var site *baja.Site
+
+		BeforeEach(func() {
+			cleanup = withSite(map[string]string{
+				"themes/t/node.html":         `{{ define "content" }}<h1>{{ .Meta.Title }}</h1>{{ if .Params.cta }}{{ partial .Params.cta . }}{{ end }}{{ end }}`,
+				"themes/t/partials/cta.html": `<aside>Subscribe to {{ .Site.Title }}, after {{ .Meta.Title }}</aside>`,
+				"baja.yaml":                  "theme: t\ntitle: Notes\nbaseURL: https://example.com\n",
+				"content/post/cta.md":        "+++\ntitle = \"Hello\"\n[params]\ncta = \"cta\"\n+++\nbody",
+				"content/post/plain.md":      "+++\ntitle = \"Plain\"\n+++\nbody",
+				"content/post/missing.md":    "+++\ntitle = \"Missing\"\n[params]\ncta = \"newsletter\"\n+++\nbody",
+			})
+
+			site = loadSite()
+			Build(site)
+		})
+
+		It("are included by front matter params", func() {
+			Expect(readPublic("post/cta/index.html")).To(ContainSubstring("<aside>Subscribe to Notes, after Hello</aside>"))
+			Expect(readPublic("post/plain/index.html")).ToNot(ContainSubstring("<aside>"))
+		})
+
+		It("fails the node naming a missing partial", func() {
+			Expect(site.Diagnostics.Items).To(HaveLen(1))
+			Expect(site.Diagnostics.Items[0].Path).To(Equal("content/post/missing.md"))
+			Expect(site.Diagnostics.Items[0].Message).To(ContainSubstring(`partial "newsletter" not found`))
+		})
+	})
+
+	Describe("broken content", func() {
+		var (
+			site *baja.Site
+			err  error
+		)
+
+		BeforeEach(func() {
+			cleanup = withSite(map[string]string{
+				"content/post/good.md":     "+++\ntitle = \"Good\"\n+++\nbody",
+				"content/post/unclosed.md": "+++\ntitle = \"Open\"\nbody",
+				"content/post/badmeta.md":  "+++\ntitle = \n+++\nbody",
+			})
+
+			site = loadSite()
+			err = Build(site)
+		})
+
+		It("returns a content error after building everything else", func() {
+			var contentErr *baja.ContentError
+			Expect(errors.As(err, &contentErr)).To(Equal(true))
+			Expect(baja.ExitCode(err)).To(Equal(baja.ExitContentError))
+
+			Expect(readPublic("post/good/index.html")).To(ContainSubstring("Good"))
+		})
+
+		It("reports each failed file", func() {
+			Expect(site.Diagnostics.WriteReport("report.json")).To(Succeed())
+
+			raw, _ := ioutil.ReadFile("report.json")
+			report := struct {
+				Errors      int
+				Diagnostics []baja.Diagnostic
+			}{}
+			Expect(json.Unmarshal(raw, &report)).To(Succeed())
+
+			Expect(report.Errors).To(Equal(2))
+			paths := []string{report.Diagnostics[0].Path, report.Diagnostics[1].Path}
+			Expect(paths).To(ConsistOf("content/post/unclosed.md", "content/post/badmeta.md"))
+		})
+	})
+
+	Describe("authors", func() {
+		BeforeEach(func() {
+			cleanup = withSite(map[string]string{
+				"baja.yaml":          "theme: t\nbaseURL: https://example.com\nauthors:\n  jane:\n    name: Jane Doe\n    bio: Writes things\n",
+				"themes/t/node.html": `{{ define "content" }}{{ range .Authors }}<a href="{{ .Permalink }}">{{ .Name }}</a>{{ end }}{{ end }}`,
+				"themes/t/author.html": `{{ define "content" }}<h1>{{ .Author.Name }}</h1><p>{{ .Author.Profile.Bio }}</p>` +
+					`{{ range .Nodes }}<a href="{{ .Permalink }}">{{ .Meta.Title }}</a>{{ end }}{{ end }}`,
+				"content/post/one.md": "+++\ntitle = \"One\"\nauthor = \"jane\"\n+++\nbody",
+				"content/post/two.md": "+++\ntitle = \"Two\"\nauthors = [\"jane\", \"Bob Smith\"]\n+++\nbody",
+			})
+
+			Expect(Build(loadSite())).To(Succeed())
+		})
+
+		It("links nodes to their author pages", func() {
+			Expect(readPublic("post/two/index.html")).To(Equal(`<a href="/authors/jane/">Jane Doe</a><a href="/authors/bob-smith/">Bob Smith</a>`))
+		})
+
+		It("renders author pages with profile and feed", func() {
+			page := readPublic("authors/jane/index.html")
+			Expect(page).To(ContainSubstring("<h1>Jane Doe</h1><p>Writes things</p>"))
+			Expect(page).To(ContainSubstring("/post/one/"))
+			Expect(page).To(ContainSubstring("/post/two/"))
+
+			Expect(readPublic("authors/bob-smith/index.html")).ToNot(ContainSubstring("/post/one/"))
+			Expect(readPublic("authors/jane/feed.xml")).To(ContainSubstring("https://example.com/post/one/"))
+		})
+	})
+
+	Describe("incremental build", func() {
+		var site *baja.Site
+
+		incremental := func() {
+			Expect(BuildWithOptions(site, Options{Incremental: true})).To(Succeed())
+		}
+
+		BeforeEach(func() {
+			cleanup = withSite(map[string]string{
+				"content/post/one.md": "+++\ntitle = \"One\"\n+++\nbody",
+			})
+
+			site = loadSite()
+			Expect(Build(site)).To(Succeed())
+			// a marker tells whether the next build rewrote the feed
+			Expect(ioutil.WriteFile("public/feed.xml", []byte("stale"), 0644)).To(Succeed())
+		})
+
+		It("keeps feeds when only a body changed", func() {
+			Expect(ioutil.WriteFile("content/post/one.md", []byte("+++\ntitle = \"One\"\n+++\nnew body"), 0644)).To(Succeed())
+			incremental()
+
+			Expect(readPublic("post/one/index.html")).To(ContainSubstring("new body"))
+			Expect(readPublic("feed.xml")).To(Equal("stale"))
+		})
+
+		It("rewrites feeds when a title changed", func() {
+			Expect(ioutil.WriteFile("content/post/one.md", []byte("+++\ntitle = \"Renamed\"\n+++\nbody"), 0644)).To(Succeed())
+			incremental()
+
+			Expect(readPublic("feed.xml")).To(ContainSubstring("Renamed"))
+		})
+
+		It("rewrites feeds when a node is added", func() {
+			Expect(ioutil.WriteFile("content/post/two.md", []byte("+++\ntitle = \"Two\"\n+++\nbody"), 0644)).To(Succeed())
+			incremental()
+
+			Expect(readPublic("feed.xml")).To(ContainSubstring("/post/two/"))
+			Expect(readPublic("sitemap.xml")).To(ContainSubstring("/post/two/"))
+		})
+
+		It("rewrites feeds when the terms of a taxonomy changed", func() {
+			Expect(ioutil.WriteFile("content/post/one.md", []byte("+++\ntitle = \"One\"\ncategories = [\"notes\"]\n+++\nbody"), 0644)).To(Succeed())
+			incremental()
+
+			Expect(readPublic("feed.xml")).To(ContainSubstring("/post/one/"))
+			Expect(readPublic("categories/notes/index.html")).To(ContainSubstring("/post/one/"))
+		})
+
+		It("rewrites the sitemap when noindex changed", func() {
+			Expect(ioutil.WriteFile("content/post/one.md", []byte("+++\ntitle = \"One\"\nnoindex = true\n+++\nbody"), 0644)).To(Succeed())
+			incremental()
+
+			Expect(readPublic("sitemap.xml")).ToNot(ContainSubstring("/post/one/"))
+		})
+
+		It("always rewrites feeds on a full build", func() {
+			Expect(Build(site)).To(Succeed())
+
+			Expect(readPublic("feed.xml")).To(ContainSubstring("/post/one/"))
+		})
+	})
+
+	Describe("aliases", func() {
+		It("are written under public and never outside it", func() {
+			cleanup = withSite(map[string]string{
+				"content/post/one.md": "+++\ntitle = \"One\"\naliases = [\"/old/one/\", \"../../escape/\", \"/a/../../b\", \"/\", \"/what?/\"]\n+++\nbody",
+			})
+
+			site := loadSite()
+			Expect(Build(site)).To(HaveOccurred())
+
+			Expect(readPublic("old/one/index.html")).To(ContainSubstring("/post/one/"))
+			Expect(readPublic("what/index.html")).To(ContainSubstring("/post/one/"))
+			Expect(readPublic("index.html")).ToNot(ContainSubstring("http-equiv"))
+			Expect(utils.HasFile("../escape")).To(Equal(false))
+			Expect(utils.HasFile("../b")).To(Equal(false))
+
+			messages := []string{}
+			for _, d := range site.Diagnostics.Items {
+				Expect(d.Path).To(Equal("content/post/one.md"))
+				messages = append(messages, d.Message)
+			}
+			Expect(messages).To(ConsistOf(
+				ContainSubstring(`invalid alias "../../escape/"`),
+				ContainSubstring(`invalid alias "/a/../../b"`),
+				ContainSubstring(`invalid alias "/": it's the home page`),
+			))
+		})
+	})
+
+	Describe("orphaned outputs", func() {
+		var site *baja.Site
+
+		BeforeEach(func() {
+			cleanup = withSite(map[string]string{
+				"baja.yaml":              "theme: t\nbaseURL: https://example.com\npruneOrphans: true\npruneProtect: [\"CNAME\"]\n",
+				"content/post/one.md":    "+++\ntitle = \"One\"\naliases = [\"/old/one/\"]\n+++\nbody",
+				"content/post/two.md":    "+++\ntitle = \"Two\"\n+++\nbody",
+				"static/keep/index.html": "static",
+			})
+
+			site = loadSite()
+			Expect(Build(site)).To(Succeed())
+			Expect(ioutil.WriteFile("public/CNAME", []byte("example.com"), 0644)).To(Succeed())
+		})
+
+		It("deletes pages of removed nodes and their empty directories", func() {
+			Expect(os.Remove("content/post/one.md")).To(Succeed())
+			Expect(BuildWithOptions(site, Options{Incremental: true})).To(Succeed())
+
+			_, err := os.Stat("public/post/one")
+			Expect(os.IsNotExist(err)).To(Equal(true))
+			_, err = os.Stat("public/old")
+			Expect(os.IsNotExist(err)).To(Equal(true))
+
+			Expect(readPublic("post/two/index.html")).To(ContainSubstring("Two"))
+			Expect(readPublic("CNAME")).To(Equal("example.com"))
+			Expect(readPublic("keep/index.html")).To(Equal("static"))
+		})
+
+		It("keeps static files shadowing a removed output", func() {
+			Expect(os.MkdirAll("static/post/one", os.ModePerm)).To(Succeed())
+			Expect(ioutil.WriteFile("static/post/one/index.html", []byte("moved"), 0644)).To(Succeed())
+			Expect(os.Remove("content/post/one.md")).To(Succeed())
+
+			Expect(BuildWithOptions(site, Options{Incremental: true})).To(Succeed())
+			Expect(readPublic("post/one/index.html")).To(Equal("moved"))
+		})
+
+		It("keeps orphans when disabled", func() {
+			site.Config.PruneOrphans = false
+			Expect(os.Remove("content/post/one.md")).To(Succeed())
+
+			Expect(BuildWithOptions(site, Options{Incremental: true})).To(Succeed())
+			Expect(readPublic("post/one/index.html")).To(ContainSubstring("One"))
+		})
+	})
+
+	Describe("zip theme", func() {
+		BeforeEach(func() {
+			cleanup = withSite(map[string]string{
+				"baja.yaml":                     "theme: t.zip\ntitle: Notes\n",
+				"themes/t/node.html":            `{{ define "content" }}<h1>{{ .Meta.Title }}</h1>{{ partial "footer" . }}{{ end }}`,
+				"themes/t/partials/footer.html": `<footer>{{ i18n "by" }} {{ .Site.Title }}</footer>`,
+				"themes/t/static/app.css":       "theme",
+				"themes/t/theme.toml":           "[params]\naccent = \"red\"\n",
+				"content/post/one.md":           "+++\ntitle = \"One\"\n+++\nbody",
+			})
+			zipTheme("t")
+		})
+
+		It("reads templates, partials, params and static files from the archive", func() {
+			site := loadSite()
+			Expect(site.Config.Validate()).To(Succeed())
+			Expect(Build(site)).To(Succeed())
+
+			Expect(readPublic("post/one/index.html")).To(ContainSubstring("<h1>One</h1><footer>by Notes</footer>"))
+			Expect(readPublic("index.html")).To(ContainSubstring(`<a href="/post/one/">One</a>`))
+			Expect(readPublic("app.css")).To(Equal("theme"))
+			Expect(site.Params().Get("accent")).To(Equal("red"))
+			_, err := os.Stat("themes/t")
+			Expect(os.IsNotExist(err)).To(Equal(true))
+		})
+
+		It("skips the unchanged static files of the archive on an incremental build", func() {
+			site := loadSite()
+			Expect(Build(site)).To(Succeed())
+			Expect(BuildWithOptions(site, Options{Incremental: true, Report: "report.json"})).To(Succeed())
+
+			var report Report
+			content, _ := ioutil.ReadFile("report.json")
+			Expect(json.Unmarshal(content, &report)).To(Succeed())
+			Expect(report.Assets.Copied).To(Equal(0))
+			Expect(report.Assets.Skipped).To(Equal(1))
+			Expect(readPublic("app.css")).To(Equal("theme"))
+		})
+
+		It("reports an archive which can't be read", func() {
+			Expect(ioutil.WriteFile("themes/t.zip", []byte("not a zip"), 0644)).To(Succeed())
+
+			_, err := baja.LoadSite("baja.yaml", "")
+			Expect(baja.ExitCode(err)).To(Equal(baja.ExitConfigError))
+			Expect(err).To(MatchError(ContainSubstring("cannot read theme archive")))
+		})
+
+		It("rejects an archive with an entry outside of the theme", func() {
+			for _, name := range []string{"t/static/../../../evil.css", "/etc/evil.css", `t\static\evil.css`} {
+				f, err := os.Create("themes/t.zip")
+				Expect(err).ToNot(HaveOccurred())
+				w := zip.NewWriter(f)
+				_, err = w.Create("t/node.html")
+				Expect(err).ToNot(HaveOccurred())
+				entry, err := w.Create(name)
+				Expect(err).ToNot(HaveOccurred())
+				entry.Write([]byte("evil"))
+				Expect(w.Close()).To(Succeed())
+				Expect(f.Close()).To(Succeed())
+
+				_, err = baja.LoadSite("baja.yaml", "")
+				Expect(baja.ExitCode(err)).To(Equal(baja.ExitConfigError))
+				Expect(err).To(MatchError(ContainSubstring("invalid entry")))
+			}
+			_, err := os.Stat("../evil.css")
+			Expect(os.IsNotExist(err)).To(Equal(true))
+		})
+	})
+
+	Describe("static files", func() {
+		var site *baja.Site
+
+		BeforeEach(func() {
+			cleanup = withSite(map[string]string{
+				"baja.yaml":                "theme: t\nstaticDir: assets\npruneOrphans: true\n",
+				"themes/t/static/app.css":  "theme",
+				"themes/t/static/font.txt": "font",
+				"assets/app.css":           "site",
+				"assets/favicon.ico":       "icon",
+				"content/post/one.md":      "+++\ntitle = \"One\"\n+++\nbody",
+			})
+
+			site = loadSite()
+			Expect(Build(site)).To(Succeed())
+		})
+
+		It("copies theme then site files, site files win", func() {
+			Expect(readPublic("app.css")).To(Equal("site"))
+			Expect(readPublic("font.txt")).To(Equal("font"))
+			Expect(readPublic("favicon.ico")).To(Equal("icon"))
+			Expect(readPublic("app-fbae041b02c41ed0fd8a4efb039bc780dd6af4a1f0c420f42561ae705dda43fe.css")).To(Equal("site"))
+		})
+
+		It("skips unchanged files and prunes removed ones on an incremental build", func() {
+			info, _ := os.Stat("assets/favicon.ico")
+			Expect(ioutil.WriteFile("public/favicon.ico", []byte("same"), 0644)).To(Succeed())
+			Expect(os.Chtimes("public/favicon.ico", info.ModTime(), info.ModTime())).To(Succeed())
+			Expect(os.Remove("themes/t/static/font.txt")).To(Succeed())
+
+			Expect(BuildWithOptions(site, Options{Incremental: true})).To(Succeed())
+
+			Expect(readPublic("favicon.ico")).To(Equal("same"))
+			_, err := os.Stat("public/font.txt")
+			Expect(os.IsNotExist(err)).To(Equal(true))
+		})
+
+		It("skips files of the same content with another time and counts them", func() {
+			now := time.Now()
+			Expect(os.Chtimes("public/app.css", now, now)).To(Succeed())
+			Expect(ioutil.WriteFile("assets/favicon.ico", []byte("new icon"), 0644)).To(Succeed())
+
+			Expect(BuildWithOptions(site, Options{Incremental: true, Report: "report.json"})).To(Succeed())
+
+			source, _ := os.Stat("assets/app.css")
+			target, _ := os.Stat("public/app.css")
+			Expect(target.ModTime()).To(BeTemporally("==", source.ModTime()))
+			Expect(readPublic("favicon.ico")).To(Equal("new icon"))
+
+			var report Report
+			content, _ := ioutil.ReadFile("report.json")
+			Expect(json.Unmarshal(content, &report)).To(Succeed())
+			Expect(report.Assets.Copied).To(Equal(1))
+			Expect(report.Assets.Skipped).To(Equal(2))
+			Expect(report.Assets.Bytes).To(Equal(int64(len("new icon"))))
+		})
+
+		It("creates empty static directories with their permissions", func() {
+			Expect(os.MkdirAll("assets/mnt/data", 0755)).To(Succeed())
+			Expect(os.Chmod("assets/mnt/data", 0775)).To(Succeed())
+
+			Expect(BuildWithOptions(site, Options{Report: "report.json"})).To(Succeed())
+
+			info, err := os.Stat("public/mnt/data")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(info.IsDir()).To(BeTrue())
+			Expect(info.Mode().Perm()).To(Equal(os.FileMode(0775)))
+
+			var report Report
+			content, _ := ioutil.ReadFile("report.json")
+			Expect(json.Unmarshal(content, &report)).To(Succeed())
+			Expect(report.Assets.Dirs).To(Equal(2))
+		})
+
+		It("copies every file and its hashed copy with maxWorkers", func() {
+			for i := 0; i < 40; i++ {
+				path := fmt.Sprintf("assets/img/%02d/%d.svg", i%4, i)
+				Expect(os.MkdirAll(filepath.Dir(path), os.ModePerm)).To(Succeed())
+				Expect(ioutil.WriteFile(path, []byte(fmt.Sprintf("logo %d", i)), 0644)).To(Succeed())
+			}
+			Expect(ioutil.WriteFile("baja.yaml", []byte("theme: t\nstaticDir: assets\nmaxWorkers: 3\n"), 0644)).To(Succeed())
+
+			Expect(BuildWithOptions(loadSite(), Options{Report: "report.json"})).To(Succeed())
+
+			var report Report
+			content, _ := ioutil.ReadFile("report.json")
+			Expect(json.Unmarshal(content, &report)).To(Succeed())
+			Expect(report.Assets.Copied).To(Equal(43))
+			Expect(readPublic("img/03/39.svg")).To(Equal("logo 39"))
+			hashed, err := filepath.Glob("public/img/*/*-*.svg")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hashed).To(HaveLen(40))
+		})
+
+		It("keeps the time of static directories", func() {
+			past := time.Date(2019, 2, 9, 10, 30, 0, 0, time.UTC)
+			Expect(os.MkdirAll("assets/img", 0755)).To(Succeed())
+			Expect(ioutil.WriteFile("assets/img/logo.svg", []byte("logo"), 0644)).To(Succeed())
+			Expect(os.Chtimes("assets/img", past, past)).To(Succeed())
+
+			Expect(Build(site)).To(Succeed())
+
+			info, err := os.Stat("public/img")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(info.ModTime()).To(BeTemporally("~", past, time.Second))
+		})
+
+		It("follows symlinked directories of static and content with followSymlinks", func() {
+			Expect(os.MkdirAll("shared/media", 0755)).To(Succeed())
+			Expect(os.MkdirAll("shared/notes", 0755)).To(Succeed())
+			Expect(ioutil.WriteFile("shared/media/logo.png", []byte("logo"), 0644)).To(Succeed())
+			Expect(ioutil.WriteFile("shared/notes/two.md", []byte("+++\ntitle = \"Two\"\n+++\nbody"), 0644)).To(Succeed())
+			Expect(os.Symlink("../shared/media", "assets/media")).To(Succeed())
+			Expect(os.Symlink("../shared/notes", "content/notes")).To(Succeed())
+			Expect(os.Symlink(".", "shared/media/loop")).To(Succeed())
+
+			Expect(Build(loadSite())).To(Succeed())
+			_, err := os.Stat("public/media/logo.png")
+			Expect(os.IsNotExist(err)).To(BeTrue())
+
+			Expect(ioutil.WriteFile("baja.yaml", []byte("theme: t\nstaticDir: assets\nfollowSymlinks: true\n"), 0644)).To(Succeed())
+			site = loadSite()
+			Expect(Build(site)).To(Succeed())
+
+			Expect(readPublic("media/logo.png")).To(Equal("logo"))
+			Expect(readPublic("notes/two/index.html")).To(ContainSubstring("Two"))
+			Expect(site.Diagnostics.Items).To(HaveLen(1))
+			Expect(site.Diagnostics.Items[0].Path).To(Equal("assets/media/loop"))
+			Expect(site.Diagnostics.Items[0].Message).To(ContainSubstring("symlink cycle"))
+		})
+
+		It("reports files it cannot copy and copies the others", func() {
+			Expect(os.Symlink("missing.css", "assets/broken.css")).To(Succeed())
+
+			err := Build(site)
+			Expect(baja.ExitCode(err)).To(Equal(baja.ExitContentError))
+			Expect(site.Diagnostics.Items).To(HaveLen(1))
+			Expect(site.Diagnostics.Items[0].Path).To(HaveSuffix("assets/broken.css"))
+			Expect(site.Diagnostics.Items[0].Message).To(ContainSubstring("cannot copy asset"))
+			Expect(readPublic("favicon.ico")).To(Equal("icon"))
+		})
+
+		It("leaves out files and directories matching staticIgnore", func() {
+			Expect(os.MkdirAll("assets/node_modules/lib", os.ModePerm)).To(Succeed())
+			Expect(ioutil.WriteFile("assets/node_modules/lib/index.js", []byte("lib"), 0644)).To(Succeed())
+			Expect(ioutil.WriteFile("assets/.DS_Store", []byte("finder"), 0644)).To(Succeed())
+			Expect(ioutil.WriteFile("baja.yaml", []byte("theme: t\nstaticDir: assets\nstaticIgnore: [node_modules, .DS_Store]\n"), 0644)).To(Succeed())
+
+			Expect(Build(loadSite())).To(Succeed())
+			Expect("public/node_modules").ToNot(BeAnExistingFile())
+			Expect("public/.DS_Store").ToNot(BeAnExistingFile())
+			Expect(readPublic("favicon.ico")).To(Equal("icon"))
+		})
+	})
+
+	Describe("archive", func() {
+		BeforeEach(func() {
+			cleanup = withSite(map[string]string{
+				"content/post/one.md": "+++\ntitle = \"One\"\n+++\nbody",
+				"static/app.css":      "body {}",
+			})
+			Expect(Build(loadSite())).To(Succeed())
+		})
+
+		for _, name := range []string{"site.tar.gz", "site.zip"} {
+			name := name
+
+			It("is the same for the same output: "+name, func() {
+				Expect(WriteArchive("public", name)).To(Succeed())
+				first, _ := ioutil.ReadFile(name)
+
+				now := time.Now()
+				Expect(os.Chtimes("public/app.css", now, now)).To(Succeed())
+				Expect(WriteArchive("public", name)).To(Succeed())
+				second, _ := ioutil.ReadFile(name)
+
+				Expect(first).ToNot(BeEmpty())
+				Expect(second).To(Equal(first))
+			})
+		}
+
+		It("lists every file of the output", func() {
+			Expect(WriteArchive("public", "site.zip")).To(Succeed())
+
+			r, err := zip.OpenReader("site.zip")
+			Expect(err).ToNot(HaveOccurred())
+			defer r.Close()
+
+			names := []string{}
+			for _, f := range r.File {
+				names = append(names, f.Name)
+			}
+			Expect(names).To(ContainElement("post/one/index.html"))
+			Expect(names).To(ContainElement("app.css"))
+		})
+
+		It("rejects unknown formats", func() {
+			Expect(WriteArchive("public", "site.rar")).To(MatchError(ContainSubstring("unknown archive format")))
+		})
+	})
+
+	Describe("drafts preview", func() {
+		BeforeEach(func() {
+			cleanup = withSite(map[string]string{
+				"themes/t/node.html":    `{{ define "content" }}{{ if .Site.Preview }}PREVIEW {{ end }}<h1>{{ .Meta.Title }}</h1>{{ end }}`,
+				"content/post/live.md":  "+++\ntitle = \"Live\"\ndate = 2019-01-01T00:00:00Z\n+++\nbody",
+				"content/post/draft.md": "+++\ntitle = \"Draft\"\ndraft = true\n+++\nbody",
+				"content/post/later.md": "+++\ntitle = \"Later\"\ndate = 2999-01-01T00:00:00Z\n+++\nbody",
+			})
+
+			Expect(Build(loadSite())).To(Succeed())
+			Expect(BuildPreview(loadSite(), Options{})).To(Succeed())
+		})
+
+		It("builds drafts and future nodes into the preview directory", func() {
+			preview := func(path string) string {
+				content, err := ioutil.ReadFile(filepath.Join("public-preview", path))
+				Expect(err).ToNot(HaveOccurred())
+				return string(content)
+			}
+
+			Expect(preview("post/draft/index.html")).To(Equal("PREVIEW <h1>Draft</h1>"))
+			Expect(preview("index.html")).To(ContainSubstring("/post/draft/"))
+			Expect(preview("index.html")).To(ContainSubstring("/post/later/"))
+			Expect(preview("index.html")).ToNot(ContainSubstring("/post/live/"))
+
+			_, err := os.Stat("public-preview/post/live")
+			Expect(os.IsNotExist(err)).To(Equal(true))
+			_, err = os.Stat("public-preview/feed.xml")
+			Expect(os.IsNotExist(err)).To(Equal(true))
+		})
+
+		It("leaves the normal build alone", func() {
+			Expect(readPublic("post/live/index.html")).To(Equal("<h1>Live</h1>"))
+			Expect(readPublic("index.html")).ToNot(ContainSubstring("/post/draft/"))
+
+			manifest, err := LoadManifest(ManifestPath)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(manifest.Outputs).To(ContainElement("post/live/index.html"))
+		})
+
+		It("writes no draft or future node into public", func() {
+			for _, dir := range []string{"public/post/draft", "public/post/later"} {
+				_, err := os.Stat(dir)
+				Expect(os.IsNotExist(err)).To(Equal(true), dir)
+			}
+			Expect(readPublic("sitemap.xml")).ToNot(ContainSubstring("/post/later/"))
+			Expect(readPublic("index.html")).ToNot(ContainSubstring("/post/later/"))
+		})
+	})
+
+	Describe("taxonomies", func() {
+		BeforeEach(func() {
+			cleanup = withSite(map[string]string{
+				"baja.yaml":                "theme: t\ntaxonomies:\n  cuisine:\n    path: cuisines\n    index: true\n  categories: false\n",
+				"themes/t/terms.html":      `{{ define "content" }}{{ range .Terms }}{{ .Name }}:{{ .Count }}:{{ .Permalink }} {{ end }}{{ end }}`,
+				"content/recipe/pho.md":    "+++\ntitle = \"Pho\"\ncuisine = \"vietnamese\"\ncategories = [\"soup\"]\ntags = [\"beef\"]\n+++\nbody",
+				"content/recipe/banh.md":   "+++\ntitle = \"Banh mi\"\ncuisine = [\"vietnamese\", \"french\"]\n+++\nbody",
+				"content/recipe/hidden.md": "+++\ntitle = \"Hidden\"\ncuisine = \"secret\"\nunlisted = true\n+++\nbody",
+			})
+
+			Expect(Build(loadSite())).To(Succeed())
+		})
+
+		It("builds term pages of configured front matter keys", func() {
+			Expect(readPublic("cuisines/vietnamese/index.html")).To(ContainSubstring("Pho"))
+			Expect(readPublic("cuisines/vietnamese/index.html")).To(ContainSubstring("Banh mi"))
+			Expect(readPublic("cuisines/french/index.html")).ToNot(ContainSubstring("Pho"))
+			Expect(readPublic("tag/beef/index.html")).To(ContainSubstring("Pho"))
+
+			_, err := os.Stat("public/cuisines/secret")
+			Expect(os.IsNotExist(err)).To(Equal(true))
+		})
+
+		It("builds the terms index", func() {
+			Expect(readPublic("cuisines/index.html")).To(Equal("french:1:/cuisines/french/ vietnamese:2:/cuisines/vietnamese/ "))
+		})
+
+		It("has no page of a disabled taxonomy", func() {
+			_, err := os.Stat("public/categories")
+			Expect(os.IsNotExist(err)).To(Equal(true))
+		})
+	})
+
+	Describe("series", func() {
+		BeforeEach(func() {
+			cleanup = withSite(map[string]string{
+				"baja.yaml":            "theme: t\n",
+				"themes/t/node.html":   `{{ define "content" }}{{ .SeriesIndex }}/{{ len .Series }}|{{ with .SeriesPrev }}{{ .Meta.Title }}{{ end }}|{{ with .SeriesNext }}{{ .Permalink }}{{ end }}{{ end }}`,
+				"content/go/setup.md":  "+++\ntitle = \"Setup\"\nseries = \"Learn Go\"\ndate = 2019-01-03T00:00:00Z\n+++\nbody",
+				"content/go/types.md":  "+++\ntitle = \"Types\"\nseries = \"Learn Go\"\ndate = 2019-01-01T00:00:00Z\nweight = 2\n+++\nbody",
+				"content/tips/test.md": "+++\ntitle = \"Tests\"\nseries = \"Learn Go\"\ndate = 2019-01-02T00:00:00Z\nweight = 3\n+++\nbody",
+				"content/go/other.md":  "+++\ntitle = \"Other\"\n+++\nbody",
+			})
+
+			Expect(Build(loadSite())).To(Succeed())
+		})
+
+		It("orders the parts by weight then date across sections and links them", func() {
+			Expect(readPublic("go/setup/index.html")).To(Equal("1/3||/go/types/"))
+			Expect(readPublic("go/types/index.html")).To(Equal("2/3|Setup|/tips/test/"))
+			Expect(readPublic("tips/test/index.html")).To(Equal("3/3|Types|"))
+			Expect(readPublic("go/other/index.html")).To(Equal("0/0||"))
+		})
+
+		It("builds the page of the series", func() {
+			page := readPublic("series/Learn Go/index.html")
+			Expect(page).To(ContainSubstring("Setup"))
+			Expect(page).To(ContainSubstring("Tests"))
+			Expect(page).ToNot(ContainSubstring("Other"))
+		})
+	})
+
+	Describe("cache", func() {
+		BeforeEach(func() {
+			cleanup = withSite(map[string]string{
+				"baja.yaml":             "theme: t\ncacheDir: tmp/cache\n",
+				"content/post/hello.md": "+++\ntitle = \"Hello\"\n+++\n# Hello",
+			})
+
+			Expect(Build(loadSite())).To(Succeed())
+		})
+
+		cached := func() []string {
+			entries := []string{}
+			filepath.Walk("tmp/cache", func(path string, info os.FileInfo, err error) error {
+				if err == nil && !info.IsDir() {
+					entries = append(entries, path)
+				}
+				return err
+			})
+			return entries
+		}
+
+		It("reuses the markdown rendered by a previous build", func() {
+			entries := cached()
+			Expect(entries).ToNot(BeEmpty())
+			for _, entry := range entries {
+				Expect(ioutil.WriteFile(entry, []byte("<p>from cache</p>"), 0644)).To(Succeed())
+			}
+
+			Expect(Build(loadSite())).To(Succeed())
+			Expect(readPublic("post/hello/index.html")).To(ContainSubstring("from cache"))
+		})
+
+		It("renders changed content again", func() {
+			Expect(ioutil.WriteFile("content/post/hello.md", []byte("+++\ntitle = \"Hello\"\n+++\n# Changed"), 0644)).To(Succeed())
+
+			Expect(Build(loadSite())).To(Succeed())
+			Expect(readPublic("post/hello/index.html")).To(ContainSubstring("Changed"))
+		})
+
+		It("evicts entries over cacheMaxSize", func() {
+			Expect(ioutil.WriteFile("baja.yaml", []byte("theme: t\ncacheDir: tmp/cache\ncacheMaxSize: 1\n"), 0644)).To(Succeed())
+
+			Expect(Build(loadSite())).To(Succeed())
+			Expect(cached()).To(BeEmpty())
+		})
+	})
+
+	Describe("home template", func() {
+		BeforeEach(func() {
+			cleanup = withSite(map[string]string{
+				"baja.yaml":               "theme: t\ntitle: Notes\nmainSections: [blog]\n",
+				"themes/t/home.html":      `{{ define "content" }}<h1>{{ .Site.Title }}</h1>{{ range .Nodes }}<a href="{{ .Permalink }}">{{ .Meta.Title }}</a>{{ end }}<ul>{{ range .Recent }}<li>{{ .Meta.Title }}</li>{{ end }}</ul>{{ end }}`,
+				"content/blog/hello.md":   "+++\ntitle = \"Hello\"\ndate = 2019-02-09T00:00:00Z\n+++\nbody",
+				"content/docs/install.md": "+++\ntitle = \"Install\"\ndate = 2019-02-10T00:00:00Z\n+++\nbody",
+			})
+
+			Expect(Build(loadSite())).To(Succeed())
+		})
+
+		It("renders the home page with home.html and the recent nodes of every section", func() {
+			Expect(readPublic("index.html")).To(ContainSubstring(`<h1>Notes</h1><a href="/blog/hello/">Hello</a><ul><li>Install</li><li>Hello</li></ul>`))
+		})
+
+		It("keeps index.html for other indexes", func() {
+			Expect(readPublic("docs/index.html")).To(ContainSubstring(`<a href="/docs/install/">Install</a>`))
+			Expect(readPublic("docs/index.html")).ToNot(ContainSubstring("<ul>"))
+		})
+	})
+
+	Describe("build report", func() {
+		BeforeEach(func() {
+			cleanup = withSite(map[string]string{
+				"baja.yaml":              "theme: t\nbaseURL: https://example.com\nbuildReport: true\n",
+				"content/post/one.md":    "+++\ntitle = \"One\"\ntags = [\"go\"]\n+++\nbody",
+				"content/post/broken.md": "+++\ntitle = \n+++\nbody",
+			})
+		})
+
+		readReport := func(path string) *Report {
+			raw, err := ioutil.ReadFile(path)
+			Expect(err).ToNot(HaveOccurred())
+
+			report := &Report{}
+			Expect(json.Unmarshal(raw, report)).To(Succeed())
+			return report
+		}
+
+		It("writes counts, timings, feeds and problems of the build into public", func() {
+			BuildWithOptions(loadSite(), Options{Report: "report.json"})
+
+			report := readReport("public/" + ReportFile)
+			Expect(report.Errors).To(Equal(1))
+			Expect(report.Diagnostics[0].Path).To(Equal("content/post/broken.md"))
+			Expect(report.Stats.Posts).To(Equal(1))
+			Expect(report.Feeds).To(ContainElement("sitemap.xml"))
+			Expect(report.Feeds).To(ContainElement("feed.xml"))
+			Expect(report.Outputs).To(BeNumerically(">", 3))
+
+			phases := []string{}
+			for _, p := range report.Phases {
+				phases = append(phases, p.Name)
+			}
+			Expect(phases).To(Equal([]string{"scan", "nodes", "feeds", "assets", "manifest"}))
+
+			Expect(readReport("report.json")).To(Equal(report))
+		})
+
+		It("reports a config error", func() {
+			site := loadSite()
+			site.Config.BuildReport = false
+			site.Config.DuplicateSlugs = "rename"
+
+			Expect(BuildWithOptions(site, Options{Report: "report.json"})).ToNot(Succeed())
+			Expect(readReport("report.json").Error).To(ContainSubstring(`invalid duplicateSlugs "rename"`))
+		})
+	})
+
+	Describe("output paths", func() {
+		var site *baja.Site
+
+		BeforeEach(func() {
+			cleanup = withSite(map[string]string{
+				"baja.yaml":               "theme: t\noutputPaths: ascii\n",
+				"content/post/Crème 🍮.md": "+++\ntitle = \"Crème\"\n+++\nbody",
+				"content/post/plain.md":   "+++\ntitle = \"Plain\"\n+++\nbody",
+			})
+
+			site = loadSite()
+			Expect(Build(site)).To(Succeed())
+		})
+
+		It("writes nodes with unsafe names to a safe directory and reports them", func() {
+			Expect(readPublic("post/Creme/index.html")).To(ContainSubstring("<h1>Crème</h1>"))
+			Expect(readPublic("post/index.html")).To(ContainSubstring(`<a href="/post/Creme/">Crème</a>`))
+
+			Expect(site.Diagnostics.Items).To(HaveLen(1))
+			Expect(site.Diagnostics.Items[0].Path).To(Equal("content/post/Crème 🍮.md"))
+			Expect(site.Diagnostics.Items[0].Message).To(ContainSubstring(`"/post/Crème 🍮/" has characters unsafe in a file path, written to /post/Creme/`))
+		})
+	})
+
+	Describe("asset base url", func() {
+		BeforeEach(func() {
+			cleanup = withSite(map[string]string{
+				"baja.yaml":           "theme: t\nbaseURL: https://example.com/\nassetBaseURL: https://cdn.example.com/\n",
+				"themes/t/node.html":  `{{ define "content" }}<link href="{{ asset "/app.css" }}">{{ end }}`,
+				"content/post/one.md": "+++\ntitle = \"One\"\n+++\nbody",
+				"static/app.css":      "site",
+				"public/app.css":      "site",
+			})
+			Expect(BuildWithOptions(loadSite(), Options{Incremental: true})).To(Succeed())
+		})
+
+		It("links assets from the asset host and pages from baseURL", func() {
+			Expect(readPublic("post/one/index.html")).To(ContainSubstring(`<link href="https://cdn.example.com/app-fbae041b02c41ed0fd8a4efb039bc780dd6af4a1f0c420f42561ae705dda43fe.css">`))
+			Expect(readPublic("sitemap.xml")).To(ContainSubstring("https://example.com/post/one/"))
+		})
+	})
+
+	Describe("date formats", func() {
+		var site *baja.Site
+
+		BeforeEach(func() {
+			cleanup = withSite(map[string]string{
+				"baja.yaml":           "theme: t\ndateFormats:\n  default: January 2, 2006\n  listing: Jan 2\n",
+				"themes/t/node.html":  `{{ define "content" }}<time>{{ .Meta.DateFormatted }}</time><small>{{ dateFormat .Params.format .Meta.Date }}</small>{{ end }}`,
+				"content/post/one.md": "+++\ntitle = \"One\"\ndate = 2019-02-09T00:00:00Z\n[params]\nformat = \"listing\"\n+++\nbody",
+				"content/post/bad.md": "+++\ntitle = \"Bad\"\ndate = 2019-02-09T00:00:00Z\n[params]\nformat = \"short\"\n+++\nbody",
+			})
+
+			site = loadSite()
+			Build(site)
+		})
+
+		It("formats dates by name", func() {
+			Expect(readPublic("post/one/index.html")).To(ContainSubstring("<time>February 9, 2019</time><small>Feb 9</small>"))
+		})
+
+		It("fails a node using an unknown name, listing the available ones", func() {
+			Expect(site.Diagnostics.Items).To(HaveLen(1))
+			Expect(site.Diagnostics.Items[0].Path).To(Equal("content/post/bad.md"))
+			Expect(site.Diagnostics.Items[0].Message).To(ContainSubstring(`unknown date format "short", available: default, listing, rfc1123, rfc3339`))
+		})
+	})
+
+	Describe("outputs", func() {
+		BeforeEach(func() {
+			cleanup = withSite(map[string]string{
+				"baja.yaml":           "theme: t\nbaseURL: https://example.com\noutputs:\n  home: [html, json]\n  section: [html, rss]\n  page: [html, json]\n",
+				"content/post/one.md": "+++\ntitle = \"One\"\ntags = [\"go\"]\n+++\nbody",
+			})
+
+			Expect(Build(loadSite())).To(Succeed())
+		})
+
+		It("generates the formats of each page kind", func() {
+			Expect(readPublic("post/one/index.html")).To(ContainSubstring("<h1>One</h1>"))
+			Expect(readPublic("post/one/index.json")).To(ContainSubstring(`"title": "One"`))
+			Expect(readPublic("post/feed.xml")).To(ContainSubstring("https://example.com/post/one/"))
+			Expect(readPublic("index.json")).To(ContainSubstring(`"permalink": "/post/one/"`))
+			Expect(readPublic("tag/go/index.html")).To(ContainSubstring("/post/one/"))
+		})
+
+		It("writes the feed of a kind with only rss output", func() {
+			Expect(ioutil.WriteFile("baja.yaml", []byte("theme: t\nbaseURL: https://example.com\noutputs:\n  term: [rss]\n  author: [rss]\n"), 0644)).To(Succeed())
+			Expect(ioutil.WriteFile("content/post/one.md", []byte("+++\ntitle = \"One\"\ntags = [\"go\"]\nauthor = \"ann\"\n+++\nbody"), 0644)).To(Succeed())
+			Expect(Build(loadSite())).To(Succeed())
+
+			Expect(readPublic("tag/go/feed.xml")).To(ContainSubstring("https://example.com/post/one/"))
+			Expect(readPublic("authors/ann/feed.xml")).To(ContainSubstring("https://example.com/post/one/"))
+			_, err := os.Stat("public/tag/go/index.html")
+			Expect(os.IsNotExist(err)).To(Equal(true))
+		})
+
+		It("leaves out the formats a kind doesn't list", func() {
+			for _, path := range []string{"feed.xml", "tag/go/feed.xml"} {
+				_, err := os.Stat(filepath.Join("public", path))
+				Expect(os.IsNotExist(err)).To(Equal(true), path)
+			}
+		})
+
+		It("rejects unknown kinds and unsupported formats", func() {
+			err := baja.ValidateOutputs(map[string][]string{"taxonomy": {"rss"}, "list": {"html"}}, nil)
+			Expect(err).To(MatchError(ContainSubstring(`unknown page kind "list"`)))
+			Expect(err).To(MatchError(ContainSubstring(`output format "rss" of taxonomy is not supported, must be one of html`)))
+		})
+	})
+
+	Describe("custom output formats", func() {
+		BeforeEach(func() {
+			cleanup = withSite(map[string]string{
+				"baja.yaml": "theme: t\noutputs:\n  page: [html, ics, md]\noutputFormats:\n" +
+					"  ics:\n    baseName: event\n    mediaType: text/calendar\n  md:\n    suffix: txt\n    markdown: true\n",
+				"themes/t/events/node.ics": "BEGIN:VEVENT\r\nSUMMARY:{{ .Meta.Title }}\r\nDTSTART:{{ .Meta.Date.UTC.Format \"20060102T150405Z\" }}\r\n" +
+					"URL:{{ .Permalink }}\r\nDESCRIPTION:{{ .Raw }}\r\nEND:VEVENT\r\n",
+				"themes/t/node.txt":        "{{ .Body }}",
+				"content/events/launch.md": "+++\ntitle = \"Launch & party\"\ndate = 2019-02-09T18:00:00Z\n+++\n*Come*",
+				"content/post/one.md":      "+++\ntitle = \"One\"\n+++\nbody",
+			})
+
+			Expect(Build(loadSite())).To(Succeed())
+		})
+
+		It("templates a file of the format name with its suffix and base name", func() {
+			Expect(readPublic("events/launch/event.ics")).To(Equal("BEGIN:VEVENT\r\nSUMMARY:Launch & party\r\nDTSTART:20190209T180000Z\r\n" +
+				"URL:/events/launch/\r\nDESCRIPTION:\n*Come*\r\nEND:VEVENT\r\n"))
+			Expect(readPublic("events/launch/index.html")).ToNot(BeEmpty())
+		})
+
+		It("renders markdown for the formats asking for it", func() {
+			Expect(readPublic("post/one/index.txt")).To(Equal("<p>body</p>\n"))
+		})
+
+		It("leaves out the nodes without a template", func() {
+			_, err := os.Stat("public/post/one/event.ics")
+			Expect(os.IsNotExist(err)).To(BeTrue())
+		})
+
+		It("rejects built in names and invalid fields", func() {
+			err := baja.ValidateOutputs(map[string][]string{"page": {"ics"}, "section": {"ics"}}, map[string]baja.OutputFormat{
+				"json": {},
+				"ics":  {Suffix: ".ics", MediaType: "calendar"},
+			})
+			Expect(err).To(MatchError(ContainSubstring("output format json is built in")))
+			Expect(err).To(MatchError(ContainSubstring(`invalid suffix ".ics" of output format ics`)))
+			Expect(err).To(MatchError(ContainSubstring(`invalid mediaType "calendar" of output format ics`)))
+			Expect(err).To(MatchError(ContainSubstring(`output format "ics" of section is not supported`)))
+		})
+	})
+
+	Describe("content mounts", func() {
+		var (
+			site *baja.Site
+			err  error
+		)
+
+		BeforeEach(func() {
+			cleanup = withSite(map[string]string{
+				"baja.yaml": "theme: t\nignoreFiles: [drafts]\nmounts:\n  - source: changelog-repo\n    target: changelog\n" +
+					"  - source: old-repo\n    target: changelog\n",
+				"content/post/one.md":         "+++\ntitle = \"One\"\n+++\nbody",
+				"changelog-repo/_index.md":    "+++\ntitle = \"Releases\"\n+++\nAll releases",
+				"changelog-repo/v1.md":        "+++\ntitle = \"Version 1\"\n+++\nfirst",
+				"changelog-repo/v2/index.md":  "+++\ntitle = \"Version 2\"\n+++\n![shot](shot.png)",
+				"changelog-repo/v2/shot.png":  "png",
+				"changelog-repo/drafts/v3.md": "+++\ntitle = \"Version 3\"\n+++\nnext",
+				"old-repo/v1.md":              "+++\ntitle = \"Old version 1\"\n+++\nold",
+			})
+
+			site = loadSite()
+			Expect(site.Config.Validate()).To(Succeed())
+			err = Build(site)
+		})
+
+		It("builds the files of a mount in its target directory", func() {
+			Expect(readPublic("changelog/v1/index.html")).To(ContainSubstring("<h1>Version 1</h1>"))
+			Expect(readPublic("changelog/v2/index.html")).To(ContainSubstring("<h1>Version 2</h1>"))
+			Expect(readPublic("changelog/v2/shot.png")).To(Equal("png"))
+			Expect(readPublic("changelog/index.html")).To(ContainSubstring("/changelog/v1/"))
+			Expect(readPublic("index.html")).To(ContainSubstring("/post/one/"))
+		})
+
+		It("applies ignoreFiles relative to the mount", func() {
+			_, err := os.Stat("public/changelog/drafts")
+			Expect(os.IsNotExist(err)).To(Equal(true))
+		})
+
+		It("fails a node at the permalink of a node of another mount", func() {
+			Expect(baja.ExitCode(err)).To(Equal(baja.ExitContentError))
+			Expect(readPublic("changelog/v1/index.html")).ToNot(ContainSubstring("Old version 1"))
+			Expect(site.Diagnostics.Items).To(HaveLen(1))
+			Expect(site.Diagnostics.Items[0].Path).To(Equal("old-repo/v1.md"))
+			Expect(site.Diagnostics.Items[0].Message).To(ContainSubstring("permalink /changelog/v1/ of old-repo/v1.md is also the permalink of changelog-repo/v1.md"))
+		})
+
+		It("rejects a target outside of content", func() {
+			Expect(baja.ValidateMounts([]baja.Mount{{Source: "changelog-repo", Target: "../x"}, {Source: "missing", Target: "x"}})).To(HaveLen(2))
+		})
+	})
+
+	Describe("page bundle", func() {
+		bundle := map[string]string{
+			"content/post/trip/index.md":      "+++\ntitle = \"Trip\"\n+++\n![map](map.png)",
+			"content/post/trip/map.png":       "png",
+			"content/post/trip/raw/video.mp4": "mp4",
+		}
+
+		It("is rendered at the url of its directory with every resource", func() {
+			cleanup = withSite(bundle)
+			Expect(Build(loadSite())).To(Succeed())
+
+			Expect(readPublic("post/trip/index.html")).To(ContainSubstring(`<img src="map.png"`))
+			Expect(readPublic("post/trip/map.png")).To(Equal("png"))
+			Expect(readPublic("post/trip/raw/video.mp4")).To(Equal("mp4"))
+			Expect(readPublic("post/index.html")).To(ContainSubstring("/post/trip/"))
+		})
+
+		It("lists its resources in the template data and not as nodes", func() {
+			files := map[string]string{
+				"baja.yaml":                     "theme: t\n",
+				"themes/t/node.html":            `{{ define "content" }}{{ range .Resources }}{{ .Name }} {{ .URL }} {{ .Size }};{{ end }}{{ end }}`,
+				"content/post/trip/my notes.md": "not a node",
+			}
+			for k, v := range bundle {
+				files[k] = v
+			}
+			cleanup = withSite(files)
+			Expect(Build(loadSite())).To(Succeed())
+
+			Expect(readPublic("post/trip/index.html")).To(Equal("map.png map.png 3;my notes.md my%20notes.md 10;raw/video.mp4 raw/video.mp4 3;"))
+			Expect(readPublic("post/trip/my notes.md")).To(Equal("not a node"))
+			_, err := os.Stat("public/post/trip/my-notes")
+			Expect(os.IsNotExist(err)).To(Equal(true))
+		})
+
+		It("copies only referenced resources when configured", func() {
+			files := map[string]string{"baja.yaml": "theme: t\nresources: referenced\n"}
+			for k, v := range bundle {
+				files[k] = v
+			}
+			cleanup = withSite(files)
+			Expect(Build(loadSite())).To(Succeed())
+
+			Expect(readPublic("post/trip/map.png")).To(Equal("png"))
+			_, err := os.Stat("public/post/trip/raw/video.mp4")
+			Expect(os.IsNotExist(err)).To(Equal(true))
+		})
+	})
+
+	Describe("shortcodes", func() {
+		shortcodes := map[string]string{
+			"themes/t/node.html":               `{{ define "content" }}{{ .Body }}{{ end }}`,
+			"themes/t/shortcodes/youtube.html": `<iframe src="https://www.youtube.com/embed/{{ index .Params 0 }}" title="{{ .Get "title" }} on {{ .Page.Meta.Title }}"></iframe>`,
+			"themes/t/shortcodes/note.html":    `<div class="note {{ .Get "type" }}">{{ markdownify .Inner }}</div>`,
+		}
+
+		It("expands theme shortcodes with their params, inner content and node", func() {
+			files := map[string]string{
+				"content/post/one.md": "+++\ntitle = \"One\"\n+++\nIntro\n\n{{< youtube dQw4w9WgXcQ title=\"A \\\"song\\\"\" >}}\n\n{{< note type=warning >}}\nMind **this** {{< youtube abc >}}\n{{< /note >}}\n",
+			}
+			for k, v := range shortcodes {
+				files[k] = v
+			}
+			cleanup = withSite(files)
+			Expect(Build(loadSite())).To(Succeed())
+
+			page := readPublic("post/one/index.html")
+			Expect(page).To(ContainSubstring(`<iframe src="https://www.youtube.com/embed/dQw4w9WgXcQ" title="A &#34;song&#34; on One"></iframe>`))
+			Expect(page).To(ContainSubstring(`<div class="note warning"><p>Mind <strong>this</strong> <iframe src="https://www.youtube.com/embed/abc"`))
+			Expect(page).ToNot(ContainSubstring("{{<"))
+		})
+
+		It("prefers the shortcodes of the site over the theme ones", func() {
+			files := map[string]string{
+				"shortcodes/youtube.html": `<video>{{ .Get 0 }}</video>`,
+				"content/post/one.md":     "+++\ntitle = \"One\"\n+++\n{{< youtube abc >}}\n",
+			}
+			for k, v := range shortcodes {
+				files[k] = v
+			}
+			cleanup = withSite(files)
+			Expect(Build(loadSite())).To(Succeed())
+
+			Expect(readPublic("post/one/index.html")).To(ContainSubstring("<video>abc</video>"))
+		})
+
+		It("ships figure, gist, tweet and toot", func() {
+			cleanup = withSite(map[string]string{
+				"themes/t/node.html": `{{ define "content" }}{{ .Body }}{{ end }}`,
+				"content/post/one.md": "+++\ntitle = \"One\"\n+++\n" +
+					"{{< figure src=\"map.png\" alt=\"Map\" caption=\"The route\" link=\"big.png\" class=\"wide\" >}}\n\n" +
+					"{{< gist yeo 0123abcd main.go >}}\n\n" +
+					"{{< tweet yeo 1234 >}}Shipped!{{< /tweet >}}\n\n" +
+					"{{< toot https://mastodon.social/@yeo/5678 >}}\n",
+			})
+			Expect(Build(loadSite())).To(Succeed())
+
+			page := readPublic("post/one/index.html")
+			Expect(page).To(ContainSubstring(`<figure class="wide"><a href="big.png"><img src="map.png" alt="Map"></a><figcaption>The route</figcaption></figure>`))
+			Expect(page).To(ContainSubstring(`<script src="https://gist.github.com/yeo/0123abcd.js?file=main.go"></script>`))
+			Expect(page).To(ContainSubstring(`<blockquote class="twitter-tweet"><p>Shipped!</p><a href="https://twitter.com/yeo/status/1234">`))
+			Expect(page).To(ContainSubstring(`<blockquote class="mastodon-toot"><a href="https://mastodon.social/@yeo/5678">`))
+			Expect(page).ToNot(ContainSubstring("platform.twitter.com"))
+			Expect(page).ToNot(ContainSubstring("<iframe"))
+		})
+
+		It("loads the scripts of tweet and toot with embedScripts", func() {
+			cleanup = withSite(map[string]string{
+				"baja.yaml":           "theme: t\nembedScripts: true\n",
+				"themes/t/node.html":  `{{ define "content" }}{{ .Body }}{{ end }}`,
+				"content/post/one.md": "+++\ntitle = \"One\"\n+++\n{{< tweet yeo 1234 >}}\n\n{{< toot https://mastodon.social/@yeo/5678 >}}\n",
+			})
+			Expect(Build(loadSite())).To(Succeed())
+
+			page := readPublic("post/one/index.html")
+			Expect(page).To(ContainSubstring(`<script async src="https://platform.twitter.com/widgets.js"`))
+			Expect(page).To(ContainSubstring(`<iframe src="https://mastodon.social/@yeo/5678/embed"`))
+		})
+
+		It("lets the theme override a built-in shortcode", func() {
+			cleanup = withSite(map[string]string{
+				"themes/t/node.html":              `{{ define "content" }}{{ .Body }}{{ end }}`,
+				"themes/t/shortcodes/figure.html": `<picture>{{ .Get "src" }}</picture>`,
+				"content/post/one.md":             "+++\ntitle = \"One\"\n+++\n{{< figure src=\"map.png\" >}}\n",
+			})
+			Expect(Build(loadSite())).To(Succeed())
+
+			Expect(readPublic("post/one/index.html")).To(ContainSubstring("<picture>map.png</picture>"))
+		})
+
+		It("fails on an unknown shortcode naming the node and the line", func() {
+			files := map[string]string{
+				"content/post/one.md": "+++\ntitle = \"One\"\n+++\nIntro\n\n{{< vimeo 123 >}}\n",
+			}
+			for k, v := range shortcodes {
+				files[k] = v
+			}
+			cleanup = withSite(files)
+
+			site := loadSite()
+			Expect(Build(site)).To(HaveOccurred())
+			Expect(site.Diagnostics.Items).To(HaveLen(1))
+			Expect(site.Diagnostics.Items[0].Message).To(ContainSubstring(`content/post/one.md:6: unknown shortcode "vimeo", create shortcodes/vimeo.html or `))
+		})
+	})
+
+	Describe("related nodes", func() {
+		related := map[string]string{
+			"themes/t/node.html":     `{{ define "content" }}{{ range .Related }}{{ .Meta.Title }},{{ end }}{{ end }}`,
+			"content/post/go.md":     "+++\ntitle = \"Go\"\ndate = 2019-01-10T00:00:00Z\ntags = [\"go\", \"web\"]\n+++\nbody",
+			"content/post/web.md":    "+++\ntitle = \"Web\"\ndate = 2019-01-01T00:00:00Z\ntags = [\"web\"]\n+++\nbody",
+			"content/post/both.md":   "+++\ntitle = \"Both\"\ndate = 2019-01-02T00:00:00Z\ntags = [\"go\", \"web\"]\n+++\nbody",
+			"content/post/jan9.md":   "+++\ntitle = \"Jan9\"\ndate = 2019-01-09T00:00:00Z\n+++\nbody",
+			"content/post/jan20.md":  "+++\ntitle = \"Jan20\"\ndate = 2019-01-20T00:00:00Z\n+++\nbody",
+			"content/post/hidden.md": "+++\ntitle = \"Hidden\"\ndate = 2019-01-11T00:00:00Z\nunlisted = true\n+++\nbody",
+			"content/note/jan10.md":  "+++\ntitle = \"Note\"\ndate = 2019-01-10T00:00:00Z\ntags = [\"go\"]\n+++\nbody",
+		}
+
+		build := func(config string) {
+			files := map[string]string{"baja.yaml": "theme: t\n" + config}
+			for k, v := range related {
+				files[k] = v
+			}
+			cleanup = withSite(files)
+			Expect(Build(loadSite())).To(Succeed())
+		}
+
+		It("lists nodes sharing tags first, then the section closest by date", func() {
+			build("relatedPosts: 5\n")
+
+			Expect(readPublic("post/go/index.html")).To(Equal("Both,Note,Web,Jan9,Jan20,"))
+			Expect(readPublic("post/jan9/index.html")).To(Equal("Go,Both,Web,Jan20,"))
+		})
+
+		It("lists the undated nodes of the section last", func() {
+			related["content/post/undated.md"] = "+++\ntitle = \"Undated\"\n+++\nbody"
+			defer delete(related, "content/post/undated.md")
+			build("relatedPosts: 6\n")
+
+			Expect(readPublic("post/go/index.html")).To(Equal("Both,Note,Web,Jan9,Jan20,Undated,"))
+			Expect(readPublic("post/jan20/index.html")).To(Equal("Go,Jan9,Both,Web,Undated,"))
+			Expect(readPublic("post/undated/index.html")).To(Equal("Jan20,Go,Jan9,Both,Web,"))
+		})
+
+		It("keeps to relatedPosts and relatedBy", func() {
+			build("relatedPosts: 2\nrelatedBy: [tags]\n")
+
+			Expect(readPublic("post/go/index.html")).To(Equal("Both,Note,"))
+			Expect(readPublic("post/jan9/index.html")).To(BeEmpty())
+		})
+	})
+
+	Describe("site env", func() {
+		BeforeEach(func() {
+			os.Setenv("BAJA_TEST_COMMIT_SHA", "abc123")
+			os.Setenv("BAJA_TEST_SECRET", "hunter2")
+			cleanup = withSite(map[string]string{
+				"baja.yaml":           "theme: t\nenv: [BAJA_TEST_COMMIT_SHA, BAJA_TEST_BUILD]\n",
+				"themes/t/node.html":  `{{ define "content" }}sha={{ .Site.Env.BAJA_TEST_COMMIT_SHA }} build={{ .Site.Env.BAJA_TEST_BUILD }} secret={{ .Site.Env.BAJA_TEST_SECRET }}{{ end }}`,
+				"content/post/one.md": "+++\ntitle = \"One\"\n+++\nbody",
+			})
+
+			Expect(Build(loadSite())).To(Succeed())
+		})
+
+		AfterEach(func() {
+			os.Unsetenv("BAJA_TEST_COMMIT_SHA")
+			os.Unsetenv("BAJA_TEST_SECRET")
+		})
+
+		It("exposes only the allowlisted variables, empty when unset", func() {
+			Expect(readPublic("post/one/index.html")).To(Equal("sha=abc123 build= secret="))
+		})
+	})
+
+	Describe("node without body", func() {
+		It("is rendered by its template alone", func() {
+			cleanup = withSite(map[string]string{
+				"themes/t/node.html":      `{{ define "content" }}<h1>{{ .Meta.Title }}</h1>{{ if .HasContent }}<article>{{ .Body }}</article>{{ end }}{{ end }}`,
+				"content/event/launch.md": "+++\ntitle = \"Launch\"\n[params]\nvenue = \"Hall\"\n+++\n",
+				"content/event/recap.md":  "+++\ntitle = \"Recap\"\n+++\nIt went well",
+			})
+			site := loadSite()
+			Expect(Build(site)).To(Succeed())
+
+			Expect(site.Diagnostics.Items).To(BeEmpty())
+			Expect(readPublic("event/launch/index.html")).To(Equal("<h1>Launch</h1>"))
+			Expect(readPublic("event/recap/index.html")).To(ContainSubstring("<article><p>It went well</p>"))
+		})
+	})
+
+	Describe("node without template", func() {
+		It("is rendered with _default/single.html", func() {
+			cleanup = withSite(map[string]string{
+				"themes/t/_default/single.html": `{{ define "content" }}single {{ .Meta.Title }}{{ end }}`,
+				"content/post/one.md":           "+++\ntitle = \"One\"\n+++\nbody",
+			})
+			Expect(os.Remove("themes/t/node.html")).To(Succeed())
+			Expect(Build(loadSite())).To(Succeed())
+
+			Expect(readPublic("post/one/index.html")).To(Equal("single One"))
+		})
+
+		It("fails naming the node and the templates searched", func() {
+			cleanup = withSite(map[string]string{
+				"content/post/one.md": "+++\ntitle = \"One\"\n+++\nbody",
+			})
+			Expect(os.Remove("themes/t/node.html")).To(Succeed())
+
+			site := loadSite()
+			Expect(Build(site)).To(HaveOccurred())
+			Expect(site.Diagnostics.Items).To(HaveLen(1))
+			Expect(site.Diagnostics.Items[0].Message).To(ContainSubstring("no node template for content/post/one.md"))
+			Expect(site.Diagnostics.Items[0].Message).To(ContainSubstring("themes/t/node.html"))
+			Expect(site.Diagnostics.Items[0].Message).To(ContainSubstring("themes/t/_default/single.html"))
+		})
+	})
+
+	Describe("bundle images", func() {
+		var photo bytes.Buffer
+		png.Encode(&photo, image.NewRGBA(image.Rect(0, 0, 40, 30)))
+
+		It("resizes and crops them into the output directory of the page", func() {
+			cleanup = withSite(map[string]string{
+				"baja.yaml":                   "theme: t\ncacheDir: tmp/cache\n",
+				"themes/t/node.html":          `{{ define "content" }}{{ with .Resources.Get "photo.png" }}{{ (.Resize 20).RelPermalink }} {{ (.Fill 10 10).URL }}{{ end }}{{ end }}`,
+				"content/post/trip/index.md":  "+++\ntitle = \"Trip\"\n+++\nbody",
+				"content/post/trip/photo.png": photo.String(),
+				"content/post/other/index.md": "+++\ntitle = \"Other\"\n+++\nbody",
+			})
+			Expect(Build(loadSite())).To(Succeed())
+
+			Expect(readPublic("post/trip/index.html")).To(Equal("/post/trip/photo_20w.png photo_10x10.png"))
+			Expect(readPublic("post/other/index.html")).To(BeEmpty())
+			for name, size := range map[string]image.Point{"photo_20w.png": {20, 15}, "photo_10x10.png": {10, 10}} {
+				config, err := png.DecodeConfig(strings.NewReader(readPublic("post/trip/" + name)))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(image.Pt(config.Width, config.Height)).To(Equal(size), name)
+			}
+
+			// derived images come from the cache once processed
+			cached, _ := filepath.Glob("tmp/cache/*/*")
+			Expect(cached).To(HaveLen(4))
+			Expect(os.RemoveAll("public")).To(Succeed())
+			Expect(Build(loadSite())).To(Succeed())
+			Expect(readPublic("post/trip/photo_20w.png")).ToNot(BeEmpty())
+		})
+
+		It("fails naming an image it can't process", func() {
+			cleanup = withSite(map[string]string{
+				"themes/t/node.html":           `{{ define "content" }}{{ (.Resources.Get "photo.webp").Resize 20 }}{{ end }}`,
+				"content/post/trip/index.md":   "+++\ntitle = \"Trip\"\n+++\nbody",
+				"content/post/trip/photo.webp": "RIFF\x00\x00\x00\x00WEBPVP8 ",
+			})
+
+			site := loadSite()
+			Expect(Build(site)).To(HaveOccurred())
+			Expect(site.Diagnostics.Items).To(HaveLen(1))
+			Expect(site.Diagnostics.Items[0].Message).To(ContainSubstring("content/post/trip/photo.webp: unsupported image format"))
+		})
+	})
+
+	Describe("permalinks", func() {
+		BeforeEach(func() {
+			cleanup = withSite(map[string]string{
+				"baja.yaml":            "theme: t\npermalinks:\n  post: /:section/:year/:title/\n",
+				"content/post/one.md":  "+++\ntitle = \"First One\"\ndate = 2019-02-09T10:00:00Z\n+++\nbody",
+				"content/note/note.md": "+++\ntitle = \"Note\"\n+++\nbody",
+			})
+
+			Expect(Build(loadSite())).To(Succeed())
+		})
+
+		It("writes nodes of a section at their pattern", func() {
+			Expect(readPublic("post/2019/first-one/index.html")).To(ContainSubstring("First One"))
+			Expect(readPublic("post/index.html")).To(ContainSubstring(`href="/post/2019/first-one/"`))
+			Expect(readPublic("note/note/index.html")).To(ContainSubstring("Note"))
+		})
+	})
+
+	Describe("ignored files", func() {
+		It("are never parsed", func() {
+			cleanup = withSite(map[string]string{
+				"baja.yaml":                          "theme: t\nignoreFiles:\n  - node_modules\n  - re:^post/.*\\.tmpl\\.md$\n",
+				"content/post/one.md":                "+++\ntitle = \"One\"\n+++\nbody",
+				"content/post/draft.tmpl.md":         "no front matter",
+				"content/post/node_modules/x/doc.md": "no front matter",
+			})
+			site := loadSite()
+
+			Expect(Build(site)).To(Succeed())
+			Expect(site.Diagnostics.Items).To(BeEmpty())
+			Expect(readPublic("post/one/index.html")).To(ContainSubstring("One"))
+
+			_, err := os.Stat("public/post/node_modules")
+			Expect(os.IsNotExist(err)).To(Equal(true))
+		})
+	})
+
+	Describe("content guards", func() {
+		It("skips files over maxContentSize with a warning", func() {
+			cleanup = withSite(map[string]string{
+				"baja.yaml":             "theme: t\nmaxContentSize: 100\n",
+				"content/post/small.md": "+++\ntitle = \"Small\"\n+++\nbody",
+				"content/post/huge.md":  "+++\ntitle = \"Huge\"\n+++\n" + strings.Repeat("- item\n", 100),
+			})
+			site := loadSite()
+
+			Expect(Build(site)).To(Succeed())
+			Expect(readPublic("post/small/index.html")).To(ContainSubstring("Small"))
+			Expect(site.Diagnostics.Items).To(HaveLen(1))
+			Expect(site.Diagnostics.Items[0].Path).To(Equal("content/post/huge.md"))
+			Expect(site.Diagnostics.Items[0].Severity).To(Equal(baja.SeverityWarning))
+
+			_, err := os.Stat("public/post/huge")
+			Expect(os.IsNotExist(err)).To(Equal(true))
+		})
+
+		It("fails a node whose markdown is over renderTimeout", func() {
+			cleanup = withSite(map[string]string{
+				"baja.yaml":            "theme: t\nrenderTimeout: 1ns\n",
+				"content/post/slow.md": "+++\ntitle = \"Slow\"\n+++\n" + strings.Repeat("| a | b |\n|---|---|\n| *x* | **y** |\n", 2000),
+			})
+			site := loadSite()
+
+			err := Build(site)
+			Expect(baja.ExitCode(err)).To(Equal(baja.ExitContentError))
+			Expect(site.Diagnostics.Items[0].Path).To(Equal("content/post/slow.md"))
+			Expect(site.Diagnostics.Items[0].Message).To(ContainSubstring("renderTimeout"))
+		})
+	})
+
+	Describe("includes", func() {
+		BeforeEach(func() {
+			cleanup = withSite(map[string]string{
+				"baja.yaml":                   "theme: t\nignoreFiles: [snippets]\n",
+				"content/docs/setup.md":       "+++\ntitle = \"Setup\"\n+++\nFirst\n\n{{< include \"snippets/install.md\" >}}\n\nLast",
+				"content/snippets/install.md": "Run *go get*\n\n{{< include \"snippets/version.md\" >}}",
+				"content/snippets/version.md": "Go 1.13",
+			})
+		})
+
+		It("renders included markdown in place", func() {
+			Expect(Build(loadSite())).To(Succeed())
+
+			Expect(readPublic("docs/setup/index.html")).To(ContainSubstring("<p>First</p>\n\n<p>Run <em>go get</em></p>\n\n<p>Go 1.13</p>\n\n<p>Last</p>"))
+		})
+
+		It("reports a missing include with the including file", func() {
+			Expect(os.Remove("content/snippets/version.md")).To(Succeed())
+			site := loadSite()
+
+			err := Build(site)
+			Expect(baja.ExitCode(err)).To(Equal(baja.ExitContentError))
+			Expect(site.Diagnostics.Items[0].Path).To(Equal("content/docs/setup.md"))
+			Expect(site.Diagnostics.Items[0].Message).To(ContainSubstring("include in content/snippets/install.md: snippets/version.md not found"))
+		})
+	})
+
+	Describe("duplicate slugs", func() {
+		posts := map[string]string{
+			"content/post/hello.md":       "+++\ntitle = \"Markdown\"\n+++\nbody",
+			"content/post/Hello.html":     "+++\ntitle = \"Html\"\n+++\nbody",
+			"content/post/hello-world.md": "+++\ntitle = \"World\"\n+++\nbody",
+			"content/note/hello.md":       "+++\ntitle = \"Note\"\n+++\nbody",
+		}
+
+		It("renames the later node with a numeric suffix", func() {
+			cleanup = withSite(posts)
+			site := loadSite()
+			Expect(Build(site)).To(Succeed())
+
+			Expect(readPublic("post/Hello/index.html")).To(ContainSubstring("Html"))
+			Expect(readPublic("post/hello-2/index.html")).To(ContainSubstring("Markdown"))
+			Expect(readPublic("note/hello/index.html")).To(ContainSubstring("Note"))
+			Expect(site.Diagnostics.Items).To(ConsistOf(baja.Diagnostic{
+				Path:     "content/post/hello.md",
+				Severity: baja.SeverityWarning,
+				Message:  `slug "hello" is also the slug of content/post/Hello.html, renamed to hello-2`,
+			}))
+		})
+
+		It("fails reporting both files when configured", func() {
+			files := map[string]string{"baja.yaml": "theme: t\nduplicateSlugs: error\n"}
+			for k, v := range posts {
+				files[k] = v
+			}
+			cleanup = withSite(files)
+			site := loadSite()
+
+			err := Build(site)
+			Expect(baja.ExitCode(err)).To(Equal(baja.ExitContentError))
+
+			paths := []string{}
+			for _, item := range site.Diagnostics.Items {
+				paths = append(paths, item.Path)
+			}
+			Expect(paths).To(ConsistOf("content/post/hello.md", "content/post/Hello.html"))
+		})
+	})
+
+	Describe("duplicate author slugs", func() {
+		It("reports the nodes of the later author", func() {
+			cleanup = withSite(map[string]string{
+				"content/post/one.md":   "+++\ntitle = \"One\"\nauthor = \"Bob Smith\"\n+++\nbody",
+				"content/post/two.md":   "+++\ntitle = \"Two\"\nauthors = [\"ann\", \"bob-smith\"]\n+++\nbody",
+				"content/post/three.md": "+++\ntitle = \"Three\"\nauthor = \"ann\"\n+++\nbody",
+			})
+			site := loadSite()
+
+			err := Build(site)
+			Expect(baja.ExitCode(err)).To(Equal(baja.ExitContentError))
+			Expect(site.Diagnostics.Items).To(ConsistOf(baja.Diagnostic{
+				Path:     "content/post/two.md",
+				Severity: baja.SeverityError,
+				Message:  `author "bob-smith" has the slug "bob-smith" of author "Bob Smith", use one of them or rename it`,
+			}))
+		})
+	})
+
+	Describe("only a section", func() {
+		BeforeEach(func() {
+			cleanup = withSite(map[string]string{
+				"content/blog/a.md": "+++\ntitle = \"A\"\ntags = [\"go\"]\n+++\nsee [news](/news/b/)",
+				"content/news/b.md": "+++\ntitle = \"B\"\ntags = [\"press\"]\n+++\nbody",
+				"static/app.css":    "body {}",
+			})
+
+			Expect(Build(loadSite())).To(Succeed())
+			for _, path := range []string{"public/news/b/index.html", "public/news/index.html", "public/tag/press/index.html"} {
+				Expect(ioutil.WriteFile(path, []byte("previous"), 0644)).To(Succeed())
+			}
+			Expect(ioutil.WriteFile("content/blog/a.md", []byte("+++\ntitle = \"A2\"\ntags = [\"go\"]\n+++\nbody"), 0644)).To(Succeed())
+		})
+
+		It("renders the nodes of the section and the indexes listing them", func() {
+			Expect(BuildWithOptions(loadSite(), Options{Only: "content/blog"})).To(Succeed())
+
+			Expect(readPublic("blog/a/index.html")).To(ContainSubstring("A2"))
+			Expect(readPublic("blog/index.html")).To(ContainSubstring("A2"))
+			Expect(readPublic("tag/go/index.html")).To(ContainSubstring("A2"))
+			Expect(readPublic("index.html")).To(ContainSubstring("A2"))
+			Expect(readPublic("index.html")).To(ContainSubstring("/news/b/"))
+			Expect(readPublic("feed.xml")).To(ContainSubstring("A2"))
+		})
+
+		It("leaves other sections of public untouched", func() {
+			Expect(BuildWithOptions(loadSite(), Options{Only: "blog"})).To(Succeed())
+
+			Expect(readPublic("news/b/index.html")).To(Equal("previous"))
+			Expect(readPublic("news/index.html")).To(Equal("previous"))
+			Expect(readPublic("tag/press/index.html")).To(Equal("previous"))
+			Expect(readPublic("app.css")).To(Equal("body {}"))
+		})
+
+		It("rejects a path outside of content", func() {
+			err := BuildWithOptions(loadSite(), Options{Only: "content/missing"})
+			Expect(baja.ExitCode(err)).To(Equal(baja.ExitConfigError))
+
+			err = BuildWithOptions(loadSite(), Options{Only: "../elsewhere"})
+			Expect(baja.ExitCode(err)).To(Equal(baja.ExitConfigError))
+		})
+	})
+
+	Describe("since a git ref", func() {
+		BeforeEach(func() {
+			cleanup = withSite(map[string]string{
+				"content/blog/a.md":   "+++\ntitle = \"A\"\n+++\nbody",
+				"content/blog/old.md": "+++\ntitle = \"Old\"\n+++\nbody",
+				"content/news/b.md":   "+++\ntitle = \"B\"\n+++\nbody",
+			})
+
+			repo, err := git.PlainInit(".", false)
+			Expect(err).NotTo(HaveOccurred())
+			wt, err := repo.Worktree()
+			Expect(err).NotTo(HaveOccurred())
+			_, err = wt.Add("content")
+			Expect(err).NotTo(HaveOccurred())
+			_, err = wt.Commit("content", &git.CommitOptions{Author: &object.Signature{Name: "baja", Email: "baja@example.com", When: time.Now()}})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(Build(loadSite())).To(Succeed())
+			Expect(ioutil.WriteFile("public/news/b/index.html", []byte("previous"), 0644)).To(Succeed())
+			Expect(ioutil.WriteFile("content/blog/a.md", []byte("+++\ntitle = \"A2\"\n+++\nbody"), 0644)).To(Succeed())
+			Expect(ioutil.WriteFile("content/blog/new.md", []byte("+++\ntitle = \"New\"\n+++\nbody"), 0644)).To(Succeed())
+			Expect(os.Remove("content/blog/old.md")).To(Succeed())
+		})
+
+		It("rebuilds the changed and added nodes and the indexes listing them", func() {
+			Expect(BuildWithOptions(loadSite(), Options{Since: "HEAD"})).To(Succeed())
+
+			Expect(readPublic("blog/a/index.html")).To(ContainSubstring("A2"))
+			Expect(readPublic("blog/new/index.html")).To(ContainSubstring("New"))
+			Expect(readPublic("blog/index.html")).To(ContainSubstring("New"))
+			Expect(readPublic("blog/index.html")).NotTo(ContainSubstring("Old"))
+			Expect(readPublic("news/b/index.html")).To(Equal("previous"))
+		})
+
+		It("removes the pages of deleted content", func() {
+			Expect(BuildWithOptions(loadSite(), Options{Since: "HEAD"})).To(Succeed())
+
+			_, err := os.Stat("public/blog/old")
+			Expect(os.IsNotExist(err)).To(BeTrue())
+
+			manifest, err := LoadManifest(ManifestPath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(manifest.Outputs).NotTo(ContainElement("blog/old/index.html"))
+			Expect(manifest.Outputs).To(ContainElement("news/b/index.html"))
+		})
+
+		It("rejects an unknown ref or its use with only", func() {
+			err := BuildWithOptions(loadSite(), Options{Since: "nope"})
+			Expect(baja.ExitCode(err)).To(Equal(baja.ExitConfigError))
+
+			err = BuildWithOptions(loadSite(), Options{Since: "HEAD", Only: "blog"})
+			Expect(baja.ExitCode(err)).To(Equal(baja.ExitConfigError))
+		})
+
+		It("fails outside of a git repository", func() {
+			Expect(os.RemoveAll(".git")).To(Succeed())
+
+			err := BuildWithOptions(loadSite(), Options{Since: "HEAD"})
+			Expect(baja.ExitCode(err)).To(Equal(baja.ExitConfigError))
+			Expect(err.Error()).To(ContainSubstring("not a git repository"))
+		})
+	})
+
+	Describe("site settings", func() {
+		BeforeEach(func() {
+			cleanup = withSite(map[string]string{
+				"baja.yaml":           "theme: t\ntitle: Notes\ndescription: About things\nbaseURL: https://example.com\nlanguage: en\n",
+				"themes/t/node.html":  `{{ define "content" }}{{ .Site.Title }}|{{ .Site.Language }}{{ end }}`,
+				"themes/t/index.html": `{{ define "content" }}{{ .Site.Title }}|{{ .Site.Description }}|{{ .Site.BaseURL }}{{ end }}`,
+				"content/post/one.md": "+++\ntitle = \"One\"\ntags = [\"go\"]\n+++\nbody",
+			})
+
+			Expect(Build(loadSite())).To(Succeed())
+		})
+
+		It("are exposed to node, index and taxonomy pages", func() {
+			Expect(readPublic("post/one/index.html")).To(Equal("Notes|en"))
+			Expect(readPublic("index.html")).To(Equal("Notes|About things|https://example.com"))
+			Expect(readPublic("tag/go/index.html")).To(Equal("Notes|About things|https://example.com"))
+		})
+
+		It("describe the feed", func() {
+			feed := readPublic("feed.xml")
+			Expect(feed).To(ContainSubstring("<title>Notes</title>"))
+			Expect(feed).To(ContainSubstring("<description>About things</description>"))
+			Expect(feed).To(ContainSubstring("<language>en</language>"))
+		})
+	})
+})
+
+var _ = Describe("JSON export", func() {
+	var cleanup func()
+
+	readJSON := func(path string, v interface{}) {
+		Expect(json.Unmarshal([]byte(readPublic(path)), v)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		cleanup = withSite(map[string]string{
+			"content/post/one.md":    "+++\ntitle = \"One\"\ndate = 2019-01-01T00:00:00Z\ntags = [\"go\"]\n+++\n# Hello",
+			"content/post/two.md":    "+++\ntitle = \"Two\"\ndate = 2019-02-01T00:00:00Z\n+++\nbody",
+			"content/post/hidden.md": "+++\ntitle = \"Hidden\"\nunlisted = true\n+++\nsecret",
+			"content/post/draft.md":  "+++\ntitle = \"Draft\"\ndraft = true\n+++\nwip",
+		})
+
+		Expect(BuildWithOptions(loadSite(), Options{Format: FormatJSON})).To(Succeed())
+	})
+
+	AfterEach(func() {
+		cleanup()
+	})
+
+	It("writes a json file per node instead of html", func() {
+		var e node.Export
+		readJSON("post/one/index.json", &e)
+
+		Expect(e.Title).To(Equal("One"))
+		Expect(e.Permalink).To(Equal("/post/one/"))
+		Expect(e.URL).To(Equal("https://example.com/post/one/"))
+		Expect(e.Tags).To(Equal([]string{"go"}))
+		Expect(e.HTML).To(ContainSubstring("<h1>Hello</h1>"))
+
+		_, err := os.Stat("public/post/one/index.html")
+		Expect(os.IsNotExist(err)).To(Equal(true))
+		_, err = os.Stat("public/feed.xml")
+		Expect(os.IsNotExist(err)).To(Equal(true))
+	})
+
+	It("lists nodes like an html index", func() {
+		var index struct {
+			Nodes []node.Export `json:"nodes"`
+		}
+		readJSON("index.json", &index)
+
+		Expect(index.Nodes).To(HaveLen(2))
+		Expect(index.Nodes[0].Title).To(Equal("Two"))
+		Expect(index.Nodes[1].Title).To(Equal("One"))
+		Expect(index.Nodes[0].HTML).To(Equal(""))
+
+		var e node.Export
+		readJSON("post/hidden/index.json", &e)
+		Expect(e.Unlisted).To(Equal(true))
+	})
+})
@@ -1,10 +1,22 @@
 package render
 
 import (
+	"flag"
+
+	"github.com/fatih/color"
+	"github.com/rs/zerolog/log"
+
 	"github.com/yeo/baja"
 )
 
-type Command struct{}
+type Command struct {
+	report  string
+	preview bool
+	format  string
+	only    string
+	since   string
+	archive string
+}
 
 func (cmd *Command) ArgDesc() string {
 	return ""
@@ -14,6 +26,64 @@ func (cmd *Command) Help() string {
 	return "Render markdown into html for deploy. HTML content is written to public directory"
 }
 
+func (cmd *Command) Flags(fs *flag.FlagSet) {
+	fs.StringVar(&cmd.report, "report", "", "write the build report as json into this file: counts, timings, feeds, per file errors and warnings")
+	fs.StringVar(&cmd.format, "format", FormatHTML, "output format: html, or json to export nodes for a headless frontend")
+	fs.StringVar(&cmd.only, "only", "", "rebuild only the nodes under this content path, eg: content/blog, with the indexes and feeds listing them")
+	fs.StringVar(&cmd.since, "since", "", "rebuild only the content added or changed since this git ref, eg: origin/main, and remove the pages of deleted content")
+	fs.StringVar(&cmd.archive, "archive", "", "write the output directory into this .tar.gz or .zip file once the build succeeds, archive of config by default")
+	fs.BoolVar(&cmd.preview, "preview", false, "build only draft and future dated nodes into previewDir, public-preview by default")
+}
+
 func (cmd *Command) Run(site *baja.Site, args []string) int {
-	return Build(site)
+	if site == nil {
+		color.Red("Cannot find baja.yaml. Run baja init to create a new site")
+		return baja.ExitConfigError
+	}
+
+	if cmd.format != FormatHTML && cmd.format != FormatJSON {
+		color.Red("Unknown format %s, must be %s or %s", cmd.format, FormatHTML, FormatJSON)
+		return baja.ExitConfigError
+	}
+
+	if cmd.only != "" && cmd.format != FormatHTML {
+		color.Red("--only builds html, it cannot be used with format %s", cmd.format)
+		return baja.ExitConfigError
+	}
+
+	if cmd.since != "" && cmd.format != FormatHTML {
+		color.Red("--since builds html, it cannot be used with format %s", cmd.format)
+		return baja.ExitConfigError
+	}
+
+	if cmd.archive != "" {
+		if _, err := baja.ArchiveFormat(cmd.archive); err != nil {
+			color.Red("%v", err)
+			return baja.ExitConfigError
+		}
+	}
+
+	var err error
+	opts := Options{Format: cmd.format, Only: cmd.only, Since: cmd.since, Report: cmd.report}
+	if cmd.preview {
+		err = BuildPreview(site, opts)
+	} else {
+		err = BuildWithOptions(site, opts)
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("Build failed")
+	}
+
+	archive := cmd.archive
+	if archive == "" {
+		archive = site.Config.Archive
+	}
+	if err == nil && archive != "" {
+		err = WriteArchive(site.OutputDir(), archive)
+		if err != nil {
+			log.Error().Err(err).Msg("Cannot write archive")
+		}
+	}
+
+	return baja.ExitCode(err)
 }
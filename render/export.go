@@ -0,0 +1,89 @@
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/yeo/baja/node"
+	"github.com/yeo/baja/utils"
+)
+
+// Output formats of a build
+const (
+	FormatHTML = "html"
+	FormatJSON = "json"
+)
+
+// exportIndex is public/index.json of a json build
+type exportIndex struct {
+	Site  exportSite     `json:"site"`
+	Nodes []*node.Export `json:"nodes"`
+}
+
+type exportSite struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	BaseURL     string `json:"baseURL,omitempty"`
+	Language    string `json:"language,omitempty"`
+}
+
+// CompileExport writes the json export of every node, the same ones an html build compiles, and
+// public/index.json listing the nodes an html index would list, newest first, without their body
+func CompileExport(db *node.NodeDB) error {
+	diagnostics := db.Site.Diagnostics
+
+	log.Info().Int("total", db.Total).Msg("Export individual page")
+	for _, n := range db.All() {
+		logger := n.Logger()
+		logger.Debug().Msg("Export node")
+		if err := n.CompileJSON(); err != nil {
+			logger.Error().Err(err).Msg("Cannot export node")
+			diagnostics.AddError(n.Path, err)
+		}
+	}
+
+	return compileExportIndex(db)
+}
+
+// compileExportIndex writes public/index.json listing the nodes of the home index
+func compileExportIndex(db *node.NodeDB) error {
+	nodes := db.MainNodes()
+	sort.SliceStable(nodes, func(i, j int) bool { return nodes[i].Meta.Date.After(nodes[j].Meta.Date) })
+
+	site := db.Site
+	index := exportIndex{
+		Site: exportSite{
+			Title:       site.Title(),
+			Description: site.Description(),
+			BaseURL:     site.BaseURL(),
+			Language:    site.Language(),
+		},
+		Nodes: make([]*node.Export, len(nodes)),
+	}
+	for i, n := range nodes {
+		e := n.Export()
+		e.HTML = ""
+		index.Nodes[i] = e
+	}
+
+	out, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return fmt.Errorf("cannot encode export index: %w", err)
+	}
+
+	if err := utils.EnsureDir(site.OutputDir(), utils.DefaultDirMode); err != nil {
+		return err
+	}
+	path := filepath.Join(site.OutputDir(), node.ExportFile)
+	if err := ioutil.WriteFile(path, out, 0644); err != nil {
+		return fmt.Errorf("cannot write export index: %w", err)
+	}
+	site.Outputs.Add(path)
+
+	return nil
+}
@@ -0,0 +1,150 @@
+package render
+
+import (
+	"encoding/xml"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/yeo/baja"
+	"github.com/yeo/baja/node"
+	"github.com/yeo/baja/utils"
+)
+
+type rss struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	XmlnsDC string     `xml:"xmlns:dc,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	Language      string    `xml:"language,omitempty"`
+	Editor        string    `xml:"managingEditor,omitempty"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	GUID        string `xml:"guid"`
+	PubDate     string `xml:"pubDate,omitempty"`
+	Author      string `xml:"author,omitempty"`     // email (name), rss only allows an email here
+	Creator     string `xml:"dc:creator,omitempty"` // name of the author
+	Description cdata  `xml:"description"`
+}
+
+// cdata keeps html of a feed item as is, indenting the feed never touches it
+type cdata struct {
+	Text string `xml:",cdata"`
+}
+
+// CompileFeed writes an RSS feed of publishable nodes of the main sections into public/feed.xml
+func CompileFeed(db *node.NodeDB) error {
+	return compileFeed(db, "", db.Site.Title(), db.MainNodes())
+}
+
+// compileFeed writes an RSS feed of nodes into public/dir/feed.xml
+func compileFeed(db *node.NodeDB, dir, title string, nodes []*node.Node) error {
+	config := db.Site.Config
+
+	nodes = append([]*node.Node{}, nodes...)
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Meta.Date.After(nodes[j].Meta.Date) })
+
+	link := "/"
+	if dir != "" {
+		link = "/" + dir + "/"
+	}
+
+	feed := rss{
+		Version: "2.0",
+		XmlnsDC: "http://purl.org/dc/elements/1.1/",
+		Channel: rssChannel{
+			Title:       title,
+			Link:        config.AbsURL(link),
+			Description: config.Description,
+			Language:    db.Site.Language(),
+			Editor:      rssAuthor(&config.Author),
+			Items:       []rssItem{},
+		},
+	}
+
+	if len(nodes) > 0 {
+		feed.Channel.LastBuildDate = nodes[0].Meta.Date.Format(time.RFC1123Z)
+	}
+
+	for _, n := range nodes {
+		if n.Meta.Draft {
+			continue
+		}
+
+		link := config.AbsURL(n.Permalink())
+		item := rssItem{
+			Title:       n.Meta.Title,
+			Link:        link,
+			GUID:        link,
+			Description: cdata{string(n.HTML())},
+		}
+		if !n.Meta.Date.IsZero() {
+			item.PubDate = n.Meta.Date.Format(time.RFC1123Z)
+		}
+		author := n.Author()
+		if author == nil {
+			author = &config.Author
+		}
+		item.Author = rssAuthor(author)
+		item.Creator = author.Name
+
+		feed.Channel.Items = append(feed.Channel.Items, item)
+	}
+
+	// the directory is only there when the kind also has html output
+	path := filepath.Join(db.Site.OutputDir(), dir, "feed.xml")
+	if err := utils.EnsureDir(filepath.Dir(path), utils.DefaultDirMode); err != nil {
+		return err
+	}
+	if err := writeXML(path, feed, config.PrettyXML); err != nil {
+		return err
+	}
+	db.Site.Outputs.Add(path)
+
+	return nil
+}
+
+// rssAuthor formats author as rss wants it, eg: yeo@example.com (Yeo). It's empty without an email
+func rssAuthor(author *baja.SiteAuthor) string {
+	if author.Email == "" {
+		return ""
+	}
+	if author.Name == "" {
+		return author.Email
+	}
+
+	return author.Email + " (" + author.Name + ")"
+}
+
+// writeXML marshals v into path, indented when pretty is set. Character data are never re-indented
+// so pretty printing doesn't change the content
+func writeXML(path string, v interface{}, pretty bool) error {
+	var (
+		out []byte
+		err error
+	)
+
+	if pretty {
+		out, err = xml.MarshalIndent(v, "", "  ")
+	} else {
+		out, err = xml.Marshal(v)
+	}
+	if err != nil {
+		return err
+	}
+
+	out = append([]byte(xml.Header), out...)
+	return ioutil.WriteFile(path, append(out, '\n'), 0644)
+}
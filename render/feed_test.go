@@ -0,0 +1,89 @@
+package render_test
+
+import (
+	"encoding/xml"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/yeo/baja"
+	"github.com/yeo/baja/node"
+	. "github.com/yeo/baja/render"
+)
+
+type parsedFeed struct {
+	Editor string `xml:"channel>managingEditor"`
+	Items  []struct {
+		Title       string `xml:"title"`
+		Description string `xml:"description"`
+		Author      string `xml:"author"`
+		Creator     string `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	} `xml:"channel>item"`
+}
+
+func readFeed() (string, parsedFeed) {
+	raw, err := ioutil.ReadFile("public/feed.xml")
+	Expect(err).ToNot(HaveOccurred())
+
+	feed := parsedFeed{}
+	Expect(xml.Unmarshal(raw, &feed)).To(Succeed())
+
+	return string(raw), feed
+}
+
+var _ = Describe("Feed", func() {
+	var db *node.NodeDB
+
+	BeforeEach(func() {
+		os.MkdirAll("public", os.ModePerm)
+
+		db = &node.NodeDB{Site: &baja.Site{Config: &baja.Config{BaseURL: "https://example.com"}}}
+		db.Append(&node.Node{
+			Path: "content/post/hello.html",
+			Name: "hello",
+			Meta: &node.NodeMeta{Title: "Hello", Date: time.Now()},
+			Body: "<pre>\n  keep   this\n</pre><p>a &amp; b ]]> c</p>",
+		})
+	})
+
+	AfterEach(func() {
+		os.RemoveAll("public")
+	})
+
+	It("keeps item html intact when pretty printing", func() {
+		Expect(CompileFeed(db)).To(Succeed())
+		compact, compactFeed := readFeed()
+
+		db.Site.Config.PrettyXML = true
+		Expect(CompileFeed(db)).To(Succeed())
+		pretty, prettyFeed := readFeed()
+
+		Expect(strings.Count(pretty, "\n")).To(BeNumerically(">", strings.Count(compact, "\n")))
+
+		Expect(prettyFeed.Items).To(HaveLen(1))
+		Expect(prettyFeed).To(Equal(compactFeed))
+		Expect(prettyFeed.Items[0].Description).To(Equal(string(db.All()[0].Body)))
+	})
+
+	It("credits the site author unless the node has one", func() {
+		db.Site.Config.Author = baja.SiteAuthor{Name: "Yeo", Email: "yeo@example.com"}
+		db.Append(&node.Node{
+			Path: "content/post/guest.html",
+			Name: "guest",
+			Meta: &node.NodeMeta{Title: "Guest", Date: time.Now().Add(-time.Hour), Params: map[string]interface{}{"author": "Guest"}},
+		})
+
+		Expect(CompileFeed(db)).To(Succeed())
+		_, feed := readFeed()
+
+		Expect(feed.Editor).To(Equal("yeo@example.com (Yeo)"))
+		Expect(feed.Items[0].Author).To(Equal("yeo@example.com (Yeo)"))
+		Expect(feed.Items[0].Creator).To(Equal("Yeo"))
+		Expect(feed.Items[1].Author).To(Equal(""))
+		Expect(feed.Items[1].Creator).To(Equal("Guest"))
+	})
+})
@@ -0,0 +1,126 @@
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/yeo/baja"
+	"github.com/yeo/baja/node"
+)
+
+const (
+	// ManifestPath is where a build keeps its manifest for the next one
+	ManifestPath = ".baja/manifest.json"
+	// PreviewManifestPath is the manifest of drafts preview builds, kept apart from normal builds
+	PreviewManifestPath = ".baja/preview/manifest.json"
+)
+
+func manifestPath(site *baja.Site) string {
+	if site.Preview {
+		return PreviewManifestPath
+	}
+
+	return ManifestPath
+}
+
+// ManifestEntry is what a build recorded about a node. It only has metadata shown in listings,
+// feeds and sitemap so a body edit doesn't change it
+type ManifestEntry struct {
+	Permalink string              `json:"permalink"`
+	Title     string              `json:"title"`
+	Date      time.Time           `json:"date"`
+	Draft     bool                `json:"draft"`
+	Unlisted  bool                `json:"unlisted"`
+	NoIndex   bool                `json:"noindex"`
+	Terms     map[string][]string `json:"terms"` // terms of each taxonomy of the node, by front matter key
+	Authors   []string            `json:"authors"`
+}
+
+// Manifest records the nodes of a build keyed by their source path, and the files it wrote
+// relative to its output directory
+type Manifest struct {
+	Nodes   map[string]*ManifestEntry `json:"nodes"`
+	Outputs []string                  `json:"outputs"`
+}
+
+// NewManifest records the nodes of db
+func NewManifest(db *node.NodeDB) *Manifest {
+	m := &Manifest{Nodes: map[string]*ManifestEntry{}}
+
+	for _, n := range db.All() {
+		entry := &ManifestEntry{
+			Permalink: n.Permalink(),
+			Title:     n.Meta.Title,
+			Date:      n.Meta.Date,
+			Draft:     n.Meta.Draft,
+			Unlisted:  n.Meta.Unlisted,
+			NoIndex:   n.Meta.NoIndex,
+			Terms:     map[string][]string{},
+			Authors:   []string{},
+		}
+		for _, key := range db.Site.Config.TaxonomyKeys() {
+			if terms := n.Terms(key); len(terms) > 0 {
+				entry.Terms[key] = terms
+			}
+		}
+		for _, a := range n.Authors() {
+			entry.Authors = append(entry.Authors, a.ID)
+		}
+
+		m.Nodes[n.Path] = entry
+	}
+
+	return m
+}
+
+// LoadManifest reads the manifest of the previous build
+func LoadManifest(path string) (*Manifest, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Manifest{}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// Save writes the manifest into path
+func (m *Manifest) Save(path string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+// ListingChanged reports whether a node was added, removed or had its listing metadata changed since prev
+func (m *Manifest) ListingChanged(prev *Manifest) bool {
+	if prev == nil {
+		return true
+	}
+
+	current, err := json.Marshal(m.Nodes)
+	if err != nil {
+		return true
+	}
+
+	previous, err := json.Marshal(prev.Nodes)
+	if err != nil {
+		return true
+	}
+
+	return !bytes.Equal(current, previous)
+}
@@ -0,0 +1,88 @@
+package render
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/yeo/baja"
+	"github.com/yeo/baja/node"
+	"github.com/yeo/baja/utils"
+)
+
+// buildScope limits a build to the nodes under some content paths, with the indexes and feeds
+// listing them. A nil scope is the whole site
+type buildScope struct {
+	paths   []string // relative to content, eg: blog or blog/hello.md
+	deleted []string // content files removed since the ref of --since, eg: content/blog/old.md
+}
+
+// onlyScope turns the path of --only, eg: content/blog or blog, into a path relative to
+// content. An empty path is the whole site
+func onlyScope(only string) (*buildScope, error) {
+	if only == "" {
+		return nil, nil
+	}
+
+	scope := strings.TrimPrefix(filepath.ToSlash(filepath.Clean(only)), "content/")
+	if scope == "." || scope == "content" || scope == ".." || strings.HasPrefix(scope, "../") || filepath.IsAbs(scope) {
+		return nil, &baja.ConfigError{Path: "--only", Err: fmt.Errorf("%s is not a path under content, eg: content/blog", only)}
+	}
+
+	if !utils.HasFile(filepath.Join("content", scope)) {
+		return nil, &baja.ConfigError{Path: "--only", Err: fmt.Errorf("content/%s does not exist", scope)}
+	}
+
+	return &buildScope{paths: []string{scope}}, nil
+}
+
+// inScope is true when the source of n, a file or a bundle directory, is under a content path of
+// scope, or for a bundle has one of them among its files
+func inScope(n *node.Node, scope *buildScope) bool {
+	if scope == nil {
+		return true
+	}
+
+	source := n.Path
+	if n.Bundle != "" {
+		source = n.Bundle
+	}
+	source = strings.TrimPrefix(filepath.ToSlash(filepath.Clean(source)), "content/")
+
+	for _, p := range scope.paths {
+		if source == p || strings.HasPrefix(source, p+"/") || (n.Bundle != "" && strings.HasPrefix(p, source+"/")) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// anyInScope is true when one of nodes is under content path scope, always for the whole site
+func anyInScope(nodes []*node.Node, scope *buildScope) bool {
+	for _, n := range nodes {
+		if inScope(n, scope) {
+			return true
+		}
+	}
+
+	return scope == nil
+}
+
+// mergeOutputs returns the sorted union of two output lists of the manifest
+func mergeOutputs(prev, current []string) []string {
+	seen := map[string]bool{}
+	merged := []string{}
+	for _, list := range [][]string{prev, current} {
+		for _, path := range list {
+			if !seen[path] {
+				seen[path] = true
+				merged = append(merged, path)
+			}
+		}
+	}
+	sort.Strings(merged)
+
+	return merged
+}
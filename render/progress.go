@@ -0,0 +1,37 @@
+package render
+
+import (
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/yeo/baja/utils"
+)
+
+// progressInterval is the time between two progress lines of a long copy
+var progressInterval = 2 * time.Second
+
+// progressLog logs the progress of a copy at most once per interval, so a copy of gigabytes of
+// static files doesn't look stuck and a small one doesn't flood the log
+type progressLog struct {
+	msg      string
+	interval time.Duration
+	last     time.Time
+	bytes    int64
+}
+
+func newProgressLog(msg string, interval time.Duration) *progressLog {
+	return &progressLog{msg: msg, interval: interval, last: time.Now()}
+}
+
+// report counts the file of p, logging a line when interval passed since the previous one. It
+// fits utils.CopyDirOpts.Progress, which never calls it concurrently
+func (l *progressLog) report(p utils.CopyProgress) {
+	l.bytes += p.Bytes
+	if time.Since(l.last) < l.interval || p.Index == p.Total {
+		return
+	}
+	l.last = time.Now()
+
+	log.Info().Int("done", p.Index).Int("total", p.Total).Int64("bytes", l.bytes).Msg(l.msg)
+}
@@ -0,0 +1,73 @@
+package render
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/yeo/baja"
+)
+
+// PruneOrphans deletes the files prev wrote into the output directory that current didn't, then their empty
+// parent directories. Files which also exist in a static directory, anything under .git and paths
+// matching Config.PruneProtect are kept. It returns the deleted files
+func PruneOrphans(site *baja.Site, prev, current *Manifest) []string {
+	if prev == nil {
+		return nil
+	}
+
+	produced := map[string]bool{}
+	for _, path := range current.Outputs {
+		produced[path] = true
+	}
+
+	output := site.OutputDir()
+	removed := []string{}
+	for _, rel := range prev.Outputs {
+		if produced[rel] || strings.HasPrefix(rel, "../") || isProtected(site, rel) {
+			continue
+		}
+
+		path := filepath.Join(output, filepath.FromSlash(rel))
+		if err := os.Remove(path); err != nil {
+			if !os.IsNotExist(err) {
+				site.Diagnostics.AddWarning(path, "cannot remove orphan: "+err.Error())
+			}
+			continue
+		}
+		removed = append(removed, path)
+
+		// os.Remove fails on the first non empty directory, which stops the walk up
+		for dir := filepath.Dir(path); dir != output && strings.HasPrefix(dir, output); dir = filepath.Dir(dir) {
+			if os.Remove(dir) != nil {
+				break
+			}
+		}
+	}
+
+	return removed
+}
+
+// isProtected reports whether rel, a path relative to the output directory, must be kept
+func isProtected(site *baja.Site, rel string) bool {
+	for _, part := range strings.Split(rel, "/") {
+		if part == ".git" {
+			return true
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(site.Config.StaticPath(), rel)); err == nil {
+		return true
+	}
+	if site.Theme.Exists(filepath.Join(site.Theme.SubPath("static"), rel)) {
+		return true
+	}
+
+	for _, pattern := range site.Config.PruneProtect {
+		if ok, _ := filepath.Match(pattern, rel); ok {
+			return true
+		}
+	}
+
+	return false
+}
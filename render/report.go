@@ -0,0 +1,98 @@
+package render
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"time"
+
+	"github.com/yeo/baja"
+	"github.com/yeo/baja/node"
+	"github.com/yeo/baja/stats"
+)
+
+// ReportFile is the build report written into the output directory with config buildReport
+const ReportFile = ".baja-report.json"
+
+// Report is the machine readable summary of a build, for CI dashboards
+type Report struct {
+	Errors      int               `json:"errors"`
+	Warnings    int               `json:"warnings"`
+	Error       string            `json:"error,omitempty"` // what stopped the build, eg: an invalid template
+	Duration    int64             `json:"durationMs"`
+	Phases      []Phase           `json:"phases"`
+	Outputs     int               `json:"outputs"` // files written into the output directory
+	Feeds       []string          `json:"feeds"`   // feeds and sitemap relative to the output directory
+	Assets      AssetCounts       `json:"assets"`  // static files copied and skipped as unchanged
+	Stats       *stats.Stats      `json:"stats"`
+	Diagnostics []baja.Diagnostic `json:"diagnostics"`
+
+	start time.Time
+	last  time.Time
+}
+
+// Phase is a step of the build with how long it took
+type Phase struct {
+	Name     string `json:"name"`
+	Duration int64  `json:"durationMs"`
+}
+
+func newReport() *Report {
+	now := time.Now()
+	return &Report{Phases: []Phase{}, Feeds: []string{}, Diagnostics: []baja.Diagnostic{}, start: now, last: now}
+}
+
+// phase records the time since the previous phase as name
+func (r *Report) phase(name string) {
+	now := time.Now()
+	r.Phases = append(r.Phases, Phase{Name: name, Duration: now.Sub(r.last).Milliseconds()})
+	r.last = now
+}
+
+// finish fills the counts of the build of db, which is nil when the build stopped before scanning
+// content, and err, the error it returned
+func (r *Report) finish(site *baja.Site, db *node.NodeDB, err error) {
+	r.Duration = time.Since(r.start).Milliseconds()
+	if err != nil {
+		r.Error = err.Error()
+	}
+
+	r.Errors = site.Diagnostics.Count(baja.SeverityError)
+	r.Warnings = site.Diagnostics.Count(baja.SeverityWarning)
+	r.Diagnostics = append(r.Diagnostics, site.Diagnostics.Items...)
+	r.Outputs = len(site.Outputs.List())
+
+	if db == nil {
+		return
+	}
+	r.Stats = stats.Collect(db, site.Now())
+	for _, path := range feedPaths(db) {
+		if rel, err := filepath.Rel(site.OutputDir(), path); err == nil && site.Outputs.Has(path) {
+			r.Feeds = append(r.Feeds, filepath.ToSlash(rel))
+		}
+	}
+}
+
+// Write writes the report as json into path
+func (r *Report) Write(path string) error {
+	out, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, out, 0644)
+}
+
+// reportPaths are the files the report of a build is written into: Options.Report, and ReportFile
+// of the output directory with config buildReport
+func reportPaths(site *baja.Site, opts Options) []string {
+	paths := []string{}
+	if opts.Report != "" {
+		paths = append(paths, opts.Report)
+	}
+	if site.Config.BuildReport {
+		paths = append(paths, filepath.Join(site.OutputDir(), ReportFile))
+	}
+
+	return paths
+}
@@ -0,0 +1,177 @@
+package render
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	git "gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+
+	"github.com/yeo/baja"
+)
+
+// sinceScope compares the content directory on disk with its tree at git ref, eg: origin/main,
+// and returns the scope of the files added or changed since. A deleted file puts its directory
+// in scope so the indexes which listed it are rebuilt; at the root of content the whole site is
+// rebuilt. Content mounts aren't compared
+func sinceScope(ref string) (*buildScope, error) {
+	repo, err := git.PlainOpenWithOptions(".", &git.PlainOpenOptions{DetectDotGit: true})
+	if err == git.ErrRepositoryNotExists {
+		return nil, &baja.ConfigError{Path: "--since", Err: errors.New("not a git repository, --since compares content with a git ref")}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot open git repository: %w", err)
+	}
+
+	hash, err := repo.ResolveRevision(plumbing.Revision(ref))
+	if err != nil {
+		return nil, &baja.ConfigError{Path: "--since", Err: fmt.Errorf("unknown git ref %q: %w", ref, err)}
+	}
+	commit, err := repo.CommitObject(*hash)
+	if err != nil {
+		return nil, err
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err := contentInRepo(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	scope := &buildScope{}
+	seen := map[string]bool{}
+	if content, err := tree.Tree(dir); err == nil {
+		err = content.Files().ForEach(func(f *object.File) error {
+			seen[f.Name] = true
+			changed, err := fileChanged(filepath.Join("content", filepath.FromSlash(f.Name)), f.Hash)
+			if os.IsNotExist(err) {
+				scope.deleted = append(scope.deleted, "content/"+f.Name)
+				return nil
+			}
+			if changed {
+				scope.paths = append(scope.paths, f.Name)
+			}
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	} else if err != object.ErrDirectoryNotFound {
+		return nil, err
+	}
+
+	err = filepath.Walk("content", func(p string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, _ := filepath.Rel("content", p)
+		if rel = filepath.ToSlash(rel); !seen[rel] {
+			scope.paths = append(scope.paths, rel)
+		}
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	for _, deleted := range scope.deleted {
+		dir := path.Dir(strings.TrimPrefix(deleted, "content/"))
+		if dir == "." {
+			return nil, nil
+		}
+		scope.paths = append(scope.paths, dir)
+	}
+	sort.Strings(scope.paths)
+
+	return scope, nil
+}
+
+// contentInRepo returns the path of the content directory relative to the root of repo
+func contentInRepo(repo *git.Repository) (string, error) {
+	wt, err := repo.Worktree()
+	if err != nil {
+		return "", err
+	}
+
+	root, err := filepath.EvalSymlinks(wt.Filesystem.Root())
+	if err != nil {
+		return "", err
+	}
+	content, err := filepath.Abs("content")
+	if err != nil {
+		return "", err
+	}
+	if resolved, err := filepath.EvalSymlinks(content); err == nil {
+		content = resolved
+	}
+
+	rel, err := filepath.Rel(root, content)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.ToSlash(rel), nil
+}
+
+// fileChanged reports whether the file at p has another content than the git blob hash
+func fileChanged(p string, hash plumbing.Hash) (bool, error) {
+	data, err := ioutil.ReadFile(p)
+	if err != nil {
+		return false, err
+	}
+
+	return plumbing.ComputeHash(plumbing.BlobObject, data) != hash, nil
+}
+
+// removeDeleted removes from public and from the outputs of current the files under the permalink
+// of the nodes of the previous build whose source was deleted since the ref of scope, unless this
+// build wrote them again. It returns the removed paths
+func removeDeleted(site *baja.Site, scope *buildScope, prev, current *Manifest) []string {
+	written := map[string]bool{}
+	for _, rel := range outputList(site) {
+		written[rel] = true
+	}
+
+	dirs := []string{}
+	for _, source := range scope.deleted {
+		for path, entry := range prev.Nodes {
+			if filepath.ToSlash(filepath.Clean(path)) == source && strings.Trim(entry.Permalink, "/") != "" {
+				dirs = append(dirs, strings.Trim(entry.Permalink, "/"))
+			}
+		}
+	}
+
+	outputs, deleted := []string{}, []string{}
+	for _, rel := range current.Outputs {
+		if !written[rel] && underAny(rel, dirs) {
+			deleted = append(deleted, rel)
+		} else {
+			outputs = append(outputs, rel)
+		}
+	}
+	current.Outputs = outputs
+
+	// PruneOrphans removes what the previous manifest has and the current one doesn't
+	return PruneOrphans(site, &Manifest{Outputs: deleted}, current)
+}
+
+// underAny is true when rel is one of dirs or a file under it
+func underAny(rel string, dirs []string) bool {
+	for _, dir := range dirs {
+		if rel == dir || strings.HasPrefix(rel, dir+"/") {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,62 @@
+package render
+
+import (
+	"encoding/xml"
+	"path/filepath"
+	"sort"
+
+	"github.com/yeo/baja/node"
+)
+
+type urlset struct {
+	XMLName xml.Name     `xml:"urlset"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+// CompileSitemap writes every node and directory index into public/sitemap.xml, but drafts, future
+// dated, unlisted and noindex nodes
+func CompileSitemap(db *node.NodeDB) error {
+	config := db.Site.Config
+
+	sitemap := urlset{
+		Xmlns: "http://www.sitemaps.org/schemas/sitemap/0.9",
+		URLs:  []sitemapURL{{Loc: config.AbsURL("/")}},
+	}
+
+	dirs := []string{}
+	for dir := range db.ByCategory() {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+
+	for _, dir := range dirs {
+		sitemap.URLs = append(sitemap.URLs, sitemapURL{Loc: config.AbsURL("/" + dir + "/")})
+	}
+
+	now := db.Site.Now()
+	for _, n := range db.All() {
+		if !n.IsPublished(now) || n.Meta.Unlisted || n.Meta.NoIndex {
+			continue
+		}
+
+		u := sitemapURL{Loc: config.AbsURL(n.Permalink())}
+		if !n.Meta.Date.IsZero() {
+			u.LastMod = n.Meta.Date.Format("2006-01-02")
+		}
+		sitemap.URLs = append(sitemap.URLs, u)
+	}
+
+	path := filepath.Join(db.Site.OutputDir(), "sitemap.xml")
+	if err := writeXML(path, sitemap, config.PrettyXML); err != nil {
+		return err
+	}
+	db.Site.Outputs.Add(path)
+
+	return nil
+}
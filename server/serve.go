@@ -1,19 +1,31 @@
 package server
 
 import (
-	"log"
+	"mime"
+	"net/http"
+	"net/url"
 	"os"
+	"path"
+	"path/filepath"
+	"strings"
 	"time"
 
-	"github.com/fatih/color"
 	"github.com/labstack/echo"
 
 	"github.com/mholt/archiver"
+	"github.com/radovskyb/watcher"
+	"github.com/rs/zerolog/log"
+
+	"github.com/yeo/baja"
+	"github.com/yeo/baja/render"
 	"github.com/yeo/baja/utils"
 )
 
 type Server struct {
 	staticPath string
+
+	// trailingSlash is baja.TrailingSlashRedirect or baja.TrailingSlashServe
+	trailingSlash string
 }
 
 func KeyAuth() {
@@ -47,36 +59,75 @@ func Deploy(c echo.Context) {
 
 func router(e *echo.Echo, s *Server) {
 	//e.Static("/deploy", Deploy)
-	e.Static("/", s.staticPath)
+	e.GET("/*", s.static)
 }
 
-func Run(addr, public string) {
+// static serves the files of public like a static host does: a directory is its index.html, and
+// a directory url without trailing slash is redirected to the one with it unless trailingSlash is
+// serve. Relative links of a page, eg: images of a bundle, only resolve with the slash
+func (s *Server) static(c echo.Context) error {
+	p, err := url.PathUnescape(c.Param("*"))
+	if err != nil {
+		return err
+	}
+	name := filepath.Join(s.staticPath, path.Clean("/"+p)) // "/"+ keeps it inside public
+
+	req := c.Request()
+	if info, err := os.Stat(name); err == nil && info.IsDir() && !strings.HasSuffix(req.URL.Path, "/") && s.trailingSlash != baja.TrailingSlashServe {
+		target := req.URL.Path + "/"
+		if req.URL.RawQuery != "" {
+			target += "?" + req.URL.RawQuery
+		}
+		return c.Redirect(http.StatusMovedPermanently, target)
+	}
+
+	return c.File(name)
+}
+
+func Run(addr, public, trailingSlash string) {
 	e := echo.New()
 	s := &Server{
-		staticPath: public,
+		staticPath:    public,
+		trailingSlash: trailingSlash,
 	}
 	router(e, s)
 
 	hostname, _ := os.Hostname()
-	log.Printf("Listen on http://%s:%d", hostname, 2803)
+	log.Info().Msgf("Listen on http://%s:%d", hostname, 2803)
 	e.Logger.Fatal(e.Start(addr))
 }
 
-// Build execute template and content to generate our real static conent
-func Serve(addr, directory string) int {
-	w := utils.Watch([]string{"./content", "./themes"})
+// Serve builds the site, then serves directory while rebuilding on change of content, theme or config
+func Serve(site *baja.Site, addr, directory string) int {
+	paths := []string{"./content", "./themes"}
+	if site != nil {
+		paths = watchPaths(site)
+	}
+	w := utils.Watch(paths)
 
 	// Build our site immediately to serve dev
-	//go Build()
+	if site != nil {
+		rebuild(site, true)
+	}
 
 	go func() {
 		for {
 			select {
 			case event := <-w.Event:
-				color.Yellow("Receive file change event %s. Rebuild", event)
-				//Build()
+				if site == nil {
+					continue
+				}
+
+				if isConfig(site, event.Path) {
+					log.Info().Str("path", event.Path).Msg("Config changed. Reload")
+					site = reload(w, site)
+					continue
+				}
+
+				log.Info().Str("path", event.Path).Str("op", event.Op.String()).Msg("File changed. Rebuild")
+				rebuild(site, true)
 			case err := <-w.Error:
-				color.Red("Watch error:%s", err)
+				log.Error().Err(err).Msg("Watch error")
 			case <-w.Closed:
 				return
 			}
@@ -86,10 +137,102 @@ func Serve(addr, directory string) int {
 	go func() {
 		// Start the watching process - it'll check for changes every 100ms.
 		if err := w.Start(time.Millisecond * 100); err != nil {
-			log.Fatalln(err)
+			log.Fatal().Err(err).Msg("Cannot watch")
 		}
 	}()
 
-	Run(addr, directory)
+	trailingSlash := baja.TrailingSlashRedirect
+	if site != nil && site.Config.TrailingSlash != "" {
+		trailingSlash = site.Config.TrailingSlash
+	}
+	Run(addr, directory, trailingSlash)
 	return 0
 }
+
+// watchPaths are the content, theme and static directories plus config files of site
+func watchPaths(site *baja.Site) []string {
+	paths := []string{site.Path.Content, site.Theme.SubPath("")}
+	for _, mount := range site.Config.Mounts {
+		paths = append(paths, mount.Source)
+	}
+	if utils.HasFile(site.Config.StaticPath()) {
+		paths = append(paths, site.Config.StaticPath())
+	}
+
+	return append(paths, site.ConfigPaths()...)
+}
+
+func isConfig(site *baja.Site, path string) bool {
+	for _, p := range site.ConfigPaths() {
+		if p == path {
+			return true
+		}
+	}
+
+	return false
+}
+
+// reload loads the config again and does a full rebuild with it. An invalid config is reported
+// and the previous one is kept, so the server keeps running
+func reload(w *watcher.Watcher, site *baja.Site) *baja.Site {
+	next, err := site.Reload()
+	if err != nil {
+		log.Error().Err(err).Msg("Invalid config, keep the previous one")
+		return site
+	}
+
+	if err := baja.SetupLogger(next.Config); err != nil {
+		log.Error().Err(err).Msg("Invalid log config, keep the previous one")
+	}
+
+	// content or theme directory may have moved, re-register watchers for what changed
+	old, current := watchPaths(site), watchPaths(next)
+	for _, p := range old {
+		if !contains(current, p) {
+			w.RemoveRecursive(p)
+		}
+	}
+	for _, p := range current {
+		if !contains(old, p) {
+			log.Info().Str("path", p).Msg("Watch to build")
+			if err := w.AddRecursive(p); err != nil {
+				log.Error().Err(err).Str("path", p).Msg("Cannot watch")
+			}
+		}
+	}
+
+	rebuild(next, false)
+	return next
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+// rebuild builds site. An incremental build only rewrites feeds and sitemap when listings change
+func rebuild(site *baja.Site, incremental bool) {
+	registerMediaTypes(site)
+	if err := render.BuildWithOptions(site, render.Options{Incremental: incremental}); err != nil {
+		log.Error().Err(err).Msg("Rebuild error")
+	}
+}
+
+// registerMediaTypes serves the files of the custom output formats of site with their mediaType,
+// eg: text/calendar for .ics
+func registerMediaTypes(site *baja.Site) {
+	for name := range site.Config.CustomFormats {
+		format, _ := site.Config.CustomFormat(name)
+		if format.MediaType == "" {
+			continue
+		}
+		if err := mime.AddExtensionType("."+format.Suffix, format.MediaType); err != nil {
+			log.Warn().Err(err).Str("format", name).Msg("Invalid media type")
+		}
+	}
+}
@@ -1,28 +1,88 @@
 package baja_test
 
 import (
+	"errors"
+	"io/ioutil"
 	"os"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
-	. "github.com/yeo/baja"
-
+	"github.com/yeo/baja"
 	"github.com/yeo/baja/utils"
 )
 
 var _ = Describe("Setup", func() {
-	It("creates directory structure", func() {
-		name := "testdir"
+	name := "testdir"
 
-		Setup(name)
+	AfterEach(func() {
+		os.RemoveAll(name)
+	})
+
+	It("creates directory structure", func() {
+		Expect(baja.Setup(name, false)).To(Succeed())
 
 		Expect(utils.HasFile("./" + name + "/baja.yaml")).To(Equal(true))
 		Expect(utils.HasFile("./" + name + "/content")).To(Equal(true))
 		Expect(utils.HasFile("./" + name + "/theme/baja")).To(Equal(true))
 		Expect(utils.HasFile("./" + name + "/public/asset")).To(Equal(true))
+		Expect(utils.HasFile("./" + name + "/static")).To(Equal(true))
 
-		os.RemoveAll(name)
+		ignore, err := ioutil.ReadFile("./" + name + "/.gitignore")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(ignore)).To(ContainSubstring("/public/"))
+		Expect(string(ignore)).To(ContainSubstring("/.baja/"))
+		Expect(string(ignore)).To(ContainSubstring("/.baja-cache/"))
+	})
+
+	It("writes a config with commented site settings", func() {
+		Expect(baja.Setup(name, false)).To(Succeed())
+
+		config, _ := ioutil.ReadFile(name + "/baja.yaml")
+		for _, key := range []string{"title", "description", "baseURL", "language"} {
+			Expect(string(config)).To(ContainSubstring("# " + key + ": "))
+		}
+
+		_, err := baja.LoadSite(name+"/baja.yaml", "")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("refuses a non empty directory", func() {
+		os.MkdirAll(name, os.ModePerm)
+		ioutil.WriteFile(name+"/baja.yaml", []byte("theme: mine\n"), 0644)
+
+		err := baja.Setup(name, false)
+		Expect(errors.Is(err, baja.ErrSiteExists)).To(Equal(true))
+		Expect(utils.HasFile("./" + name + "/content")).To(Equal(false))
+	})
+
+	It("keeps existing files when forced", func() {
+		os.MkdirAll(name, os.ModePerm)
+		ioutil.WriteFile(name+"/baja.yaml", []byte("theme: mine\n"), 0644)
+
+		Expect(baja.Setup(name, true)).To(Succeed())
+
+		config, _ := ioutil.ReadFile(name + "/baja.yaml")
+		Expect(string(config)).To(Equal("theme: mine\n"))
+		Expect(utils.HasFile("./" + name + "/content")).To(Equal(true))
+	})
+
+	It("reports filesystem failures as is", func() {
+		ioutil.WriteFile(name, []byte("not a directory"), 0644)
+
+		err := baja.Setup(name+"/site", true)
+		Expect(err).To(HaveOccurred())
+		Expect(errors.Is(err, baja.ErrSiteExists)).To(Equal(false))
+	})
+
+	It("creates directories with the given mode", func() {
+		Expect(baja.SetupWithMode(name, false, 0750)).To(Succeed())
+
+		for _, dir := range []string{name, name + "/content", name + "/public/asset"} {
+			info, err := os.Stat(dir)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(info.Mode().Perm()).To(Equal(os.FileMode(0750)), dir)
+		}
 	})
 
 	It("Copy default theme", func() {
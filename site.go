@@ -1,16 +1,17 @@
 package baja
 
 import (
-	"gopkg.in/yaml.v2"
-	"io/ioutil"
-	"log"
+	"os"
 	"path/filepath"
+	"time"
+
+	"github.com/yeo/baja/utils"
 )
 
 type SiteMeta struct {
-	Name    string `"yaml":name`
-	Author  string `"yaml":author`
-	BaseURL string `"yaml":baseURL`
+	Name    string `yaml:"name"`
+	Author  string `yaml:"author"`
+	BaseURL string `yaml:"baseURL"`
 }
 
 type SitePath struct {
@@ -24,34 +25,370 @@ type Site struct {
 
 	Meta *SiteMeta
 	Path *SitePath
+
+	// Environment is the name of the config overlay in use, eg: staging. Empty for the base config
+	Environment string
+
+	// Diagnostics collects per file errors and warnings of the current build
+	Diagnostics *Diagnostics
+
+	// Outputs records the files the current build writes into public
+	Outputs *Outputs
+
+	// Preview is set by a drafts preview build, themes can watermark pages with {{ if .Site.Preview }}
+	Preview bool
+
+	// RecentPosts are the newest nodes of the main sections, a []*node.Node set once content is
+	// scanned so every page can list them with {{ range .Site.RecentPosts }}
+	RecentPosts interface{}
+
+	// params are the theme defaults merged with config params
+	params Params
+
+	// env are the environment variables of config env, read when the site is loaded
+	env map[string]string
+
+	// menus are the config menus assembled into trees
+	menus map[string][]*MenuEntry
+
+	// permalinks are the compiled config permalinks keyed by section
+	permalinks map[string]PermalinkFunc
+
+	// location is the zone of config timezone
+	location *time.Location
+
+	// markup renders the markdown of the site
+	markup *Markup
+
+	// cache keeps processed output across builds, in config cacheDir
+	cache *utils.Cache
+
+	// ignore is the compiled config ignoreFiles
+	ignore IgnoreFunc
+
+	// translations are the i18n strings of the site language
+	translations Translations
+
+	// baseURL is the --baseURL override, kept so a reloaded config still uses it
+	baseURL string
 }
 
-func LoadSite(configpath string) *Site {
+// LoadSite loads config file at configpath, merged with the overlay of environment if any.
+// Errors are a *ConfigError, a missing config file can be checked with errors.Is(err, os.ErrNotExist)
+func LoadSite(configpath, environment string) (*Site, error) {
 	path, err := filepath.Abs(configpath)
 	if err != nil {
-		log.Fatalf("configpath invalid %s", configpath)
+		return nil, &ConfigError{configpath, err}
+	}
+
+	data, err := readConfig(path, environment)
+	if err != nil {
+		return nil, &ConfigError{configpath, err}
+	}
+
+	config, err := ParseConfig(path, data)
+	if err != nil {
+		return nil, &ConfigError{configpath, err}
+	}
+
+	if config.BaseURL != "" {
+		if err := ValidateBaseURL(config.BaseURL); err != nil {
+			return nil, &ConfigError{configpath, err}
+		}
+	}
+
+	if config.AssetBaseURL != "" {
+		if err := ValidateAssetBaseURL(config.AssetBaseURL); err != nil {
+			return nil, &ConfigError{configpath, err}
+		}
+	}
+
+	if err := ValidateSanitize(config.Sanitize); err != nil {
+		return nil, &ConfigError{configpath, err}
+	}
+
+	if err := ValidateMenus(config.Menus); err != nil {
+		return nil, &ConfigError{configpath, err}
+	}
+
+	if err := ValidateMarkup(config.Markup); err != nil {
+		return nil, &ConfigError{configpath, err}
+	}
+
+	permalinks, err := CompilePermalinks(config.Permalinks)
+	if err != nil {
+		return nil, &ConfigError{configpath, err}
+	}
+
+	if err := ValidateOutputs(config.Outputs, config.CustomFormats); err != nil {
+		return nil, &ConfigError{configpath, err}
+	}
+
+	if err := ValidateDateFormats(config.DateFormats); err != nil {
+		return nil, &ConfigError{configpath, err}
+	}
+
+	if err := ValidateSocial(config.Social); err != nil {
+		return nil, &ConfigError{configpath, err}
+	}
+
+	if err := ValidateDefaults(config.Defaults); err != nil {
+		return nil, &ConfigError{configpath, err}
+	}
+
+	ignore, err := CompileIgnore(config.IgnoreFiles)
+	if err != nil {
+		return nil, &ConfigError{configpath, err}
+	}
+
+	if config.Timezone != "" {
+		if err := ValidateTimezone(config.Timezone); err != nil {
+			return nil, &ConfigError{configpath, err}
+		}
+	}
+
+	if config.Language != "" {
+		if err := ValidateLanguage(config.Language); err != nil {
+			return nil, &ConfigError{configpath, err}
+		}
+	}
+
+	if err := ValidateLanguages(config.DefaultLanguage, config.Languages); err != nil {
+		return nil, &ConfigError{configpath, err}
+	}
+
+	theme, err := LoadTheme(config)
+	if err != nil {
+		return nil, &ConfigError{configpath, err}
 	}
 
-	data, err := ioutil.ReadFile(path)
+	themeParams, err := theme.ReadParams()
 	if err != nil {
-		return nil
+		return nil, &ConfigError{configpath, err}
 	}
 
-	config := &Config{path: path}
-	err = yaml.Unmarshal(data, &config)
+	translations, err := ReadTranslations(theme, config.LanguageCode())
+	if err != nil {
+		return nil, &ConfigError{configpath, err}
+	}
 
 	outputPath, _ := filepath.Abs("./public")
 	contentPath, _ := filepath.Abs("./content")
 	site := Site{
 		Config: config,
-		Theme:  NewThemeFromConfig(config),
+		Theme:  theme,
 		Meta:   &SiteMeta{},
+		params: mergeParams(themeParams, NewParams(config.Params)),
+		env:    ReadEnv(config.Env),
+		menus:  BuildMenus(config.Menus),
+
+		permalinks: permalinks,
+		ignore:     ignore,
+		markup:     NewMarkup(config),
+		cache:      utils.NewCache(config.CachePath(), config.CacheLimit()),
+		location:   config.Location(),
+
+		translations: translations,
+
+		Environment: environment,
 		Path: &SitePath{
 			// TODO: Load these from config
 			Output:  outputPath,
 			Content: contentPath,
 		},
+		Diagnostics: &Diagnostics{},
+		Outputs:     &Outputs{},
+	}
+
+	return &site, nil
+}
+
+// SetBaseURL overrides the config BaseURL for this build, eg: with the url of a preview deploy.
+// Every absolute url of the site is built from it
+func (s *Site) SetBaseURL(baseURL string) error {
+	if err := ValidateBaseURL(baseURL); err != nil {
+		return &ConfigError{"--baseURL", err}
+	}
+
+	s.Config.BaseURL = baseURL
+	s.baseURL = baseURL
+	return nil
+}
+
+// Title is the site title, exposed to templates as .Site.Title
+func (s *Site) Title() string {
+	if s.Config.Title != "" {
+		return s.Config.Title
+	}
+
+	return s.Config.Site
+}
+
+// Description is exposed to templates as .Site.Description
+func (s *Site) Description() string {
+	return s.Config.Description
+}
+
+// BaseURL is exposed to templates as .Site.BaseURL
+func (s *Site) BaseURL() string {
+	return s.Config.BaseURL
+}
+
+// Author is the site owner, exposed to templates as .Site.Author
+func (s *Site) Author() *SiteAuthor {
+	return &s.Config.Author
+}
+
+// Social are the profiles of config social in order, exposed to templates as .Site.Social
+func (s *Site) Social() []SocialLink {
+	return s.Config.Social
+}
+
+// Language is the code of the site language, exposed to templates as .Site.Language for the html
+// lang attribute
+func (s *Site) Language() string {
+	return s.Config.LanguageCode()
+}
+
+// Languages are the languages of config sorted by code, exposed to templates as .Site.Languages
+func (s *Site) Languages() []*Language {
+	return s.Config.SiteLanguages()
+}
+
+// Translations are the i18n strings of the site language, from the i18n directory of the site and
+// of its theme
+func (s *Site) Translations() Translations {
+	if s.translations == nil {
+		translations, _ := ReadTranslations(NewThemeFromConfig(s.Config), s.Config.LanguageCode())
+		return translations
+	}
+
+	return s.translations
+}
+
+// Params are the theme params of config over the defaults of theme, exposed to templates as .Site.Params
+func (s *Site) Params() Params {
+	if s.params == nil {
+		return NewParams(s.Config.Params)
+	}
+
+	return s.params
+}
+
+// Env are the environment variables of config env, exposed to templates as .Site.Env, eg:
+// {{ .Site.Env.COMMIT_SHA }}. An unset variable is empty
+func (s *Site) Env() map[string]string {
+	if s.env == nil {
+		return ReadEnv(s.Config.Env)
+	}
+
+	return s.env
+}
+
+// ReadEnv returns the value of each environment variable of names, empty when it's not set
+func ReadEnv(names []string) map[string]string {
+	env := map[string]string{}
+	for _, name := range names {
+		env[name] = os.Getenv(name)
+	}
+
+	return env
+}
+
+// Menus are the menus of config keyed by name, exposed to templates as .Site.Menus. Each one is a
+// list of top level entries with their Children, sorted by weight
+func (s *Site) Menus() map[string][]*MenuEntry {
+	if s.menus == nil {
+		return BuildMenus(s.Config.Menus)
+	}
+
+	return s.menus
+}
+
+// Permalink returns the permalink pattern of section compiled from config, nil when it has none
+func (s *Site) Permalink(section string) PermalinkFunc {
+	if s.permalinks == nil {
+		permalinks, _ := CompilePermalinks(s.Config.Permalinks)
+		return permalinks[section]
+	}
+
+	return s.permalinks[section]
+}
+
+// Location returns the time zone of config, dates of nodes and of the build are in it
+func (s *Site) Location() *time.Location {
+	if s.location == nil {
+		return s.Config.Location()
+	}
+
+	return s.location
+}
+
+// Now returns the current time in the zone of the site
+func (s *Site) Now() time.Time {
+	return time.Now().In(s.Location())
+}
+
+// Markup returns the markdown renderer of the site
+func (s *Site) Markup() *Markup {
+	if s.markup == nil {
+		return NewMarkup(s.Config)
+	}
+
+	return s.markup
+}
+
+// Cache returns the processed output cache of the site, nil for a site not loaded from config
+// which keeps nothing
+func (s *Site) Cache() *utils.Cache {
+	return s.cache
+}
+
+// Ignored reports whether rel, a path relative to content, matches config ignoreFiles
+func (s *Site) Ignored(rel string) bool {
+	if s.ignore == nil {
+		ignore, err := CompileIgnore(s.Config.IgnoreFiles)
+		return err == nil && ignore(rel)
+	}
+
+	return s.ignore(rel)
+}
+
+// OutputDir is the directory a build writes into, public unless it's a preview build
+func (s *Site) OutputDir() string {
+	if s.Path == nil || s.Path.Output == "" {
+		return "public"
+	}
+
+	return s.Path.Output
+}
+
+// ConfigPaths returns the config file and the overlay file of the environment when it exists
+func (s *Site) ConfigPaths() []string {
+	paths := []string{s.Config.path}
+	if s.Environment != "" {
+		overlay := EnvironmentPath(s.Config.path, s.Environment)
+		if _, err := os.Stat(overlay); err == nil {
+			paths = append(paths, overlay)
+		}
+	}
+
+	return paths
+}
+
+// Reload loads the config files again with the same environment and baseURL override.
+// On error s is still usable, the caller can carry on with it
+func (s *Site) Reload() (*Site, error) {
+	site, err := LoadSite(s.Config.path, s.Environment)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.baseURL != "" {
+		if err := site.SetBaseURL(s.baseURL); err != nil {
+			return nil, err
+		}
 	}
 
-	return &site
+	return site, nil
 }
@@ -0,0 +1,84 @@
+package baja
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// SocialLink is a profile of the site in config social, exposed to templates as .Site.Social
+type SocialLink struct {
+	Name string `yaml:"name" toml:"name"` // eg: Mastodon, GitHub
+	URL  string `yaml:"url" toml:"url"`
+	Icon string `yaml:"icon" toml:"icon"` // whatever the theme understands, eg: an icon class or image path
+}
+
+// Rel is the rel attribute of the link. me is what Mastodon and IndieAuth look for to verify the
+// profile belongs to the site
+func (l SocialLink) Rel() string {
+	return "me"
+}
+
+// TwitterHandle returns @user of a Twitter or X profile url, empty for other links
+func (l SocialLink) TwitterHandle() string {
+	u, err := url.Parse(l.URL)
+	if err != nil {
+		return ""
+	}
+
+	switch strings.TrimPrefix(strings.ToLower(u.Host), "www.") {
+	case "twitter.com", "x.com":
+	default:
+		return ""
+	}
+
+	user := strings.Split(strings.Trim(u.Path, "/"), "/")[0]
+	if user == "" {
+		return ""
+	}
+
+	return "@" + strings.TrimPrefix(user, "@")
+}
+
+// ValidateSocial checks that every social link has a name and an absolute url. All problems are
+// returned as ValidationErrors
+func ValidateSocial(links []SocialLink) error {
+	var errs ValidationErrors
+
+	for i, l := range links {
+		if l.Name == "" {
+			errs = append(errs, fmt.Errorf("social link %d (%s) has no name", i+1, l.URL))
+		}
+		if u, err := url.Parse(l.URL); err != nil || u.Scheme == "" || u.Host == "" {
+			errs = append(errs, fmt.Errorf("url %q of social link %s must be absolute, eg: https://github.com/yeo", l.URL, l.Name))
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return errs
+}
+
+// TwitterSite returns the handle of the first Twitter or X link of config social, for the
+// twitter:site card tag. Empty when there is none
+func (c *Config) TwitterSite() string {
+	for _, l := range c.Social {
+		if handle := l.TwitterHandle(); handle != "" {
+			return handle
+		}
+	}
+
+	return ""
+}
+
+// SameAs returns the urls of config social, the sameAs of the site in structured data
+func (c *Config) SameAs() []string {
+	urls := make([]string, len(c.Social))
+	for i, l := range c.Social {
+		urls[i] = l.URL
+	}
+
+	return urls
+}
@@ -0,0 +1,33 @@
+package baja_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/yeo/baja"
+)
+
+var _ = Describe("Social", func() {
+	It("reads the twitter handle of a Twitter or X link", func() {
+		config := &baja.Config{Social: []baja.SocialLink{
+			{Name: "GitHub", URL: "https://github.com/yeo"},
+			{Name: "Twitter", URL: "https://twitter.com/yeo/"},
+		}}
+
+		Expect(config.TwitterSite()).To(Equal("@yeo"))
+		Expect(baja.SocialLink{URL: "https://www.x.com/@other"}.TwitterHandle()).To(Equal("@other"))
+		Expect((&baja.Config{}).TwitterSite()).To(BeEmpty())
+	})
+
+	It("marks links as rel me", func() {
+		Expect(baja.SocialLink{Name: "Mastodon", URL: "https://mastodon.social/@yeo"}.Rel()).To(Equal("me"))
+	})
+
+	It("requires a name and an absolute url", func() {
+		err := baja.ValidateSocial([]baja.SocialLink{{URL: "https://github.com/yeo"}, {Name: "Mastodon", URL: "@yeo@mastodon.social"}})
+
+		Expect(err).To(HaveLen(2))
+		Expect(err.Error()).To(ContainSubstring("social link 1 (https://github.com/yeo) has no name"))
+		Expect(err.Error()).To(ContainSubstring(`url "@yeo@mastodon.social" of social link Mastodon must be absolute`))
+	})
+})
@@ -0,0 +1,82 @@
+package stats
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/fatih/color"
+
+	"github.com/yeo/baja"
+	"github.com/yeo/baja/node"
+)
+
+type Command struct {
+	json bool
+}
+
+func (cmd *Command) ArgDesc() string {
+	return ""
+}
+
+func (cmd *Command) Help() string {
+	return "Print content statistics. Nothing is written to public directory"
+}
+
+func (cmd *Command) Flags(fs *flag.FlagSet) {
+	fs.BoolVar(&cmd.json, "json", false, "print statistics as json")
+}
+
+func (cmd *Command) Run(site *baja.Site, args []string) int {
+	if site == nil {
+		color.Red("Cannot find baja.yaml. Run baja init to create a new site")
+		return baja.ExitConfigError
+	}
+
+	if cmd.json {
+		// keep stdout for the json document only
+		color.Output = os.Stderr
+	}
+
+	db := node.BuildDB(site, baja.NewContext(site.Config))
+	s := Collect(db, site.Now())
+
+	if cmd.json {
+		out, err := json.MarshalIndent(s, "", "  ")
+		if err != nil {
+			color.Red("Cannot encode stats %v", err)
+			return baja.ExitInternalError
+		}
+		fmt.Println(string(out))
+
+		return baja.ExitOK
+	}
+
+	fmt.Printf("Posts: %d\nPages: %d\nDrafts: %d\n", s.Posts, s.Pages, s.Drafts)
+	fmt.Printf("Words: %d\nAverage post words: %d\n", s.Words, s.AverageWord)
+	if s.Longest != nil {
+		fmt.Printf("Longest post: %s (%d words)\n", s.Longest.Path, s.Longest.Words)
+		fmt.Printf("Shortest post: %s (%d words)\n", s.Shortest.Path, s.Shortest.Words)
+	}
+
+	printCounts("Posts per year", s.PerYear)
+	printCounts("Posts per tag", s.PerTag)
+	printCounts("Posts per category", s.PerCategory)
+
+	return baja.ExitOK
+}
+
+func printCounts(title string, counts map[string]int) {
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	fmt.Printf("\n%s:\n", title)
+	for _, k := range keys {
+		fmt.Printf("  %s: %d\n", k, counts[k])
+	}
+}
@@ -0,0 +1,84 @@
+package stats
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/yeo/baja/node"
+)
+
+// NodeWords is a node with its word count
+type NodeWords struct {
+	Path  string `json:"path"`
+	Title string `json:"title"`
+	Words int    `json:"words"`
+}
+
+// Stats is the summary of a site content
+type Stats struct {
+	Posts       int `json:"posts"`
+	Pages       int `json:"pages"`
+	Drafts      int `json:"drafts"` // drafts and future dated nodes, none of the other counts has them
+	Words       int `json:"words"`
+	AverageWord int `json:"averagePostWords"`
+
+	PerYear     map[string]int `json:"perYear"`
+	PerTag      map[string]int `json:"perTag"`
+	PerCategory map[string]int `json:"perCategory"`
+
+	Longest  *NodeWords `json:"longest"`
+	Shortest *NodeWords `json:"shortest"`
+}
+
+// Collect counts the nodes of db published at now. Word counts are the same ones used for reading
+// time
+func Collect(db *node.NodeDB, now time.Time) *Stats {
+	s := &Stats{
+		PerYear:     map[string]int{},
+		PerTag:      map[string]int{},
+		PerCategory: map[string]int{},
+	}
+
+	postWords := 0
+	for _, n := range db.All() {
+		if !n.IsPublished(now) {
+			s.Drafts++
+			continue
+		}
+
+		words := n.WordCount()
+		s.Words += words
+
+		if n.IsPage() {
+			s.Pages++
+			continue
+		}
+
+		s.Posts++
+		postWords += words
+
+		if !n.Meta.Date.IsZero() {
+			s.PerYear[strconv.Itoa(n.Meta.Date.Year())]++
+		}
+		for _, tag := range n.Meta.Tags {
+			s.PerTag[tag]++
+		}
+		if n.Meta.Category != "" {
+			s.PerCategory[n.Meta.Category]++
+		}
+
+		current := &NodeWords{Path: n.Path, Title: n.Meta.Title, Words: words}
+		if s.Longest == nil || words > s.Longest.Words {
+			s.Longest = current
+		}
+		if s.Shortest == nil || words < s.Shortest.Words {
+			s.Shortest = current
+		}
+	}
+
+	if s.Posts > 0 {
+		s.AverageWord = postWords / s.Posts
+	}
+
+	return s
+}
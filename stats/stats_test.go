@@ -0,0 +1,65 @@
+package stats_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/yeo/baja"
+	"github.com/yeo/baja/node"
+	. "github.com/yeo/baja/stats"
+)
+
+var _ = Describe("Collect", func() {
+	var cwd, dir string
+
+	BeforeEach(func() {
+		cwd, _ = os.Getwd()
+		dir, _ = ioutil.TempDir("", "baja-stats")
+		Expect(os.Chdir(dir)).To(Succeed())
+
+		for name, content := range map[string]string{
+			"baja.yaml":            "theme: t\n",
+			"themes/t/node.html":   `{{ define "content" }}{{ .Body }}{{ end }}`,
+			"content/post/one.md":  "+++\ntitle = \"One\"\ndate = 2019-02-09T00:00:00Z\ntags = [\"go\"]\n+++\none two three",
+			"content/post/two.md":  "+++\ntitle = \"Two\"\ndate = 2020-03-01T00:00:00Z\ntags = [\"go\", \"web\"]\n+++\none two three four five",
+			"content/post/idea.md": "+++\ntitle = \"Idea\"\ndate = 2020-04-01T00:00:00Z\ndraft = true\ntags = [\"go\"]\n+++\nnot counted at all",
+			"content/post/soon.md": "+++\ntitle = \"Soon\"\ndate = 2030-01-01T00:00:00Z\ntags = [\"web\"]\n+++\nnot published yet either",
+			"content/about.md":     "+++\ntitle = \"About\"\ntype = \"page\"\n+++\nme",
+		} {
+			os.MkdirAll(filepath.Dir(name), os.ModePerm)
+			Expect(ioutil.WriteFile(name, []byte(content), 0644)).To(Succeed())
+		}
+	})
+
+	AfterEach(func() {
+		os.Chdir(cwd)
+		os.RemoveAll(dir)
+	})
+
+	It("counts published nodes, drafts and future dated ones only as drafts", func() {
+		site, err := baja.LoadSite("baja.yaml", "")
+		Expect(err).ToNot(HaveOccurred())
+		db := node.BuildDB(site, baja.NewContext(site.Config))
+
+		s := Collect(db, time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+		Expect(s.Posts).To(Equal(2))
+		Expect(s.Pages).To(Equal(1))
+		Expect(s.Drafts).To(Equal(2))
+		Expect(s.Words).To(Equal(9))
+		Expect(s.AverageWord).To(Equal(4))
+		Expect(s.PerYear).To(Equal(map[string]int{"2019": 1, "2020": 1}))
+		Expect(s.PerTag).To(Equal(map[string]int{"go": 2, "web": 1}))
+		Expect(s.PerCategory).To(Equal(map[string]int{"post": 2}))
+		Expect(s.Longest.Title).To(Equal("Two"))
+		Expect(s.Shortest.Title).To(Equal("One"))
+
+		s = Collect(db, time.Date(2031, 1, 1, 0, 0, 0, 0, time.UTC))
+		Expect(s.Posts).To(Equal(3))
+		Expect(s.Drafts).To(Equal(1))
+	})
+})
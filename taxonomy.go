@@ -0,0 +1,128 @@
+package baja
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Taxonomy configures the pages of a front matter key grouping nodes, such as tags
+type Taxonomy struct {
+	Path     string `yaml:"path" toml:"path"`         // url base of term pages, default to the front matter key
+	Index    bool   `yaml:"index" toml:"index"`       // generate a page listing every term at /<path>/
+	Disabled bool   `yaml:"disabled" toml:"disabled"` // generate no page, categories: false in short
+}
+
+// DefaultTaxonomies are the taxonomies of a site, the Taxonomies of config are applied over them
+var DefaultTaxonomies = map[string]Taxonomy{
+	"tags":       {Path: "tag"},
+	"categories": {Path: "categories"},
+	"series":     {Path: "series"},
+}
+
+// UnmarshalYAML reads a taxonomy as a table, or as a boolean to turn it on or off
+func (t *Taxonomy) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var enabled bool
+	if err := unmarshal(&enabled); err == nil {
+		*t = Taxonomy{Disabled: !enabled}
+		return nil
+	}
+
+	type taxonomy Taxonomy
+	return unmarshal((*taxonomy)(t))
+}
+
+// UnmarshalTOML is UnmarshalYAML of toml configs
+func (t *Taxonomy) UnmarshalTOML(v interface{}) error {
+	switch v := v.(type) {
+	case bool:
+		*t = Taxonomy{Disabled: !v}
+	case map[string]interface{}:
+		for key, value := range v {
+			var ok bool
+			switch key {
+			case "path":
+				t.Path, ok = value.(string)
+			case "index":
+				t.Index, ok = value.(bool)
+			case "disabled":
+				t.Disabled, ok = value.(bool)
+			default:
+				ok = true
+			}
+			if !ok {
+				return fmt.Errorf("key taxonomies.%s: unexpected %T", key, value)
+			}
+		}
+	default:
+		return fmt.Errorf("taxonomy must be a table or a boolean, got %T", v)
+	}
+
+	return nil
+}
+
+// EnabledTaxonomies returns the taxonomies of the site keyed by front matter key, with their path set
+func (c *Config) EnabledTaxonomies() map[string]Taxonomy {
+	taxonomies := map[string]Taxonomy{}
+	for key, t := range DefaultTaxonomies {
+		taxonomies[key] = t
+	}
+
+	for key, t := range c.Taxonomies {
+		if t == nil {
+			continue
+		}
+
+		merged := *t
+		if d, ok := DefaultTaxonomies[key]; ok && merged.Path == "" {
+			merged.Path = d.Path
+		}
+		if merged.Path == "" {
+			merged.Path = key
+		}
+		taxonomies[key] = merged
+	}
+
+	for key, t := range taxonomies {
+		if t.Disabled {
+			delete(taxonomies, key)
+		}
+	}
+
+	return taxonomies
+}
+
+// TaxonomyKeys returns the front matter keys of enabled taxonomies, sorted
+func (c *Config) TaxonomyKeys() []string {
+	keys := []string{}
+	for key := range c.EnabledTaxonomies() {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// ValidateTaxonomies checks that taxonomy paths are single url segments used once
+func ValidateTaxonomies(c *Config) error {
+	var errs ValidationErrors
+
+	taxonomies := c.EnabledTaxonomies()
+	paths := map[string]string{}
+	for _, key := range c.TaxonomyKeys() {
+		path := taxonomies[key].Path
+		if strings.ContainsAny(path, `/\ ?#`) || path == "." || path == ".." {
+			errs = append(errs, fmt.Errorf("invalid path %q of taxonomy %s: must be a single url segment, eg: tag", path, key))
+		}
+		if other, ok := paths[path]; ok {
+			errs = append(errs, fmt.Errorf("taxonomies %s and %s have the same path %q", other, key, path))
+		}
+		paths[path] = key
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return errs
+}
@@ -0,0 +1,44 @@
+package baja_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/yeo/baja"
+)
+
+var _ = Describe("Taxonomies", func() {
+	It("defaults to tags, categories and series", func() {
+		config, err := baja.ParseConfig("baja.yaml", []byte("theme: t\n"))
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(config.TaxonomyKeys()).To(Equal([]string{"categories", "series", "tags"}))
+		Expect(config.EnabledTaxonomies()["tags"].Path).To(Equal("tag"))
+	})
+
+	It("adds taxonomies and turns defaults off", func() {
+		for path, data := range map[string]string{
+			"baja.yaml": "taxonomies:\n  cuisine:\n    path: cuisines\n    index: true\n  ingredient: true\n  categories: false\n",
+			"baja.toml": "[taxonomies]\ncategories = false\ningredient = true\n[taxonomies.cuisine]\npath = \"cuisines\"\nindex = true\n",
+		} {
+			config, err := baja.ParseConfig(path, []byte(data))
+			Expect(err).ToNot(HaveOccurred())
+
+			taxonomies := config.EnabledTaxonomies()
+			Expect(config.TaxonomyKeys()).To(Equal([]string{"cuisine", "ingredient", "series", "tags"}))
+			Expect(taxonomies["cuisine"]).To(Equal(baja.Taxonomy{Path: "cuisines", Index: true}))
+			Expect(taxonomies["ingredient"].Path).To(Equal("ingredient"))
+		}
+	})
+
+	It("rejects paths which aren't a single unique segment", func() {
+		config := &baja.Config{Taxonomies: map[string]*baja.Taxonomy{
+			"cuisine":    {Path: "food/cuisine"},
+			"ingredient": {Path: "tag"},
+		}}
+
+		err := baja.ValidateTaxonomies(config)
+		Expect(err).To(MatchError(ContainSubstring(`invalid path "food/cuisine" of taxonomy cuisine`)))
+		Expect(err).To(MatchError(ContainSubstring(`taxonomies ingredient and tags have the same path "tag"`)))
+	})
+})
@@ -1,8 +1,13 @@
 package baja
 
 import (
+	"bytes"
+	"fmt"
 	"html/template"
+	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/yeo/baja/utils"
 )
@@ -10,9 +15,22 @@ import (
 type Theme struct {
 	Name string
 	path string
+
+	// archive are the files of a zip theme keyed by their slash path in the theme, nil for a
+	// directory theme
+	archive map[string]*archiveFile
 }
 
+// NewThemeFromConfig returns the theme of config, see LoadTheme. An archive which can't be read is
+// a theme without files
 func NewThemeFromConfig(config *Config) *Theme {
+	t, _ := LoadTheme(config)
+	return t
+}
+
+// LoadTheme returns the theme of config, the directory themes/<theme>. A theme ending with .zip is
+// read from the archive themes/<theme> instead, without unpacking it
+func LoadTheme(config *Config) (*Theme, error) {
 	path, _ := filepath.Abs("themes/" + config.Theme)
 
 	t := Theme{
@@ -20,7 +38,18 @@ func NewThemeFromConfig(config *Config) *Theme {
 		path: path,
 	}
 
-	return &t
+	if !IsThemeArchive(config.Theme) {
+		return &t, nil
+	}
+
+	archive, err := readThemeArchive(path)
+	if err != nil {
+		t.archive = map[string]*archiveFile{}
+		return &t, &utils.PathError{Op: "read theme archive", Path: path, Err: err}
+	}
+	t.archive = archive
+
+	return &t, nil
 }
 
 func (t *Theme) LayoutPath(name string) string {
@@ -31,6 +60,11 @@ func (t *Theme) NodePath(node string) string {
 	return t.path + "/" + node + ".html"
 }
 
+// PartialPath returns the path of a partial template, eg: cta is partials/cta.html of the theme
+func (t *Theme) PartialPath(name string) string {
+	return t.path + "/partials/" + name + ".html"
+}
+
 func (t *Theme) Path() string {
 	return t.path + "/"
 }
@@ -39,10 +73,97 @@ func (t *Theme) SubPath(subpath string) string {
 	return t.path + "/" + subpath
 }
 
-func FuncMaps() template.FuncMap {
+// FuncMaps returns the functions of templates. asset looks for files in the output directory of site
+// and links them from config assetBaseURL,
+// readFile and readDir in its ReadRoot, markdownify renders markdown like node bodies, slugify
+// makes the url path segment of a string like permalinks do, partial
+// renders a partial template of its theme and i18n translates a string id in the site language.
+// site can be nil to only list the functions
+func FuncMaps(site *Site) template.FuncMap {
+	output, root, markup := "public", DefaultReadRoot, NewMarkup(nil)
+	if site != nil {
+		output = site.OutputDir()
+		markup = site.Markup()
+		if site.Config.ReadRoot != "" {
+			root = site.Config.ReadRoot
+		}
+	}
+
 	funcMap := template.FuncMap{
-		"asset": utils.GenerateAssetHash,
+		"asset": func(path string) (string, error) {
+			hashed, err := utils.GenerateAssetHash(output, path)
+			if err != nil || site == nil || site.Config == nil {
+				return hashed, err
+			}
+			return site.Config.AssetURL(hashed), nil
+		},
+		"readFile": func(path string) (string, error) {
+			return readFile(root, path)
+		},
+		"readDir": func(path string) ([]os.FileInfo, error) {
+			return readDir(root, path)
+		},
+		"markdownify": func(s interface{}) template.HTML {
+			return template.HTML(markup.HTML("", []byte(fmt.Sprint(s))))
+		},
+		"slugify": utils.Slugify,
+		"partial": func(name string, data interface{}) (template.HTML, error) {
+			return renderPartial(site, name, data)
+		},
+		"dateFormat": func(name string, t time.Time) (string, error) {
+			config := &Config{}
+			if site != nil && site.Config != nil {
+				config = site.Config
+			}
+			layout, err := config.DateFormat(name)
+			if err != nil {
+				return "", err
+			}
+			return t.Format(layout), nil
+		},
+		"i18n": func(id string) string {
+			if site == nil {
+				return id
+			}
+			if s, ok := site.Translations()[id]; ok {
+				return s
+			}
+			return id
+		},
 	}
 
 	return funcMap
 }
+
+// renderPartial executes partials/<name>.html of the site theme with data. A partial can call
+// partial itself
+func renderPartial(site *Site, name string, data interface{}) (template.HTML, error) {
+	if site == nil || site.Config == nil {
+		return "", fmt.Errorf("partial %q: no site", name)
+	}
+	if name == "" || strings.Contains(name, "..") || filepath.IsAbs(name) {
+		return "", fmt.Errorf("partial %q: invalid name", name)
+	}
+
+	theme := site.Theme
+	if theme == nil {
+		theme = NewThemeFromConfig(site.Config)
+	}
+
+	path := theme.PartialPath(name)
+	if !theme.Exists(path) {
+		return "", fmt.Errorf("partial %q not found, create %s", name, path)
+	}
+
+	tpl, err := theme.ParseFiles(template.New(filepath.Base(path)).Funcs(FuncMaps(site)), path)
+	if err != nil {
+		return "", fmt.Errorf("cannot parse partial %q: %w", name, err)
+	}
+
+	var b bytes.Buffer
+	if err := tpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("cannot render partial %q: %w", name, err)
+	}
+
+	return template.HTML(b.String()), nil
+}
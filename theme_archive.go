@@ -0,0 +1,214 @@
+package baja
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/yeo/baja/utils"
+)
+
+// ThemeArchiveExt is the extension of a theme distributed as a zip archive, eg: theme: mytheme.zip
+const ThemeArchiveExt = ".zip"
+
+// archiveFile is a file of a zip theme, read in memory when the theme is loaded
+type archiveFile struct {
+	data    []byte
+	modTime time.Time
+}
+
+// IsThemeArchive reports whether the theme of config is a zip archive
+func IsThemeArchive(theme string) bool {
+	return strings.EqualFold(filepath.Ext(theme), ThemeArchiveExt)
+}
+
+// readThemeArchive reads every file of a zip theme. An archive of a single directory, as zip -r
+// makes it, has this directory stripped so layout/default.html is at the root. An entry whose name
+// could point outside of the theme is an error, see archiveEntryName
+func readThemeArchive(file string) (map[string]*archiveFile, error) {
+	r, err := zip.OpenReader(file)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	files := map[string]*archiveFile{}
+	for _, f := range r.File {
+		if strings.HasSuffix(f.Name, "/") {
+			continue
+		}
+		name, err := archiveEntryName(f.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		data, err := ioutil.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		files[name] = &archiveFile{data: data, modTime: f.Modified}
+	}
+
+	if _, ok := files["layout/default.html"]; ok {
+		return files, nil
+	}
+	for name := range files {
+		if prefix := strings.TrimSuffix(name, "layout/default.html"); prefix != name && strings.Count(prefix, "/") == 1 {
+			stripped := map[string]*archiveFile{}
+			for name, f := range files {
+				if strings.HasPrefix(name, prefix) {
+					stripped[strings.TrimPrefix(name, prefix)] = f
+				}
+			}
+			return stripped, nil
+		}
+	}
+
+	return files, nil
+}
+
+// archiveEntryName returns the cleaned name of a zip entry. An absolute name, a name with a
+// backslash or one going up with .. is an error: its file would be synced outside of public
+func archiveEntryName(name string) (string, error) {
+	clean := path.Clean(name)
+	if strings.Contains(name, `\`) || path.IsAbs(clean) || filepath.IsAbs(name) || clean == "." ||
+		clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", fmt.Errorf("invalid entry %q: it's outside of the theme", name)
+	}
+
+	return clean, nil
+}
+
+// IsArchive reports whether the theme is read from a zip archive
+func (t *Theme) IsArchive() bool {
+	return t.archive != nil
+}
+
+// archiveName returns the name in the archive of path, a theme path such as NodePath returns.
+// It's false for a directory theme or a path outside of the theme
+func (t *Theme) archiveName(path string) (string, bool) {
+	if t.archive == nil {
+		return "", false
+	}
+
+	rel, err := filepath.Rel(t.path, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+
+	return filepath.ToSlash(rel), true
+}
+
+// ReadFile reads a file of the theme, from the archive of a zip theme
+func (t *Theme) ReadFile(path string) ([]byte, error) {
+	name, ok := t.archiveName(path)
+	if !ok {
+		return ioutil.ReadFile(path)
+	}
+
+	f, ok := t.archive[name]
+	if !ok {
+		return nil, &os.PathError{Op: "open", Path: path, Err: os.ErrNotExist}
+	}
+
+	return f.data, nil
+}
+
+// Exists reports whether path is a file or directory of the theme
+func (t *Theme) Exists(path string) bool {
+	name, ok := t.archiveName(path)
+	if !ok {
+		_, err := os.Stat(path)
+		return err == nil
+	}
+
+	if _, ok := t.archive[name]; ok || name == "." {
+		return true
+	}
+	for file := range t.archive {
+		if strings.HasPrefix(file, name+"/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ParseFiles parses theme files into tpl like template.ParseFiles does, each file is a template
+// named by its base name
+func (t *Theme) ParseFiles(tpl *template.Template, paths ...string) (*template.Template, error) {
+	for _, path := range paths {
+		data, err := t.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		name := filepath.Base(path)
+		tmpl := tpl
+		if name != tpl.Name() {
+			tmpl = tpl.New(name)
+		}
+		if _, err := tmpl.Parse(string(data)); err != nil {
+			return nil, err
+		}
+	}
+
+	return tpl, nil
+}
+
+// ArchiveFiles returns the files under dir of a zip theme, eg: static, keyed by their path relative
+// to dir. The values are theme paths for ReadFile and SyncFile
+func (t *Theme) ArchiveFiles(dir string) map[string]string {
+	files := map[string]string{}
+	for name := range t.archive {
+		if rel := strings.TrimPrefix(name, dir+"/"); rel != name {
+			files[filepath.FromSlash(rel)] = filepath.Join(t.path, filepath.FromSlash(name))
+		}
+	}
+
+	return files
+}
+
+// SyncFile is utils.SyncFile reading source from the archive of a zip theme
+func (t *Theme) SyncFile(source, dest string) (bool, error) {
+	name, ok := t.archiveName(source)
+	if !ok {
+		return utils.SyncFile(source, dest)
+	}
+
+	f, ok := t.archive[name]
+	if !ok {
+		return false, &os.PathError{Op: "open", Path: source, Err: os.ErrNotExist}
+	}
+
+	if di, err := os.Stat(dest); err == nil && di.Size() == int64(len(f.data)) {
+		if di.ModTime().Equal(f.modTime) {
+			return false, nil
+		}
+		if data, err := ioutil.ReadFile(dest); err == nil && bytes.Equal(data, f.data) {
+			return false, os.Chtimes(dest, f.modTime, f.modTime)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dest), os.ModePerm); err != nil {
+		return false, err
+	}
+	if err := ioutil.WriteFile(dest, f.data, 0644); err != nil {
+		return false, err
+	}
+
+	return true, os.Chtimes(dest, f.modTime, f.modTime)
+}
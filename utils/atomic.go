@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// WriteFileAtomic writes r into path with mode through a temporary file of the same directory,
+// renamed over path once synced, so path is never seen half written, even when the build is
+// interrupted. The directory is created when missing. On error the temporary file is removed
+// and path is left as it was
+func WriteFileAtomic(path string, r io.Reader, mode os.FileMode) (err error) {
+	dir := filepath.Dir(path)
+	if err := EnsureDir(dir, DefaultDirMode); err != nil {
+		return err
+	}
+
+	// a random name per call, concurrent writers of one directory don't share it
+	f, err := ioutil.TempFile(dir, "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+
+	if _, err = io.Copy(f, r); err != nil {
+		return err
+	}
+	if err = f.Chmod(mode); err != nil {
+		return err
+	}
+	if err = f.Sync(); err != nil {
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), path)
+}
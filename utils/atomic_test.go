@@ -0,0 +1,96 @@
+package utils_test
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/yeo/baja/utils"
+)
+
+// failingReader returns some data then an error
+type failingReader struct{ done bool }
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if r.done {
+		return 0, errors.New("read failed")
+	}
+	r.done = true
+	return copy(p, "partial"), nil
+}
+
+var _ = Describe("WriteFileAtomic", func() {
+	var dir string
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "baja-atomic")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	names := func(dir string) []string {
+		infos, err := ioutil.ReadDir(dir)
+		Expect(err).NotTo(HaveOccurred())
+		names := []string{}
+		for _, info := range infos {
+			names = append(names, info.Name())
+		}
+		return names
+	}
+
+	It("creates the missing directory and writes with mode", func() {
+		path := filepath.Join(dir, "a", "b", "index.html")
+		Expect(WriteFileAtomic(path, strings.NewReader("page"), 0640)).To(Succeed())
+
+		data, err := ioutil.ReadFile(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal("page"))
+
+		info, err := os.Stat(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Mode().Perm()).To(Equal(os.FileMode(0640)))
+		Expect(names(filepath.Dir(path))).To(ConsistOf("index.html"))
+	})
+
+	It("keeps the previous file and no temporary one on error", func() {
+		path := filepath.Join(dir, "index.html")
+		Expect(ioutil.WriteFile(path, []byte("previous"), 0644)).To(Succeed())
+
+		Expect(WriteFileAtomic(path, &failingReader{}, 0644)).NotTo(Succeed())
+
+		data, err := ioutil.ReadFile(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal("previous"))
+		Expect(names(dir)).To(ConsistOf("index.html"))
+	})
+
+	It("lets concurrent writers share a directory", func() {
+		var wg sync.WaitGroup
+		errs := make(chan error, 20)
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				errs <- WriteFileAtomic(filepath.Join(dir, "out", fmt.Sprintf("%d.html", i)), strings.NewReader(fmt.Sprint(i)), 0644)
+			}(i)
+		}
+		wg.Wait()
+		close(errs)
+
+		for err := range errs {
+			Expect(err).NotTo(HaveOccurred())
+		}
+		Expect(names(filepath.Join(dir, "out"))).To(HaveLen(20))
+	})
+})
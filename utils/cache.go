@@ -0,0 +1,161 @@
+package utils
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cache keeps the output of processors on disk across builds, eg: .baja-cache restored by CI,
+// keyed by CacheKey of the content they process so an unchanged input is never processed twice.
+// When it grows over its max size the least recently used entries are evicted. It's safe for
+// concurrent use, entries are written atomically so another build reading the directory never
+// sees half of one. A nil Cache keeps nothing
+type Cache struct {
+	dir     string
+	maxSize int64 // bytes, 0 or less is no limit
+
+	mu sync.RWMutex // writes share it, an eviction holds it alone
+}
+
+// NewCache returns the cache in dir, created on first write. maxSize bounds its size in bytes, 0
+// or less is no limit
+func NewCache(dir string, maxSize int64) *Cache {
+	return &Cache{dir: dir, maxSize: maxSize}
+}
+
+// CacheKey returns the key of the output of processor for parts, eg: the content processed and
+// the options it's processed with. A key changes with any of them
+func CacheKey(processor string, parts ...[]byte) string {
+	hash, _ := HashReader(bytes.NewReader(bytes.Join(append([][]byte{[]byte(processor)}, parts...), []byte{0})))
+	return hash
+}
+
+// cacheDirRe, cacheEntryRe and cacheTempRe match the names of the cache layout: a directory of
+// the first two characters of a key, the entry of a key in it and the temporary file of a write
+// of it by WriteFileAtomic
+var (
+	cacheDirRe   = regexp.MustCompile(`^[0-9a-f]{2}$`)
+	cacheEntryRe = regexp.MustCompile(`^[0-9a-f]{64}$`)
+	cacheTempRe  = regexp.MustCompile(`^\.([0-9a-f]{64})\.[0-9]+\.tmp$`)
+)
+
+// path returns the file of key, under a directory of its first two characters to keep
+// directories small
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.dir, key[:2], key)
+}
+
+// Get returns the entry of key and marks it used, false when there is none
+func (c *Cache) Get(key string) ([]byte, bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	path := c.path(key)
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	now := time.Now()
+	os.Chtimes(path, now, now)
+
+	return data, true
+}
+
+// Put stores data as the entry of key, replacing an entry of the same key
+func (c *Cache) Put(key string, data []byte) error {
+	if c == nil {
+		return nil
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return WriteFileAtomic(c.path(key), bytes.NewReader(data), 0644)
+}
+
+// staleWrite is the age of the temporary file of a write, maybe of another build, after which it's
+// left over by a build which stopped
+const staleWrite = time.Hour
+
+// cacheEntry is a file of the cache as seen by Evict
+type cacheEntry struct {
+	path string
+	size int64
+	used time.Time
+}
+
+// Evict removes the least recently used entries until the cache is under its max size, and the
+// temporary files of writes which didn't finish within staleWrite. Only files of the cache layout
+// are looked at, anything else in the directory is left alone. It returns how many entries it
+// removed
+func (c *Cache) Evict() (int, error) {
+	if c == nil || c.maxSize <= 0 {
+		return 0, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	dirs, err := ioutil.ReadDir(c.dir)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	entries := []cacheEntry{}
+	var total int64
+	for _, dir := range dirs {
+		if !dir.IsDir() || !cacheDirRe.MatchString(dir.Name()) {
+			continue
+		}
+		files, err := ioutil.ReadDir(filepath.Join(c.dir, dir.Name()))
+		if err != nil {
+			return 0, err
+		}
+
+		for _, f := range files {
+			path := filepath.Join(c.dir, dir.Name(), f.Name())
+			if !f.Mode().IsRegular() {
+				continue
+			}
+			if m := cacheTempRe.FindStringSubmatch(f.Name()); m != nil && strings.HasPrefix(m[1], dir.Name()) {
+				if time.Since(f.ModTime()) > staleWrite {
+					if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+						return 0, err
+					}
+				}
+				continue
+			}
+			if cacheEntryRe.MatchString(f.Name()) && strings.HasPrefix(f.Name(), dir.Name()) {
+				entries = append(entries, cacheEntry{path, f.Size(), f.ModTime()})
+				total += f.Size()
+			}
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].used.Before(entries[j].used) })
+
+	removed := 0
+	for _, e := range entries {
+		if total <= c.maxSize {
+			break
+		}
+		if err := os.Remove(e.path); err != nil && !os.IsNotExist(err) {
+			return removed, err
+		}
+		total -= e.size
+		removed++
+	}
+
+	return removed, nil
+}
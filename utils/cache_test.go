@@ -0,0 +1,127 @@
+package utils_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/yeo/baja/utils"
+)
+
+var _ = Describe("Cache", func() {
+	var dir string
+
+	BeforeEach(func() {
+		dir, _ = ioutil.TempDir("", "baja-cache")
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("keeps entries across instances by key", func() {
+		key := CacheKey("markdown", []byte("# Title"))
+		Expect(key).ToNot(Equal(CacheKey("markdown", []byte("# Other"))))
+		Expect(key).ToNot(Equal(CacheKey("image", []byte("# Title"))))
+
+		_, ok := NewCache(dir, 0).Get(key)
+		Expect(ok).To(BeFalse())
+
+		Expect(NewCache(dir, 0).Put(key, []byte("<h1>Title</h1>"))).To(Succeed())
+		data, ok := NewCache(dir, 0).Get(key)
+		Expect(ok).To(BeTrue())
+		Expect(string(data)).To(Equal("<h1>Title</h1>"))
+	})
+
+	It("keeps nothing when nil", func() {
+		var cache *Cache
+		Expect(cache.Put("key", []byte("x"))).To(Succeed())
+		_, ok := cache.Get("key")
+		Expect(ok).To(BeFalse())
+	})
+
+	It("evicts the least recently used entries over its max size", func() {
+		cache := NewCache(dir, 20)
+		old := time.Now().Add(-time.Hour)
+		for i, name := range []string{"a", "b", "c"} {
+			key := CacheKey(name)
+			Expect(cache.Put(key, []byte("0123456789"))).To(Succeed())
+			used := old.Add(time.Duration(i) * time.Minute)
+			Expect(os.Chtimes(filepath.Join(dir, key[:2], key), used, used)).To(Succeed())
+		}
+		_, ok := cache.Get(CacheKey("a"))
+		Expect(ok).To(BeTrue())
+
+		removed, err := cache.Evict()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(removed).To(Equal(1))
+
+		for name, kept := range map[string]bool{"a": true, "b": false, "c": true} {
+			_, ok := cache.Get(CacheKey(name))
+			Expect(ok).To(Equal(kept), name)
+		}
+	})
+
+	It("evicts only the files of its layout", func() {
+		cache := NewCache(dir, 1)
+		key := CacheKey("a")
+		Expect(cache.Put(key, []byte("0123456789"))).To(Succeed())
+
+		old := time.Now().Add(-2 * time.Hour)
+		stale := filepath.Join(dir, key[:2], "."+key+".123.tmp")
+		foreign := []string{
+			filepath.Join(dir, "notes.txt"),
+			filepath.Join(dir, key[:2], "notes.txt"),
+			filepath.Join(dir, key[:2], ".notes.tmp"),
+			filepath.Join(dir, "src", key),
+			filepath.Join(dir, "ff", key),
+		}
+		for _, path := range append(foreign, stale) {
+			Expect(os.MkdirAll(filepath.Dir(path), os.ModePerm)).To(Succeed())
+			Expect(ioutil.WriteFile(path, []byte("0123456789"), 0644)).To(Succeed())
+			Expect(os.Chtimes(path, old, old)).To(Succeed())
+		}
+
+		removed, err := cache.Evict()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(removed).To(Equal(1))
+
+		_, ok := cache.Get(key)
+		Expect(ok).To(BeFalse())
+		_, err = os.Stat(stale)
+		Expect(os.IsNotExist(err)).To(BeTrue())
+		for _, path := range foreign {
+			Expect(path).To(BeAnExistingFile())
+		}
+	})
+
+	It("is safe for concurrent writers, readers and evictions", func() {
+		cache := NewCache(dir, 1<<10)
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer GinkgoRecover()
+				defer wg.Done()
+				for j := 0; j < 20; j++ {
+					key := CacheKey("test", []byte(fmt.Sprint(j)))
+					Expect(cache.Put(key, []byte(fmt.Sprint(j)))).To(Succeed())
+					if data, ok := cache.Get(key); ok {
+						Expect(string(data)).To(Equal(fmt.Sprint(j)))
+					}
+					if j%5 == 0 {
+						_, err := cache.Evict()
+						Expect(err).ToNot(HaveOccurred())
+					}
+				}
+			}(i)
+		}
+		wg.Wait()
+	})
+})
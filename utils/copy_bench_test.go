@@ -0,0 +1,62 @@
+package utils_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/yeo/baja/utils"
+)
+
+// benchTree writes many small files and a few large ones, like a static directory of photos
+func benchTree(b *testing.B) string {
+	dir, err := ioutil.TempDir("", "baja-bench")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	small := make([]byte, 4<<10)
+	large := make([]byte, 16<<20)
+	for i := 0; i < 500; i++ {
+		path := filepath.Join(dir, fmt.Sprintf("small/%02d/%d.txt", i%20, i))
+		os.MkdirAll(filepath.Dir(path), os.ModePerm)
+		if err := ioutil.WriteFile(path, small, 0644); err != nil {
+			b.Fatal(err)
+		}
+	}
+	for i := 0; i < 4; i++ {
+		os.MkdirAll(filepath.Join(dir, "photos"), os.ModePerm)
+		if err := ioutil.WriteFile(filepath.Join(dir, fmt.Sprintf("photos/%d.jpg", i)), large, 0644); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+func BenchmarkCopyDir(b *testing.B) {
+	source := benchTree(b)
+	defer os.RemoveAll(source)
+
+	for _, workers := range []int{1, 0} {
+		name := "serial"
+		if workers == 0 {
+			name = "workers"
+		}
+
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				dest := filepath.Join(os.TempDir(), fmt.Sprintf("baja-bench-copy-%d", i))
+				if _, err := utils.CopyDir(source, dest, utils.CopyDirOpts{Workers: workers}); err != nil {
+					b.Fatal(err)
+				}
+
+				b.StopTimer()
+				os.RemoveAll(dest)
+				b.StartTimer()
+			}
+		})
+	}
+}
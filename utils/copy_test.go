@@ -0,0 +1,350 @@
+package utils_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/yeo/baja/utils"
+)
+
+// copyDir is CopyDir without its result
+func copyDir(source, dest string, opts CopyDirOpts) error {
+	_, err := CopyDir(source, dest, opts)
+	return err
+}
+
+var _ = Describe("CopyDir", func() {
+	var dir, source, dest string
+
+	write := func(path, content string, mode os.FileMode) {
+		os.MkdirAll(filepath.Dir(path), os.ModePerm)
+		Expect(ioutil.WriteFile(path, []byte(content), mode)).To(Succeed())
+		Expect(os.Chmod(path, mode)).To(Succeed())
+	}
+
+	read := func(path string) string {
+		content, err := ioutil.ReadFile(path)
+		Expect(err).ToNot(HaveOccurred())
+		return string(content)
+	}
+
+	mode := func(path string) os.FileMode {
+		fi, err := os.Stat(path)
+		Expect(err).ToNot(HaveOccurred())
+		return fi.Mode().Perm()
+	}
+
+	BeforeEach(func() {
+		dir, _ = ioutil.TempDir("", "baja-copy")
+		source = filepath.Join(dir, "source")
+		dest = filepath.Join(dir, "dest")
+
+		write(filepath.Join(source, "app.css"), "site", 0644)
+		write(filepath.Join(source, "img/logo.svg"), "site logo", 0600)
+		write(filepath.Join(source, "img/icons/new.svg"), "new", 0644)
+	})
+
+	AfterEach(func() {
+		os.Chmod(filepath.Join(dest, "img/logo.svg"), 0644)
+		os.RemoveAll(dir)
+	})
+
+	withDest := func() {
+		write(filepath.Join(dest, "app.css"), "theme", 0644)
+		write(filepath.Join(dest, "img/logo.svg"), "theme logo", 0444)
+		write(filepath.Join(dest, "img/icons/old.svg"), "old", 0644)
+	}
+
+	It("copies a nested directory into a new destination", func() {
+		Expect(copyDir(source, dest, CopyDirOpts{})).To(Succeed())
+
+		Expect(read(filepath.Join(dest, "app.css"))).To(Equal("site"))
+		Expect(read(filepath.Join(dest, "img/icons/new.svg"))).To(Equal("new"))
+		Expect(mode(filepath.Join(dest, "img/logo.svg"))).To(Equal(os.FileMode(0600)))
+	})
+
+	It("reports the progress of each file in order", func() {
+		progress := []CopyProgress{}
+		opts := CopyDirOpts{Workers: 4, Progress: func(p CopyProgress) { progress = append(progress, p) }}
+		Expect(copyDir(source, dest, opts)).To(Succeed())
+
+		Expect(progress).To(HaveLen(3))
+		paths := []string{}
+		var bytes int64
+		for i, p := range progress {
+			Expect(p.Index).To(Equal(i + 1))
+			Expect(p.Total).To(Equal(3))
+			paths = append(paths, p.Path)
+			bytes += p.Bytes
+		}
+		Expect(paths).To(ConsistOf(filepath.Join(source, "app.css"), filepath.Join(source, "img/logo.svg"), filepath.Join(source, "img/icons/new.svg")))
+		Expect(bytes).To(Equal(int64(len("site") + len("site logo") + len("new"))))
+	})
+
+	It("leaves out files and directories matching exclude", func() {
+		write(filepath.Join(source, ".git/HEAD"), "ref", 0644)
+		write(filepath.Join(source, "img/.DS_Store"), "finder", 0644)
+
+		Expect(copyDir(source, dest, CopyDirOpts{Exclude: []string{".git", ".DS_Store", "img/icons"}})).To(Succeed())
+		Expect(filepath.Join(dest, ".git")).ToNot(BeADirectory())
+		Expect(filepath.Join(dest, "img/.DS_Store")).ToNot(BeAnExistingFile())
+		Expect(filepath.Join(dest, "img/icons")).ToNot(BeADirectory())
+		Expect(read(filepath.Join(dest, "img/logo.svg"))).To(Equal("site logo"))
+	})
+
+	It("creates empty directories with their permissions and counts the copy", func() {
+		Expect(os.MkdirAll(filepath.Join(source, "mnt/data"), 0755)).To(Succeed())
+		Expect(os.Chmod(filepath.Join(source, "mnt/data"), 0775)).To(Succeed())
+		Expect(os.Chmod(filepath.Join(source, "img"), 0750)).To(Succeed())
+		Expect(os.MkdirAll(filepath.Join(dest, "mnt"), 0755)).To(Succeed())
+
+		result, err := CopyDir(source, dest, CopyDirOpts{Existing: CopySkip})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(filepath.Join(dest, "mnt/data")).To(BeADirectory())
+		Expect(mode(filepath.Join(dest, "mnt/data"))).To(Equal(os.FileMode(0775)))
+		Expect(mode(filepath.Join(dest, "img"))).To(Equal(os.FileMode(0750)))
+		Expect(result).To(Equal(CopyDirResult{Files: 3, Dirs: 4, Bytes: int64(len("site" + "site logo" + "new"))}))
+
+		result, err = CopyDir(source, dest, CopyDirOpts{Existing: CopySkip})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result).To(Equal(CopyDirResult{Dirs: 4, Skipped: 3}))
+	})
+
+	It("returns invalid exclude patterns", func() {
+		Expect(copyDir(source, dest, CopyDirOpts{Exclude: []string{"[unclosed"}})).To(MatchError(ContainSubstring("invalid exclude pattern")))
+	})
+
+	Describe("modification times", func() {
+		past := time.Date(2019, 2, 9, 10, 30, 0, 0, time.UTC)
+
+		modTime := func(path string) time.Time {
+			fi, err := os.Stat(path)
+			Expect(err).ToNot(HaveOccurred())
+			return fi.ModTime()
+		}
+
+		BeforeEach(func() {
+			for _, p := range []string{"app.css", "img/logo.svg", "img/icons/new.svg", "img/icons", "img", ""} {
+				Expect(os.Chtimes(filepath.Join(source, p), past, past)).To(Succeed())
+			}
+		})
+
+		It("keeps the times of files and directories", func() {
+			Expect(copyDir(source, dest, CopyDirOpts{})).To(Succeed())
+
+			for _, p := range []string{"app.css", "img/icons/new.svg", "img/icons", "img", ""} {
+				Expect(modTime(filepath.Join(dest, p))).To(BeTemporally("~", past, time.Second), p)
+			}
+		})
+
+		It("leaves the time of the copy with ResetModTimes", func() {
+			Expect(copyDir(source, dest, CopyDirOpts{ResetModTimes: true})).To(Succeed())
+			Expect(modTime(filepath.Join(dest, "app.css"))).To(BeTemporally("~", time.Now(), time.Minute))
+		})
+
+		It("keeps the time of a single file", func() {
+			Expect(os.MkdirAll(dest, os.ModePerm)).To(Succeed())
+			Expect(CopyFile(filepath.Join(source, "app.css"), filepath.Join(dest, "app.css"))).To(Succeed())
+			Expect(modTime(filepath.Join(dest, "app.css"))).To(BeTemporally("~", past, time.Second))
+		})
+
+		It("warns about times it cannot set and copies anyway", func() {
+			defer SetChtimes(func(string, time.Time, time.Time) error { return os.ErrPermission })()
+
+			warnings := []error{}
+			err := copyDir(source, dest, CopyDirOpts{Warn: func(err error) { warnings = append(warnings, err) }})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(warnings).To(HaveLen(6))
+			Expect(read(filepath.Join(dest, "img/icons/new.svg"))).To(Equal("new"))
+
+			err = CopyFile(filepath.Join(source, "app.css"), filepath.Join(dest, "other.css"))
+			var timeErr *ModTimeError
+			Expect(errors.As(err, &timeErr)).To(BeTrue())
+			Expect(read(filepath.Join(dest, "other.css"))).To(Equal("site"))
+		})
+	})
+
+	Describe("symlinks", func() {
+		var warnings []error
+
+		BeforeEach(func() {
+			warnings = []error{}
+			write(filepath.Join(dir, "fonts/serif.woff"), "serif", 0644)
+			Expect(os.Symlink(filepath.Join(dir, "fonts"), filepath.Join(source, "fonts"))).To(Succeed())
+			Expect(os.Symlink("app.css", filepath.Join(source, "site.css"))).To(Succeed())
+			Expect(os.Symlink("..", filepath.Join(source, "img/up"))).To(Succeed())
+		})
+
+		copyWith := func(mode SymlinkMode) error {
+			return copyDir(source, dest, CopyDirOpts{Symlinks: mode, Warn: func(err error) { warnings = append(warnings, err) }})
+		}
+
+		It("copies the content of targets and breaks cycles by default", func() {
+			Expect(copyWith(SymlinkFollow)).To(Succeed())
+
+			Expect(read(filepath.Join(dest, "fonts/serif.woff"))).To(Equal("serif"))
+			Expect(read(filepath.Join(dest, "site.css"))).To(Equal("site"))
+			Expect(filepath.Join(dest, "img/up")).ToNot(BeADirectory())
+			Expect(warnings).To(HaveLen(1))
+			Expect(errors.Is(warnings[0], ErrSymlinkCycle)).To(BeTrue())
+			Expect(warnings[0].Error()).To(ContainSubstring("img/up -> .."))
+		})
+
+		It("recreates links", func() {
+			Expect(copyWith(SymlinkKeep)).To(Succeed())
+
+			target, err := os.Readlink(filepath.Join(dest, "site.css"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(target).To(Equal("app.css"))
+			Expect(read(filepath.Join(dest, "fonts/serif.woff"))).To(Equal("serif"))
+			Expect(warnings).To(BeEmpty())
+		})
+
+		It("skips links with a warning", func() {
+			Expect(copyWith(SymlinkSkip)).To(Succeed())
+
+			_, err := os.Lstat(filepath.Join(dest, "site.css"))
+			Expect(os.IsNotExist(err)).To(BeTrue())
+			Expect(warnings).To(HaveLen(3))
+			Expect(errors.Is(warnings[0], ErrSymlinkSkipped)).To(BeTrue())
+		})
+
+		It("copies file links and skips directory links with a warning", func() {
+			Expect(copyWith(SymlinkFiles)).To(Succeed())
+
+			Expect(read(filepath.Join(dest, "site.css"))).To(Equal("site"))
+			Expect(filepath.Join(dest, "fonts")).ToNot(BeADirectory())
+			Expect(warnings).To(HaveLen(2))
+			Expect(errors.Is(warnings[0], ErrSymlinkSkipped)).To(BeTrue())
+		})
+
+		It("reports dangling links with their target", func() {
+			Expect(os.Symlink("missing.css", filepath.Join(source, "broken.css"))).To(Succeed())
+
+			err := copyWith(SymlinkFollow)
+			var errs CopyErrors
+			Expect(errors.As(err, &errs)).To(BeTrue())
+			Expect(errs).To(HaveLen(1))
+			Expect(errors.Is(errs[0], ErrSymlinkDangling)).To(BeTrue())
+			Expect(errs[0].Error()).To(ContainSubstring("broken.css -> missing.css"))
+			Expect(read(filepath.Join(dest, "app.css"))).To(Equal("site"))
+		})
+	})
+
+	It("fails on a source which isn't a directory", func() {
+		file := filepath.Join(source, "app.css")
+
+		err := copyDir(file, dest, CopyDirOpts{})
+		Expect(errors.Is(err, ErrNotDirectory)).To(BeTrue())
+		Expect(err.Error()).To(Equal("cannot copy dir " + file + ": not a directory"))
+
+		err = CopyFile(source, filepath.Join(dir, "copy"))
+		Expect(errors.Is(err, ErrIsDirectory)).To(BeTrue())
+		Expect(filepath.Join(dir, "copy")).ToNot(BeAnExistingFile())
+	})
+
+	It("fails on an existing destination by default", func() {
+		withDest()
+
+		err := copyDir(source, dest, CopyDirOpts{Existing: CopyFail})
+		Expect(errors.Is(err, ErrDestinationExists)).To(BeTrue())
+		var pathErr *PathError
+		Expect(errors.As(err, &pathErr)).To(BeTrue())
+		Expect(pathErr.Path).To(Equal(dest))
+		Expect(read(filepath.Join(dest, "app.css"))).To(Equal("theme"))
+		Expect(filepath.Join(dest, "img/icons/new.svg")).ToNot(BeAnExistingFile())
+	})
+
+	It("skips the files an existing destination has", func() {
+		withDest()
+
+		Expect(copyDir(source, dest, CopyDirOpts{Existing: CopySkip})).To(Succeed())
+		Expect(read(filepath.Join(dest, "app.css"))).To(Equal("theme"))
+		Expect(read(filepath.Join(dest, "img/logo.svg"))).To(Equal("theme logo"))
+		Expect(mode(filepath.Join(dest, "img/logo.svg"))).To(Equal(os.FileMode(0444)))
+		Expect(read(filepath.Join(dest, "img/icons/new.svg"))).To(Equal("new"))
+		Expect(read(filepath.Join(dest, "img/icons/old.svg"))).To(Equal("old"))
+	})
+
+	It("replaces only the files of an existing destination which differ from their source", func() {
+		withDest()
+		write(filepath.Join(dest, "img/icons/new.svg"), "new", 0644)
+		info, _ := os.Stat(filepath.Join(source, "img/icons/new.svg"))
+		Expect(os.Chtimes(filepath.Join(dest, "img/icons/new.svg"), info.ModTime(), info.ModTime())).To(Succeed())
+
+		result, err := CopyDir(source, dest, CopyDirOpts{Existing: CopySync})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(read(filepath.Join(dest, "app.css"))).To(Equal("site"))
+		Expect(read(filepath.Join(dest, "img/logo.svg"))).To(Equal("site logo"))
+		Expect(read(filepath.Join(dest, "img/icons/old.svg"))).To(Equal("old"))
+		Expect(result.Files).To(Equal(2))
+		Expect(result.Skipped).To(Equal(1))
+	})
+
+	It("copies only the files include accepts, with a hashed copy each", func() {
+		progress := []CopyProgress{}
+		opts := CopyDirOpts{
+			Include:     func(rel string) bool { return rel != "app.css" },
+			Fingerprint: true,
+			Progress:    func(p CopyProgress) { progress = append(progress, p) },
+		}
+		Expect(copyDir(source, dest, opts)).To(Succeed())
+
+		Expect(filepath.Join(dest, "app.css")).ToNot(BeAnExistingFile())
+		Expect(progress).To(HaveLen(2))
+		for _, p := range progress {
+			Expect(p.Copied).To(BeTrue())
+			Expect(read(p.Hashed)).To(Equal(read(p.Dest)))
+			Expect(p.Hashed).To(MatchRegexp(`-[0-9a-f]{64}\.svg$`))
+		}
+	})
+
+	It("overwrites the files and permissions of an existing destination", func() {
+		withDest()
+
+		Expect(copyDir(source, dest, CopyDirOpts{Existing: CopyOverwrite})).To(Succeed())
+		Expect(read(filepath.Join(dest, "app.css"))).To(Equal("site"))
+		Expect(read(filepath.Join(dest, "img/logo.svg"))).To(Equal("site logo"))
+		Expect(mode(filepath.Join(dest, "img/logo.svg"))).To(Equal(os.FileMode(0600)))
+		Expect(read(filepath.Join(dest, "img/icons/new.svg"))).To(Equal("new"))
+		Expect(read(filepath.Join(dest, "img/icons/old.svg"))).To(Equal("old"))
+	})
+})
+
+var _ = Describe("SyncFile", func() {
+	var dir string
+
+	BeforeEach(func() {
+		dir, _ = ioutil.TempDir("", "baja-sync")
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("skips a destination of the same content with another time and gives it the source time", func() {
+		past := time.Date(2019, 2, 9, 10, 30, 0, 0, time.UTC)
+		source, dest := filepath.Join(dir, "app.css"), filepath.Join(dir, "public/app.css")
+		Expect(ioutil.WriteFile(source, []byte("body {}"), 0644)).To(Succeed())
+		Expect(os.Chtimes(source, past, past)).To(Succeed())
+		Expect(os.MkdirAll(filepath.Dir(dest), os.ModePerm)).To(Succeed())
+		Expect(ioutil.WriteFile(dest, []byte("body {}"), 0644)).To(Succeed())
+
+		copied, err := SyncFile(source, dest)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(copied).To(BeFalse())
+		fi, _ := os.Stat(dest)
+		Expect(fi.ModTime()).To(BeTemporally("~", past, time.Second))
+
+		Expect(ioutil.WriteFile(dest, []byte("body {x"), 0644)).To(Succeed())
+		copied, err = SyncFile(source, dest)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(copied).To(BeTrue())
+	})
+})
@@ -0,0 +1,30 @@
+package utils
+
+import (
+	"os"
+)
+
+// DefaultDirMode is the mode of the directories baja creates, before umask
+const DefaultDirMode os.FileMode = 0755
+
+// EnsureDir creates directory path with mode, and its missing parents, unless it exists. A path
+// which exists but isn't a directory is a PathError of ErrNotDirectory, any other failure, eg:
+// a permission denied, a PathError of the file system error
+func EnsureDir(path string, mode os.FileMode) error {
+	info, err := os.Stat(path)
+	if err == nil {
+		if !info.IsDir() {
+			return &PathError{Op: "create dir", Path: path, Err: ErrNotDirectory}
+		}
+		return nil
+	}
+	if !os.IsNotExist(err) {
+		return &PathError{Op: "create dir", Path: path, Err: err}
+	}
+
+	if err := os.MkdirAll(path, mode); err != nil {
+		return &PathError{Op: "create dir", Path: path, Err: err}
+	}
+
+	return nil
+}
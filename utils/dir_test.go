@@ -0,0 +1,50 @@
+package utils_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/yeo/baja/utils"
+)
+
+var _ = Describe("EnsureDir", func() {
+	var dir string
+
+	BeforeEach(func() {
+		dir, _ = ioutil.TempDir("", "baja-dir")
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("creates the directory and its parents with mode", func() {
+		path := filepath.Join(dir, "a", "b")
+		Expect(EnsureDir(path, 0750)).To(Succeed())
+
+		info, err := os.Stat(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.IsDir()).To(BeTrue())
+		Expect(info.Mode().Perm()).To(Equal(os.FileMode(0750)))
+
+		Expect(EnsureDir(path, 0750)).To(Succeed())
+	})
+
+	It("refuses a file in place of the directory", func() {
+		path := filepath.Join(dir, "public")
+		Expect(ioutil.WriteFile(path, []byte("file"), 0644)).To(Succeed())
+
+		err := EnsureDir(path, DefaultDirMode)
+		Expect(errors.Is(err, ErrNotDirectory)).To(BeTrue())
+		Expect(err.Error()).To(Equal("cannot create dir " + path + ": not a directory"))
+
+		var pathErr *PathError
+		Expect(errors.As(EnsureDir(filepath.Join(path, "post"), DefaultDirMode), &pathErr)).To(BeTrue())
+		Expect(pathErr.Path).To(Equal(filepath.Join(path, "post")))
+	})
+})
@@ -0,0 +1,12 @@
+package utils
+
+import (
+	"os"
+	"time"
+)
+
+// SetChtimes replaces os.Chtimes in copies and returns a func restoring it
+func SetChtimes(f func(string, time.Time, time.Time) error) func() {
+	chtimes = f
+	return func() { chtimes = os.Chtimes }
+}
@@ -0,0 +1,74 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// hashKey is a file as last seen, a file with another size or time is hashed again
+type hashKey struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+var (
+	hashMu    sync.Mutex
+	hashCache = map[hashKey]string{}
+)
+
+// HashReader returns the hex SHA-256 of what's read from r, streamed
+func HashReader(r io.Reader) (string, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+// HashFile returns the hex SHA-256 of the content of the file at path. It's remembered by path,
+// size and modification time until ResetHashCache, so asset fingerprints and static copies of
+// one build read a file once
+func HashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+
+	key := hashKey{path, info.Size(), info.ModTime()}
+	hashMu.Lock()
+	hash, ok := hashCache[key]
+	hashMu.Unlock()
+	if ok {
+		return hash, nil
+	}
+
+	if hash, err = HashReader(f); err != nil {
+		return "", err
+	}
+
+	hashMu.Lock()
+	hashCache[key] = hash
+	hashMu.Unlock()
+
+	return hash, nil
+}
+
+// ResetHashCache forgets the hashes of HashFile, which each build does first: a file rewritten
+// with the same size within the time resolution of the file system would keep its old hash
+func ResetHashCache() {
+	hashMu.Lock()
+	hashCache = map[hashKey]string{}
+	hashMu.Unlock()
+}
@@ -0,0 +1,71 @@
+package utils_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/yeo/baja/utils"
+)
+
+// BenchmarkHashFile hashes a 64MB file, failing when a run allocates a fraction of its size:
+// the file must be streamed, not read into memory
+func BenchmarkHashFile(b *testing.B) {
+	dir, err := ioutil.TempDir("", "baja-bench")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	const size = 64 << 20
+	path := filepath.Join(dir, "video.mp4")
+	if err := ioutil.WriteFile(path, make([]byte, size), 0644); err != nil {
+		b.Fatal(err)
+	}
+
+	b.SetBytes(size)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		utils.ResetHashCache()
+
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		if _, err := utils.HashFile(path); err != nil {
+			b.Fatal(err)
+		}
+		runtime.ReadMemStats(&after)
+
+		if allocated := after.TotalAlloc - before.TotalAlloc; allocated > size/16 {
+			b.Fatalf("hashing allocated %d bytes, the file isn't streamed", allocated)
+		}
+	}
+}
+
+// BenchmarkHashFileCached is HashFile of a file already hashed by the build
+func BenchmarkHashFileCached(b *testing.B) {
+	dir, err := ioutil.TempDir("", "baja-bench")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "video.mp4")
+	if err := ioutil.WriteFile(path, make([]byte, 64<<20), 0644); err != nil {
+		b.Fatal(err)
+	}
+	utils.ResetHashCache()
+	if _, err := utils.HashFile(path); err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := utils.HashFile(path); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
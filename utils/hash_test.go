@@ -0,0 +1,59 @@
+package utils_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/yeo/baja/utils"
+)
+
+var _ = Describe("HashFile", func() {
+	var dir, path string
+
+	BeforeEach(func() {
+		dir, _ = ioutil.TempDir("", "baja-hash")
+		path = filepath.Join(dir, "app.css")
+		Expect(ioutil.WriteFile(path, []byte("site"), 0644)).To(Succeed())
+		ResetHashCache()
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("is the hex SHA-256 of the content, like HashReader", func() {
+		hash, err := HashFile(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(hash).To(Equal("fbae041b02c41ed0fd8a4efb039bc780dd6af4a1f0c420f42561ae705dda43fe"))
+		Expect(HashReader(strings.NewReader("site"))).To(Equal(hash))
+	})
+
+	It("is remembered until the size or time of the file changes", func() {
+		first, _ := HashFile(path)
+		info, _ := os.Stat(path)
+
+		// same size and time, the remembered hash is returned
+		Expect(ioutil.WriteFile(path, []byte("edit"), 0644)).To(Succeed())
+		Expect(os.Chtimes(path, info.ModTime(), info.ModTime())).To(Succeed())
+		Expect(HashFile(path)).To(Equal(first))
+
+		Expect(os.Chtimes(path, info.ModTime().Add(time.Second), info.ModTime().Add(time.Second))).To(Succeed())
+		Expect(HashFile(path)).NotTo(Equal(first))
+	})
+
+	It("is read again after ResetHashCache", func() {
+		first, _ := HashFile(path)
+		info, _ := os.Stat(path)
+		Expect(ioutil.WriteFile(path, []byte("edit"), 0644)).To(Succeed())
+		Expect(os.Chtimes(path, info.ModTime(), info.ModTime())).To(Succeed())
+
+		ResetHashCache()
+		Expect(HashFile(path)).NotTo(Equal(first))
+	})
+})
@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// MaxSlugLength is the longest slug Slugify returns, in bytes
+const MaxSlugLength = 80
+
+// transliterations are the latin letters NFD doesn't decompose into a base letter and marks
+var transliterations = map[rune]string{
+	'đ': "d", 'Đ': "d", 'ð': "d", 'Ð': "d",
+	'ł': "l", 'Ł': "l",
+	'ø': "o", 'Ø': "o",
+	'ı': "i",
+	'ß': "ss",
+	'æ': "ae", 'Æ': "ae",
+	'œ': "oe", 'Œ': "oe",
+	'þ': "th", 'Þ': "th",
+}
+
+// Slugify returns s as a url path segment: lower case ASCII letters and digits joined by single
+// dashes, eg: "Phở Hà Nội!" to pho-ha-noi. Accents are dropped, other characters such as emoji
+// separate words. A slug longer than MaxSlugLength is cut after its last whole word.
+// Permalinks, author pages, footnote anchors and the slugify template function all use it so
+// their urls agree
+func Slugify(s string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range norm.NFD.String(s) {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		if t, ok := transliterations[r]; ok {
+			b.WriteString(t)
+			dash = false
+			continue
+		}
+
+		r = unicode.ToLower(r)
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+
+	slug := strings.TrimSuffix(b.String(), "-")
+	if len(slug) > MaxSlugLength {
+		slug = slug[:MaxSlugLength+1]
+		if i := strings.LastIndexByte(slug, '-'); i > 0 {
+			slug = slug[:i]
+		} else {
+			slug = slug[:MaxSlugLength]
+		}
+	}
+
+	return slug
+}
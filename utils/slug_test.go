@@ -0,0 +1,40 @@
+package utils_test
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	"github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	. "github.com/yeo/baja/utils"
+)
+
+var _ = Describe("Slugify", func() {
+	table.DescribeTable("makes a url path segment",
+		func(s, slug string) {
+			Expect(Slugify(s)).To(Equal(slug))
+		},
+		table.Entry("already clean", "hello-world", "hello-world"),
+		table.Entry("upper case and punctuation", "Hello, World!", "hello-world"),
+		table.Entry("runs of separators", "  a -- b__c  ", "a-b-c"),
+		table.Entry("accents", "Crème brûlée à Zürich", "creme-brulee-a-zurich"),
+		table.Entry("vietnamese", "Phở Hà Nội được ưa thích", "pho-ha-noi-duoc-ua-thich"),
+		table.Entry("latin extended", "Łódź Straße Æsir Œuvre Søren", "lodz-strasse-aesir-oeuvre-soren"),
+		table.Entry("emoji", "🎉 launch 🚀 day", "launch-day"),
+		table.Entry("other scripts", "日本 go", "go"),
+		table.Entry("nothing left", "🎉!?", ""),
+	)
+
+	It("cuts a long slug after a whole word", func() {
+		slug := Slugify(strings.Repeat("word ", 30))
+
+		Expect(len(slug)).To(BeNumerically("<=", MaxSlugLength))
+		Expect(slug).To(HavePrefix("word-word"))
+		Expect(slug).To(HaveSuffix("-word"))
+	})
+
+	It("cuts a single long word at the limit", func() {
+		Expect(Slugify(strings.Repeat("a", 100))).To(Equal(strings.Repeat("a", MaxSlugLength)))
+	})
+})
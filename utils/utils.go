@@ -1,13 +1,16 @@
 package utils
 
 import (
-	"crypto/md5"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
-	"log"
 	"os"
+	"path"
+	"path/filepath"
+	"runtime"
 	"strings"
+	"sync"
 )
 
 func HasFile(path string) bool {
@@ -15,125 +18,681 @@ func HasFile(path string) bool {
 	return err == nil
 }
 
-func GenerateAssetHash(path string) (string, error) {
-	f, err := os.Open("./public/" + path)
+// GenerateAssetHash returns path with the hash of the file at root/path in its name, eg: /app-<hash>.css
+func GenerateAssetHash(root, path string) (string, error) {
+	hash, err := HashFile(filepath.Join(root, path))
 	if err != nil {
 		return "", err
 	}
-	defer f.Close()
 
-	h := md5.New()
-	if _, err := io.Copy(h, f); err != nil {
-		return "", err
-	}
+	return hashedPath(path, hash), nil
+}
 
-	hash := fmt.Sprintf("%x", h.Sum(nil))
-	fileExtPos := strings.LastIndex(path, ".")
-	pathWithHash := path[0:fileExtPos] + "-" + hash + path[fileExtPos:]
+// SameContent reports whether the files at a and b have the same content
+func SameContent(a, b string) (bool, error) {
+	ha, err := HashFile(a)
+	if err != nil {
+		return false, err
+	}
+	hb, err := HashFile(b)
+	if err != nil {
+		return false, err
+	}
 
-	return pathWithHash, nil
+	return ha == hb, nil
 }
 
 func CopyFileWithHash(path string) error {
-	f, err := os.Open(path)
+	hashed, err := GenerateAssetHash("", path)
 	if err != nil {
 		return err
 	}
-	defer f.Close()
 
-	h := md5.New()
-	if _, err := io.Copy(h, f); err != nil {
-		return err
+	return CopyFile(path, hashed)
+}
+
+// SyncFile copies source to dest unless dest has the size and modification time of source, as
+// left by a previous SyncFile. When only the time differs, eg: after a fresh checkout, dest of
+// the same content isn't copied but gets the time of source. It returns whether the file was copied
+func SyncFile(source, dest string) (bool, error) {
+	si, err := os.Stat(source)
+	if err != nil {
+		return false, err
+	}
+
+	if di, err := os.Stat(dest); err == nil {
+		if synced, err := inSync(source, dest, si, di); synced || err != nil {
+			return false, err
+		}
+	}
+
+	if err := EnsureDir(filepath.Dir(dest), DefaultDirMode); err != nil {
+		return false, err
 	}
+	return true, CopyFile(source, dest)
+}
 
-	hash := fmt.Sprintf("%x", h.Sum(nil))
-	//TODO: naieve, will break if file name has no . and other upper directory has .
-	fileExtPos := strings.LastIndex(path, ".")
-	pathWithHash := path[0:fileExtPos] + "-" + hash + path[fileExtPos:]
+// inSync reports whether dest, of info di, is a copy of source, of info si: same size and time,
+// or same content whose time is then set to the one of source
+func inSync(source, dest string, si, di os.FileInfo) (bool, error) {
+	if di.Size() != si.Size() {
+		return false, nil
+	}
+	if di.ModTime().Equal(si.ModTime()) {
+		return true, nil
+	}
+	if same, _ := SameContent(source, dest); same {
+		return true, keepModTime(dest, si)
+	}
 
-	return CopyFile(path, pathWithHash)
+	return false, nil
 }
 
-// Copies file source to destination dest.
-func CopyFile(source string, dest string) (err error) {
-	sf, err := os.Open(source)
+// hashedPath inserts hash before the extension of path: app.css becomes app-<hash>.css
+func hashedPath(path, hash string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "-" + hash + ext
+}
+
+// Copies file source to destination dest, with the permissions and modification time of source
+// so tools comparing time and size, eg: a deploy, see it unchanged. A time which can't be set is
+// a ModTimeError, dest is copied anyway
+func CopyFile(source string, dest string) error {
+	si, err := copyFile(source, dest, nil)
 	if err != nil {
 		return err
 	}
+
+	return keepModTime(dest, si)
+}
+
+// copyFile copies the content and permissions of source to dest through buf, allocated when nil,
+// and returns the info of source
+func copyFile(source, dest string, buf []byte) (os.FileInfo, error) {
+	sf, err := os.Open(source)
+	if err != nil {
+		return nil, err
+	}
 	defer sf.Close()
+
+	si, err := sf.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if si.IsDir() {
+		return nil, &PathError{Op: "copy file", Path: source, Err: ErrIsDirectory}
+	}
+
 	df, err := os.Create(dest)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if _, err := io.CopyBuffer(df, sf, buf); err != nil {
+		df.Close()
+		return nil, err
+	}
+	if err := df.Close(); err != nil {
+		return nil, err
+	}
+
+	return si, os.Chmod(dest, si.Mode())
+}
+
+// chtimes sets the times of a file, replaced by tests
+var chtimes = os.Chtimes
+
+// ModTimeError is a copy whose modification time couldn't be set to the one of its source. It's a
+// warning, the content is copied
+type ModTimeError struct {
+	Path string
+	Err  error
+}
+
+func (e *ModTimeError) Error() string {
+	return "cannot keep modification time of " + e.Path + ": " + e.Err.Error()
+}
+
+func (e *ModTimeError) Unwrap() error {
+	return e.Err
+}
+
+// keepModTime sets the modification time of dest to the one of source info si
+func keepModTime(dest string, si os.FileInfo) error {
+	if err := chtimes(dest, si.ModTime(), si.ModTime()); err != nil {
+		return &ModTimeError{Path: dest, Err: err}
 	}
-	defer df.Close()
-	_, err = io.Copy(df, sf)
-	if err == nil {
-		si, err := os.Stat(source)
-		if err == nil {
-			err = os.Chmod(dest, si.Mode())
+
+	return nil
+}
+
+var (
+	// ErrNotDirectory is a path which must be a directory and isn't, eg: the source of CopyDir
+	ErrNotDirectory = errors.New("not a directory")
+
+	// ErrIsDirectory is a path which must be a file and is a directory, eg: the source of CopyFile
+	ErrIsDirectory = errors.New("is a directory")
+
+	// ErrDestinationExists is returned by CopyDir when the destination exists in CopyFail mode
+	ErrDestinationExists = errors.New("destination already exists")
+
+	// Deprecated: ErrDestExists is ErrDestinationExists
+	ErrDestExists = ErrDestinationExists
+)
+
+// PathError is an operation which failed on a path, eg: copy dir of CopyDir. Err is a sentinel
+// such as ErrNotDirectory, to check with errors.Is, or the error of the file system
+type PathError struct {
+	Op   string
+	Path string
+	Err  error
+}
+
+func (e *PathError) Error() string {
+	return "cannot " + e.Op + " " + e.Path + ": " + e.Err.Error()
+}
+
+func (e *PathError) Unwrap() error {
+	return e.Err
+}
+
+// CopyMode is what CopyDir does with a destination which exists
+type CopyMode int
+
+const (
+	CopyFail      CopyMode = iota // return ErrDestinationExists, the default
+	CopySkip                      // copy into it, keeping the files it has
+	CopyOverwrite                 // copy into it, replacing files and permissions of the same name
+	CopySync                      // copy into it, replacing the files which differ from their source, see SyncFile
+)
+
+// SymlinkMode is what CopyDir does with a symlink of the source
+type SymlinkMode int
+
+const (
+	SymlinkFollow SymlinkMode = iota // copy the content of its target, the default
+	SymlinkKeep                      // create the same link at the destination
+	SymlinkSkip                      // leave it out with a warning
+	SymlinkFiles                     // copy the content of a file link, leave a directory link out with a warning
+)
+
+var (
+	// ErrSymlinkSkipped is the SymlinkError warning of a link left out with SymlinkSkip, or a
+	// directory link of SymlinkFiles or of Walk without follow
+	ErrSymlinkSkipped = errors.New("symlink skipped")
+	// ErrSymlinkCycle is the SymlinkError warning of a directory link to a directory it's in
+	ErrSymlinkCycle = errors.New("symlink cycle, not followed")
+	// ErrSymlinkDangling is the SymlinkError of a link whose target doesn't exist
+	ErrSymlinkDangling = errors.New("dangling symlink")
+)
+
+// SymlinkError is a symlink of a CopyDir source which wasn't copied as is
+type SymlinkError struct {
+	Path   string
+	Target string
+	Err    error
+}
+
+func (e *SymlinkError) Error() string {
+	return e.Path + " -> " + e.Target + ": " + e.Err.Error()
+}
+
+func (e *SymlinkError) Unwrap() error {
+	return e.Err
+}
+
+// CopyDirOpts are the options of CopyDir
+type CopyDirOpts struct {
+	Existing CopyMode
+	Exclude  []string // files and directories not copied, see Excluded
+	Symlinks SymlinkMode
+
+	// Include, when set, is called with the path relative to the source of each file, which is
+	// only copied when it returns true, eg: to leave out the files another copy provides
+	Include func(rel string) bool
+
+	// Fingerprint also copies each file next to its copy with the hash of its content in the name,
+	// see GenerateAssetHash. The hashed copy is made again when the file is copied
+	Fingerprint bool
+
+	// ResetModTimes leaves copies with the time of the copy, by default files and directories
+	// keep the modification time of their source
+	ResetModTimes bool
+
+	// Warn is called with each problem which isn't a copy failure: a ModTimeError, or the
+	// SymlinkError of a skipped link, a cycle or a dangling link kept. Nil ignores them. Calls
+	// don't overlap
+	Warn func(err error)
+
+	// Workers is the number of files copied at once, default to the number of CPUs
+	Workers int
+
+	// Progress is called once a file is done with, copied or not, eg: to log a progress line
+	// during a large copy. Calls don't overlap and come in the order of CopyProgress.Index
+	Progress func(p CopyProgress)
+}
+
+// CopyProgress is a file of a CopyDir done with
+type CopyProgress struct {
+	Path   string // source of the file
+	Dest   string // copy of the file, empty when it couldn't be copied
+	Hashed string // hashed copy of the file with Fingerprint
+	Copied bool   // false when Dest was kept, with CopySkip or unchanged with CopySync
+	Bytes  int64  // size of the file
+	Index  int    // number of files done, this one included
+	Total  int    // number of files to copy
+}
+
+// copyBufferSize is the buffer of each CopyDir worker, large files such as photos are copied in
+// fewer reads and writes than with the io.Copy default
+const copyBufferSize = 256 << 10
+
+// Excluded reports whether rel, a path relative to the root of a copy, or one of its parent
+// directories matches one of the glob patterns, against the whole path or its name so .git
+// matches at any depth. Patterns use slashes, rel is matched the same on Windows
+func Excluded(patterns []string, rel string) bool {
+	for p := filepath.ToSlash(rel); p != "." && p != "/" && p != ""; p = path.Dir(p) {
+		for _, pattern := range patterns {
+			whole, _ := path.Match(pattern, p)
+			name, _ := path.Match(pattern, path.Base(p))
+			if whole || name {
+				return true
+			}
 		}
+	}
+
+	return false
+}
 
+// CopyError is an entry of a directory CopyDir couldn't copy
+type CopyError struct {
+	Path string
+	Err  error
+}
+
+func (e *CopyError) Error() string {
+	return e.Path + ": " + e.Err.Error()
+}
+
+func (e *CopyError) Unwrap() error {
+	return e.Err
+}
+
+// CopyErrors are the entries CopyDir couldn't copy, the others are copied anyway
+type CopyErrors []*CopyError
+
+func (e CopyErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
 	}
 
-	return
+	return fmt.Sprintf("cannot copy %d files: %s", len(e), strings.Join(messages, "; "))
 }
 
-// Recursively copies a directory tree, attempting to preserve permissions.
-// Source directory must exist, destination directory must *not* exist.
-func CopyDir(source string, dest string) (err error) {
-	// get properties of source dir
+// CopyDirResult counts what a CopyDir did, eg: for the stats of a build
+type CopyDirResult struct {
+	Files   int   // files copied
+	Dirs    int   // directories of the source under the destination, empty ones included
+	Bytes   int64 // size of the files copied
+	Skipped int   // files kept at the destination with CopySkip, or unchanged with CopySync
+}
+
+// CopyDir recursively copies a directory tree, attempting to preserve permissions and, unless
+// opts.ResetModTimes, modification times. Source directory must exist. Every directory is
+// created with the permissions of its source, an empty one too. opts.Existing says what
+// to do when the destination exists, eg: CopyOverwrite to copy the static files of a site over
+// the ones of its theme. Files and directories matching opts.Exclude, and files opts.Include
+// rejects, are skipped, symlinks are handled by opts.Symlinks. Every entry which can't be copied, eg: a
+// dangling symlink followed, is returned in CopyErrors, warnings only go to opts.Warn.
+// Directories are created first, then their files are copied by opts.Workers at once, reported
+// to opts.Progress. The result counts what was copied, errors or not
+func CopyDir(source string, dest string, opts CopyDirOpts) (CopyDirResult, error) {
 	fi, err := os.Stat(source)
 	if err != nil {
-		log.Println("Error", err)
-		return err
+		return CopyDirResult{}, err
 	}
 
 	if !fi.IsDir() {
-		return &CustomError{"Source is not a directory"}
+		return CopyDirResult{}, &PathError{Op: "copy dir", Path: source, Err: ErrNotDirectory}
 	}
 
-	// ensure dest dir does not already exist
+	for _, pattern := range opts.Exclude {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return CopyDirResult{}, fmt.Errorf("invalid exclude pattern %q: %w", pattern, err)
+		}
+	}
 
-	_, err = os.Open(dest)
-	if os.IsNotExist(err) {
-		err = os.MkdirAll(dest, fi.Mode())
-		if err != nil {
-			return err
+	if _, err := os.Stat(dest); err == nil && opts.Existing == CopyFail {
+		return CopyDirResult{}, &PathError{Op: "copy dir", Path: dest, Err: ErrDestinationExists}
+	}
+	chmod, err := makeDir(dest, opts)
+	if err != nil {
+		return CopyDirResult{}, err
+	}
+
+	resolved, err := filepath.EvalSymlinks(source)
+	if err != nil {
+		return CopyDirResult{}, err
+	}
+
+	c := newCopier(opts)
+	c.dir(source, dest, "", []string{resolved})
+	c.result.Dirs = len(c.dirs)
+	c.dirs = append([]copiedDir{{dest, fi, chmod}}, c.dirs...)
+	c.copy()
+
+	if len(c.errs) == 0 {
+		return c.result, nil
+	}
+
+	return c.result, c.errs
+}
+
+// copier copies the files of a CopyDir with a pool of workers
+type copier struct {
+	opts   CopyDirOpts
+	files  []copyJob
+	mu     sync.Mutex // guards errs, count, result and calls to opts.Warn and opts.Progress
+	errs   CopyErrors
+	count  int // files done
+	result CopyDirResult
+	dirs   []copiedDir // in the order they're created, parents first
+}
+
+// copyJob is a file of the source to copy to dest
+type copyJob struct {
+	source string
+	dest   string
+	size   int64
+}
+
+// copiedDir is a directory of the destination with the info of its source, chmod when it gets
+// the permissions of the source
+type copiedDir struct {
+	path  string
+	info  os.FileInfo
+	chmod bool
+}
+
+func newCopier(opts CopyDirOpts) *copier {
+	c := &copier{opts: opts}
+	if warn := opts.Warn; warn != nil {
+		c.opts.Warn = func(err error) {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			warn(err)
+		}
+	}
+
+	return c
+}
+
+// fail records the entry at path which couldn't be copied
+func (c *copier) fail(path string, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.errs = append(c.errs, &CopyError{Path: path, Err: err})
+}
+
+// copy copies the files queued by dir with opts.Workers, then sets the permissions and times of
+// directories, children first as copying into a directory changes its time and a read only
+// directory is written into before
+func (c *copier) copy() {
+	workers := c.opts.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	jobs := make(chan copyJob, workers)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			buf := make([]byte, copyBufferSize)
+			for job := range jobs {
+				p, err := c.file(job, buf)
+				if err != nil {
+					c.fail(job.source, err)
+					p = CopyProgress{Path: job.source, Bytes: job.size}
+				}
+				c.done(p)
+			}
+		}()
+	}
+	for _, job := range c.files {
+		jobs <- job
+	}
+	close(jobs)
+	wg.Wait()
+
+	for i := len(c.dirs) - 1; i >= 0; i-- {
+		dir := c.dirs[i]
+		if dir.chmod {
+			if err := os.Chmod(dir.path, dir.info.Mode().Perm()); err != nil {
+				c.fail(dir.path, err)
+			}
 		}
+		if !c.opts.ResetModTimes {
+			c.opts.warn(keepModTime(dir.path, dir.info))
+		}
+	}
+}
+
+// file copies the file of job through buf, then its hashed copy with opts.Fingerprint. It returns
+// the progress of job without its index
+func (c *copier) file(job copyJob, buf []byte) (CopyProgress, error) {
+	p := CopyProgress{Path: job.source, Bytes: job.size}
+	copied, err := copyEntry(job.source, job.dest, c.opts, buf)
+	if err != nil {
+		return p, err
+	}
+	p.Dest, p.Copied = job.dest, copied
+	if !c.opts.Fingerprint {
+		return p, nil
 	}
 
+	if p.Hashed, err = GenerateAssetHash("", job.dest); err != nil {
+		return p, err
+	}
+	if copied || !HasFile(p.Hashed) {
+		err = c.opts.warn(CopyFile(job.dest, p.Hashed))
+	}
+
+	return p, err
+}
+
+// done adds the file of p to the result, copied or kept, and reports it to opts.Progress
+func (c *copier) done(p CopyProgress) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if p.Copied {
+		c.result.Files++
+		c.result.Bytes += p.Bytes
+	} else if p.Dest != "" {
+		c.result.Skipped++
+	}
+
+	c.count++
+	if c.opts.Progress != nil {
+		p.Index, p.Total = c.count, len(c.files)
+		c.opts.Progress(p)
+	}
+}
+
+// dir creates the directories of source, at rel under the root of the copy, into dest and queues
+// its files for copy. parents are the real paths of source and the directories it's in, a link to one of
+// them is a cycle
+func (c *copier) dir(source, dest, rel string, parents []string) {
 	entries, err := ioutil.ReadDir(source)
 	if err != nil {
-		log.Fatal("Cannot copy", err)
+		c.fail(source, err)
+		return
 	}
 
 	for _, entry := range entries {
+		sfp := filepath.Join(source, entry.Name())
+		dfp := filepath.Join(dest, entry.Name())
+		rfp := filepath.Join(rel, entry.Name())
+		if Excluded(c.opts.Exclude, rfp) {
+			continue
+		}
 
-		sfp := source + "/" + entry.Name()
-		dfp := dest + "/" + entry.Name()
-		if entry.IsDir() {
-			err = CopyDir(sfp, dfp)
-			if err != nil {
-				log.Println(err)
+		if entry.Mode()&os.ModeSymlink != 0 {
+			if entry, err = followSymlink(sfp, dfp, parents, c.opts); err != nil {
+				c.fail(sfp, err)
+				continue
+			}
+			if entry == nil {
+				continue
 			}
-		} else {
-			// perform copy
-			err = CopyFile(sfp, dfp)
+		}
+
+		if !entry.IsDir() {
+			if c.opts.Include != nil && !c.opts.Include(rfp) {
+				continue
+			}
+			c.files = append(c.files, copyJob{sfp, dfp, entry.Size()})
+			continue
+		}
+
+		chmod, err := makeDir(dfp, c.opts)
+		if err != nil {
+			c.fail(sfp, err)
+			continue
+		}
+		c.dirs = append(c.dirs, copiedDir{dfp, entry, chmod})
+		resolved, _ := filepath.EvalSymlinks(sfp)
+		c.dir(sfp, dfp, rfp, append(parents[:len(parents):len(parents)], resolved))
+	}
+}
+
+// followSymlink handles the symlink source with opts.Symlinks. It returns the info of its target
+// when it's to be copied as a file or directory, nil when it's done with
+func followSymlink(source, dest string, parents []string, opts CopyDirOpts) (os.FileInfo, error) {
+	target, err := os.Readlink(source)
+	if err != nil {
+		return nil, err
+	}
+
+	switch opts.Symlinks {
+	case SymlinkSkip:
+		opts.warning(&SymlinkError{Path: source, Target: target, Err: ErrSymlinkSkipped})
+		return nil, nil
+	case SymlinkKeep:
+		if _, err := os.Stat(source); err != nil {
+			opts.warning(&SymlinkError{Path: source, Target: target, Err: ErrSymlinkDangling})
+		}
+		return nil, copySymlink(target, dest, opts)
+	}
+
+	info, err := os.Stat(source)
+	if err != nil {
+		return nil, &SymlinkError{Path: source, Target: target, Err: ErrSymlinkDangling}
+	}
+	if info.IsDir() {
+		if opts.Symlinks == SymlinkFiles {
+			opts.warning(&SymlinkError{Path: source, Target: target, Err: ErrSymlinkSkipped})
+			return nil, nil
+		}
+		resolved, err := filepath.EvalSymlinks(source)
+		if err != nil {
+			return nil, err
+		}
+		for _, parent := range parents {
+			if resolved == parent {
+				opts.warning(&SymlinkError{Path: source, Target: target, Err: ErrSymlinkCycle})
+				return nil, nil
+			}
+		}
+	}
+
+	return info, nil
+}
+
+// copySymlink creates dest as a link to target, an existing dest is kept with CopySkip
+func copySymlink(target, dest string, opts CopyDirOpts) error {
+	if _, err := os.Lstat(dest); err == nil {
+		if opts.Existing == CopySkip {
+			return nil
+		}
+		if err := os.RemoveAll(dest); err != nil {
+			return err
+		}
+	}
+
+	return os.Symlink(target, dest)
+}
+
+// copyEntry copies file source to dest through buf, dest is kept with CopySkip, or with CopySync
+// when it's in sync with source, else it's removed first so a read only file is replaced too. It
+// reports whether the file was copied
+func copyEntry(source, dest string, opts CopyDirOpts, buf []byte) (bool, error) {
+	if di, err := os.Lstat(dest); err == nil {
+		switch opts.Existing {
+		case CopySkip:
+			return false, nil
+		case CopySync:
+			si, err := os.Stat(source)
 			if err != nil {
-				log.Println(err)
+				return false, err
+			}
+			if synced, err := inSync(source, dest, si, di); synced || err != nil {
+				return false, opts.warn(err)
 			}
 		}
+		if err := os.Remove(dest); err != nil {
+			return false, err
+		}
+	}
+
+	si, err := copyFile(source, dest, buf)
+	if err != nil {
+		return false, err
+	}
+	if opts.ResetModTimes {
+		return true, nil
+	}
+
+	return true, opts.warn(keepModTime(dest, si))
+}
 
+// warn passes a ModTimeError to Warn and returns nil for it, other errors are returned
+func (opts CopyDirOpts) warn(err error) error {
+	var timeErr *ModTimeError
+	if !errors.As(err, &timeErr) {
+		return err
 	}
-	return
+
+	opts.warning(err)
+	return nil
+}
+
+// warning passes err to Warn when it's set
+func (opts CopyDirOpts) warning(err error) {
+	if opts.Warn != nil {
+		opts.Warn(err)
+	}
+}
+
+// makeDir creates directory dest, writable until the copy sets the permissions of its source. It
+// reports whether dest gets them: when it's created, or when it exists with CopyOverwrite or
+// CopySync
+func makeDir(dest string, opts CopyDirOpts) (bool, error) {
+	if _, err := os.Stat(dest); err == nil {
+		return opts.Existing == CopyOverwrite || opts.Existing == CopySync, nil
+	}
+
+	return true, EnsureDir(dest, DefaultDirMode)
 }
 
-// A struct for returning custom error messages
+// CustomError is an error of a message only.
+//
+// Deprecated: utils returns a PathError with a sentinel error, eg: ErrNotDirectory
 type CustomError struct {
 	What string
 }
 
-// Returns the error message defined in What as a string
 func (e *CustomError) Error() string {
 	return e.What
 }
@@ -0,0 +1,87 @@
+package utils
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// Walk is filepath.Walk which, with follow, also walks the directories symlinks point to. The
+// walk of a linked directory has the paths under the link, eg: content/media/a.jpg. A directory
+// link left out, or one to a directory it's in, is passed to fn with the link info and a
+// SymlinkError of ErrSymlinkSkipped or ErrSymlinkCycle, and not walked. File links are passed
+// as files, as filepath.Walk does
+func Walk(root string, follow bool, fn filepath.WalkFunc) error {
+	info, err := os.Stat(root)
+	if err != nil {
+		err = fn(root, nil, err)
+	} else {
+		err = walk(root, info, follow, nil, fn)
+	}
+
+	return skipDir(err)
+}
+
+// walk walks path, parents are the resolved paths of the directories it's in. A SkipDir of a
+// directory is handled here, the one of a file is returned to skip the rest of its directory
+func walk(path string, info os.FileInfo, follow bool, parents []string, fn filepath.WalkFunc) error {
+	if info.Mode()&os.ModeSymlink != 0 {
+		target, err := os.Stat(path)
+		if err != nil || !target.IsDir() {
+			return fn(path, info, nil)
+		}
+
+		link, _ := os.Readlink(path)
+		if !follow {
+			return skipDir(fn(path, info, &SymlinkError{Path: path, Target: link, Err: ErrSymlinkSkipped}))
+		}
+		if resolved, err := filepath.EvalSymlinks(path); err == nil && contains(parents, resolved) {
+			return skipDir(fn(path, info, &SymlinkError{Path: path, Target: link, Err: ErrSymlinkCycle}))
+		}
+		info = target
+	}
+
+	if !info.IsDir() {
+		return fn(path, info, nil)
+	}
+
+	if err := fn(path, info, nil); err != nil {
+		return skipDir(err)
+	}
+
+	entries, err := ioutil.ReadDir(path)
+	if err != nil {
+		return skipDir(fn(path, info, err))
+	}
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		parents = append(parents, resolved)
+	}
+
+	for _, entry := range entries {
+		if err := walk(filepath.Join(path, entry.Name()), entry, follow, parents, fn); err != nil {
+			return skipDir(err)
+		}
+	}
+
+	return nil
+}
+
+// skipDir returns err, nil for filepath.SkipDir
+func skipDir(err error) error {
+	if err == filepath.SkipDir {
+		return nil
+	}
+
+	return err
+}
+
+// contains is true when list has s
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+
+	return false
+}
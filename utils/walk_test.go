@@ -0,0 +1,73 @@
+package utils_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/yeo/baja/utils"
+)
+
+var _ = Describe("Walk", func() {
+	var dir string
+
+	// walked returns the files walked under dir and the symlink errors
+	walked := func(follow bool) ([]string, []error) {
+		files, errs := []string{}, []error{}
+		err := Walk(filepath.Join(dir, "site"), follow, func(path string, info os.FileInfo, err error) error {
+			rel, _ := filepath.Rel(dir, path)
+			if err != nil {
+				errs = append(errs, err)
+			} else if !info.IsDir() {
+				files = append(files, filepath.ToSlash(rel))
+			}
+			return nil
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		return files, errs
+	}
+
+	BeforeEach(func() {
+		dir, _ = ioutil.TempDir("", "baja-walk")
+		Expect(os.MkdirAll(filepath.Join(dir, "media", "photos"), 0755)).To(Succeed())
+		Expect(os.MkdirAll(filepath.Join(dir, "site"), 0755)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(dir, "media", "photos", "a.jpg"), []byte("a"), 0644)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(dir, "site", "index.md"), []byte("index"), 0644)).To(Succeed())
+		Expect(os.Symlink(filepath.Join(dir, "media"), filepath.Join(dir, "site", "media"))).To(Succeed())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("walks a symlinked directory under the link with follow", func() {
+		files, errs := walked(true)
+		Expect(errs).To(BeEmpty())
+		Expect(files).To(Equal([]string{"site/index.md", "site/media/photos/a.jpg"}))
+	})
+
+	It("skips a symlinked directory by default", func() {
+		files, errs := walked(false)
+		Expect(files).To(Equal([]string{"site/index.md"}))
+		Expect(errs).To(HaveLen(1))
+		Expect(errors.Is(errs[0], ErrSymlinkSkipped)).To(BeTrue())
+	})
+
+	It("reports and skips a symlink cycle", func() {
+		Expect(os.Symlink("..", filepath.Join(dir, "media", "photos", "loop"))).To(Succeed())
+
+		files, errs := walked(true)
+		Expect(files).To(Equal([]string{"site/index.md", "site/media/photos/a.jpg"}))
+		Expect(errs).To(HaveLen(1))
+		Expect(errors.Is(errs[0], ErrSymlinkCycle)).To(BeTrue())
+
+		var linkErr *SymlinkError
+		Expect(errors.As(errs[0], &linkErr)).To(BeTrue())
+		Expect(linkErr.Path).To(Equal(filepath.Join(dir, "site", "media", "photos", "loop")))
+	})
+})
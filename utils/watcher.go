@@ -1,8 +1,8 @@
 package utils
 
 import (
-	"github.com/fatih/color"
 	"github.com/radovskyb/watcher"
+	"github.com/rs/zerolog/log"
 )
 
 func Watch(dir []string) *watcher.Watcher {
@@ -11,9 +11,9 @@ func Watch(dir []string) *watcher.Watcher {
 	w.SetMaxEvents(1)
 
 	for _, d := range dir {
-		color.Yellow("Watch to build %s", d)
+		log.Info().Str("path", d).Msg("Watch to build")
 		if err := w.AddRecursive(d); err != nil {
-			color.Red("Cannot watch %v", err)
+			log.Error().Err(err).Str("path", d).Msg("Cannot watch")
 		}
 	}
 
@@ -0,0 +1,302 @@
+package baja
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// ValidationErrors are all the problems of a config, so they can be fixed in one pass
+type ValidationErrors []error
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = "  - " + err.Error()
+	}
+
+	return fmt.Sprintf("%d problems:\n%s", len(e), strings.Join(messages, "\n"))
+}
+
+// envNameRe matches the name of an environment variable, eg: COMMIT_SHA
+var envNameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ValidateWorkDir checks dir, the value of config key, can be a directory baja removes: it must
+// not be the site root or one of its ancestors, nor be, contain or be inside public, content,
+// the static directory, themes or .baja
+func (c *Config) ValidateWorkDir(key, dir string) error {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return fmt.Errorf("invalid %s %q: %w", key, dir, err)
+	}
+	root, err := filepath.Abs(".")
+	if err != nil {
+		return err
+	}
+
+	if abs == root || isUnder(root, abs) {
+		return fmt.Errorf("invalid %s %q: it's the site directory or one of its parents, baja removes it", key, dir)
+	}
+
+	for _, protected := range []string{"public", "content", c.StaticPath(), "themes", ".baja"} {
+		if overlaps(abs, filepath.Join(root, protected)) {
+			return fmt.Errorf("invalid %s %q: it overlaps %s, baja removes it", key, dir, protected)
+		}
+	}
+
+	return nil
+}
+
+// overlaps reports whether dir is abs, or is inside or contains it
+func overlaps(abs, dir string) bool {
+	dir, err := filepath.Abs(dir)
+	return err == nil && (dir == abs || isUnder(dir, abs) || isUnder(abs, dir))
+}
+
+// isUnder reports whether path is inside dir, both absolute
+func isUnder(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// ValidateTimezone checks that name is an IANA time zone, eg: Europe/Paris
+func ValidateTimezone(name string) error {
+	if _, err := time.LoadLocation(name); err != nil || name == "Local" {
+		return fmt.Errorf("unknown timezone %q, must be an IANA name such as Asia/Ho_Chi_Minh or UTC", name)
+	}
+
+	return nil
+}
+
+// Validate checks the settings a build relies on, before anything is rendered. Problems are
+// returned together as ValidationErrors wrapped in a *ConfigError, nil when config is valid
+func (c *Config) Validate() error {
+	var errs ValidationErrors
+
+	unset := append([]UnsetVariable{}, c.unset...)
+	sort.Slice(unset, func(i, j int) bool {
+		if unset[i].Key != unset[j].Key {
+			return unset[i].Key < unset[j].Key
+		}
+		return unset[i].Name < unset[j].Name
+	})
+	for _, v := range unset {
+		errs = append(errs, fmt.Errorf("environment variable %s of %s is not set, set it or use ${%s:-default}", v.Name, v.Key, v.Name))
+	}
+
+	if c.Theme == "" {
+		errs = append(errs, fmt.Errorf("theme is not set, set it to a directory of themes"))
+	} else if info, err := os.Stat(filepath.Join("themes", c.Theme)); err != nil || info.IsDir() == IsThemeArchive(c.Theme) {
+		errs = append(errs, fmt.Errorf("theme %q not found, create themes/%s or fix theme", c.Theme, c.Theme))
+	}
+
+	if c.BaseURL != "" {
+		if err := ValidateBaseURL(c.BaseURL); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if c.AssetBaseURL != "" {
+		if err := ValidateAssetBaseURL(c.AssetBaseURL); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if c.Language != "" {
+		if err := ValidateLanguage(c.Language); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if err := ValidateLanguages(c.DefaultLanguage, c.Languages); err != nil {
+		errs = append(errs, err.(ValidationErrors)...)
+	}
+
+	if c.Timezone != "" {
+		if err := ValidateTimezone(c.Timezone); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if err := ValidateSanitize(c.Sanitize); err != nil {
+		errs = append(errs, err)
+	}
+
+	if err := ValidateMarkup(c.Markup); err != nil {
+		errs = append(errs, err.(ValidationErrors)...)
+	}
+
+	for pattern, name := range c.Encodings {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			errs = append(errs, fmt.Errorf("invalid encodings pattern %q: %w", pattern, err))
+		}
+		if _, err := htmlindex.Get(name); err != nil {
+			errs = append(errs, fmt.Errorf("unknown encoding %q of %s, eg: latin1 or shift_jis", name, pattern))
+		}
+	}
+
+	if c.StaticDir != "" {
+		if info, err := os.Stat(c.StaticDir); err != nil || !info.IsDir() {
+			errs = append(errs, fmt.Errorf("staticDir %q is not a directory", c.StaticDir))
+		}
+	}
+
+	if c.Archive != "" {
+		if _, err := ArchiveFormat(c.Archive); err != nil {
+			errs = append(errs, fmt.Errorf("invalid archive: %w", err))
+		}
+	}
+
+	for _, pattern := range c.PruneProtect {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			errs = append(errs, fmt.Errorf("invalid pruneProtect pattern %q: %w", pattern, err))
+		}
+	}
+
+	for _, name := range c.Env {
+		if !envNameRe.MatchString(name) {
+			errs = append(errs, fmt.Errorf("invalid env name %q: must be letters, digits and underscores, eg: COMMIT_SHA", name))
+		}
+	}
+
+	for _, pattern := range c.StaticIgnore {
+		if _, err := path.Match(pattern, ""); err != nil {
+			errs = append(errs, fmt.Errorf("invalid staticIgnore pattern %q: %w", pattern, err))
+		}
+	}
+
+	switch c.Resources {
+	case "", ResourcesAll, ResourcesReferenced:
+	default:
+		errs = append(errs, fmt.Errorf("invalid resources %q: must be %s or %s", c.Resources, ResourcesAll, ResourcesReferenced))
+	}
+
+	if c.RecentPosts < 0 {
+		errs = append(errs, fmt.Errorf("invalid recentPosts %d: must be a number of nodes", c.RecentPosts))
+	}
+
+	if c.RelatedPosts < 0 {
+		errs = append(errs, fmt.Errorf("invalid relatedPosts %d: must be a number of nodes", c.RelatedPosts))
+	}
+	for _, by := range c.RelatedBy {
+		if by != RelatedByTags && by != RelatedBySection {
+			errs = append(errs, fmt.Errorf("invalid relatedBy %q: must be %s or %s", by, RelatedByTags, RelatedBySection))
+		}
+	}
+
+	if c.SummaryLength < 0 {
+		errs = append(errs, fmt.Errorf("invalid summaryLength %d: must be a positive length", c.SummaryLength))
+	}
+	switch c.SummaryUnit {
+	case "", SummaryWords, SummaryRunes:
+	default:
+		errs = append(errs, fmt.Errorf("invalid summaryUnit %q: must be %s or %s", c.SummaryUnit, SummaryWords, SummaryRunes))
+	}
+
+	if c.Paginate < 0 {
+		errs = append(errs, fmt.Errorf("invalid paginate %d: must be a number of nodes, 0 for one page", c.Paginate))
+	}
+	for dir, size := range c.SectionPaginate {
+		if size < 0 {
+			errs = append(errs, fmt.Errorf("invalid sectionPaginate %d of %s: must be a number of nodes, 0 for one page", size, dir))
+		}
+	}
+
+	if err := c.ValidateWorkDir("previewDir", c.PreviewPath()); err != nil {
+		errs = append(errs, err)
+	}
+	if err := c.ValidateWorkDir("cacheDir", c.CachePath()); err != nil {
+		errs = append(errs, err)
+	} else if preview, _ := filepath.Abs(c.PreviewPath()); overlaps(preview, c.CachePath()) {
+		errs = append(errs, fmt.Errorf("invalid cacheDir %q: it overlaps previewDir %q", c.CacheDir, c.PreviewPath()))
+	}
+
+	if c.CacheMaxSize < -1 {
+		errs = append(errs, fmt.Errorf("invalid cacheMaxSize %d: must be a size in bytes, or -1 for no limit", c.CacheMaxSize))
+	}
+
+	if c.MaxWorkers < 0 {
+		errs = append(errs, fmt.Errorf("invalid maxWorkers %d: must be a number of files, or 0 for the number of CPUs", c.MaxWorkers))
+	}
+
+	if c.MaxContentSize < 0 {
+		errs = append(errs, fmt.Errorf("invalid maxContentSize %d: must be a size in bytes, or 0 for no limit", c.MaxContentSize))
+	}
+
+	if c.RenderTimeout != "" {
+		if d, err := time.ParseDuration(c.RenderTimeout); err != nil || d < 0 {
+			errs = append(errs, fmt.Errorf("invalid renderTimeout %q: must be a duration such as 10s", c.RenderTimeout))
+		}
+	}
+
+	switch c.OutputPaths {
+	case "", OutputPathsUnicode, OutputPathsASCII:
+	default:
+		errs = append(errs, fmt.Errorf("invalid outputPaths %q: must be %s or %s", c.OutputPaths, OutputPathsUnicode, OutputPathsASCII))
+	}
+
+	switch c.TrailingSlash {
+	case "", TrailingSlashRedirect, TrailingSlashServe:
+	default:
+		errs = append(errs, fmt.Errorf("invalid trailingSlash %q: must be %s or %s", c.TrailingSlash, TrailingSlashRedirect, TrailingSlashServe))
+	}
+
+	switch c.DuplicateSlugs {
+	case "", DuplicateSlugsSuffix, DuplicateSlugsError:
+	default:
+		errs = append(errs, fmt.Errorf("invalid duplicateSlugs %q: must be %s or %s", c.DuplicateSlugs, DuplicateSlugsSuffix, DuplicateSlugsError))
+	}
+
+	if err := ValidateTaxonomies(c); err != nil {
+		errs = append(errs, err.(ValidationErrors)...)
+	}
+
+	if err := ValidateMenus(c.Menus); err != nil {
+		errs = append(errs, err.(ValidationErrors)...)
+	}
+
+	if err := ValidateOutputs(c.Outputs, c.CustomFormats); err != nil {
+		errs = append(errs, err.(ValidationErrors)...)
+	}
+
+	if err := ValidateDateFormats(c.DateFormats); err != nil {
+		errs = append(errs, err.(ValidationErrors)...)
+	}
+
+	if err := ValidateSocial(c.Social); err != nil {
+		errs = append(errs, err.(ValidationErrors)...)
+	}
+
+	if err := ValidateMounts(c.Mounts); err != nil {
+		errs = append(errs, err.(ValidationErrors)...)
+	}
+
+	if err := ValidateDefaults(c.Defaults); err != nil {
+		errs = append(errs, err.(ValidationErrors)...)
+	}
+
+	if _, err := CompileIgnore(c.IgnoreFiles); err != nil {
+		errs = append(errs, err.(ValidationErrors)...)
+	}
+
+	if _, err := CompilePermalinks(c.Permalinks); err != nil {
+		errs = append(errs, err.(ValidationErrors)...)
+	}
+
+	if err := ValidateLog(c.LogLevel, c.LogFormat); err != nil {
+		errs = append(errs, err)
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return &ConfigError{c.path, errs}
+}